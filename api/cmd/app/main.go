@@ -2,22 +2,34 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/redis"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/database/postgres"
+	grpcserver "github.com/Pshimaf-Git/url-shortener/api/internal/grpc-server/server"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/grpc-server/shortener"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
 	mwlogger "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/logger"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/ratelimiter"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/pages"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/server"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/logger/zaphandler"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/domains"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/healthcheck"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/metadata"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/reputation"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/trash"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
@@ -44,6 +56,13 @@ func main() {
 		}
 	}()
 
+	// run subcommand (e.g. `migrate up`) instead of the server
+	if args := flag.Args(); len(args) > 0 {
+		code := runCommand(ctx, cfg, logger, args)
+		_ = sync()
+		os.Exit(code)
+	}
+
 	// init database
 	db, err := postgres.New(ctx, &cfg.Postgres, postgres.WithConfig(&cfg.Postgres.Options))
 	if err != nil {
@@ -57,6 +76,22 @@ func main() {
 		}
 	}()
 
+	// apply migrations
+	if cfg.Postgres.AutoMigrate {
+		migrator, err := db.Migrator()
+		if err != nil {
+			logger.Error("failed to load migrations", sl.Error(err))
+			return // handle error appropriately
+		}
+
+		if err := migrator.Up(ctx); err != nil {
+			logger.Error("failed to apply migrations", sl.Error(err))
+			return // handle error appropriately
+		}
+
+		logger.Info("database schema is up to date")
+	}
+
 	// init cache
 	cache, err := redis.New(ctx, &cfg.Redis)
 	if err != nil {
@@ -70,8 +105,75 @@ func main() {
 		}
 	}()
 
+	// init destination policy
+	destPolicy, err := policy.Load(policyConfig(&cfg.Policy), nil)
+	if err != nil {
+		logger.Error("failed to load destination policy", sl.Error(err))
+		return // handle error appropriately
+	}
+
+	// init domains, each with its own aliases
+	registry, err := domains.Load(domainsConfig(&cfg.Domains), db, logger)
+	if err != nil {
+		logger.Error("failed to load domains", sl.Error(err))
+		return // handle error appropriately
+	}
+
 	// init handler
 	handler := handlers.New(db, cache, &cfg.Server, logger)
+	if len(cfg.Server.APIKeys) == 0 {
+		logger.Warn("no api keys configured, admin endpoints reject every request")
+	}
+	// links to the short links of any served domain would loop
+	handler.UsePolicy(destPolicy.WithSelfHosts(registry.Serves))
+	// click-limited links are counted in redis, so instances share the limit
+	handler.UseClickCounter(cache)
+
+	if cfg.Reputation.Enabled {
+		checker := reputation.Load(&cfg.Reputation, nil, logger)
+		handler.UseReputation(checker, cfg.Reputation.CheckOnRedirect)
+	}
+
+	handler.UseDomains(registry)
+
+	// init the pages shown to visitors, templates of the config replace
+	// the embedded ones
+	visitorPages, err := pages.Load(cfg.Server.TemplatesDir)
+	if err != nil {
+		logger.Error("failed to load templates", sl.Error(err))
+		return // handle error appropriately
+	}
+	handler.UsePages(visitorPages)
+
+	// init metadata enrichment, pages are fetched in the background
+	if cfg.Metadata.Enabled {
+		enricher := metadata.Load(&cfg.Metadata, db, logger)
+		// cached links are read again once their page is described
+		enricher.UseEvictor(handler.Service())
+		handler.UseEnricher(enricher)
+
+		enrichCtx, stopEnrich := context.WithCancel(context.Background())
+		defer stopEnrich()
+
+		go enricher.Run(enrichCtx)
+	}
+
+	// start purging the trash, deleted links can be restored until then
+	purger := trash.Load(&cfg.Trash, db, logger)
+	handler.UseRetention(purger.Retention())
+
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+
+	go purger.Run(purgeCtx)
+
+	// init rate limiter, the counters are shared through redis
+	limiter, err := ratelimiter.NewLimiter(cfg.Server.RateLimitStore, cache.Client(), logger)
+	if err != nil {
+		logger.Error("failed to initialize rate limiter", sl.Error(err))
+		return // handle error appropriately
+	}
+	handler.UseLimiter(limiter)
 
 	// middlewares
 	middlewares := []func(http.Handler) http.Handler{
@@ -79,7 +181,6 @@ func main() {
 		middleware.RealIP,
 		middleware.RequestID,
 		mwlogger.New(logger),
-		ratelimiter.New(cfg.Server.RequesLimit, cfg.Server.WindowLength, handler.NewLimit()),
 	}
 
 	// init router
@@ -88,6 +189,40 @@ func main() {
 	// init server
 	server := server.NewWithConfig(&cfg.Server, router)
 
+	// start link health checks, disabled links are evicted from the cache
+	if cfg.Health.Enabled {
+		checker := healthcheck.Load(&cfg.Health, db, handler.Service(), logger)
+
+		checkCtx, stopChecks := context.WithCancel(context.Background())
+		defer stopChecks()
+
+		go checker.Run(checkCtx)
+	}
+
+	// start gRPC server, disabled without a port
+	if cfg.GRPC.Port != "" {
+		grpcServer := grpcserver.New(&cfg.GRPC, shortener.New(handler.Service(), logger), logger,
+			grpcserver.WithAPIKeys(cfg.Server.APIKeys),
+			grpcserver.WithLimits(limiter, cfg.Server.GroupLimits()),
+			grpcserver.WithDomains(registry),
+		)
+
+		go func() {
+			logger.Info("starting grpc server", slog.String("address", grpcServer.Addr()))
+
+			if err := grpcServer.Run(); err != nil {
+				logger.Error("failed to start grpc server", sl.Error(err))
+			}
+		}()
+
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), grpcShutdownTimeout)
+			defer cancel()
+
+			grpcServer.Shutdown(ctx)
+		}()
+	}
+
 	// Signals
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
@@ -101,6 +236,8 @@ func main() {
 	}
 }
 
+const grpcShutdownTimeout = 10 * time.Second
+
 func level(cfg *config.LoggerConfig) slog.Level {
 	lvl, err := cfg.LevelFromString()
 	if err != nil {
@@ -113,3 +250,27 @@ func level(cfg *config.LoggerConfig) slog.Level {
 func address(cfg *config.ServerConfig) string {
 	return net.JoinHostPort(cfg.Host, cfg.Port)
 }
+
+func policyConfig(cfg *config.PolicyConfig) policy.Config {
+	return policy.Config{
+		MaxURLLength:  cfg.MaxURLLength,
+		Resolve:       cfg.Resolve,
+		DenyListFile:  cfg.DenyListFile,
+		AllowListFile: cfg.AllowListFile,
+		SelfHosts:     cfg.SelfHosts,
+		Shorteners:    cfg.Shorteners,
+	}
+}
+
+func domainsConfig(cfg *config.DomainsConfig) domains.Config {
+	list := make([]database.Domain, 0, len(cfg.Domains))
+	for _, d := range cfg.Domains {
+		list = append(list, database.Domain{
+			Name:        d.Name,
+			AliasLength: d.AliasLength,
+			NotFoundURL: d.NotFoundURL,
+		})
+	}
+
+	return domains.Config{Domains: list, Refresh: cfg.Refresh}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/postgres"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const migrateUsage = `usage: app [-config_path path] migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [N]    roll back the last N migrations (default 1)
+  to N        migrate up or down to version N (0 rolls back everything)
+  status      list migrations and whether they are applied`
+
+// runCommand executes a subcommand given on the command line and returns
+// the process exit code.
+func runCommand(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) int {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(ctx, cfg, logger, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", args[0], migrateUsage)
+		return exitUsage
+	}
+}
+
+func runMigrate(ctx context.Context, cfg *config.Config, logger *slog.Logger, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return exitUsage
+	}
+
+	db, err := postgres.New(ctx, &cfg.Postgres, postgres.WithConfig(&cfg.Postgres.Options))
+	if err != nil {
+		logger.Error("failed to initialize database", sl.Error(err))
+		return exitError
+	}
+
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Error("failed to close database connection", sl.Error(err))
+		}
+	}()
+
+	m, err := db.Migrator()
+	if err != nil {
+		logger.Error("failed to load migrations", sl.Error(err))
+		return exitError
+	}
+
+	switch args[0] {
+	case "up":
+		err = m.Up(ctx)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				fmt.Fprintf(os.Stderr, "invalid number of steps %q\n", args[1])
+				return exitUsage
+			}
+		}
+		err = m.Down(ctx, steps)
+
+	case "to":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return exitUsage
+		}
+
+		version, perr := strconv.ParseUint(args[1], 10, 64)
+		if perr != nil {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return exitUsage
+		}
+		err = m.To(ctx, version)
+
+	case "status":
+		var statuses []postgres.MigrationStatus
+		statuses, err = m.Status(ctx)
+		if err == nil {
+			printStatus(statuses)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n\n%s\n", args[0], migrateUsage)
+		return exitUsage
+	}
+
+	if err != nil {
+		logger.Error("migrate "+args[0], sl.Error(err))
+		return exitError
+	}
+
+	return exitOK
+}
+
+func printStatus(statuses []postgres.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, s := range statuses {
+		status := "pending"
+		if s.Applied {
+			status = "applied"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, status)
+	}
+
+	_ = w.Flush()
+}
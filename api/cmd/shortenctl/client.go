@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/linkio"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+)
+
+const (
+	urlPath    = "/api/v1/url"
+	infoPath   = "/api/v1/url/info"
+	listPath   = "/api/v1/urls"
+	statsPath  = "/api/v1/stats"
+	importPath = "/api/v1/import"
+	exportPath = "/api/v1/export"
+)
+
+// knownErrors are matched by message when the server sends no error code,
+// as servers before error codes did.
+var knownErrors = []error{
+	apierr.ErrURLNotFound,
+	apierr.ErrEmptyAlias,
+	apierr.ErrEmptyURL,
+	apierr.ErrInternalServer,
+	apierr.ErrAliasExist,
+	apierr.ErrInvalidURLFormat,
+	apierr.ErrTooManyRequests,
+	apierr.ErrCanNotGenAlias,
+	apierr.ErrInvalidPage,
+	apierr.ErrUnauthorized,
+	apierr.ErrUnknownFormat,
+	apierr.ErrUnknownImportMode,
+	apierr.ErrMalformedImport,
+	apierr.ErrInvalidImport,
+}
+
+// apiError is an error response of the server. It unwraps to the matching
+// handler error, so callers can use errors.Is.
+type apiError struct {
+	Status int
+	Code   string
+	Msg    string
+	err    error
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("server responded %d: %s", e.Status, e.Msg)
+}
+
+func (e *apiError) Unwrap() error { return e.err }
+
+// unavailableError means the request did not reach the server.
+type unavailableError struct{ err error }
+
+func (e *unavailableError) Error() string { return "server unavailable: " + e.err.Error() }
+func (e *unavailableError) Unwrap() error { return e.err }
+
+type client struct {
+	base   string
+	apiKey string
+	http   *http.Client
+	// transfer has no timeout, imports and exports take as long as the
+	// data needs. They are still cancelled with the context.
+	transfer *http.Client
+}
+
+func newClient(s *settings) *client {
+	// the redirect endpoint answers with 302, which is the result itself
+	noRedirect := func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &client{
+		base:     s.Server,
+		apiKey:   s.APIKey,
+		http:     &http.Client{Timeout: s.Timeout, CheckRedirect: noRedirect},
+		transfer: &http.Client{CheckRedirect: noRedirect},
+	}
+}
+
+func (c *client) create(ctx context.Context, longURL, alias string) (string, error) {
+	var out handlers.Responce
+
+	err := c.do(ctx, http.MethodPost, urlPath, nil, handlers.Request{URL: longURL, Alias: alias}, &out)
+	if err != nil {
+		return "", err
+	}
+
+	return out.Alias, nil
+}
+
+func (c *client) get(ctx context.Context, alias string) (database.Link, error) {
+	var out handlers.LinkResponce
+
+	err := c.do(ctx, http.MethodGet, infoPath, url.Values{"alias": {alias}}, nil, &out)
+	return out.Link, err
+}
+
+func (c *client) delete(ctx context.Context, alias string) error {
+	return c.do(ctx, http.MethodDelete, urlPath, url.Values{"alias": {alias}}, nil, nil)
+}
+
+func (c *client) list(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	var out handlers.ListResponce
+
+	query := url.Values{
+		"limit":  {strconv.Itoa(limit)},
+		"offset": {strconv.Itoa(offset)},
+	}
+
+	err := c.do(ctx, http.MethodGet, listPath, query, nil, &out)
+	return out.URLs, err
+}
+
+func (c *client) stats(ctx context.Context) (database.Stats, error) {
+	var out handlers.StatsResponce
+
+	err := c.do(ctx, http.MethodGet, statsPath, nil, nil, &out)
+	return out.Stats, err
+}
+
+// importLinks uploads a file in the given format to the import endpoint.
+func (c *client) importLinks(ctx context.Context, r io.Reader, format, mode string) (handlers.ImportResponce, error) {
+	var out handlers.ImportResponce
+
+	query := url.Values{"format": {format}, "mode": {mode}}
+
+	res, err := c.roundTrip(ctx, c.transfer, http.MethodPost, importPath, query, linkio.ContentType(linkio.Format(format)), r)
+	if err != nil {
+		return out, err
+	}
+	defer res.Body.Close()
+
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil && res.StatusCode < http.StatusBadRequest {
+		return out, err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return out, newAPIError(res.StatusCode, out.Response)
+	}
+
+	return out, nil
+}
+
+// export copies every link in the given format to w.
+func (c *client) export(ctx context.Context, format string, w io.Writer) error {
+	res, err := c.roundTrip(ctx, c.transfer, http.MethodGet, exportPath, url.Values{"format": {format}}, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return decodeError(res)
+	}
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// shortURL returns the address that redirects to the link.
+func (c *client) shortURL(alias string) string {
+	return c.base + urlPath + "?" + url.Values{"alias": {alias}}.Encode()
+}
+
+func (c *client) do(ctx context.Context, method, path string, query url.Values, in any, out any) error {
+	var (
+		body        io.Reader
+		contentType string
+	)
+
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body, contentType = bytes.NewReader(buf), "application/json"
+	}
+
+	res, err := c.roundTrip(ctx, c.http, method, path, query, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return decodeError(res)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func (c *client) roundTrip(ctx context.Context, hc *http.Client, method, path string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set(apikey.Header, c.apiKey)
+	}
+
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, &unavailableError{err: err}
+	}
+
+	return res, nil
+}
+
+func decodeError(res *http.Response) error {
+	var body resp.Response
+
+	raw, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return newAPIError(res.StatusCode, resp.Response{})
+	}
+
+	return newAPIError(res.StatusCode, body)
+}
+
+func newAPIError(status int, body resp.Response) *apiError {
+	e := &apiError{Status: status, Code: body.Code, Msg: body.Error}
+	if e.Msg == "" {
+		e.Msg = http.StatusText(status)
+	}
+
+	e.err = apierr.ByCode(body.Code)
+	if e.err == nil && body.Error != "" {
+		for _, known := range knownErrors {
+			if known.Error() == body.Error {
+				e.err = known
+				break
+			}
+		}
+	}
+
+	if e.err == nil && status == http.StatusTooManyRequests {
+		e.err = apierr.ErrTooManyRequests
+	}
+
+	return e
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/linkio"
+)
+
+// listPageSize is the page size used when every link is fetched.
+const listPageSize = 1000
+
+const (
+	listUsage   = "list [-limit N] [-offset N] [-all]"
+	importUsage = "import [-format F] [-mode skip|overwrite|fail] <file|->"
+	exportUsage = "export [-format F] [file]"
+)
+
+var errUsage = errors.New("invalid usage")
+
+// env is what every command runs with.
+type env struct {
+	client *client
+	output string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	usage string
+	help  string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"create": {"create <url> [alias]", "shorten a url, the alias is generated when omitted", runCreate},
+	"get":    {"get <alias>", "show a link", runGet},
+	"delete": {"delete <alias>...", "delete links", runDelete},
+	"list":   {listUsage, "list links ordered by id", runList},
+	"stats":  {"stats", "show service-wide statistics", runStats},
+	"import": {importUsage, "create links from a csv, ndjson, bitly or yourls file", runImport},
+	"export": {exportUsage, "write every link to a file or stdout", runExport},
+}
+
+var commandOrder = []string{"create", "get", "delete", "list", "stats", "import", "export"}
+
+func usageError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
+
+func newFlagSet(name, usage string, e *env) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: shortenctl %s\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the command flags. The flag package has already printed
+// the problem, so the returned error only carries the exit code.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %s", errUsage, err)
+	}
+	return nil
+}
+
+func runCreate(ctx context.Context, e *env, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return usageError("create expects <url> [alias]")
+	}
+
+	var alias string
+	if len(args) == 2 {
+		alias = args[1]
+	}
+
+	alias, err := e.client.create(ctx, args[0], alias)
+	if err != nil {
+		return err
+	}
+
+	short := e.client.shortURL(alias)
+
+	return render(e.stdout, e.output, table{
+		header: []string{"alias", "url", "short_url"},
+		rows:   [][]string{{alias, args[0], short}},
+		value: map[string]string{
+			"alias":     alias,
+			"url":       args[0],
+			"short_url": short,
+		},
+	})
+}
+
+func runGet(ctx context.Context, e *env, args []string) error {
+	if len(args) != 1 {
+		return usageError("get expects <alias>")
+	}
+
+	link, err := e.client.get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	return render(e.stdout, e.output, table{
+		header: linkHeader,
+		rows:   [][]string{linkRow(link)},
+		value:  link,
+	})
+}
+
+// runDelete deletes every alias and returns the first error, so one missing
+// alias does not stop the others from being deleted.
+func runDelete(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		return usageError("delete expects at least one alias")
+	}
+
+	var first error
+	for _, alias := range args {
+		if err := e.client.delete(ctx, alias); err != nil {
+			fmt.Fprintf(e.stderr, "delete %s: %v\n", alias, err)
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		fmt.Fprintf(e.stderr, "deleted %s\n", alias)
+	}
+
+	return first
+}
+
+func runList(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("list", listUsage, e)
+	limit := fs.Int("limit", 100, "maximum number of links")
+	offset := fs.Int("offset", 0, "number of links to skip")
+	all := fs.Bool("all", false, "fetch every link, page by page")
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var (
+		links []database.Link
+		err   error
+	)
+
+	if *all {
+		err = eachLink(ctx, e.client, func(l database.Link) error {
+			links = append(links, l)
+			return nil
+		})
+	} else {
+		links, err = e.client.list(ctx, *limit, *offset)
+	}
+	if err != nil {
+		return err
+	}
+
+	return render(e.stdout, e.output, linksTable(links))
+}
+
+func runStats(ctx context.Context, e *env, args []string) error {
+	if len(args) != 0 {
+		return usageError("stats takes no arguments")
+	}
+
+	stats, err := e.client.stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	var last string
+	if stats.LastCreatedAt != nil {
+		last = formatTime(*stats.LastCreatedAt)
+	}
+
+	return render(e.stdout, e.output, table{
+		header: []string{"total", "created_last_24h", "last_created_at"},
+		rows: [][]string{{
+			strconv.FormatInt(stats.Total, 10),
+			strconv.FormatInt(stats.CreatedLast24, 10),
+			last,
+		}},
+		value: stats,
+	})
+}
+
+func runImport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("import", importUsage, e)
+	format := fs.String("format", string(linkio.FormatCSV), "input format: "+formatNames())
+	mode := fs.String("mode", string(database.ConflictSkip), "existing aliases: skip, overwrite or fail")
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return usageError("import expects a file or -")
+	}
+
+	if _, err := linkio.ParseFormat(*format); err != nil {
+		return usageError("unknown format %q", *format)
+	}
+
+	if _, err := database.ParseConflictMode(*mode); err != nil {
+		return usageError("unknown mode %q", *mode)
+	}
+
+	in := e.stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	out, err := e.client.importLinks(ctx, in, *format, *mode)
+
+	for _, ie := range out.Errors {
+		fmt.Fprintf(e.stderr, "line %d: %s: %s\n", ie.Line, ie.Alias, ie.Error)
+	}
+	if n := out.Invalid - int64(len(out.Errors)); n > 0 {
+		fmt.Fprintf(e.stderr, "... and %d more invalid links\n", n)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return render(e.stdout, e.output, table{
+		header: []string{"created", "updated", "skipped", "invalid"},
+		rows: [][]string{{
+			strconv.FormatInt(out.Result.Created, 10),
+			strconv.FormatInt(out.Result.Updated, 10),
+			strconv.FormatInt(out.Result.Skipped, 10),
+			strconv.FormatInt(out.Invalid, 10),
+		}},
+		value: struct {
+			database.ImportResult
+			Invalid int64 `json:"invalid"`
+		}{out.Result, out.Invalid},
+	})
+}
+
+func runExport(ctx context.Context, e *env, args []string) error {
+	fs := newFlagSet("export", exportUsage, e)
+	format := fs.String("format", string(linkio.FormatCSV), "output format: "+formatNames())
+
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 1 {
+		return usageError("export expects at most one file")
+	}
+
+	if _, err := linkio.ParseFormat(*format); err != nil {
+		return usageError("unknown format %q", *format)
+	}
+
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		return e.client.export(ctx, *format, e.stdout)
+	}
+
+	f, err := os.Create(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if err := e.client.export(ctx, *format, f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(linkio.Formats))
+	for _, f := range linkio.Formats {
+		names = append(names, string(f))
+	}
+	return strings.Join(names, ", ")
+}
+
+// eachLink calls fn for every link, fetching them page by page.
+func eachLink(ctx context.Context, c *client, fn func(database.Link) error) error {
+	for offset := 0; ; offset += listPageSize {
+		links, err := c.list(ctx, listPageSize, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, l := range links {
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+
+		if len(links) < listPageSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+const configEnv = "SHORTENCTL_CONFIG"
+
+// settings are resolved from flags, then the environment, then the config
+// file and finally the defaults.
+type settings struct {
+	Server  string        `yaml:"server"  env:"SHORTENCTL_SERVER"  env-default:"http://localhost:8000"`
+	APIKey  string        `yaml:"api_key" env:"SHORTENCTL_API_KEY"`
+	Output  string        `yaml:"output"  env:"SHORTENCTL_OUTPUT"  env-default:"table"`
+	Timeout time.Duration `yaml:"timeout" env:"SHORTENCTL_TIMEOUT" env-default:"30s"`
+}
+
+type globalFlags struct {
+	config  string
+	server  string
+	apiKey  string
+	output  string
+	timeout time.Duration
+}
+
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.config, "config", "", "path to config file (env "+configEnv+")")
+	fs.StringVar(&g.server, "server", "", "base URL of the shortener API (env SHORTENCTL_SERVER)")
+	fs.StringVar(&g.apiKey, "api-key", "", "API key for admin endpoints (env SHORTENCTL_API_KEY)")
+	fs.StringVar(&g.output, "o", "", "output format: table, json or csv (env SHORTENCTL_OUTPUT)")
+	fs.DurationVar(&g.timeout, "timeout", 0, "request timeout (env SHORTENCTL_TIMEOUT)")
+}
+
+func loadSettings(fs *flag.FlagSet, g *globalFlags) (*settings, error) {
+	var s settings
+
+	path := g.config
+	if path == "" {
+		path = os.Getenv(configEnv)
+	}
+	if path == "" {
+		path = defaultConfigPath()
+	}
+
+	var err error
+	if path != "" {
+		err = cleanenv.ReadConfig(path, &s)
+	} else {
+		err = cleanenv.ReadEnv(&s)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "server":
+			s.Server = g.server
+		case "api-key":
+			s.APIKey = g.apiKey
+		case "o":
+			s.Output = g.output
+		case "timeout":
+			s.Timeout = g.timeout
+		}
+	})
+
+	s.Server = strings.TrimRight(strings.TrimSpace(s.Server), "/")
+	if s.Server == "" {
+		return nil, errors.New("server URL must not be empty")
+	}
+
+	switch s.Output {
+	case outputTable, outputJSON, outputCSV:
+	default:
+		return nil, errors.New("unknown output format " + s.Output)
+	}
+
+	return &s, nil
+}
+
+// defaultConfigPath returns `<user config dir>/shortenctl/config.yaml` when
+// the file exists.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	path := filepath.Join(dir, "shortenctl", "config.yaml")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+
+	return path
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+)
+
+// Exit codes are part of the CLI contract, scripts may rely on them.
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitNotFound     = 3
+	exitAliasExist   = 4
+	exitInvalid      = 5
+	exitRateLimited  = 6
+	exitUnauthorized = 7
+	exitServer       = 8
+	exitUnavailable  = 9
+)
+
+// exitCode maps an error returned by a command to the process exit code.
+func exitCode(err error) int {
+	var (
+		unavailable *unavailableError
+		apiErr      *apiError
+	)
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, apierr.ErrURLNotFound):
+		return exitNotFound
+	case errors.Is(err, apierr.ErrAliasExist):
+		return exitAliasExist
+	case errors.Is(err, apierr.ErrEmptyAlias),
+		errors.Is(err, apierr.ErrEmptyURL),
+		errors.Is(err, apierr.ErrInvalidURLFormat),
+		errors.Is(err, apierr.ErrInvalidPage),
+		errors.Is(err, apierr.ErrUnknownFormat),
+		errors.Is(err, apierr.ErrUnknownImportMode),
+		errors.Is(err, apierr.ErrMalformedImport),
+		errors.Is(err, apierr.ErrInvalidImport):
+		return exitInvalid
+	case errors.Is(err, apierr.ErrTooManyRequests):
+		return exitRateLimited
+	case errors.Is(err, apierr.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, apierr.ErrInternalServer),
+		errors.Is(err, apierr.ErrCanNotGenAlias):
+		return exitServer
+	case errors.As(err, &unavailable):
+		return exitUnavailable
+	case errors.As(err, &apiErr) && apiErr.Status >= 500:
+		return exitServer
+	default:
+		return exitError
+	}
+}
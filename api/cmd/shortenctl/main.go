@@ -0,0 +1,90 @@
+// Command shortenctl manages short links through the HTTP API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("shortenctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { printUsage(fs, stderr) }
+
+	var g globalFlags
+	g.register(fs)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		printUsage(fs, stderr)
+		return exitUsage
+	}
+
+	name := fs.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+		printUsage(fs, stderr)
+		return exitUsage
+	}
+
+	s, err := loadSettings(fs, &g)
+	if err != nil {
+		fmt.Fprintf(stderr, "shortenctl: %v\n", err)
+		return exitUsage
+	}
+
+	e := &env{
+		client: newClient(s),
+		output: s.Output,
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+
+	if err := cmd.run(ctx, e, fs.Args()[1:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "shortenctl %s: %v\n", name, err)
+		}
+		return exitCode(err)
+	}
+
+	return exitOK
+}
+
+func printUsage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintf(w, "usage: shortenctl [flags] <command> [args]\n\ncommands:\n")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, name := range commandOrder {
+		cmd := commands[name]
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.usage, cmd.help)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintf(w, "\nflags:\n")
+	fs.PrintDefaults()
+}
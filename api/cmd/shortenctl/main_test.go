@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "secret"
+
+// fakeServer answers like the real API for a fixed set of links.
+func fakeServer(t *testing.T, links map[string]string) *httptest.Server {
+	t.Helper()
+
+	writeJSON := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /api/v1/url", func(w http.ResponseWriter, r *http.Request) {
+		var req handlers.Request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		if _, ok := links[req.Alias]; ok {
+			writeJSON(w, http.StatusBadRequest, resp.ErrorCode(apierr.CodeAliasTaken, apierr.ErrAliasExist))
+			return
+		}
+
+		links[req.Alias] = req.URL
+		writeJSON(w, http.StatusCreated, handlers.Responce{Response: resp.OK(), Alias: req.Alias})
+	})
+
+	mux.HandleFunc("GET /api/v1/url/info", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(apikey.Header) != testKey {
+			writeJSON(w, http.StatusUnauthorized, resp.Error(apierr.ErrUnauthorized))
+			return
+		}
+
+		alias := r.URL.Query().Get("alias")
+		u, ok := links[alias]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, resp.Error(apierr.ErrURLNotFound))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, handlers.LinkResponce{
+			Response: resp.OK(),
+			Link:     database.Link{ID: 1, Alias: alias, URL: u},
+		})
+	})
+
+	// import accepts csv with alias,url rows and reports existing aliases
+	// according to the mode
+	mux.HandleFunc("POST /api/v1/import", func(w http.ResponseWriter, r *http.Request) {
+		records, err := csv.NewReader(r.Body).ReadAll()
+		require.NoError(t, err)
+
+		var out handlers.ImportResponce
+		for _, record := range records[1:] {
+			if _, ok := links[record[0]]; !ok {
+				links[record[0]] = record[1]
+				out.Result.Created++
+				continue
+			}
+
+			if r.URL.Query().Get("mode") == "fail" {
+				writeJSON(w, http.StatusBadRequest, handlers.ImportResponce{Response: resp.ErrorCode(apierr.CodeAliasTaken, apierr.ErrAliasExist)})
+				return
+			}
+			out.Result.Skipped++
+		}
+
+		out.Response = resp.OK()
+		writeJSON(w, http.StatusOK, out)
+	})
+
+	mux.HandleFunc("GET /api/v1/export", func(w http.ResponseWriter, r *http.Request) {
+		for alias, u := range links {
+			_ = json.NewEncoder(w).Encode(database.Link{Alias: alias, URL: u})
+		}
+	})
+
+	mux.HandleFunc("GET /api/v1/urls", func(w http.ResponseWriter, r *http.Request) {
+		var out []database.Link
+		if r.URL.Query().Get("offset") == "0" {
+			for alias, u := range links {
+				out = append(out, database.Link{Alias: alias, URL: u})
+			}
+		}
+
+		writeJSON(w, http.StatusOK, handlers.ListResponce{Response: resp.OK(), URLs: out})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestRun(t *testing.T) {
+	srv := fakeServer(t, map[string]string{"google": "https://google.com"})
+
+	testCases := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+	}{
+		{
+			name:       "get",
+			args:       []string{"-api-key", testKey, "-o", "csv", "get", "google"},
+			wantCode:   exitOK,
+			wantStdout: "id,alias,url,created_at,updated_at\n1,google,https://google.com,,\n",
+		},
+		{
+			name:     "get not found",
+			args:     []string{"-api-key", testKey, "get", "unknown"},
+			wantCode: exitNotFound,
+		},
+		{
+			name:     "get without api key",
+			args:     []string{"get", "google"},
+			wantCode: exitUnauthorized,
+		},
+		{
+			name:     "create existing alias",
+			args:     []string{"create", "https://example.com", "google"},
+			wantCode: exitAliasExist,
+		},
+		{
+			name:     "import skips existing aliases",
+			args:     []string{"-o", "json", "import", "-mode", "skip", "-"},
+			stdin:    "alias,url\ngoogle,https://google.com\nyandex,https://ya.ru\n",
+			wantCode: exitOK,
+			wantStdout: `{
+  "created": 1,
+  "updated": 0,
+  "skipped": 1,
+  "invalid": 0
+}
+`,
+		},
+		{
+			name:     "import fails on existing aliases",
+			args:     []string{"import", "-mode", "fail", "-"},
+			stdin:    "alias,url\ngoogle,https://google.com\n",
+			wantCode: exitAliasExist,
+		},
+		{
+			name:     "import with unknown format",
+			args:     []string{"import", "-format", "xml", "-"},
+			wantCode: exitUsage,
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"rename"},
+			wantCode: exitUsage,
+		},
+		{
+			name:     "missing arguments",
+			args:     []string{"get"},
+			wantCode: exitUsage,
+		},
+		{
+			name:     "unknown output",
+			args:     []string{"-o", "xml", "stats"},
+			wantCode: exitUsage,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(configEnv, "")
+			t.Setenv("SHORTENCTL_SERVER", srv.URL)
+
+			var stdout, stderr bytes.Buffer
+
+			code := run(context.Background(), tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+			assert.Equal(t, tt.wantCode, code, stderr.String())
+			if tt.wantStdout != "" {
+				assert.Equal(t, tt.wantStdout, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRun_Export(t *testing.T) {
+	srv := fakeServer(t, map[string]string{"google": "https://google.com"})
+
+	t.Setenv(configEnv, "")
+
+	var stdout, stderr bytes.Buffer
+
+	code := run(context.Background(), []string{"-server", srv.URL, "export", "-format", "ndjson"}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+
+	var link database.Link
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &link))
+	assert.Equal(t, "google", link.Alias)
+	assert.Equal(t, "https://google.com", link.URL)
+}
+
+func TestExitCode_Unavailable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	t.Setenv(configEnv, "")
+
+	var stdout, stderr bytes.Buffer
+
+	code := run(context.Background(), []string{"-server", srv.URL, "stats"}, nil, &stdout, &stderr)
+	assert.Equal(t, exitUnavailable, code, stderr.String())
+}
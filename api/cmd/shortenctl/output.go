@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// table is a command result that can be printed in every output format.
+// value is used for json, header and rows for table and csv.
+type table struct {
+	header []string
+	rows   [][]string
+	value  any
+}
+
+func render(w io.Writer, format string, t table) error {
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(t.value)
+
+	case outputCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(t.header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(t.rows); err != nil {
+			return err
+		}
+		return cw.Error()
+
+	default:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+var linkHeader = []string{"id", "alias", "url", "created_at", "updated_at"}
+
+func linkRow(l database.Link) []string {
+	return []string{
+		strconv.FormatInt(l.ID, 10),
+		l.Alias,
+		l.URL,
+		formatTime(l.CreatedAt),
+		formatTime(l.UpdatedAt),
+	}
+}
+
+func linksTable(links []database.Link) table {
+	rows := make([][]string, 0, len(links))
+	for _, l := range links {
+		rows = append(rows, linkRow(l))
+	}
+
+	if links == nil {
+		links = []database.Link{}
+	}
+
+	return table{header: linkHeader, rows: rows, value: links}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,113 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/redis/go-redis/v9"
+)
+
+// clicksPrefix namespaces the click counters of links.
+const clicksPrefix = "clicks:"
+
+// click checks and counts a redirect in one step, so concurrent redirects
+// never count past the limit.
+//
+// KEYS[1] counter
+// ARGV[1] limit
+//
+// It returns {counted, clicks}, {-1, 0} when there is no counter.
+var click = redis.NewScript(`
+local clicks = redis.call('GET', KEYS[1])
+if not clicks then
+	return {-1, 0}
+end
+
+clicks = tonumber(clicks)
+if clicks >= tonumber(ARGV[1]) then
+	return {0, clicks}
+end
+
+return {1, redis.call('INCR', KEYS[1])}
+`)
+
+// Click counts a redirect of the link cached under key unless limit
+// redirects were counted. Counters do not expire, they are the count of
+// every instance.
+func (r *redisClient) Click(ctx context.Context, key string, limit int64) (int64, bool, error) {
+	const fn = "cache.redis.(*redisClient).Click"
+
+	wp := wraper.New(fn)
+
+	if isEmpty(key) {
+		return 0, false, wp.Wrap(cache.ErrEmptyKey)
+	}
+
+	res, err := click.Run(ctx, r.rdb, []string{clicksPrefix + key}, limit).Int64Slice()
+	if err != nil {
+		return 0, false, wp.Wrapf(err, "key=%s", key)
+	}
+
+	if res[0] < 0 {
+		return 0, false, wp.Wrapf(cache.ErrKeyNotExist, "key=%s", key)
+	}
+
+	return res[1], res[0] == 1, nil
+}
+
+// Count returns the redirects counted for the link cached under key.
+func (r *redisClient) Count(ctx context.Context, key string) (int64, error) {
+	const fn = "cache.redis.(*redisClient).Count"
+
+	wp := wraper.New(fn)
+
+	if isEmpty(key) {
+		return 0, wp.Wrap(cache.ErrEmptyKey)
+	}
+
+	clicks, err := r.rdb.Get(ctx, clicksPrefix+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, wp.Wrapf(cache.ErrKeyNotExist, "key=%s", key)
+	}
+	if err != nil {
+		return 0, wp.Wrapf(err, "key=%s", key)
+	}
+
+	return clicks, nil
+}
+
+// Seed starts the counter of key at clicks unless it has one.
+func (r *redisClient) Seed(ctx context.Context, key string, clicks int64) error {
+	const fn = "cache.redis.(*redisClient).Seed"
+
+	wp := wraper.New(fn)
+
+	if isEmpty(key) {
+		return wp.Wrap(cache.ErrEmptyKey)
+	}
+
+	if err := r.rdb.SetNX(ctx, clicksPrefix+key, clicks, 0).Err(); err != nil {
+		return wp.Wrapf(err, "key=%s", key)
+	}
+
+	return nil
+}
+
+// Reset drops the counter of key.
+func (r *redisClient) Reset(ctx context.Context, key string) error {
+	const fn = "cache.redis.(*redisClient).Reset"
+
+	wp := wraper.New(fn)
+
+	if isEmpty(key) {
+		return wp.Wrap(cache.ErrEmptyKey)
+	}
+
+	if err := r.rdb.Del(ctx, clicksPrefix+key).Err(); err != nil {
+		return wp.Wrapf(err, "key=%s", key)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClick(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	ctx := context.Background()
+
+	t.Run("no counter", func(t *testing.T) {
+		_, _, err := client.Click(ctx, "report", 3)
+		assert.ErrorIs(t, err, cache.ErrKeyNotExist)
+
+		_, err = client.Count(ctx, "report")
+		assert.ErrorIs(t, err, cache.ErrKeyNotExist)
+	})
+
+	t.Run("counts up to the limit", func(t *testing.T) {
+		require.NoError(t, client.Seed(ctx, "report", 1))
+
+		clicks, ok, err := client.Click(ctx, "report", 3)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.EqualValues(t, 2, clicks)
+
+		clicks, ok, err = client.Click(ctx, "report", 3)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.EqualValues(t, 3, clicks)
+
+		clicks, ok, err = client.Click(ctx, "report", 3)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.EqualValues(t, 3, clicks)
+
+		clicks, err = client.Count(ctx, "report")
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, clicks)
+	})
+
+	t.Run("seed keeps a counter", func(t *testing.T) {
+		require.NoError(t, client.Seed(ctx, "report", 0))
+
+		got, err := mr.Get(clicksPrefix + "report")
+		require.NoError(t, err)
+		assert.Equal(t, "3", got)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		require.NoError(t, client.Reset(ctx, "report"))
+		assert.False(t, mr.Exists(clicksPrefix+"report"))
+
+		// resetting a missing counter is fine
+		require.NoError(t, client.Reset(ctx, "report"))
+	})
+
+	t.Run("concurrent clicks", func(t *testing.T) {
+		require.NoError(t, client.Seed(ctx, "download", 0))
+
+		var (
+			wg      sync.WaitGroup
+			counted atomic.Int64
+		)
+		for range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, ok, err := client.Click(ctx, "download", 10)
+				assert.NoError(t, err)
+				if ok {
+					counted.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.EqualValues(t, 10, counted.Load())
+	})
+
+	t.Run("empty key", func(t *testing.T) {
+		_, _, err := client.Click(ctx, "", 1)
+		assert.ErrorIs(t, err, cache.ErrEmptyKey)
+		_, err = client.Count(ctx, "")
+		assert.ErrorIs(t, err, cache.ErrEmptyKey)
+		assert.ErrorIs(t, client.Seed(ctx, " ", 0), cache.ErrEmptyKey)
+		assert.ErrorIs(t, client.Reset(ctx, ""), cache.ErrEmptyKey)
+	})
+}
+
+func TestClick_AliasNamingCounter(t *testing.T) {
+	client, mr := setupTestRedis(t)
+	defer mr.Close()
+	defer client.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+
+	report := database.Target{URL: "https://files.example.com/report.pdf", Options: database.Options{MaxClicks: 1}}
+
+	db := mocks.NewMockDatabase(ctrl)
+	db.EXPECT().SaveTarget(gomock.Any(), "report", gomock.Any()).Return(nil)
+	// the store lags behind the counter
+	db.EXPECT().GetTarget(gomock.Any(), "report").Return(report, nil).AnyTimes()
+	db.EXPECT().RecordClicks(gomock.Any(), "report", int64(1)).Return(nil).AnyTimes()
+	db.EXPECT().SaveURL(gomock.Any(), "https://example.com", "clicks:report").Return(nil)
+	db.EXPECT().DeleteURL(gomock.Any(), "clicks:report").Return(int64(1), nil)
+
+	svc := shortener.New(db, client, shortener.Config{}, nil).WithClickCounter(client)
+
+	_, err := svc.ShortenWith(ctx, report.URL, "report", report.Options, "")
+	require.NoError(t, err)
+
+	_, err = svc.Visit(ctx, "report", nil)
+	require.NoError(t, err)
+
+	// an alias named like the counter of report neither replaces nor
+	// resets it
+	_, err = svc.Shorten(ctx, "https://example.com", "clicks:report")
+	require.NoError(t, err)
+	require.NoError(t, svc.Delete(ctx, "clicks:report"))
+
+	_, err = svc.Visit(ctx, "report", nil)
+	assert.ErrorIs(t, err, shortener.ErrLinkExhausted)
+}
@@ -156,6 +156,12 @@ func (r *redisClient) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+// Client returns the underlying connection, to share it with other Redis
+// users such as the rate limiter.
+func (r *redisClient) Client() *redis.Client {
+	return r.rdb
+}
+
 // Close terminates the Redis connection
 func (r *redisClient) Close() error {
 	const fn = "cache.redis.(*redisClient).Close"
@@ -18,11 +18,18 @@ const (
 )
 
 type Config struct {
-	Env      string          `yaml:"env"  env:"ENV"`
-	Server   ServerConfig    `yaml:"server"`
-	Logger   LoggerConfig    `yaml:"logger"`
-	Postgres PostreSQLConfig `yaml:"postgres"`
-	Redis    RedisCongig     `yaml:"redis"`
+	Env        string            `yaml:"env"  env:"ENV"`
+	Server     ServerConfig      `yaml:"server"`
+	GRPC       GRPCConfig        `yaml:"grpc"`
+	Logger     LoggerConfig      `yaml:"logger"`
+	Postgres   PostreSQLConfig   `yaml:"postgres"`
+	Redis      RedisCongig       `yaml:"redis"`
+	Policy     PolicyConfig      `yaml:"policy"`
+	Reputation ReputationConfig  `yaml:"reputation"`
+	Health     HealthCheckConfig `yaml:"health_check"`
+	Trash      TrashConfig       `yaml:"trash"`
+	Metadata   MetadataConfig    `yaml:"metadata"`
+	Domains    DomainsConfig     `yaml:"domains"`
 }
 
 type ServerConfig struct {
@@ -32,18 +39,264 @@ type ServerConfig struct {
 	IdleTimeout  time.Duration `yaml:"idle_timeout"  env:"SERVER_IDLE_TIMEOUT"  env-default:"10m"`
 	ReadTimeout  time.Duration `yaml:"read_timeout"  env:"SERVER_READ_TIMEOUT"  env-default:"5m"`
 	WriteTimeout time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" env-default:"5m"`
+	// RequesLimit per WindowLength limits the routes outside the route
+	// groups of RateLimits and the groups that are not configured, zero
+	// meaning no limit.
 	RequesLimit  int           `yaml:"request_limit" env:"SERVER_REQUEST_LIMIT" env-default:"100"`
 	WindowLength time.Duration `yaml:"window_length" env:"SERVER_WINDOW_LENGTH" env-default:"1m"`
+	APIKeys      APIKeys       `yaml:"api_keys" env:"SERVER_API_KEYS"`
+
+	// RateLimitStore is where request counters are kept: redis, shared by
+	// all instances, or local.
+	RateLimitStore string `yaml:"rate_limit_store" env:"SERVER_RATE_LIMIT_STORE" env-default:"redis"`
+
+	// RateLimits are the limits of the route groups.
+	RateLimits RateLimits `yaml:"rate_limits"`
+
+	Passwords PasswordsConfig `yaml:"passwords"`
+
+	// CountryHeader is the header the proxy in front of the server tells
+	// the country of visitors in, for redirect rules. Clients can send it
+	// themselves unless the proxy overwrites it.
+	CountryHeader string `yaml:"country_header" env:"SERVER_COUNTRY_HEADER" env-default:"CF-IPCountry"`
+
+	// TemplatesDir holds templates replacing the embedded pages shown to
+	// visitors, e.g. preview.html.
+	TemplatesDir string `yaml:"templates_dir" env:"SERVER_TEMPLATES_DIR"`
+
+	NotActive NotActiveConfig `yaml:"not_active"`
+}
+
+// NotActiveConfig configures the answer to visits of links before their
+// active window starts.
+type NotActiveConfig struct {
+	// Status of the answer, 404 when zero.
+	Status int `yaml:"status" env:"SERVER_NOT_ACTIVE_STATUS" env-default:"404"`
+	// URL visitors are redirected to instead, e.g. a coming soon page.
+	URL string `yaml:"url" env:"SERVER_NOT_ACTIVE_URL"`
+}
+
+// PasswordsConfig configures password-protected links. Unlocked links
+// are visited with a signed cookie until it expires.
+type PasswordsConfig struct {
+	// CookieSecret signs the cookies. Without it a random secret is used,
+	// cookies then only work on the instance that issued them until it
+	// restarts.
+	CookieSecret string        `env:"SERVER_PASSWORD_COOKIE_SECRET"`
+	CookieTTL    time.Duration `yaml:"cookie_ttl" env:"SERVER_PASSWORD_COOKIE_TTL" env-default:"15m"`
+	// LinkAttempts and IPAttempts limit the passwords tried per
+	// AttemptWindow for one link and from one IP, zero meaning no limit.
+	LinkAttempts  int           `yaml:"link_attempts" env:"SERVER_PASSWORD_LINK_ATTEMPTS" env-default:"20"`
+	IPAttempts    int           `yaml:"ip_attempts" env:"SERVER_PASSWORD_IP_ATTEMPTS" env-default:"5"`
+	AttemptWindow time.Duration `yaml:"attempt_window" env:"SERVER_PASSWORD_ATTEMPT_WINDOW" env-default:"15m"`
+}
+
+// Rate limited route groups.
+const (
+	RouteRedirects = "redirects"
+	RouteWrites    = "writes"
+	RouteAdmin     = "admin"
+)
+
+// Client tiers. Clients without an API key, or with a key without a tier,
+// belong to the default tier.
+const (
+	TierFree     = "free"
+	TierPartner  = "partner"
+	TierInternal = "internal"
+)
+
+// What the requests of a route group are counted by.
+const (
+	KeyByIP     = "ip"
+	KeyByAPIKey = "api_key"
+)
+
+// RateLimits configures the limits per route group and tier.
+type RateLimits struct {
+	DefaultTier string                    `yaml:"default_tier" env:"SERVER_RATE_LIMIT_DEFAULT_TIER" env-default:"free"`
+	Groups      map[string]RateLimitGroup `yaml:"groups"`
+
+	// Fallback limits every tier of the groups without tiers, see
+	// ServerConfig.GroupLimits.
+	Fallback RateLimit `yaml:"-"`
+}
+
+// GroupLimits returns the limits of the route groups, the groups that are
+// not configured limited by RequesLimit per WindowLength like before they
+// had limits of their own.
+func (c *ServerConfig) GroupLimits() RateLimits {
+	limits := c.RateLimits
+	limits.Fallback = RateLimit{Requests: c.RequesLimit, Window: c.WindowLength}
+	return limits
+}
+
+// RateLimitGroup limits the requests of one route group. Requests are
+// counted by IP, or by API key for clients sending one when By is api_key.
+type RateLimitGroup struct {
+	By    string               `yaml:"by"`
+	Tiers map[string]RateLimit `yaml:"tiers"`
+}
+
+// RateLimit allows Requests per Window, zero requests meaning no limit.
+// With Burst set the requests are counted by a token bucket of Burst
+// tokens refilled at Requests per Window, so a client may use up to Burst
+// requests at once.
+type RateLimit struct {
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+	Burst    int           `yaml:"burst"`
+}
+
+// GRPCConfig configures the gRPC server, which is disabled without a port.
+type GRPCConfig struct {
+	Host       string `yaml:"host" env:"GRPC_HOST"`
+	Port       string `yaml:"port" env:"GRPC_PORT"`
+	Reflection bool   `yaml:"reflection" env:"GRPC_REFLECTION" env-default:"true"`
+}
+
+// PolicyConfig decides which destinations links may point to. Links to
+// private addresses are always rejected.
+type PolicyConfig struct {
+	MaxURLLength int `yaml:"max_url_length" env:"POLICY_MAX_URL_LENGTH" env-default:"2048"`
+	// Resolve checks the addresses host names resolve to as well.
+	Resolve       bool   `yaml:"resolve" env:"POLICY_RESOLVE" env-default:"false"`
+	DenyListFile  string `yaml:"deny_list_file" env:"POLICY_DENY_LIST_FILE"`
+	AllowListFile string `yaml:"allow_list_file" env:"POLICY_ALLOW_LIST_FILE"`
+	// SelfHosts are the domains the shortener is served on.
+	SelfHosts []string `yaml:"self_hosts" env:"POLICY_SELF_HOSTS"`
+	// Shorteners are other shorteners, a built-in list when empty.
+	Shorteners []string `yaml:"shorteners" env:"POLICY_SHORTENERS"`
+}
+
+// ReputationConfig configures the check of urls against a Safe Browsing
+// Lookup API, disabled without Enabled.
+type ReputationConfig struct {
+	Enabled     bool          `yaml:"enabled" env:"REPUTATION_ENABLED" env-default:"false"`
+	Endpoint    string        `yaml:"endpoint" env:"REPUTATION_ENDPOINT" env-default:"https://safebrowsing.googleapis.com"`
+	APIKey      string        `env:"REPUTATION_API_KEY"`
+	ThreatTypes []string      `yaml:"threat_types" env:"REPUTATION_THREAT_TYPES"`
+	Timeout     time.Duration `yaml:"timeout" env:"REPUTATION_TIMEOUT" env-default:"2s"`
+	CacheTTL    time.Duration `yaml:"cache_ttl" env:"REPUTATION_CACHE_TTL" env-default:"30m"`
+	// FailOpen accepts urls while the API is unavailable, otherwise they
+	// are rejected.
+	FailOpen bool `yaml:"fail_open" env:"REPUTATION_FAIL_OPEN" env-default:"true"`
+	// CheckOnRedirect checks urls again before redirecting to them.
+	CheckOnRedirect bool `yaml:"check_on_redirect" env:"REPUTATION_CHECK_ON_REDIRECT" env-default:"false"`
+}
+
+// HealthCheckConfig configures the background check of link
+// destinations.
+type HealthCheckConfig struct {
+	Enabled bool `yaml:"enabled" env:"HEALTH_CHECK_ENABLED" env-default:"false"`
+	// Interval is how often a batch of links is checked.
+	Interval time.Duration `yaml:"interval" env:"HEALTH_CHECK_INTERVAL" env-default:"1m"`
+	// Recheck is how long a checked link is left alone.
+	Recheck     time.Duration `yaml:"recheck" env:"HEALTH_CHECK_RECHECK" env-default:"24h"`
+	BatchSize   int           `yaml:"batch_size" env:"HEALTH_CHECK_BATCH_SIZE" env-default:"100"`
+	Concurrency int           `yaml:"concurrency" env:"HEALTH_CHECK_CONCURRENCY" env-default:"8"`
+	// HostDelay is the least time between two requests to the same host.
+	HostDelay time.Duration `yaml:"host_delay" env:"HEALTH_CHECK_HOST_DELAY" env-default:"1s"`
+	Timeout   time.Duration `yaml:"timeout" env:"HEALTH_CHECK_TIMEOUT" env-default:"10s"`
+	// DisableAfter disables links failing that many checks in a row,
+	// never when zero.
+	DisableAfter int    `yaml:"disable_after" env:"HEALTH_CHECK_DISABLE_AFTER" env-default:"0"`
+	UserAgent    string `yaml:"user_agent" env:"HEALTH_CHECK_USER_AGENT" env-default:"url-shortener-healthcheck/1.0"`
+}
+
+// TrashConfig configures how long deleted links can be restored before
+// they are purged.
+type TrashConfig struct {
+	Retention time.Duration `yaml:"retention" env:"TRASH_RETENTION" env-default:"720h"`
+	// PurgeInterval is how often links past the retention are purged.
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"TRASH_PURGE_INTERVAL" env-default:"1h"`
+	BatchSize     int           `yaml:"batch_size" env:"TRASH_BATCH_SIZE" env-default:"1000"`
+}
+
+// MetadataConfig configures fetching the title and the Open Graph tags of
+// the pages links point to.
+type MetadataConfig struct {
+	Enabled bool          `yaml:"enabled" env:"METADATA_ENABLED" env-default:"false"`
+	Timeout time.Duration `yaml:"timeout" env:"METADATA_TIMEOUT" env-default:"5s"`
+	// MaxBodySize is the most bytes read from a page.
+	MaxBodySize int64  `yaml:"max_body_size" env:"METADATA_MAX_BODY_SIZE" env-default:"524288"`
+	UserAgent   string `yaml:"user_agent" env:"METADATA_USER_AGENT" env-default:"url-shortener-metadata/1.0"`
+	Workers     int    `yaml:"workers" env:"METADATA_WORKERS" env-default:"2"`
+	// QueueSize is how many links wait for a fetch before new ones are
+	// dropped.
+	QueueSize int `yaml:"queue_size" env:"METADATA_QUEUE_SIZE" env-default:"1000"`
+}
+
+// DomainsConfig lists the hosts links are served on besides the default
+// one. More domains can be registered through the API.
+type DomainsConfig struct {
+	Domains []Domain `yaml:"list"`
+	// Refresh is how long domains registered through the API are cached.
+	Refresh time.Duration `yaml:"refresh" env:"DOMAINS_REFRESH" env-default:"30s"`
+}
+
+// Domain is a host with its own aliases. AliasLength falls back to
+// server.std_alias_len when zero; requests for unknown aliases are
+// redirected to NotFoundURL when set.
+type Domain struct {
+	Name        string `yaml:"name"`
+	AliasLength int    `yaml:"alias_length"`
+	NotFoundURL string `yaml:"not_found_url"`
+}
+
+// APIKey is a named key accepted by the admin endpoints. Tier selects the
+// rate limits of its requests.
+type APIKey struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+	Tier string `yaml:"tier"`
+}
+
+type APIKeys []APIKey
+
+var ErrBadAPIKey = errors.New("api key must look like name:key or name:key:tier")
+
+// SetValue parses keys from an environment variable in the form
+// `name:key,name2:key2:tier`.
+func (keys *APIKeys) SetValue(s string) error {
+	parsed := make(APIKeys, 0)
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, key, ok := strings.Cut(pair, ":")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(key) == "" {
+			return wraper.Wrap("APIKeys.SetValue", ErrBadAPIKey)
+		}
+
+		key, tier, _ := strings.Cut(key, ":")
+		if strings.TrimSpace(key) == "" {
+			return wraper.Wrap("APIKeys.SetValue", ErrBadAPIKey)
+		}
+
+		parsed = append(parsed, APIKey{
+			Name: strings.TrimSpace(name),
+			Key:  strings.TrimSpace(key),
+			Tier: strings.TrimSpace(tier),
+		})
+	}
+
+	*keys = parsed
+	return nil
 }
 
 type PostreSQLConfig struct {
-	Host     string                   `yaml:"host" env:"POSTGRES_HOST" env-default:"localhost"`
-	Name     string                   `yaml:"name" env:"POSTGRES_DB" env-default:"postgres"`
-	User     string                   `yaml:"user" env:"POSTGRES_USER" env-default:"postgres"`
-	Port     string                   `yaml:"port" env:"POSTGRES_PORT" env-default:"5432"`
-	SSLMode  string                   `yaml:"ssl_mode" env:"POSTGRES_SSLMODE" env-default:"enable"`
-	Password string                   `env:"POSTGRES_PASSWORD"`
-	Options  OptionalPostgreSQLConfig `yaml:"options"`
+	Host        string                   `yaml:"host" env:"POSTGRES_HOST" env-default:"localhost"`
+	Name        string                   `yaml:"name" env:"POSTGRES_DB" env-default:"postgres"`
+	User        string                   `yaml:"user" env:"POSTGRES_USER" env-default:"postgres"`
+	Port        string                   `yaml:"port" env:"POSTGRES_PORT" env-default:"5432"`
+	SSLMode     string                   `yaml:"ssl_mode" env:"POSTGRES_SSLMODE" env-default:"enable"`
+	Password    string                   `env:"POSTGRES_PASSWORD"`
+	AutoMigrate bool                     `yaml:"auto_migrate" env:"POSTGRES_AUTO_MIGRATE" env-default:"false"`
+	Options     OptionalPostgreSQLConfig `yaml:"options"`
 }
 
 type OptionalPostgreSQLConfig struct {
@@ -198,3 +198,61 @@ func TestLoad(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIKeys_SetValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    APIKeys
+		wantErr bool
+	}{
+		{
+			name:  "single key",
+			value: "ops:secret",
+			want:  APIKeys{{Name: "ops", Key: "secret"}},
+		},
+		{
+			name:  "several keys with spaces",
+			value: " ops:secret , ci:token,",
+			want:  APIKeys{{Name: "ops", Key: "secret"}, {Name: "ci", Key: "token"}},
+		},
+		{
+			name:  "with tier",
+			value: "partner:token:partner",
+			want:  APIKeys{{Name: "partner", Key: "token", Tier: TierPartner}},
+		},
+		{
+			name:  "empty value",
+			value: "",
+			want:  APIKeys{},
+		},
+		{
+			name:    "without name",
+			value:   "secret",
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			value:   "ops:",
+			wantErr: true,
+		},
+		{
+			name:    "empty key with tier",
+			value:   "ops::free",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys APIKeys
+			err := keys.SetValue(tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrBadAPIKey)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, keys)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// AuditAction is what a change did to a link.
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+)
+
+func ParseAuditAction(s string) (AuditAction, error) {
+	switch action := AuditAction(s); action {
+	case AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge:
+		return action, nil
+	default:
+		return "", ErrUnknownAuditAction
+	}
+}
+
+// Actor is who changes links. Changes of background jobs have none.
+type Actor struct {
+	// APIKey is the name of the key the request carried.
+	APIKey    string `json:"api_key,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx recording the changes made with it as
+// done by a.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFrom returns the actor of ctx, the zero Actor without one.
+func ActorFrom(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	return a
+}
+
+// AuditEvent is a recorded change of a link. Before is nil for created
+// links, After for purged ones.
+type AuditEvent struct {
+	ID     int64       `json:"id"`
+	Action AuditAction `json:"action"`
+	Alias  string      `json:"alias"`
+	// Domain is the namespace of the alias, empty for DefaultDomain.
+	Domain    string    `json:"domain,omitempty"`
+	Actor     Actor     `json:"actor"`
+	Before    *Link     `json:"before,omitempty"`
+	After     *Link     `json:"after,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditFilter selects audit events, every set field has to match. The
+// alias is looked up in the domain of the context.
+type AuditFilter struct {
+	Alias     string
+	Action    AuditAction
+	APIKey    string
+	IP        string
+	RequestID string
+	// Since and Until bound the time of the events, Until excluded.
+	Since *time.Time
+	Until *time.Time
+}
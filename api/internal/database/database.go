@@ -3,31 +3,208 @@ package database
 import (
 	"context"
 	"errors"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
 )
 
+// Link is a stored short link. The health fields are set once the link
+// was checked, Metadata once its page was fetched; Options are empty
+// unless set; DeletedAt once it was moved to the trash. The password of
+// protected links is never read back.
+type Link struct {
+	ID    int64  `json:"id"`
+	URL   string `json:"url"`
+	Alias string `json:"alias"`
+	// Domain is the namespace of the alias, empty for DefaultDomain.
+	Domain    string    `json:"domain,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	LastStatus    *int       `json:"last_status,omitempty"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	CheckFailures int        `json:"check_failures,omitempty"`
+	DisabledAt    *time.Time `json:"disabled_at,omitempty"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+
+	Metadata *Metadata `json:"metadata,omitempty"`
+
+	Options
+	Protected bool `json:"protected,omitempty"`
+	// Clicks counts the redirects of click-limited links.
+	Clicks int64 `json:"clicks,omitempty"`
+}
+
+// Metadata describes the page a link points to.
+type Metadata = shortener.Metadata
+
+// CheckResult is the outcome of a health check of a link. Status is zero
+// when no response was received.
+type CheckResult struct {
+	Status    int
+	CheckedAt time.Time
+	Failed    bool
+}
+
+// Stats is an aggregate over all stored links.
+type Stats struct {
+	Total         int64      `json:"total"`
+	CreatedLast24 int64      `json:"created_last_24h"`
+	LastCreatedAt *time.Time `json:"last_created_at,omitempty"`
+}
+
 type URLProvider interface {
 	GetURl(ctx context.Context, alias string) (string, error)
+	GetLink(ctx context.Context, alias string) (Link, error)
+	// GetTarget is GetURl with the options of the link.
+	GetTarget(ctx context.Context, alias string) (Target, error)
+}
+
+type URLLister interface {
+	ListURLs(ctx context.Context, limit, offset int) ([]Link, error)
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// ConflictMode tells an import what to do with aliases that already exist.
+type ConflictMode string
+
+const (
+	ConflictSkip      ConflictMode = "skip"
+	ConflictOverwrite ConflictMode = "overwrite"
+	ConflictFail      ConflictMode = "fail"
+)
+
+func ParseConflictMode(s string) (ConflictMode, error) {
+	switch mode := ConflictMode(s); mode {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return mode, nil
+	default:
+		return "", ErrUnknownConflictMode
+	}
+}
+
+// LinkReader yields links one by one and returns io.EOF after the last one.
+type LinkReader interface {
+	Read() (Link, error)
+}
+
+// ImportResult counts what an import did. Overwritten holds the links
+// whose url was replaced, so cached redirects can be evicted.
+type ImportResult struct {
+	Created     int64  `json:"created"`
+	Updated     int64  `json:"updated"`
+	Skipped     int64  `json:"skipped"`
+	Overwritten []Link `json:"-"`
+}
+
+type URLTransferer interface {
+	// ImportURLs saves every link of r in a single transaction, into the
+	// domain of the link or else the domain of ctx.
+	ImportURLs(ctx context.Context, r LinkReader, mode ConflictMode) (ImportResult, error)
+	// ExportURLs calls fn for every stored link of every domain ordered
+	// by id.
+	ExportURLs(ctx context.Context, fn func(Link) error) error
 }
 
 type URLDeleter interface {
+	// DeleteURL moves the link saved under alias to the trash. Links in
+	// the trash are not served, listed or changed, but keep their alias
+	// until they are purged.
 	DeleteURL(ctx context.Context, alias string) (int64, error)
 }
 
+type URLTrash interface {
+	// TrashedURLs lists the deleted links of every domain, the most
+	// recently deleted first.
+	TrashedURLs(ctx context.Context, limit, offset int) ([]Link, error)
+	// RestoreURL takes the link saved under alias out of the trash. Links
+	// deleted before since cannot be restored any more and are reported
+	// as ErrURLNotFound.
+	RestoreURL(ctx context.Context, alias string, since time.Time) error
+	// PurgeURLs removes up to limit links deleted before before for good
+	// and returns how many it removed.
+	PurgeURLs(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
 type URLSaver interface {
 	SaveURL(ctx context.Context, userURl string, alias string) error
-	SaveGeneratedURl(ctx context.Context, originalURL string, length, maxAttempts int) (string, error)
+	// SaveTarget is SaveURL with the options of the link.
+	SaveTarget(ctx context.Context, alias string, t Target) error
+}
+
+type URLUpdater interface {
+	UpdateURL(ctx context.Context, alias string, userURl string) error
+	// SetOptions replaces the options of the link saved under alias.
+	SetOptions(ctx context.Context, alias string, opts Options) error
+}
+
+type ClickRecorder interface {
+	// RecordClicks raises the redirects counted for the link saved under
+	// alias to clicks, lower counts arriving late are ignored.
+	RecordClicks(ctx context.Context, alias string, clicks int64) error
+}
+
+type LinkChecker interface {
+	// LinksToCheck returns up to limit enabled links not checked since
+	// before, the least recently checked first.
+	LinksToCheck(ctx context.Context, before time.Time, limit int) ([]Link, error)
+	// RecordCheck stores a check of the link with the given id. A failed
+	// check counts as a consecutive failure, any other resets them. With
+	// disableAfter above zero the link is disabled once it failed that
+	// often; disabled reports whether this check disabled it.
+	RecordCheck(ctx context.Context, id int64, res CheckResult, disableAfter int) (disabled bool, err error)
+	// BrokenLinks lists links whose last check failed, the most failures
+	// first.
+	BrokenLinks(ctx context.Context, limit, offset int) ([]Link, error)
+	// EnableURL enables a disabled link again and resets its failures.
+	EnableURL(ctx context.Context, alias string) error
+}
+
+type MetadataSaver interface {
+	// SaveMetadata stores the metadata of the link saved under alias,
+	// unless it points to another url by now. It returns ErrURLNotFound
+	// when no link has the alias and url.
+	SaveMetadata(ctx context.Context, alias, url string, m Metadata) error
+}
+
+type DomainStore interface {
+	// SaveDomain returns ErrDomainExist when the domain is registered.
+	SaveDomain(ctx context.Context, d Domain) error
+	ListDomains(ctx context.Context) ([]Domain, error)
+}
+
+type AuditLog interface {
+	// AuditEvents lists the events matching f, the newest first.
+	AuditEvents(ctx context.Context, f AuditFilter, limit, offset int) ([]AuditEvent, error)
+	// ExportAudit calls fn for every event matching f, the oldest first.
+	ExportAudit(ctx context.Context, f AuditFilter, fn func(AuditEvent) error) error
 }
 
 type Database interface {
 	URLProvider
 	URLDeleter
+	URLTrash
 	URLSaver
+	URLUpdater
+	URLLister
+	URLTransferer
+	ClickRecorder
+	LinkChecker
+	MetadataSaver
+	DomainStore
+	AuditLog
 
 	Close() error
 }
 
 var (
-	ErrURLNotFound           = errors.New("url not found")
-	ErrURLExist              = errors.New("url exists")
-	ErrMaxRetriesForGenerate = errors.New("max retries for generate unique alias")
+	ErrURLNotFound           = shortener.ErrURLNotFound
+	ErrURLExist              = shortener.ErrAliasExist
+	ErrMaxRetriesForGenerate = shortener.ErrMaxRetries
+	ErrInvalidPage           = errors.New("invalid page limit or offset")
+	ErrUnknownConflictMode   = errors.New("unknown conflict mode")
+	ErrUnknownAuditAction    = errors.New("unknown audit action")
+	ErrLinkDisabled          = shortener.ErrLinkDisabled
+	ErrLinkExhausted         = shortener.ErrLinkExhausted
+	ErrDomainExist           = errors.New("domain exists")
 )
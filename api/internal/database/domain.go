@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+)
+
+// DefaultDomain is the namespace of the aliases of hosts that are no
+// registered domain.
+const DefaultDomain = ""
+
+// Domain is a host links are served on. Every domain has its own aliases.
+type Domain = shortener.Domain
+
+// WithDomain returns a copy of ctx scoping the aliases used with it to d.
+// Stores read and write aliases in the namespace of the domain of ctx.
+func WithDomain(ctx context.Context, d Domain) context.Context {
+	return shortener.WithDomain(ctx, d)
+}
+
+// DomainFrom returns the domain of ctx, the zero Domain of DefaultDomain
+// without one.
+func DomainFrom(ctx context.Context) Domain {
+	return shortener.DomainFrom(ctx)
+}
@@ -7,7 +7,9 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
+	database "github.com/Pshimaf-Git/url-shortener/api/internal/database"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,6 +36,36 @@ func (m *MockURLProvider) EXPECT() *MockURLProviderMockRecorder {
 	return m.recorder
 }
 
+// GetLink mocks base method.
+func (m *MockURLProvider) GetLink(ctx context.Context, alias string) (database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLink", ctx, alias)
+	ret0, _ := ret[0].(database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLink indicates an expected call of GetLink.
+func (mr *MockURLProviderMockRecorder) GetLink(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLink", reflect.TypeOf((*MockURLProvider)(nil).GetLink), ctx, alias)
+}
+
+// GetTarget mocks base method.
+func (m *MockURLProvider) GetTarget(ctx context.Context, alias string) (database.Target, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTarget", ctx, alias)
+	ret0, _ := ret[0].(database.Target)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTarget indicates an expected call of GetTarget.
+func (mr *MockURLProviderMockRecorder) GetTarget(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTarget", reflect.TypeOf((*MockURLProvider)(nil).GetTarget), ctx, alias)
+}
+
 // GetURl mocks base method.
 func (m *MockURLProvider) GetURl(ctx context.Context, alias string) (string, error) {
 	m.ctrl.T.Helper()
@@ -49,6 +81,149 @@ func (mr *MockURLProviderMockRecorder) GetURl(ctx, alias interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURl", reflect.TypeOf((*MockURLProvider)(nil).GetURl), ctx, alias)
 }
 
+// MockURLLister is a mock of URLLister interface.
+type MockURLLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockURLListerMockRecorder
+}
+
+// MockURLListerMockRecorder is the mock recorder for MockURLLister.
+type MockURLListerMockRecorder struct {
+	mock *MockURLLister
+}
+
+// NewMockURLLister creates a new mock instance.
+func NewMockURLLister(ctrl *gomock.Controller) *MockURLLister {
+	mock := &MockURLLister{ctrl: ctrl}
+	mock.recorder = &MockURLListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockURLLister) EXPECT() *MockURLListerMockRecorder {
+	return m.recorder
+}
+
+// ListURLs mocks base method.
+func (m *MockURLLister) ListURLs(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListURLs", ctx, limit, offset)
+	ret0, _ := ret[0].([]database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListURLs indicates an expected call of ListURLs.
+func (mr *MockURLListerMockRecorder) ListURLs(ctx, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListURLs", reflect.TypeOf((*MockURLLister)(nil).ListURLs), ctx, limit, offset)
+}
+
+// Stats mocks base method.
+func (m *MockURLLister) Stats(ctx context.Context) (database.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats", ctx)
+	ret0, _ := ret[0].(database.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockURLListerMockRecorder) Stats(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockURLLister)(nil).Stats), ctx)
+}
+
+// MockLinkReader is a mock of LinkReader interface.
+type MockLinkReader struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkReaderMockRecorder
+}
+
+// MockLinkReaderMockRecorder is the mock recorder for MockLinkReader.
+type MockLinkReaderMockRecorder struct {
+	mock *MockLinkReader
+}
+
+// NewMockLinkReader creates a new mock instance.
+func NewMockLinkReader(ctrl *gomock.Controller) *MockLinkReader {
+	mock := &MockLinkReader{ctrl: ctrl}
+	mock.recorder = &MockLinkReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkReader) EXPECT() *MockLinkReaderMockRecorder {
+	return m.recorder
+}
+
+// Read mocks base method.
+func (m *MockLinkReader) Read() (database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read")
+	ret0, _ := ret[0].(database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockLinkReaderMockRecorder) Read() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockLinkReader)(nil).Read))
+}
+
+// MockURLTransferer is a mock of URLTransferer interface.
+type MockURLTransferer struct {
+	ctrl     *gomock.Controller
+	recorder *MockURLTransfererMockRecorder
+}
+
+// MockURLTransfererMockRecorder is the mock recorder for MockURLTransferer.
+type MockURLTransfererMockRecorder struct {
+	mock *MockURLTransferer
+}
+
+// NewMockURLTransferer creates a new mock instance.
+func NewMockURLTransferer(ctrl *gomock.Controller) *MockURLTransferer {
+	mock := &MockURLTransferer{ctrl: ctrl}
+	mock.recorder = &MockURLTransfererMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockURLTransferer) EXPECT() *MockURLTransfererMockRecorder {
+	return m.recorder
+}
+
+// ExportURLs mocks base method.
+func (m *MockURLTransferer) ExportURLs(ctx context.Context, fn func(database.Link) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportURLs", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportURLs indicates an expected call of ExportURLs.
+func (mr *MockURLTransfererMockRecorder) ExportURLs(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportURLs", reflect.TypeOf((*MockURLTransferer)(nil).ExportURLs), ctx, fn)
+}
+
+// ImportURLs mocks base method.
+func (m *MockURLTransferer) ImportURLs(ctx context.Context, r database.LinkReader, mode database.ConflictMode) (database.ImportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportURLs", ctx, r, mode)
+	ret0, _ := ret[0].(database.ImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportURLs indicates an expected call of ImportURLs.
+func (mr *MockURLTransfererMockRecorder) ImportURLs(ctx, r, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportURLs", reflect.TypeOf((*MockURLTransferer)(nil).ImportURLs), ctx, r, mode)
+}
+
 // MockURLDeleter is a mock of URLDeleter interface.
 type MockURLDeleter struct {
 	ctrl     *gomock.Controller
@@ -87,6 +262,73 @@ func (mr *MockURLDeleterMockRecorder) DeleteURL(ctx, alias interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURL", reflect.TypeOf((*MockURLDeleter)(nil).DeleteURL), ctx, alias)
 }
 
+// MockURLTrash is a mock of URLTrash interface.
+type MockURLTrash struct {
+	ctrl     *gomock.Controller
+	recorder *MockURLTrashMockRecorder
+}
+
+// MockURLTrashMockRecorder is the mock recorder for MockURLTrash.
+type MockURLTrashMockRecorder struct {
+	mock *MockURLTrash
+}
+
+// NewMockURLTrash creates a new mock instance.
+func NewMockURLTrash(ctrl *gomock.Controller) *MockURLTrash {
+	mock := &MockURLTrash{ctrl: ctrl}
+	mock.recorder = &MockURLTrashMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockURLTrash) EXPECT() *MockURLTrashMockRecorder {
+	return m.recorder
+}
+
+// PurgeURLs mocks base method.
+func (m *MockURLTrash) PurgeURLs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeURLs", ctx, before, limit)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeURLs indicates an expected call of PurgeURLs.
+func (mr *MockURLTrashMockRecorder) PurgeURLs(ctx, before, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeURLs", reflect.TypeOf((*MockURLTrash)(nil).PurgeURLs), ctx, before, limit)
+}
+
+// RestoreURL mocks base method.
+func (m *MockURLTrash) RestoreURL(ctx context.Context, alias string, since time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreURL", ctx, alias, since)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreURL indicates an expected call of RestoreURL.
+func (mr *MockURLTrashMockRecorder) RestoreURL(ctx, alias, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreURL", reflect.TypeOf((*MockURLTrash)(nil).RestoreURL), ctx, alias, since)
+}
+
+// TrashedURLs mocks base method.
+func (m *MockURLTrash) TrashedURLs(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrashedURLs", ctx, limit, offset)
+	ret0, _ := ret[0].([]database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrashedURLs indicates an expected call of TrashedURLs.
+func (mr *MockURLTrashMockRecorder) TrashedURLs(ctx, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashedURLs", reflect.TypeOf((*MockURLTrash)(nil).TrashedURLs), ctx, limit, offset)
+}
+
 // MockURLSaver is a mock of URLSaver interface.
 type MockURLSaver struct {
 	ctrl     *gomock.Controller
@@ -110,19 +352,18 @@ func (m *MockURLSaver) EXPECT() *MockURLSaverMockRecorder {
 	return m.recorder
 }
 
-// SaveGeneratedURl mocks base method.
-func (m *MockURLSaver) SaveGeneratedURl(ctx context.Context, originalURL string, length, maxAttempts int) (string, error) {
+// SaveTarget mocks base method.
+func (m *MockURLSaver) SaveTarget(ctx context.Context, alias string, t database.Target) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveGeneratedURl", ctx, originalURL, length, maxAttempts)
-	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "SaveTarget", ctx, alias, t)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// SaveGeneratedURl indicates an expected call of SaveGeneratedURl.
-func (mr *MockURLSaverMockRecorder) SaveGeneratedURl(ctx, originalURL, length, maxAttempts interface{}) *gomock.Call {
+// SaveTarget indicates an expected call of SaveTarget.
+func (mr *MockURLSaverMockRecorder) SaveTarget(ctx, alias, t interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveGeneratedURl", reflect.TypeOf((*MockURLSaver)(nil).SaveGeneratedURl), ctx, originalURL, length, maxAttempts)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTarget", reflect.TypeOf((*MockURLSaver)(nil).SaveTarget), ctx, alias, t)
 }
 
 // SaveURL mocks base method.
@@ -139,6 +380,317 @@ func (mr *MockURLSaverMockRecorder) SaveURL(ctx, userURl, alias interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockURLSaver)(nil).SaveURL), ctx, userURl, alias)
 }
 
+// MockURLUpdater is a mock of URLUpdater interface.
+type MockURLUpdater struct {
+	ctrl     *gomock.Controller
+	recorder *MockURLUpdaterMockRecorder
+}
+
+// MockURLUpdaterMockRecorder is the mock recorder for MockURLUpdater.
+type MockURLUpdaterMockRecorder struct {
+	mock *MockURLUpdater
+}
+
+// NewMockURLUpdater creates a new mock instance.
+func NewMockURLUpdater(ctrl *gomock.Controller) *MockURLUpdater {
+	mock := &MockURLUpdater{ctrl: ctrl}
+	mock.recorder = &MockURLUpdaterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockURLUpdater) EXPECT() *MockURLUpdaterMockRecorder {
+	return m.recorder
+}
+
+// SetOptions mocks base method.
+func (m *MockURLUpdater) SetOptions(ctx context.Context, alias string, opts database.Options) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetOptions", ctx, alias, opts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetOptions indicates an expected call of SetOptions.
+func (mr *MockURLUpdaterMockRecorder) SetOptions(ctx, alias, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOptions", reflect.TypeOf((*MockURLUpdater)(nil).SetOptions), ctx, alias, opts)
+}
+
+// UpdateURL mocks base method.
+func (m *MockURLUpdater) UpdateURL(ctx context.Context, alias, userURl string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateURL", ctx, alias, userURl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateURL indicates an expected call of UpdateURL.
+func (mr *MockURLUpdaterMockRecorder) UpdateURL(ctx, alias, userURl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateURL", reflect.TypeOf((*MockURLUpdater)(nil).UpdateURL), ctx, alias, userURl)
+}
+
+// MockClickRecorder is a mock of ClickRecorder interface.
+type MockClickRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockClickRecorderMockRecorder
+}
+
+// MockClickRecorderMockRecorder is the mock recorder for MockClickRecorder.
+type MockClickRecorderMockRecorder struct {
+	mock *MockClickRecorder
+}
+
+// NewMockClickRecorder creates a new mock instance.
+func NewMockClickRecorder(ctrl *gomock.Controller) *MockClickRecorder {
+	mock := &MockClickRecorder{ctrl: ctrl}
+	mock.recorder = &MockClickRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClickRecorder) EXPECT() *MockClickRecorderMockRecorder {
+	return m.recorder
+}
+
+// RecordClicks mocks base method.
+func (m *MockClickRecorder) RecordClicks(ctx context.Context, alias string, clicks int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordClicks", ctx, alias, clicks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordClicks indicates an expected call of RecordClicks.
+func (mr *MockClickRecorderMockRecorder) RecordClicks(ctx, alias, clicks interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordClicks", reflect.TypeOf((*MockClickRecorder)(nil).RecordClicks), ctx, alias, clicks)
+}
+
+// MockLinkChecker is a mock of LinkChecker interface.
+type MockLinkChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkCheckerMockRecorder
+}
+
+// MockLinkCheckerMockRecorder is the mock recorder for MockLinkChecker.
+type MockLinkCheckerMockRecorder struct {
+	mock *MockLinkChecker
+}
+
+// NewMockLinkChecker creates a new mock instance.
+func NewMockLinkChecker(ctrl *gomock.Controller) *MockLinkChecker {
+	mock := &MockLinkChecker{ctrl: ctrl}
+	mock.recorder = &MockLinkCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkChecker) EXPECT() *MockLinkCheckerMockRecorder {
+	return m.recorder
+}
+
+// BrokenLinks mocks base method.
+func (m *MockLinkChecker) BrokenLinks(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BrokenLinks", ctx, limit, offset)
+	ret0, _ := ret[0].([]database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BrokenLinks indicates an expected call of BrokenLinks.
+func (mr *MockLinkCheckerMockRecorder) BrokenLinks(ctx, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BrokenLinks", reflect.TypeOf((*MockLinkChecker)(nil).BrokenLinks), ctx, limit, offset)
+}
+
+// EnableURL mocks base method.
+func (m *MockLinkChecker) EnableURL(ctx context.Context, alias string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableURL", ctx, alias)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableURL indicates an expected call of EnableURL.
+func (mr *MockLinkCheckerMockRecorder) EnableURL(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableURL", reflect.TypeOf((*MockLinkChecker)(nil).EnableURL), ctx, alias)
+}
+
+// LinksToCheck mocks base method.
+func (m *MockLinkChecker) LinksToCheck(ctx context.Context, before time.Time, limit int) ([]database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinksToCheck", ctx, before, limit)
+	ret0, _ := ret[0].([]database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinksToCheck indicates an expected call of LinksToCheck.
+func (mr *MockLinkCheckerMockRecorder) LinksToCheck(ctx, before, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinksToCheck", reflect.TypeOf((*MockLinkChecker)(nil).LinksToCheck), ctx, before, limit)
+}
+
+// RecordCheck mocks base method.
+func (m *MockLinkChecker) RecordCheck(ctx context.Context, id int64, res database.CheckResult, disableAfter int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordCheck", ctx, id, res, disableAfter)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordCheck indicates an expected call of RecordCheck.
+func (mr *MockLinkCheckerMockRecorder) RecordCheck(ctx, id, res, disableAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordCheck", reflect.TypeOf((*MockLinkChecker)(nil).RecordCheck), ctx, id, res, disableAfter)
+}
+
+// MockMetadataSaver is a mock of MetadataSaver interface.
+type MockMetadataSaver struct {
+	ctrl     *gomock.Controller
+	recorder *MockMetadataSaverMockRecorder
+}
+
+// MockMetadataSaverMockRecorder is the mock recorder for MockMetadataSaver.
+type MockMetadataSaverMockRecorder struct {
+	mock *MockMetadataSaver
+}
+
+// NewMockMetadataSaver creates a new mock instance.
+func NewMockMetadataSaver(ctrl *gomock.Controller) *MockMetadataSaver {
+	mock := &MockMetadataSaver{ctrl: ctrl}
+	mock.recorder = &MockMetadataSaverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMetadataSaver) EXPECT() *MockMetadataSaverMockRecorder {
+	return m.recorder
+}
+
+// SaveMetadata mocks base method.
+func (m_2 *MockMetadataSaver) SaveMetadata(ctx context.Context, alias, url string, m database.Metadata) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SaveMetadata", ctx, alias, url, m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveMetadata indicates an expected call of SaveMetadata.
+func (mr *MockMetadataSaverMockRecorder) SaveMetadata(ctx, alias, url, m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMetadata", reflect.TypeOf((*MockMetadataSaver)(nil).SaveMetadata), ctx, alias, url, m)
+}
+
+// MockDomainStore is a mock of DomainStore interface.
+type MockDomainStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockDomainStoreMockRecorder
+}
+
+// MockDomainStoreMockRecorder is the mock recorder for MockDomainStore.
+type MockDomainStoreMockRecorder struct {
+	mock *MockDomainStore
+}
+
+// NewMockDomainStore creates a new mock instance.
+func NewMockDomainStore(ctrl *gomock.Controller) *MockDomainStore {
+	mock := &MockDomainStore{ctrl: ctrl}
+	mock.recorder = &MockDomainStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDomainStore) EXPECT() *MockDomainStoreMockRecorder {
+	return m.recorder
+}
+
+// ListDomains mocks base method.
+func (m *MockDomainStore) ListDomains(ctx context.Context) ([]database.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDomains", ctx)
+	ret0, _ := ret[0].([]database.Domain)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDomains indicates an expected call of ListDomains.
+func (mr *MockDomainStoreMockRecorder) ListDomains(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDomains", reflect.TypeOf((*MockDomainStore)(nil).ListDomains), ctx)
+}
+
+// SaveDomain mocks base method.
+func (m *MockDomainStore) SaveDomain(ctx context.Context, d database.Domain) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDomain", ctx, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDomain indicates an expected call of SaveDomain.
+func (mr *MockDomainStoreMockRecorder) SaveDomain(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDomain", reflect.TypeOf((*MockDomainStore)(nil).SaveDomain), ctx, d)
+}
+
+// MockAuditLog is a mock of AuditLog interface.
+type MockAuditLog struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditLogMockRecorder
+}
+
+// MockAuditLogMockRecorder is the mock recorder for MockAuditLog.
+type MockAuditLogMockRecorder struct {
+	mock *MockAuditLog
+}
+
+// NewMockAuditLog creates a new mock instance.
+func NewMockAuditLog(ctrl *gomock.Controller) *MockAuditLog {
+	mock := &MockAuditLog{ctrl: ctrl}
+	mock.recorder = &MockAuditLogMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditLog) EXPECT() *MockAuditLogMockRecorder {
+	return m.recorder
+}
+
+// AuditEvents mocks base method.
+func (m *MockAuditLog) AuditEvents(ctx context.Context, f database.AuditFilter, limit, offset int) ([]database.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuditEvents", ctx, f, limit, offset)
+	ret0, _ := ret[0].([]database.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuditEvents indicates an expected call of AuditEvents.
+func (mr *MockAuditLogMockRecorder) AuditEvents(ctx, f, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditEvents", reflect.TypeOf((*MockAuditLog)(nil).AuditEvents), ctx, f, limit, offset)
+}
+
+// ExportAudit mocks base method.
+func (m *MockAuditLog) ExportAudit(ctx context.Context, f database.AuditFilter, fn func(database.AuditEvent) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportAudit", ctx, f, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportAudit indicates an expected call of ExportAudit.
+func (mr *MockAuditLogMockRecorder) ExportAudit(ctx, f, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportAudit", reflect.TypeOf((*MockAuditLog)(nil).ExportAudit), ctx, f, fn)
+}
+
 // MockDatabase is a mock of Database interface.
 type MockDatabase struct {
 	ctrl     *gomock.Controller
@@ -162,6 +714,36 @@ func (m *MockDatabase) EXPECT() *MockDatabaseMockRecorder {
 	return m.recorder
 }
 
+// AuditEvents mocks base method.
+func (m *MockDatabase) AuditEvents(ctx context.Context, f database.AuditFilter, limit, offset int) ([]database.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuditEvents", ctx, f, limit, offset)
+	ret0, _ := ret[0].([]database.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuditEvents indicates an expected call of AuditEvents.
+func (mr *MockDatabaseMockRecorder) AuditEvents(ctx, f, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditEvents", reflect.TypeOf((*MockDatabase)(nil).AuditEvents), ctx, f, limit, offset)
+}
+
+// BrokenLinks mocks base method.
+func (m *MockDatabase) BrokenLinks(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BrokenLinks", ctx, limit, offset)
+	ret0, _ := ret[0].([]database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BrokenLinks indicates an expected call of BrokenLinks.
+func (mr *MockDatabaseMockRecorder) BrokenLinks(ctx, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BrokenLinks", reflect.TypeOf((*MockDatabase)(nil).BrokenLinks), ctx, limit, offset)
+}
+
 // Close mocks base method.
 func (m *MockDatabase) Close() error {
 	m.ctrl.T.Helper()
@@ -191,6 +773,78 @@ func (mr *MockDatabaseMockRecorder) DeleteURL(ctx, alias interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteURL", reflect.TypeOf((*MockDatabase)(nil).DeleteURL), ctx, alias)
 }
 
+// EnableURL mocks base method.
+func (m *MockDatabase) EnableURL(ctx context.Context, alias string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableURL", ctx, alias)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableURL indicates an expected call of EnableURL.
+func (mr *MockDatabaseMockRecorder) EnableURL(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableURL", reflect.TypeOf((*MockDatabase)(nil).EnableURL), ctx, alias)
+}
+
+// ExportAudit mocks base method.
+func (m *MockDatabase) ExportAudit(ctx context.Context, f database.AuditFilter, fn func(database.AuditEvent) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportAudit", ctx, f, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportAudit indicates an expected call of ExportAudit.
+func (mr *MockDatabaseMockRecorder) ExportAudit(ctx, f, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportAudit", reflect.TypeOf((*MockDatabase)(nil).ExportAudit), ctx, f, fn)
+}
+
+// ExportURLs mocks base method.
+func (m *MockDatabase) ExportURLs(ctx context.Context, fn func(database.Link) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportURLs", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportURLs indicates an expected call of ExportURLs.
+func (mr *MockDatabaseMockRecorder) ExportURLs(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportURLs", reflect.TypeOf((*MockDatabase)(nil).ExportURLs), ctx, fn)
+}
+
+// GetLink mocks base method.
+func (m *MockDatabase) GetLink(ctx context.Context, alias string) (database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLink", ctx, alias)
+	ret0, _ := ret[0].(database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLink indicates an expected call of GetLink.
+func (mr *MockDatabaseMockRecorder) GetLink(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLink", reflect.TypeOf((*MockDatabase)(nil).GetLink), ctx, alias)
+}
+
+// GetTarget mocks base method.
+func (m *MockDatabase) GetTarget(ctx context.Context, alias string) (database.Target, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTarget", ctx, alias)
+	ret0, _ := ret[0].(database.Target)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTarget indicates an expected call of GetTarget.
+func (mr *MockDatabaseMockRecorder) GetTarget(ctx, alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTarget", reflect.TypeOf((*MockDatabase)(nil).GetTarget), ctx, alias)
+}
+
 // GetURl mocks base method.
 func (m *MockDatabase) GetURl(ctx context.Context, alias string) (string, error) {
 	m.ctrl.T.Helper()
@@ -206,19 +860,164 @@ func (mr *MockDatabaseMockRecorder) GetURl(ctx, alias interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetURl", reflect.TypeOf((*MockDatabase)(nil).GetURl), ctx, alias)
 }
 
-// SaveGeneratedURl mocks base method.
-func (m *MockDatabase) SaveGeneratedURl(ctx context.Context, originalURL string, length, maxAttempts int) (string, error) {
+// ImportURLs mocks base method.
+func (m *MockDatabase) ImportURLs(ctx context.Context, r database.LinkReader, mode database.ConflictMode) (database.ImportResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveGeneratedURl", ctx, originalURL, length, maxAttempts)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "ImportURLs", ctx, r, mode)
+	ret0, _ := ret[0].(database.ImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportURLs indicates an expected call of ImportURLs.
+func (mr *MockDatabaseMockRecorder) ImportURLs(ctx, r, mode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportURLs", reflect.TypeOf((*MockDatabase)(nil).ImportURLs), ctx, r, mode)
+}
+
+// LinksToCheck mocks base method.
+func (m *MockDatabase) LinksToCheck(ctx context.Context, before time.Time, limit int) ([]database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinksToCheck", ctx, before, limit)
+	ret0, _ := ret[0].([]database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinksToCheck indicates an expected call of LinksToCheck.
+func (mr *MockDatabaseMockRecorder) LinksToCheck(ctx, before, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinksToCheck", reflect.TypeOf((*MockDatabase)(nil).LinksToCheck), ctx, before, limit)
+}
+
+// ListDomains mocks base method.
+func (m *MockDatabase) ListDomains(ctx context.Context) ([]database.Domain, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDomains", ctx)
+	ret0, _ := ret[0].([]database.Domain)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// SaveGeneratedURl indicates an expected call of SaveGeneratedURl.
-func (mr *MockDatabaseMockRecorder) SaveGeneratedURl(ctx, originalURL, length, maxAttempts interface{}) *gomock.Call {
+// ListDomains indicates an expected call of ListDomains.
+func (mr *MockDatabaseMockRecorder) ListDomains(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveGeneratedURl", reflect.TypeOf((*MockDatabase)(nil).SaveGeneratedURl), ctx, originalURL, length, maxAttempts)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDomains", reflect.TypeOf((*MockDatabase)(nil).ListDomains), ctx)
+}
+
+// ListURLs mocks base method.
+func (m *MockDatabase) ListURLs(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListURLs", ctx, limit, offset)
+	ret0, _ := ret[0].([]database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListURLs indicates an expected call of ListURLs.
+func (mr *MockDatabaseMockRecorder) ListURLs(ctx, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListURLs", reflect.TypeOf((*MockDatabase)(nil).ListURLs), ctx, limit, offset)
+}
+
+// PurgeURLs mocks base method.
+func (m *MockDatabase) PurgeURLs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeURLs", ctx, before, limit)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeURLs indicates an expected call of PurgeURLs.
+func (mr *MockDatabaseMockRecorder) PurgeURLs(ctx, before, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeURLs", reflect.TypeOf((*MockDatabase)(nil).PurgeURLs), ctx, before, limit)
+}
+
+// RecordCheck mocks base method.
+func (m *MockDatabase) RecordCheck(ctx context.Context, id int64, res database.CheckResult, disableAfter int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordCheck", ctx, id, res, disableAfter)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordCheck indicates an expected call of RecordCheck.
+func (mr *MockDatabaseMockRecorder) RecordCheck(ctx, id, res, disableAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordCheck", reflect.TypeOf((*MockDatabase)(nil).RecordCheck), ctx, id, res, disableAfter)
+}
+
+// RecordClicks mocks base method.
+func (m *MockDatabase) RecordClicks(ctx context.Context, alias string, clicks int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordClicks", ctx, alias, clicks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordClicks indicates an expected call of RecordClicks.
+func (mr *MockDatabaseMockRecorder) RecordClicks(ctx, alias, clicks interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordClicks", reflect.TypeOf((*MockDatabase)(nil).RecordClicks), ctx, alias, clicks)
+}
+
+// RestoreURL mocks base method.
+func (m *MockDatabase) RestoreURL(ctx context.Context, alias string, since time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreURL", ctx, alias, since)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreURL indicates an expected call of RestoreURL.
+func (mr *MockDatabaseMockRecorder) RestoreURL(ctx, alias, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreURL", reflect.TypeOf((*MockDatabase)(nil).RestoreURL), ctx, alias, since)
+}
+
+// SaveDomain mocks base method.
+func (m *MockDatabase) SaveDomain(ctx context.Context, d database.Domain) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDomain", ctx, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDomain indicates an expected call of SaveDomain.
+func (mr *MockDatabaseMockRecorder) SaveDomain(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDomain", reflect.TypeOf((*MockDatabase)(nil).SaveDomain), ctx, d)
+}
+
+// SaveMetadata mocks base method.
+func (m_2 *MockDatabase) SaveMetadata(ctx context.Context, alias, url string, m database.Metadata) error {
+	m_2.ctrl.T.Helper()
+	ret := m_2.ctrl.Call(m_2, "SaveMetadata", ctx, alias, url, m)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveMetadata indicates an expected call of SaveMetadata.
+func (mr *MockDatabaseMockRecorder) SaveMetadata(ctx, alias, url, m interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMetadata", reflect.TypeOf((*MockDatabase)(nil).SaveMetadata), ctx, alias, url, m)
+}
+
+// SaveTarget mocks base method.
+func (m *MockDatabase) SaveTarget(ctx context.Context, alias string, t database.Target) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTarget", ctx, alias, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTarget indicates an expected call of SaveTarget.
+func (mr *MockDatabaseMockRecorder) SaveTarget(ctx, alias, t interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTarget", reflect.TypeOf((*MockDatabase)(nil).SaveTarget), ctx, alias, t)
 }
 
 // SaveURL mocks base method.
@@ -234,3 +1033,61 @@ func (mr *MockDatabaseMockRecorder) SaveURL(ctx, userURl, alias interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveURL", reflect.TypeOf((*MockDatabase)(nil).SaveURL), ctx, userURl, alias)
 }
+
+// SetOptions mocks base method.
+func (m *MockDatabase) SetOptions(ctx context.Context, alias string, opts database.Options) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetOptions", ctx, alias, opts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetOptions indicates an expected call of SetOptions.
+func (mr *MockDatabaseMockRecorder) SetOptions(ctx, alias, opts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOptions", reflect.TypeOf((*MockDatabase)(nil).SetOptions), ctx, alias, opts)
+}
+
+// Stats mocks base method.
+func (m *MockDatabase) Stats(ctx context.Context) (database.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats", ctx)
+	ret0, _ := ret[0].(database.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockDatabaseMockRecorder) Stats(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockDatabase)(nil).Stats), ctx)
+}
+
+// TrashedURLs mocks base method.
+func (m *MockDatabase) TrashedURLs(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrashedURLs", ctx, limit, offset)
+	ret0, _ := ret[0].([]database.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrashedURLs indicates an expected call of TrashedURLs.
+func (mr *MockDatabaseMockRecorder) TrashedURLs(ctx, limit, offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashedURLs", reflect.TypeOf((*MockDatabase)(nil).TrashedURLs), ctx, limit, offset)
+}
+
+// UpdateURL mocks base method.
+func (m *MockDatabase) UpdateURL(ctx context.Context, alias, userURl string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateURL", ctx, alias, userURl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateURL indicates an expected call of UpdateURL.
+func (mr *MockDatabaseMockRecorder) UpdateURL(ctx, alias, userURl interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateURL", reflect.TypeOf((*MockDatabase)(nil).UpdateURL), ctx, alias, userURl)
+}
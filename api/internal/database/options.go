@@ -0,0 +1,29 @@
+package database
+
+import "github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+
+// The options of links are the ones of the shortener.
+type (
+	Passthrough     = shortener.Passthrough
+	UTM             = shortener.UTM
+	Options         = shortener.Options
+	Platform        = shortener.Platform
+	Rule            = shortener.Rule
+	HeaderCondition = shortener.HeaderCondition
+	Variant         = shortener.Variant
+	Target          = shortener.Target
+)
+
+const (
+	PassthroughOff         = shortener.PassthroughOff
+	PassthroughIncoming    = shortener.PassthroughIncoming
+	PassthroughDestination = shortener.PassthroughDestination
+)
+
+const (
+	PlatformIOS     = shortener.PlatformIOS
+	PlatformAndroid = shortener.PlatformAndroid
+	PlatformWindows = shortener.PlatformWindows
+	PlatformMacOS   = shortener.PlatformMacOS
+	PlatformLinux   = shortener.PlatformLinux
+)
@@ -0,0 +1,252 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/jackc/pgx/v5"
+)
+
+// auditColumns are the columns scanEvent reads, in order.
+const auditColumns = `id, action, alias, domain,
+	COALESCE(api_key, ''), COALESCE(ip, ''), COALESCE(request_id, ''),
+	before, after, created_at`
+
+const insertAuditQuery = `INSERT INTO audit_log(action, alias, domain, api_key, ip, request_id, before, after)
+	VALUES($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8)`
+
+// inTx runs fn in a transaction, committed when fn succeeds.
+func (s *storage) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background()) //nolint:errcheck
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockLink reads the link saved under alias and locks it for the rest of
+// tx. It reads the link in the trash when trashed is set, the live one
+// otherwise.
+func lockLink(ctx context.Context, tx pgx.Tx, alias string, trashed bool) (database.Link, error) {
+	cond := `deleted_at IS NULL`
+	if trashed {
+		cond = `deleted_at IS NOT NULL`
+	}
+
+	query := `SELECT ` + linkColumns + ` FROM urls WHERE alias = $1 AND domain = $2 AND ` + cond + ` FOR UPDATE`
+
+	link, err := scanLink(tx.QueryRow(ctx, query, alias, domain(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.Link{}, database.ErrURLNotFound
+		}
+		return database.Link{}, err
+	}
+
+	return link, nil
+}
+
+// create inserts a link with query, which returns linkColumns, and
+// records its creation.
+func (s *storage) create(ctx context.Context, query string, args ...any) error {
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		link, err := scanLink(tx.QueryRow(ctx, query, args...))
+		if err != nil {
+			return err
+		}
+
+		return audit(ctx, tx, event(ctx, database.AuditCreate, nil, &link))
+	})
+}
+
+// change locks the link saved under alias, updates it with query and
+// records the change as action. The first parameter of query is the id of
+// the link, args follow; it returns linkColumns, and no row when the link
+// cannot be changed, which is reported as ErrURLNotFound.
+func (s *storage) change(ctx context.Context, action database.AuditAction, alias string, trashed bool, query string, args ...any) error {
+	return s.inTx(ctx, func(tx pgx.Tx) error {
+		before, err := lockLink(ctx, tx, alias, trashed)
+		if err != nil {
+			return err
+		}
+
+		after, err := scanLink(tx.QueryRow(ctx, query, append([]any{before.ID}, args...)...))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return database.ErrURLNotFound
+			}
+			return err
+		}
+
+		return audit(ctx, tx, event(ctx, action, &before, &after))
+	})
+}
+
+// event describes a change of a link by the actor of ctx. before or after
+// may be nil, not both.
+func event(ctx context.Context, action database.AuditAction, before, after *database.Link) database.AuditEvent {
+	e := database.AuditEvent{
+		Action: action,
+		Actor:  database.ActorFrom(ctx),
+		Before: before,
+		After:  after,
+	}
+
+	link := after
+	if link == nil {
+		link = before
+	}
+	e.Alias, e.Domain = link.Alias, link.Domain
+
+	return e
+}
+
+// audit records events in tx, so that they are kept exactly when the
+// changes they describe are.
+func audit(ctx context.Context, tx pgx.Tx, events ...database.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, e := range events {
+		batch.Queue(insertAuditQuery, e.Action, e.Alias, e.Domain,
+			e.Actor.APIKey, e.Actor.IP, e.Actor.RequestID, e.Before, e.After)
+	}
+
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+func (s *storage) AuditEvents(ctx context.Context, f database.AuditFilter, limit, offset int) ([]database.AuditEvent, error) {
+	const fn = "database.postgres.(*storage).AuditEvents"
+
+	wp := wraper.New(fn)
+
+	if limit <= 0 || offset < 0 {
+		return nil, wp.Wrap(database.ErrInvalidPage)
+	}
+
+	where, args := auditWhere(ctx, f)
+	query := fmt.Sprintf(`SELECT %s FROM audit_log %s ORDER BY id DESC LIMIT $%d OFFSET $%d`,
+		auditColumns, where, len(args)+1, len(args)+2)
+
+	events, err := s.queryEvents(ctx, limit, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	return events, nil
+}
+
+// ExportAudit pages through the events by id, so only one page is held in
+// memory whatever the size of the log.
+func (s *storage) ExportAudit(ctx context.Context, f database.AuditFilter, each func(database.AuditEvent) error) error {
+	const fn = "database.postgres.(*storage).ExportAudit"
+
+	wp := wraper.New(fn)
+
+	where, args := auditWhere(ctx, f)
+	if where == "" {
+		where = "WHERE true"
+	}
+	query := fmt.Sprintf(`SELECT %s FROM audit_log %s AND id > $%d ORDER BY id LIMIT %d`,
+		auditColumns, where, len(args)+1, exportFetchSize)
+
+	var last int64
+	for {
+		page, err := s.queryEvents(ctx, exportFetchSize, query, append(args, last)...)
+		if err != nil {
+			return wp.Wrap(err)
+		}
+
+		for _, e := range page {
+			if err := each(e); err != nil {
+				return wp.Wrap(err)
+			}
+		}
+
+		if len(page) < exportFetchSize {
+			return nil
+		}
+		last = page[len(page)-1].ID
+	}
+}
+
+// auditWhere returns the WHERE clause selecting the events of f, empty
+// without conditions, and its arguments numbered from $1.
+func auditWhere(ctx context.Context, f database.AuditFilter) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.Alias != "" {
+		add("alias = $%d", f.Alias)
+		add("domain = $%d", domain(ctx))
+	}
+	if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.APIKey != "" {
+		add("api_key = $%d", f.APIKey)
+	}
+	if f.IP != "" {
+		add("ip = $%d", f.IP)
+	}
+	if f.RequestID != "" {
+		add("request_id = $%d", f.RequestID)
+	}
+	if f.Since != nil {
+		add("created_at >= $%d", *f.Since)
+	}
+	if f.Until != nil {
+		add("created_at < $%d", *f.Until)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// queryEvents runs a query selecting auditColumns. size is the expected
+// number of events.
+func (s *storage) queryEvents(ctx context.Context, size int, query string, args ...any) ([]database.AuditEvent, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]database.AuditEvent, 0, size)
+	for rows.Next() {
+		var e database.AuditEvent
+		err := rows.Scan(&e.ID, &e.Action, &e.Alias, &e.Domain,
+			&e.Actor.APIKey, &e.Actor.IP, &e.Actor.RequestID,
+			&e.Before, &e.After, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	_, err := db.pool.Exec(context.Background(), "DELETE FROM audit_log")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	ops := database.Actor{APIKey: "ops", IP: "203.0.113.7", RequestID: "host/abc-000001"}
+	opsCtx := database.WithActor(ctx, ops)
+
+	require.NoError(t, db.SaveURL(opsCtx, "https://example.com/old", "report"))
+	require.NoError(t, db.UpdateURL(opsCtx, "report", "https://example.com/new"))
+	_, err = db.DeleteURL(database.WithActor(ctx, database.Actor{IP: "198.51.100.1"}), "report")
+	require.NoError(t, err)
+
+	// failed changes record nothing
+	assert.ErrorIs(t, db.SaveURL(opsCtx, "https://example.com", "report"), database.ErrURLExist)
+	assert.ErrorIs(t, db.UpdateURL(opsCtx, "unknown", "https://example.com"), database.ErrURLNotFound)
+
+	events, err := db.AuditEvents(ctx, database.AuditFilter{Alias: "report"}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	// the newest first
+	deleted, updated, created := events[0], events[1], events[2]
+
+	assert.Equal(t, database.AuditCreate, created.Action)
+	assert.Equal(t, ops, created.Actor)
+	assert.Nil(t, created.Before)
+	require.NotNil(t, created.After)
+	assert.Equal(t, "https://example.com/old", created.After.URL)
+
+	assert.Equal(t, database.AuditUpdate, updated.Action)
+	require.NotNil(t, updated.Before)
+	require.NotNil(t, updated.After)
+	assert.Equal(t, "https://example.com/old", updated.Before.URL)
+	assert.Equal(t, "https://example.com/new", updated.After.URL)
+
+	assert.Equal(t, database.AuditDelete, deleted.Action)
+	assert.Equal(t, database.Actor{IP: "198.51.100.1"}, deleted.Actor)
+	require.NotNil(t, deleted.After)
+	assert.NotNil(t, deleted.After.DeletedAt)
+
+	// filters
+	events, err = db.AuditEvents(ctx, database.AuditFilter{APIKey: "ops"}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	events, err = db.AuditEvents(ctx, database.AuditFilter{Action: database.AuditDelete, IP: "198.51.100.1"}, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	future := time.Now().Add(time.Hour)
+	events, err = db.AuditEvents(ctx, database.AuditFilter{Since: &future}, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	events, err = db.AuditEvents(ctx, database.AuditFilter{}, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, database.AuditUpdate, events[0].Action)
+
+	// purges have no actor
+	purged, err := db.PurgeURLs(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var exported []database.AuditEvent
+	require.NoError(t, db.ExportAudit(ctx, database.AuditFilter{Alias: "report"}, func(e database.AuditEvent) error {
+		exported = append(exported, e)
+		return nil
+	}))
+	require.Len(t, exported, 4)
+	assert.Equal(t, database.AuditCreate, exported[0].Action)
+
+	purge := exported[3]
+	assert.Equal(t, database.AuditPurge, purge.Action)
+	assert.Equal(t, database.Actor{}, purge.Actor)
+	assert.NotNil(t, purge.Before)
+	assert.Nil(t, purge.After)
+
+	_, err = db.AuditEvents(ctx, database.AuditFilter{}, 0, 0)
+	assert.ErrorIs(t, err, database.ErrInvalidPage)
+}
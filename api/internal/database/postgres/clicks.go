@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+)
+
+func (s *storage) RecordClicks(ctx context.Context, alias string, clicks int64) error {
+	const fn = "database.postgres.(*storage).RecordClicks"
+
+	wp := wraper.New(fn)
+
+	query := `UPDATE urls SET clicks = GREATEST(clicks, $1) WHERE alias = $2 AND domain = $3 AND deleted_at IS NULL`
+
+	res, err := s.pool.Exec(ctx, query, clicks, alias, domain(ctx))
+	if err != nil {
+		return wp.Wrap(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return wp.Wrap(database.ErrURLNotFound)
+	}
+
+	return nil
+}
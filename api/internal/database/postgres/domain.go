@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (s *storage) SaveDomain(ctx context.Context, d database.Domain) error {
+	const fn = "database.postgres.(*storage).SaveDomain"
+
+	wp := wraper.New(fn)
+
+	query := `INSERT INTO domains(name, alias_length, not_found_url) VALUES($1, $2, NULLIF($3, ''))`
+
+	if _, err := s.pool.Exec(ctx, query, d.Name, d.AliasLength, d.NotFoundURL); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgconnUniqueConstraintViolation {
+			return wp.WrapMsg("domain already exists", database.ErrDomainExist)
+		}
+
+		return wp.Wrap(err)
+	}
+
+	return nil
+}
+
+func (s *storage) ListDomains(ctx context.Context) ([]database.Domain, error) {
+	const fn = "database.postgres.(*storage).ListDomains"
+
+	wp := wraper.New(fn)
+
+	query := `SELECT name, alias_length, COALESCE(not_found_url, ''), created_at FROM domains ORDER BY name`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+	defer rows.Close()
+
+	var domains []database.Domain
+	for rows.Next() {
+		var d database.Domain
+		if err := rows.Scan(&d.Name, &d.AliasLength, &d.NotFoundURL, &d.CreatedAt); err != nil {
+			return nil, wp.Wrap(err)
+		}
+		domains = append(domains, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	return domains, nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainNamespaces(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	brandA := database.WithDomain(ctx, database.Domain{Name: "go.brand-a.com"})
+	brandB := database.WithDomain(ctx, database.Domain{Name: "brand-b.link"})
+
+	require.NoError(t, db.SaveURL(brandA, "https://brand-a.com/sale", "sale"))
+	require.NoError(t, db.SaveURL(brandB, "https://brand-b.com/sale", "sale"))
+	assert.ErrorIs(t, db.SaveURL(brandA, "https://brand-a.com/other", "sale"), database.ErrURLExist)
+
+	url, err := db.GetURl(brandB, "sale")
+	require.NoError(t, err)
+	assert.Equal(t, "https://brand-b.com/sale", url)
+
+	link, err := db.GetLink(brandA, "sale")
+	require.NoError(t, err)
+	assert.Equal(t, "go.brand-a.com", link.Domain)
+
+	// the default namespace has no such alias
+	_, err = db.GetURl(ctx, "sale")
+	assert.ErrorIs(t, err, database.ErrURLNotFound)
+
+	_, err = db.DeleteURL(brandA, "sale")
+	require.NoError(t, err)
+
+	_, err = db.GetURl(brandB, "sale")
+	assert.NoError(t, err)
+}
+
+func TestDomains(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	defer db.pool.Exec(context.Background(), "DELETE FROM domains") //nolint:errcheck
+
+	require.NoError(t, db.SaveDomain(ctx, database.Domain{Name: "go.brand-a.com", AliasLength: 4}))
+	require.NoError(t, db.SaveDomain(ctx, database.Domain{Name: "brand-b.link", NotFoundURL: "https://brand-b.com"}))
+	assert.ErrorIs(t, db.SaveDomain(ctx, database.Domain{Name: "brand-b.link"}), database.ErrDomainExist)
+
+	domains, err := db.ListDomains(ctx)
+	require.NoError(t, err)
+	require.Len(t, domains, 2)
+
+	assert.Equal(t, "brand-b.link", domains[0].Name)
+	assert.Equal(t, "https://brand-b.com", domains[0].NotFoundURL)
+	assert.Equal(t, "go.brand-a.com", domains[1].Name)
+	assert.Equal(t, 4, domains[1].AliasLength)
+	assert.NotNil(t, domains[1].CreatedAt)
+}
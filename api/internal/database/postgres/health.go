@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *storage) LinksToCheck(ctx context.Context, before time.Time, limit int) ([]database.Link, error) {
+	const fn = "database.postgres.(*storage).LinksToCheck"
+
+	wp := wraper.New(fn)
+
+	if limit <= 0 {
+		return nil, wp.Wrap(database.ErrInvalidPage)
+	}
+
+	query := `SELECT ` + linkColumns + ` FROM urls
+	WHERE disabled_at IS NULL AND deleted_at IS NULL AND (last_checked_at IS NULL OR last_checked_at < $1)
+	ORDER BY last_checked_at NULLS FIRST, id
+	LIMIT $2`
+
+	links, err := s.queryLinks(ctx, limit, query, before, limit)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	return links, nil
+}
+
+func (s *storage) RecordCheck(ctx context.Context, id int64, res database.CheckResult, disableAfter int) (bool, error) {
+	const fn = "database.postgres.(*storage).RecordCheck"
+
+	wp := wraper.New(fn)
+
+	var status *int
+	if res.Status != 0 {
+		status = &res.Status
+	}
+
+	// disabled_at is only set by the check that reaches the limit, so the
+	// returned flag tells whether this check disabled the link
+	query := `UPDATE urls SET
+		last_status = $2,
+		last_checked_at = $3,
+		check_failures = CASE WHEN $4 THEN check_failures + 1 ELSE 0 END,
+		disabled_at = CASE
+			WHEN disabled_at IS NULL AND $4 AND $5 > 0 AND check_failures + 1 >= $5 THEN $3
+			ELSE disabled_at
+		END
+	WHERE id = $1
+	RETURNING disabled_at IS NOT DISTINCT FROM $3::timestamptz`
+
+	var disabled bool
+	err := s.pool.QueryRow(ctx, query, id, status, res.CheckedAt, res.Failed, disableAfter).Scan(&disabled)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, wp.Wrap(database.ErrURLNotFound)
+		}
+		return false, wp.Wrap(err)
+	}
+
+	return disabled, nil
+}
+
+func (s *storage) BrokenLinks(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	const fn = "database.postgres.(*storage).BrokenLinks"
+
+	wp := wraper.New(fn)
+
+	if limit <= 0 || offset < 0 {
+		return nil, wp.Wrap(database.ErrInvalidPage)
+	}
+
+	query := `SELECT ` + linkColumns + ` FROM urls
+	WHERE check_failures > 0 AND deleted_at IS NULL
+	ORDER BY check_failures DESC, id
+	LIMIT $1 OFFSET $2`
+
+	links, err := s.queryLinks(ctx, limit, query, limit, offset)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	return links, nil
+}
+
+func (s *storage) EnableURL(ctx context.Context, alias string) error {
+	const fn = "database.postgres.(*storage).EnableURL"
+
+	wp := wraper.New(fn)
+
+	query := `UPDATE urls SET disabled_at = NULL, check_failures = 0 WHERE id = $1 RETURNING ` + linkColumns
+
+	if err := s.change(ctx, database.AuditUpdate, alias, false, query); err != nil {
+		return wp.Wrap(err)
+	}
+
+	return nil
+}
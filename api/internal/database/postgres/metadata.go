@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+)
+
+func (s *storage) SaveMetadata(ctx context.Context, alias, url string, m database.Metadata) error {
+	const fn = "database.postgres.(*storage).SaveMetadata"
+
+	wp := wraper.New(fn)
+
+	// the url is compared so that a page fetched before an update does not
+	// describe the new destination
+	query := `UPDATE urls SET
+		title = NULLIF($3, ''),
+		og_title = NULLIF($4, ''),
+		og_description = NULLIF($5, ''),
+		og_image = NULLIF($6, ''),
+		metadata_fetched_at = $7
+	WHERE alias = $1 AND url = $2 AND domain = $8 AND deleted_at IS NULL`
+
+	res, err := s.pool.Exec(ctx, query, alias, url, m.Title, m.OGTitle, m.OGDescription, m.OGImage, m.FetchedAt, domain(ctx))
+	if err != nil {
+		return wp.Wrap(err)
+	}
+
+	if res.RowsAffected() == 0 {
+		return wp.Wrap(database.ErrURLNotFound)
+	}
+
+	return nil
+}
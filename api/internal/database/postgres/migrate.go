@@ -0,0 +1,321 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/schema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The version table keeps the layout of golang-migrate, so databases that
+// were migrated by the external CLI are picked up as is.
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT  NOT NULL PRIMARY KEY,
+	dirty   BOOLEAN NOT NULL
+)`
+
+// migrationLockID is the key of the advisory lock held while migrating, so
+// replicas starting at the same time apply every migration exactly once.
+const migrationLockID int64 = 0x75726c73686f7274 // "urlshort"
+
+const pgconnUndefinedTable = "42P01"
+
+var (
+	ErrDirtyVersion   = errors.New("database version is dirty, fix it manually")
+	ErrUnknownVersion = errors.New("unknown migration version")
+	ErrNoDownScript   = errors.New("migration has no down script")
+)
+
+// MigrationStatus describes whether a migration is applied to the database.
+type MigrationStatus struct {
+	Version uint64
+	Name    string
+	Applied bool
+}
+
+// Migrator applies the embedded schema migrations.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []schema.Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool, migrations []schema.Migration) *Migrator {
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+	}
+}
+
+// Migrator returns a migrator over the migrations embedded into the binary.
+func (s *storage) Migrator() (*Migrator, error) {
+	const fn = "database.postgres.(*storage).Migrator"
+
+	migrations, err := schema.Load()
+	if err != nil {
+		return nil, wraper.Wrap(fn, err)
+	}
+
+	return NewMigrator(s.pool, migrations), nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	const fn = "database.postgres.(*Migrator).Up"
+
+	return wraper.Wrap(fn, m.To(ctx, m.latest()))
+}
+
+// Down rolls back the given number of applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	const fn = "database.postgres.(*Migrator).Down"
+
+	wp := wraper.New(fn)
+
+	return wp.Wrap(m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		target, err := downTarget(m.migrations, current, steps)
+		if err != nil {
+			return err
+		}
+
+		return m.migrate(ctx, conn, current, target)
+	}))
+}
+
+// To migrates the database up or down to the given version. Version 0
+// rolls back every migration.
+func (m *Migrator) To(ctx context.Context, version uint64) error {
+	const fn = "database.postgres.(*Migrator).To"
+
+	wp := wraper.New(fn)
+
+	return wp.Wrap(m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		return m.migrate(ctx, conn, current, version)
+	}))
+}
+
+// Status reports every known migration and whether it is applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	const fn = "database.postgres.(*Migrator).Status"
+
+	wp := wraper.New(fn)
+
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+	defer conn.Release()
+
+	// the version is read without the lock, so a running migration does not
+	// block the status; it is reported once its step committed
+	current, err := currentVersion(ctx, conn)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) || pgErr.Code != pgconnUndefinedTable {
+			return nil, wp.Wrap(err)
+		}
+		// nothing was migrated yet
+		current = 0
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: migration.Version <= current,
+		})
+	}
+
+	return statuses, nil
+}
+
+func (m *Migrator) latest() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) migrate(ctx context.Context, conn *pgxpool.Conn, current, target uint64) error {
+	steps, err := plan(m.migrations, current, target)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range steps {
+		if err := applyStep(ctx, conn, s); err != nil {
+			return wraper.Wrapf("database.postgres.(*Migrator).migrate", err, "version=%d up=%t", s.migration.Version, s.up)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID) //nolint:errcheck
+
+	if _, err := conn.Exec(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+type step struct {
+	migration schema.Migration
+	up        bool
+	// version is written to schema_migrations once the step is applied
+	version uint64
+}
+
+// plan returns the ordered steps that move the schema from current to target.
+func plan(migrations []schema.Migration, current, target uint64) ([]step, error) {
+	if current != 0 && indexOf(migrations, current) < 0 {
+		return nil, wraper.Wrapf("database.postgres.plan", ErrUnknownVersion, "current=%d", current)
+	}
+
+	if target != 0 && indexOf(migrations, target) < 0 {
+		return nil, wraper.Wrapf("database.postgres.plan", ErrUnknownVersion, "target=%d", target)
+	}
+
+	var steps []step
+
+	if target >= current {
+		for _, migration := range migrations {
+			if migration.Version > current && migration.Version <= target {
+				steps = append(steps, step{migration: migration, up: true, version: migration.Version})
+			}
+		}
+
+		return steps, nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version > current || migration.Version <= target {
+			continue
+		}
+
+		if migration.Down == "" {
+			return nil, wraper.Wrapf("database.postgres.plan", ErrNoDownScript, "version=%d", migration.Version)
+		}
+
+		var previous uint64
+		if i > 0 {
+			previous = migrations[i-1].Version
+		}
+
+		steps = append(steps, step{migration: migration, up: false, version: previous})
+	}
+
+	return steps, nil
+}
+
+// downTarget returns the version reached after rolling back steps migrations.
+func downTarget(migrations []schema.Migration, current uint64, steps int) (uint64, error) {
+	if current == 0 {
+		return 0, nil
+	}
+
+	idx := indexOf(migrations, current)
+	if idx < 0 {
+		return 0, wraper.Wrapf("database.postgres.downTarget", ErrUnknownVersion, "current=%d", current)
+	}
+
+	if steps <= 0 {
+		steps = 1
+	}
+
+	if idx-steps < 0 {
+		return 0, nil
+	}
+
+	return migrations[idx-steps].Version, nil
+}
+
+func indexOf(migrations []schema.Migration, version uint64) int {
+	for i, migration := range migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func currentVersion(ctx context.Context, conn *pgxpool.Conn) (uint64, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if dirty {
+		return 0, wraper.Wrapf("database.postgres.currentVersion", ErrDirtyVersion, "version=%d", version)
+	}
+
+	if version < 0 {
+		return 0, nil
+	}
+
+	return uint64(version), nil
+}
+
+func applyStep(ctx context.Context, conn *pgxpool.Conn, s step) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background()) //nolint:errcheck
+
+	script := s.migration.Up
+	if !s.up {
+		script = s.migration.Down
+	}
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `TRUNCATE schema_migrations`); err != nil {
+		return err
+	}
+
+	if s.version != 0 {
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations(version, dirty) VALUES($1, false)`, int64(s.version)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
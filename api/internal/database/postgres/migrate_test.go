@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = []schema.Migration{
+	{Version: 1, Name: "first", Up: "UP 1", Down: "DOWN 1"},
+	{Version: 2, Name: "second", Up: "UP 2", Down: "DOWN 2"},
+	{Version: 3, Name: "third", Up: "UP 3", Down: "DOWN 3"},
+}
+
+func Test_plan(t *testing.T) {
+	type want struct {
+		version uint64
+		up      bool
+		next    uint64
+	}
+
+	testCases := []struct {
+		name    string
+		current uint64
+		target  uint64
+		want    []want
+		wantErr error
+	}{
+		{
+			name:    "up from empty database",
+			current: 0,
+			target:  3,
+			want:    []want{{1, true, 1}, {2, true, 2}, {3, true, 3}},
+		},
+		{
+			name:    "up from the middle",
+			current: 1,
+			target:  3,
+			want:    []want{{2, true, 2}, {3, true, 3}},
+		},
+		{
+			name:    "already up to date",
+			current: 3,
+			target:  3,
+			want:    nil,
+		},
+		{
+			name:    "down to the middle",
+			current: 3,
+			target:  1,
+			want:    []want{{3, false, 2}, {2, false, 1}},
+		},
+		{
+			name:    "down to empty database",
+			current: 2,
+			target:  0,
+			want:    []want{{2, false, 1}, {1, false, 0}},
+		},
+		{
+			name:    "unknown target",
+			current: 1,
+			target:  42,
+			wantErr: ErrUnknownVersion,
+		},
+		{
+			name:    "unknown current",
+			current: 42,
+			target:  3,
+			wantErr: ErrUnknownVersion,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := plan(testMigrations, tt.current, tt.target)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, steps, len(tt.want))
+
+			for i, s := range steps {
+				assert.Equal(t, tt.want[i].version, s.migration.Version)
+				assert.Equal(t, tt.want[i].up, s.up)
+				assert.Equal(t, tt.want[i].next, s.version)
+			}
+		})
+	}
+
+	t.Run("missing down script", func(t *testing.T) {
+		migrations := []schema.Migration{{Version: 1, Name: "first", Up: "UP 1"}}
+
+		_, err := plan(migrations, 1, 0)
+		assert.ErrorIs(t, err, ErrNoDownScript)
+	})
+}
+
+func Test_downTarget(t *testing.T) {
+	testCases := []struct {
+		name    string
+		current uint64
+		steps   int
+		want    uint64
+		wantErr error
+	}{
+		{name: "one step", current: 3, steps: 1, want: 2},
+		{name: "zero steps means one", current: 3, steps: 0, want: 2},
+		{name: "two steps", current: 3, steps: 2, want: 1},
+		{name: "more steps than applied", current: 2, steps: 10, want: 0},
+		{name: "empty database", current: 0, steps: 1, want: 0},
+		{name: "unknown current", current: 42, steps: 1, wantErr: ErrUnknownVersion},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := downTarget(testMigrations, tt.current, tt.steps)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	m, err := db.Migrator()
+	require.NoError(t, err)
+
+	require.NoError(t, m.Up(ctx))
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, "version %d", s.Version)
+	}
+
+	require.NoError(t, m.To(ctx, 0))
+
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.False(t, s.Applied, "version %d", s.Version)
+	}
+
+	// leave the schema in place for the other tests
+	require.NoError(t, m.Up(ctx))
+}
+
+func TestMigrator_StatusWithoutLock(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	m, err := db.Migrator()
+	require.NoError(t, err)
+
+	// another replica is migrating
+	conn, err := db.pool.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID)
+	require.NoError(t, err)
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID) //nolint:errcheck
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, "version %d", s.Version)
+	}
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (s *storage) GetTarget(ctx context.Context, alias string) (database.Target, error) {
+	const fn = "database.postgres.(*storage).GetTarget"
+
+	wp := wraper.New(fn)
+
+	query := `SELECT url, passthrough, utm, interstitial, rules, variants, COALESCE(max_clicks, 0), clicks,
+	active_from, active_until, COALESCE(password_hash, ''), disabled_at IS NOT NULL,
+	created_at, title, og_title, og_description, og_image, metadata_fetched_at
+	FROM urls WHERE alias = $1 AND domain = $2 AND deleted_at IS NULL`
+
+	var (
+		t                               database.Target
+		disabled                        bool
+		title, ogTitle, ogDesc, ogImage *string
+		fetchedAt                       *time.Time
+	)
+	err := s.pool.QueryRow(ctx, query, alias, domain(ctx)).Scan(&t.URL, &t.Passthrough, &t.UTM, &t.Interstitial, &t.Rules, &t.Variants,
+		&t.MaxClicks, &t.Clicks, &t.ActiveFrom, &t.ActiveUntil, &t.PasswordHash, &disabled,
+		&t.CreatedAt, &title, &ogTitle, &ogDesc, &ogImage, &fetchedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Target{}, wp.WrapMsg("url not found", database.ErrURLNotFound)
+		}
+
+		return database.Target{}, wp.WrapMsg("failed to get URL", err)
+	}
+
+	if disabled {
+		return database.Target{}, wp.Wrap(database.ErrLinkDisabled)
+	}
+
+	if t.MaxClicks > 0 && t.Clicks >= t.MaxClicks {
+		return database.Target{}, wp.Wrap(database.ErrLinkExhausted)
+	}
+
+	t.Metadata = metadataOf(title, ogTitle, ogDesc, ogImage, fetchedAt)
+
+	return t, nil
+}
+
+func (s *storage) SaveTarget(ctx context.Context, alias string, t database.Target) error {
+	const fn = "database.postgres.(*storage).SaveTarget"
+
+	wp := wraper.New(fn)
+
+	query := `INSERT INTO urls(url, alias, domain, passthrough, utm, interstitial, rules, variants, max_clicks,
+	active_from, active_until, password_hash)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, 0), $10, $11, NULLIF($12, ''))
+	RETURNING ` + linkColumns
+
+	err := s.create(ctx, query, t.URL, alias, domain(ctx), t.Passthrough, utm(t.Options), t.Interstitial,
+		rules(t.Options), variants(t.Options), t.MaxClicks, t.ActiveFrom, t.ActiveUntil, t.PasswordHash)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgconnUniqueConstraintViolation {
+			return wp.WrapMsg("alias already exists", database.ErrURLExist)
+		}
+
+		return wp.WrapMsg("failed to save URL", err)
+	}
+	return nil
+}
+
+func (s *storage) SetOptions(ctx context.Context, alias string, opts database.Options) error {
+	const fn = "database.postgres.(*storage).SetOptions"
+
+	wp := wraper.New(fn)
+
+	query := `UPDATE urls SET passthrough = $2, utm = $3, interstitial = $4, rules = $5, variants = $6,
+	max_clicks = NULLIF($7, 0), active_from = $8, active_until = $9, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	RETURNING ` + linkColumns
+
+	err := s.change(ctx, database.AuditUpdate, alias, false, query, opts.Passthrough, utm(opts), opts.Interstitial,
+		rules(opts), variants(opts), opts.MaxClicks, opts.ActiveFrom, opts.ActiveUntil)
+	if err != nil {
+		return wp.Wrap(err)
+	}
+
+	return nil
+}
+
+// utm returns the UTM parameters of opts, nil to store NULL when there
+// are none.
+func utm(opts database.Options) *database.UTM {
+	if opts.UTM == nil || *opts.UTM == (database.UTM{}) {
+		return nil
+	}
+	return opts.UTM
+}
+
+// rules returns the rules of opts, nil to store NULL when there are none.
+func rules(opts database.Options) []database.Rule {
+	if len(opts.Rules) == 0 {
+		return nil
+	}
+	return opts.Rules
+}
+
+// variants returns the variants of opts, nil to store NULL when there are
+// none.
+func variants(opts database.Options) []database.Variant {
+	if len(opts.Variants) == 0 {
+		return nil
+	}
+	return opts.Variants
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	activeFrom := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	activeUntil := activeFrom.AddDate(0, 0, 7)
+
+	opts := database.Options{
+		Passthrough:  database.PassthroughIncoming,
+		UTM:          &database.UTM{Source: "newsletter", Campaign: "spring"},
+		Interstitial: true,
+		Rules: []database.Rule{
+			{URL: "https://apps.apple.com/app/id1", Platforms: []database.Platform{database.PlatformIOS}},
+			{URL: "https://shop.example.com/de", Countries: []string{"DE"}, Header: &database.HeaderCondition{Name: "X-Beta"}},
+		},
+		Variants: []database.Variant{
+			{URL: "https://shop.example.com/sale-a", Weight: 70},
+			{URL: "https://shop.example.com/sale-b", Weight: 30},
+		},
+		ActiveFrom:  &activeFrom,
+		ActiveUntil: &activeUntil,
+	}
+
+	require.NoError(t, db.SaveTarget(ctx, "sale", database.Target{URL: "https://shop.example.com/sale", Options: opts}))
+	assert.ErrorIs(t, db.SaveTarget(ctx, "sale", database.Target{URL: "https://example.com"}), database.ErrURLExist)
+
+	target, err := db.GetTarget(ctx, "sale")
+	require.NoError(t, err)
+	assert.False(t, target.CreatedAt.IsZero())
+	target.CreatedAt = time.Time{}
+	assert.Equal(t, database.Target{URL: "https://shop.example.com/sale", Options: opts}, target)
+
+	link, err := db.GetLink(ctx, "sale")
+	require.NoError(t, err)
+	assert.Equal(t, opts, link.Options)
+
+	// links saved without options have none
+	require.NoError(t, db.SaveURL(ctx, "https://example.com", "plain"))
+
+	target, err = db.GetTarget(ctx, "plain")
+	require.NoError(t, err)
+	target.CreatedAt = time.Time{}
+	assert.Equal(t, database.Target{URL: "https://example.com"}, target)
+
+	// empty UTM parameters are stored as none
+	require.NoError(t, db.SetOptions(ctx, "sale", database.Options{UTM: &database.UTM{}}))
+
+	target, err = db.GetTarget(ctx, "sale")
+	require.NoError(t, err)
+	assert.True(t, target.Options.IsZero())
+	assert.Nil(t, target.UTM)
+
+	assert.ErrorIs(t, db.SetOptions(ctx, "unknown", opts), database.ErrURLNotFound)
+
+	_, err = db.GetTarget(ctx, "unknown")
+	assert.ErrorIs(t, err, database.ErrURLNotFound)
+}
+
+func TestPasswords(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	require.NoError(t, db.SaveTarget(ctx, "internal", database.Target{URL: "https://wiki.example.com", PasswordHash: "$2a$10$hash"}))
+
+	target, err := db.GetTarget(ctx, "internal")
+	require.NoError(t, err)
+	assert.Equal(t, "$2a$10$hash", target.PasswordHash)
+
+	link, err := db.GetLink(ctx, "internal")
+	require.NoError(t, err)
+	assert.True(t, link.Protected)
+
+	require.NoError(t, db.SaveURL(ctx, "https://example.com", "plain"))
+
+	link, err = db.GetLink(ctx, "plain")
+	require.NoError(t, err)
+	assert.False(t, link.Protected)
+}
+
+func TestClicks(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	opts := database.Options{MaxClicks: 3}
+	require.NoError(t, db.SaveTarget(ctx, "report", database.Target{URL: "https://files.example.com/report.pdf", Options: opts}))
+
+	require.NoError(t, db.RecordClicks(ctx, "report", 2))
+	// counts arriving late do not lower the clicks
+	require.NoError(t, db.RecordClicks(ctx, "report", 1))
+
+	target, err := db.GetTarget(ctx, "report")
+	require.NoError(t, err)
+	assert.Equal(t, database.Target{URL: "https://files.example.com/report.pdf", Options: opts, Clicks: 2}, target)
+
+	require.NoError(t, db.RecordClicks(ctx, "report", 3))
+
+	_, err = db.GetTarget(ctx, "report")
+	assert.ErrorIs(t, err, database.ErrLinkExhausted)
+
+	link, err := db.GetLink(ctx, "report")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, link.Clicks)
+	assert.EqualValues(t, 3, link.MaxClicks)
+
+	// a raised limit serves the link again
+	require.NoError(t, db.SetOptions(ctx, "report", database.Options{MaxClicks: 5}))
+
+	_, err = db.GetTarget(ctx, "report")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, db.RecordClicks(ctx, "unknown", 1), database.ErrURLNotFound)
+}
@@ -6,11 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
-	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/random"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -52,9 +53,9 @@ func (s *storage) SaveURL(ctx context.Context, originalURL string, alias string)
 
 	wp := wraper.New(fn)
 
-	query := `INSERT INTO urls(url, alias) VALUES($1, $2)`
+	query := `INSERT INTO urls(url, alias, domain) VALUES($1, $2, $3) RETURNING ` + linkColumns
 
-	_, err := s.pool.Exec(ctx, query, originalURL, alias)
+	err := s.create(ctx, query, originalURL, alias, domain(ctx))
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgconnUniqueConstraintViolation {
@@ -94,98 +95,211 @@ func ping(ctx context.Context, p *pgxpool.Pool) error {
 
 }
 
-func (s *storage) SaveGeneratedURl(ctx context.Context, originalURL string, length, maxAttempts int) (string, error) {
-	const fn = "database.postgres.(*storage).SaveGeneratedURl"
+// UpdateURL points an existing alias to another url.
+func (s *storage) UpdateURL(ctx context.Context, alias string, originalURL string) error {
+	const fn = "database.postgres.(*storage).UpdateURL"
 
 	wp := wraper.New(fn)
 
-	if err := validateSaveGeneratedURl(originalURL, length, maxAttempts); err != nil {
-		return "", wp.Wrap(err)
+	// a new destination has not been checked or fetched yet
+	query := `UPDATE urls SET url = $2, updated_at = CURRENT_TIMESTAMP,
+		last_status = NULL, last_checked_at = NULL, check_failures = 0,
+		title = NULL, og_title = NULL, og_description = NULL, og_image = NULL,
+		metadata_fetched_at = NULL
+	WHERE id = $1
+	RETURNING ` + linkColumns
+
+	if err := s.change(ctx, database.AuditUpdate, alias, false, query, originalURL); err != nil {
+		if errors.Is(err, database.ErrURLNotFound) {
+			return wp.Wrap(err)
+		}
+		return wp.WrapMsg("failed to update URL", err)
 	}
 
-	for i := 0; i < maxAttempts; i++ {
-		alias := random.StringRandV2(length)
+	return nil
+}
 
-		query := `INSERT INTO urls(url, alias) VALUES($1, $2) RETURNING alias`
+func (s *storage) GetURl(ctx context.Context, alias string) (string, error) {
+	const fn = "database.postgres.(*storage).GetURL"
 
-		var insertedAlias string
-		row := s.pool.QueryRow(ctx, query, originalURL, alias)
+	wp := wraper.New(fn)
 
-		err := row.Scan(&insertedAlias)
-		if err == nil {
-			return insertedAlias, nil
-		}
+	row := s.pool.QueryRow(ctx, "SELECT url, disabled_at IS NOT NULL FROM urls WHERE alias=$1 AND domain=$2 AND deleted_at IS NULL", alias, domain(ctx))
 
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgconnUniqueConstraintViolation {
-			continue
+	var (
+		url      string
+		disabled bool
+	)
+	err := row.Scan(&url, &disabled)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", wp.WrapMsg("url not found", database.ErrURLNotFound)
 		}
 
-		return "", wp.Wrap(err)
+		return "", wp.WrapMsg("failed to get URL", err)
+	}
+
+	if disabled {
+		return "", wp.Wrap(database.ErrLinkDisabled)
 	}
 
-	return "", wp.Wrap(database.ErrMaxRetriesForGenerate)
+	return url, nil
 }
 
-func validateSaveGeneratedURl(originalURL string, length, maxAttempts int) error {
-	errs := make([]error, 0, 3)
-	if originalURL == "" {
-		errs = append(errs, errors.New("url must not be empty"))
+func (s *storage) GetLink(ctx context.Context, alias string) (database.Link, error) {
+	const fn = "database.postgres.(*storage).GetLink"
+
+	wp := wraper.New(fn)
+
+	query := `SELECT ` + linkColumns + ` FROM urls WHERE alias=$1 AND domain=$2 AND deleted_at IS NULL`
+
+	link, err := scanLink(s.pool.QueryRow(ctx, query, alias, domain(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.Link{}, wp.WrapMsg("url not found", database.ErrURLNotFound)
+		}
+
+		return database.Link{}, wp.WrapMsg("failed to get link", err)
 	}
 
-	if length <= 0 {
-		errs = append(errs, errors.New("invlid alias length"))
+	return link, nil
+}
+
+func (s *storage) ListURLs(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	const fn = "database.postgres.(*storage).ListURLs"
+
+	wp := wraper.New(fn)
+
+	if limit <= 0 || offset < 0 {
+		return nil, wp.Wrap(database.ErrInvalidPage)
 	}
 
-	if maxAttempts <= 0 {
-		errs = append(errs, errors.New("invalid max attemts"))
+	query := `SELECT ` + linkColumns + ` FROM urls WHERE deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2`
+
+	links, err := s.queryLinks(ctx, limit, query, limit, offset)
+	if err != nil {
+		return nil, wp.Wrap(err)
 	}
 
-	if len(errs) == 0 {
-		return nil
+	return links, nil
+}
+
+// linkColumns are the columns scanLink reads, in order.
+const linkColumns = `id, url, alias, domain, created_at, updated_at,
+	last_status, last_checked_at, check_failures, disabled_at, deleted_at,
+	title, og_title, og_description, og_image, metadata_fetched_at,
+	passthrough, utm, interstitial, rules, variants, COALESCE(max_clicks, 0), active_from, active_until,
+	password_hash IS NOT NULL, clicks`
+
+func scanLink(row pgx.Row) (database.Link, error) {
+	var (
+		link                            database.Link
+		title, ogTitle, ogDesc, ogImage *string
+		fetchedAt                       *time.Time
+	)
+	err := row.Scan(
+		&link.ID, &link.URL, &link.Alias, &link.Domain, &link.CreatedAt, &link.UpdatedAt,
+		&link.LastStatus, &link.LastCheckedAt, &link.CheckFailures, &link.DisabledAt, &link.DeletedAt,
+		&title, &ogTitle, &ogDesc, &ogImage, &fetchedAt,
+		&link.Passthrough, &link.UTM, &link.Interstitial, &link.Rules, &link.Variants, &link.MaxClicks,
+		&link.ActiveFrom, &link.ActiveUntil, &link.Protected,
+		&link.Clicks,
+	)
+	if err != nil {
+		return database.Link{}, err
 	}
 
-	return errors.Join(errs...)
+	link.Metadata = metadataOf(title, ogTitle, ogDesc, ogImage, fetchedAt)
+
+	return link, nil
 }
 
-func (s *storage) GetURl(ctx context.Context, alias string) (string, error) {
-	const fn = "database.postgres.(*storage).GetURL"
+// metadataOf returns the metadata of the scanned columns, nil when the
+// page of the link was not fetched yet.
+func metadataOf(title, ogTitle, ogDesc, ogImage *string, fetchedAt *time.Time) *database.Metadata {
+	if fetchedAt == nil {
+		return nil
+	}
 
-	wp := wraper.New(fn)
+	return &database.Metadata{
+		Title:         deref(title),
+		OGTitle:       deref(ogTitle),
+		OGDescription: deref(ogDesc),
+		OGImage:       deref(ogImage),
+		FetchedAt:     *fetchedAt,
+	}
+}
 
-	row := s.pool.QueryRow(ctx, "SELECT url FROM urls WHERE alias=$1", alias)
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
 
-	var url string
-	err := row.Scan(&url)
+// queryLinks runs a query selecting linkColumns. size is the expected
+// number of links.
+func (s *storage) queryLinks(ctx context.Context, size int, query string, args ...any) ([]database.Link, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", wp.WrapMsg("url not found", database.ErrURLNotFound)
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]database.Link, 0, size)
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
 		}
+		links = append(links, link)
+	}
 
-		return "", wp.WrapMsg("failed to get URL", err)
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return url, nil
+	return links, nil
+}
+
+func (s *storage) Stats(ctx context.Context) (database.Stats, error) {
+	const fn = "database.postgres.(*storage).Stats"
+
+	wp := wraper.New(fn)
+
+	query := `SELECT
+		count(*),
+		count(*) FILTER (WHERE created_at > now() - interval '24 hours'),
+		max(created_at)
+	FROM urls WHERE deleted_at IS NULL`
+
+	var stats database.Stats
+	if err := s.pool.QueryRow(ctx, query).Scan(&stats.Total, &stats.CreatedLast24, &stats.LastCreatedAt); err != nil {
+		return database.Stats{}, wp.Wrap(err)
+	}
+
+	return stats, nil
 }
 
+// DeleteURL only marks the link as deleted, the row keeps the alias
+// reserved until PurgeURLs removes it.
 func (s *storage) DeleteURL(ctx context.Context, alias string) (int64, error) {
 	const fn = "database.postgres.(*storage).DeleteURL"
 
 	wp := wraper.New(fn)
 
-	query := `DELETE FROM urls WHERE alias=$1`
+	query := `UPDATE urls SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING ` + linkColumns
 
-	res, err := s.pool.Exec(ctx, query, alias)
-	if err != nil {
+	if err := s.change(ctx, database.AuditDelete, alias, false, query); err != nil {
 		return 0, wp.Wrap(err)
 	}
 
-	n := res.RowsAffected()
-	if n == 0 {
-		return 0, wp.Wrap(database.ErrURLNotFound)
-	}
+	return 1, nil
+}
 
-	return n, nil
+// domain returns the namespace of the aliases used with ctx.
+func domain(ctx context.Context) string {
+	return database.DomainFrom(ctx).Name
 }
 
 func (s *storage) Close() error {
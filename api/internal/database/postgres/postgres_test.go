@@ -8,8 +8,6 @@ import (
 
 	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
-	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/random"
-	"github.com/agiledragon/gomonkey/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -48,6 +46,10 @@ func setupTestDB(t *testing.T) (*storage, func()) {
 	db, err := New(ctx, POSTGRES_CFG)
 	require.NoError(t, err)
 
+	m, err := db.Migrator()
+	require.NoError(t, err)
+	require.NoError(t, m.Up(ctx))
+
 	cleanup := func() {
 		_, err := db.pool.Exec(context.Background(), "DELETE FROM urls")
 		require.NoError(t, err)
@@ -445,123 +447,29 @@ func TestDeleteURL(t *testing.T) {
 	}
 }
 
-func TestSaveGeneratedURL(t *testing.T) {
+func TestUpdateURL(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	t.Run("context_deadline_exceeded", func(t *testing.T) {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
-		defer cancel()
-
-		time.Sleep(time.Nanosecond * 10)
-
-		testURL := "https://context_deadline_exceeded.com"
-
-		alias, err := db.SaveGeneratedURl(ctx, testURL, 10, 10)
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, context.DeadlineExceeded)
-		assert.Equal(t, "", alias)
-	})
-
-	t.Run("context_canceled", func(t *testing.T) {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
-		testURL := "https://context_deadline_exceeded.com"
-
-		cancel()
-		alias, err := db.SaveGeneratedURl(ctx, testURL, 10, 10)
-
-		assert.Error(t, err)
-		assert.ErrorIs(t, err, context.Canceled)
-		assert.Equal(t, "", alias)
-	})
-
-	t.Run("max_retries_for_generate", func(t *testing.T) {
-		ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
-		defer cancel()
-
-		conflictAlias := "abc123"
-		err := db.SaveURL(ctx, "https://occupied.com", conflictAlias)
-		require.NoError(t, err)
-
-		patches := gomonkey.ApplyFunc(random.StringRandV2, func(int) string {
-			return conflictAlias
-		})
-		defer patches.Reset()
-
-		_, err = db.SaveGeneratedURl(ctx, "https://new-url.com", len(conflictAlias), 2)
-
-		assert.ErrorIs(t, err, database.ErrMaxRetriesForGenerate)
-	})
-
-	tests := []struct {
-		name        string
-		url         string
-		length      int
-		maxAttempts int
-		wantErr     bool
-		errType     error
-		setup       func() // Optional setup function
-	}{
-		{
-			name:        "successful generation",
-			url:         "https://example.com",
-			length:      6,
-			maxAttempts: 3,
-			wantErr:     false,
-		},
-		{
-			name:        "empty url",
-			url:         "",
-			length:      6,
-			maxAttempts: 3,
-			wantErr:     true,
-		},
-		{
-			name:        "invalid length",
-			url:         "https://example.com",
-			length:      0,
-			maxAttempts: 3,
-			wantErr:     true,
-		},
-		{
-			name:        "invalid attempts",
-			url:         "https://example.com",
-			length:      6,
-			maxAttempts: 0,
-			wantErr:     true,
-		},
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
-			defer cancel()
+	const testAlias = "update"
+	require.NoError(t, db.SaveURL(ctx, "https://example.com", testAlias))
 
-			if tt.setup != nil {
-				tt.setup()
-			}
+	before, err := db.GetLink(ctx, testAlias)
+	require.NoError(t, err)
 
-			alias, err := db.SaveGeneratedURl(ctx, tt.url, tt.length, tt.maxAttempts)
+	require.NoError(t, db.UpdateURL(ctx, testAlias, "https://example.org"))
 
-			if tt.wantErr {
-				assert.Error(t, err)
-				if tt.errType != nil {
-					assert.ErrorIs(t, err, tt.errType)
-				}
-				assert.Empty(t, alias)
-			} else {
-				assert.NoError(t, err)
-				assert.Len(t, alias, tt.length)
+	after, err := db.GetLink(ctx, testAlias)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", after.URL)
+	assert.Equal(t, before.CreatedAt, after.CreatedAt)
+	assert.False(t, after.UpdatedAt.Before(before.UpdatedAt))
 
-				// Verify the URL was actually saved
-				url, err := db.GetURl(ctx, alias)
-				require.NoError(t, err)
-				assert.Equal(t, tt.url, url)
-			}
-		})
-	}
+	err = db.UpdateURL(ctx, "nonexistent", "https://example.org")
+	assert.ErrorIs(t, err, database.ErrURLNotFound)
 }
 
 func TestClose(t *testing.T) {
@@ -641,74 +549,95 @@ func TestBuildConnString(t *testing.T) {
 	})
 }
 
-func Test_validateSaveGeneratedURL(t *testing.T) {
-	type input struct {
-		url         string
-		length      int
-		maxAttempts int
-	}
-	testCases := []struct {
-		name    string
-		input   input
-		wantErr bool
-	}{
-		{
-			name: "good input",
-			input: input{
-				url:         "http://good.com",
-				length:      10,
-				maxAttempts: 5,
-			},
-			wantErr: false,
-		},
+func TestGetLink(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
 
-		{
-			name: "bad max attempts",
-			input: input{
-				url:         "http://good.com",
-				length:      10,
-				maxAttempts: -10,
-			},
-			wantErr: true,
-		},
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
 
-		{
-			name: "bad length",
-			input: input{
-				url:         "http://good.com",
-				length:      0,
-				maxAttempts: 1000,
-			},
-			wantErr: true,
-		},
+	require.NoError(t, db.SaveURL(ctx, "https://example.com", "example"))
 
-		{
-			name: "empty URL",
-			input: input{
-				url:         "",
-				length:      1,
-				maxAttempts: 1,
-			},
-			wantErr: true,
-		},
+	t.Run("existing alias", func(t *testing.T) {
+		link, err := db.GetLink(ctx, "example")
+		require.NoError(t, err)
 
-		{
-			name:    "bad all inputs",
-			input:   input{}, // zero value: "", 0, 0
-			wantErr: true,
-		},
+		assert.Equal(t, "https://example.com", link.URL)
+		assert.Equal(t, "example", link.Alias)
+		assert.NotZero(t, link.ID)
+		assert.False(t, link.CreatedAt.IsZero())
+	})
+
+	t.Run("non-existent alias", func(t *testing.T) {
+		_, err := db.GetLink(ctx, "nonexistent")
+		assert.ErrorIs(t, err, database.ErrURLNotFound)
+	})
+}
+
+func TestListURLs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.SaveURL(ctx, fmt.Sprintf("https://example.com/%d", i), fmt.Sprintf("list%d", i)))
+	}
+
+	testCases := []struct {
+		name      string
+		limit     int
+		offset    int
+		wantLen   int
+		wantFirst string
+		wantErr   error
+	}{
+		{name: "first page", limit: 2, offset: 0, wantLen: 2, wantFirst: "list0"},
+		{name: "second page", limit: 2, offset: 2, wantLen: 2, wantFirst: "list2"},
+		{name: "last page", limit: 2, offset: 4, wantLen: 1, wantFirst: "list4"},
+		{name: "past the end", limit: 2, offset: 10, wantLen: 0},
+		{name: "zero limit", limit: 0, offset: 0, wantErr: database.ErrInvalidPage},
+		{name: "negative offset", limit: 1, offset: -1, wantErr: database.ErrInvalidPage},
 	}
 
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateSaveGeneratedURl(tt.input.url, tt.input.length, tt.input.maxAttempts)
+			links, err := db.ListURLs(ctx, tt.limit, tt.offset)
 
-			if !tt.wantErr {
-				assert.NoError(t, err)
-			} else {
-				assert.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
 			}
 
+			require.NoError(t, err)
+			require.Len(t, links, tt.wantLen)
+
+			if tt.wantLen > 0 {
+				assert.Equal(t, tt.wantFirst, links[0].Alias)
+			}
 		})
 	}
 }
+
+func TestStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	stats, err := db.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Total)
+	assert.Nil(t, stats.LastCreatedAt)
+
+	require.NoError(t, db.SaveURL(ctx, "https://example.com/1", "stats1"))
+	require.NoError(t, db.SaveURL(ctx, "https://example.com/2", "stats2"))
+
+	stats, err = db.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total)
+	assert.Equal(t, int64(2), stats.CreatedLast24)
+	assert.NotNil(t, stats.LastCreatedAt)
+}
@@ -0,0 +1,231 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// importBatchSize is the number of inserts sent to the server at once.
+	importBatchSize = 1000
+	// exportFetchSize is the number of rows fetched from the cursor at once.
+	exportFetchSize = 1000
+)
+
+const (
+	importSkipQuery = `INSERT INTO urls(url, alias, created_at, domain)
+	VALUES($1, $2, COALESCE($3, CURRENT_TIMESTAMP), $4)
+	ON CONFLICT (domain, alias) DO NOTHING
+	RETURNING ` + linkColumns
+
+	// the link is read before it is overwritten, for the audit log
+	importLockQuery = `SELECT ` + linkColumns + ` FROM urls
+	WHERE alias = $1 AND domain = $2 AND deleted_at IS NULL
+	FOR UPDATE`
+
+	// Aliases in the trash stay reserved and return no row.
+	importOverwriteQuery = `INSERT INTO urls(url, alias, created_at, domain)
+	VALUES($1, $2, COALESCE($3, CURRENT_TIMESTAMP), $4)
+	ON CONFLICT (domain, alias) DO UPDATE SET url = EXCLUDED.url, updated_at = CURRENT_TIMESTAMP
+	WHERE urls.deleted_at IS NULL
+	RETURNING ` + linkColumns
+)
+
+// ImportURLs saves the links in one transaction, so an import in the fail
+// mode leaves the table untouched on the first existing alias.
+func (s *storage) ImportURLs(ctx context.Context, r database.LinkReader, mode database.ConflictMode) (database.ImportResult, error) {
+	const fn = "database.postgres.(*storage).ImportURLs"
+
+	wp := wraper.New(fn)
+
+	if _, err := database.ParseConflictMode(string(mode)); err != nil {
+		return database.ImportResult{}, wp.Wrap(err)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return database.ImportResult{}, wp.Wrap(err)
+	}
+	defer tx.Rollback(context.Background()) //nolint:errcheck
+
+	var (
+		result  database.ImportResult
+		pending = make([]database.Link, 0, importBatchSize)
+	)
+
+	for {
+		link, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return database.ImportResult{}, wp.Wrap(err)
+		}
+
+		if link.Domain == "" {
+			link.Domain = domain(ctx)
+		}
+
+		pending = append(pending, link)
+		if len(pending) < importBatchSize {
+			continue
+		}
+
+		if err := importBatch(ctx, tx, pending, mode, &result); err != nil {
+			return database.ImportResult{}, wp.Wrap(err)
+		}
+		pending = pending[:0]
+	}
+
+	if err := importBatch(ctx, tx, pending, mode, &result); err != nil {
+		return database.ImportResult{}, wp.Wrap(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return database.ImportResult{}, wp.Wrap(err)
+	}
+
+	return result, nil
+}
+
+func importBatch(ctx context.Context, tx pgx.Tx, links []database.Link, mode database.ConflictMode, result *database.ImportResult) error {
+	const fn = "database.postgres.importBatch"
+
+	if len(links) == 0 {
+		return nil
+	}
+
+	overwrite := mode == database.ConflictOverwrite
+
+	query := importSkipQuery
+	if overwrite {
+		query = importOverwriteQuery
+	}
+
+	batch := &pgx.Batch{}
+	for _, link := range links {
+		if overwrite {
+			batch.Queue(importLockQuery, link.Alias, link.Domain)
+		}
+		batch.Queue(query, link.URL, link.Alias, createdAt(link), link.Domain)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	defer br.Close()
+
+	events := make([]database.AuditEvent, 0, len(links))
+
+	for _, link := range links {
+		var before *database.Link
+		if overwrite {
+			old, err := scanLink(br.QueryRow())
+			switch {
+			case err == nil:
+				before = &old
+			case !errors.Is(err, pgx.ErrNoRows):
+				return wraper.Wrapf(fn, err, "alias=%s", link.Alias)
+			}
+		}
+
+		after, err := scanLink(br.QueryRow())
+		if errors.Is(err, pgx.ErrNoRows) {
+			if mode == database.ConflictFail {
+				return wraper.Wrapf(fn, database.ErrURLExist, "alias=%s", link.Alias)
+			}
+			result.Skipped++
+			continue
+		}
+		if err != nil {
+			return wraper.Wrapf(fn, err, "alias=%s", link.Alias)
+		}
+
+		if before == nil {
+			result.Created++
+			events = append(events, event(ctx, database.AuditCreate, nil, &after))
+		} else {
+			result.Updated++
+			result.Overwritten = append(result.Overwritten, link)
+			events = append(events, event(ctx, database.AuditUpdate, before, &after))
+		}
+	}
+
+	if err := br.Close(); err != nil {
+		return err
+	}
+
+	return audit(ctx, tx, events...)
+}
+
+func createdAt(link database.Link) *time.Time {
+	if link.CreatedAt.IsZero() {
+		return nil
+	}
+	return &link.CreatedAt
+}
+
+// ExportURLs reads the table through a server-side cursor, so only one page
+// of rows is held in memory whatever the table size.
+func (s *storage) ExportURLs(ctx context.Context, each func(database.Link) error) error {
+	const fn = "database.postgres.(*storage).ExportURLs"
+
+	wp := wraper.New(fn)
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return wp.Wrap(err)
+	}
+	defer tx.Rollback(context.Background()) //nolint:errcheck
+
+	declare := `DECLARE export_urls NO SCROLL CURSOR FOR
+	SELECT id, url, alias, domain, created_at, updated_at FROM urls WHERE deleted_at IS NULL ORDER BY id`
+
+	if _, err := tx.Exec(ctx, declare); err != nil {
+		return wp.Wrap(err)
+	}
+
+	fetch := fmt.Sprintf(`FETCH FORWARD %d FROM export_urls`, exportFetchSize)
+
+	page := make([]database.Link, 0, exportFetchSize)
+
+	for {
+		page = page[:0]
+
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return wp.Wrap(err)
+		}
+
+		for rows.Next() {
+			var link database.Link
+			if err := rows.Scan(&link.ID, &link.URL, &link.Alias, &link.Domain, &link.CreatedAt, &link.UpdatedAt); err != nil {
+				rows.Close()
+				return wp.Wrap(err)
+			}
+			page = append(page, link)
+		}
+
+		if err := rows.Err(); err != nil {
+			return wp.Wrap(err)
+		}
+
+		for _, link := range page {
+			if err := each(link); err != nil {
+				return wp.Wrap(err)
+			}
+		}
+
+		if len(page) < exportFetchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sliceReader []database.Link
+
+func (s *sliceReader) Read() (database.Link, error) {
+	if len(*s) == 0 {
+		return database.Link{}, io.EOF
+	}
+
+	link := (*s)[0]
+	*s = (*s)[1:]
+	return link, nil
+}
+
+func TestImportURLs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	require.NoError(t, db.SaveURL(ctx, "https://old.example.com", "existing"))
+
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	links := func() *sliceReader {
+		return &sliceReader{
+			{URL: "https://new.example.com", Alias: "existing"},
+			{URL: "https://example.com/fresh", Alias: "fresh", CreatedAt: created},
+		}
+	}
+
+	t.Run("fail rolls back", func(t *testing.T) {
+		_, err := db.ImportURLs(ctx, links(), database.ConflictFail)
+		assert.ErrorIs(t, err, database.ErrURLExist)
+
+		_, err = db.GetURl(ctx, "fresh")
+		assert.ErrorIs(t, err, database.ErrURLNotFound)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		res, err := db.ImportURLs(ctx, links(), database.ConflictSkip)
+		require.NoError(t, err)
+		assert.Equal(t, database.ImportResult{Created: 1, Skipped: 1}, res)
+
+		url, err := db.GetURl(ctx, "existing")
+		require.NoError(t, err)
+		assert.Equal(t, "https://old.example.com", url)
+
+		link, err := db.GetLink(ctx, "fresh")
+		require.NoError(t, err)
+		assert.True(t, created.Equal(link.CreatedAt))
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		res, err := db.ImportURLs(ctx, links(), database.ConflictOverwrite)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), res.Updated)
+		require.Len(t, res.Overwritten, 2)
+		assert.Equal(t, "existing", res.Overwritten[0].Alias)
+		assert.Equal(t, "fresh", res.Overwritten[1].Alias)
+
+		url, err := db.GetURl(ctx, "existing")
+		require.NoError(t, err)
+		assert.Equal(t, "https://new.example.com", url)
+	})
+
+	t.Run("unknown mode", func(t *testing.T) {
+		_, err := db.ImportURLs(ctx, links(), "merge")
+		assert.ErrorIs(t, err, database.ErrUnknownConflictMode)
+	})
+}
+
+func TestExportURLs(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	// more than one cursor page
+	const total = exportFetchSize + 10
+
+	var r sliceReader
+	for i := 0; i < total; i++ {
+		r = append(r, database.Link{URL: fmt.Sprintf("https://example.com/%d", i), Alias: fmt.Sprintf("export%d", i)})
+	}
+
+	_, err := db.ImportURLs(ctx, &r, database.ConflictFail)
+	require.NoError(t, err)
+
+	var got []database.Link
+	require.NoError(t, db.ExportURLs(ctx, func(link database.Link) error {
+		got = append(got, link)
+		return nil
+	}))
+
+	require.Len(t, got, total)
+	for i := 1; i < len(got); i++ {
+		assert.Less(t, got[i-1].ID, got[i].ID)
+	}
+}
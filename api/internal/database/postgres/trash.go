@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *storage) TrashedURLs(ctx context.Context, limit, offset int) ([]database.Link, error) {
+	const fn = "database.postgres.(*storage).TrashedURLs"
+
+	wp := wraper.New(fn)
+
+	if limit <= 0 || offset < 0 {
+		return nil, wp.Wrap(database.ErrInvalidPage)
+	}
+
+	query := `SELECT ` + linkColumns + ` FROM urls
+	WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC, id
+	LIMIT $1 OFFSET $2`
+
+	links, err := s.queryLinks(ctx, limit, query, limit, offset)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	return links, nil
+}
+
+func (s *storage) RestoreURL(ctx context.Context, alias string, since time.Time) error {
+	const fn = "database.postgres.(*storage).RestoreURL"
+
+	wp := wraper.New(fn)
+
+	query := `UPDATE urls SET deleted_at = NULL WHERE id = $1 AND deleted_at >= $2 RETURNING ` + linkColumns
+
+	if err := s.change(ctx, database.AuditRestore, alias, true, query, since); err != nil {
+		return wp.Wrap(err)
+	}
+
+	return nil
+}
+
+// PurgeURLs removes the links deleted longest ago first, so that repeated
+// calls work through a large trash in batches. Every purged link is
+// recorded in the audit log.
+func (s *storage) PurgeURLs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	const fn = "database.postgres.(*storage).PurgeURLs"
+
+	wp := wraper.New(fn)
+
+	if limit <= 0 {
+		return 0, wp.Wrap(database.ErrInvalidPage)
+	}
+
+	query := `DELETE FROM urls WHERE id IN (
+		SELECT id FROM urls
+		WHERE deleted_at < $1
+		ORDER BY deleted_at
+		LIMIT $2
+	)
+	RETURNING ` + linkColumns
+
+	var n int64
+	err := s.inTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, before, limit)
+		if err != nil {
+			return err
+		}
+
+		links, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (database.Link, error) {
+			return scanLink(row)
+		})
+		if err != nil {
+			return err
+		}
+
+		events := make([]database.AuditEvent, 0, len(links))
+		for i := range links {
+			events = append(events, event(ctx, database.AuditPurge, &links[i], nil))
+		}
+
+		n = int64(len(links))
+		return audit(ctx, tx, events...)
+	})
+	if err != nil {
+		return 0, wp.Wrap(err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	require.NoError(t, db.SaveURL(ctx, "https://example.com/report", "report"))
+	require.NoError(t, db.SaveURL(ctx, "https://example.com/kept", "kept"))
+
+	n, err := db.DeleteURL(ctx, "report")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// deleted links are gone everywhere but the trash
+	_, err = db.GetURl(ctx, "report")
+	assert.ErrorIs(t, err, database.ErrURLNotFound)
+	_, err = db.GetTarget(ctx, "report")
+	assert.ErrorIs(t, err, database.ErrURLNotFound)
+	assert.ErrorIs(t, db.UpdateURL(ctx, "report", "https://example.com"), database.ErrURLNotFound)
+	_, err = db.DeleteURL(ctx, "report")
+	assert.ErrorIs(t, err, database.ErrURLNotFound)
+
+	links, err := db.ListURLs(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "kept", links[0].Alias)
+
+	stats, err := db.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Total)
+
+	trashed, err := db.TrashedURLs(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, "report", trashed[0].Alias)
+	require.NotNil(t, trashed[0].DeletedAt)
+
+	// the alias stays reserved
+	assert.ErrorIs(t, db.SaveURL(ctx, "https://other.example.com", "report"), database.ErrURLExist)
+
+	res, err := db.ImportURLs(ctx, &sliceReader{{URL: "https://other.example.com", Alias: "report"}}, database.ConflictOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, database.ImportResult{Skipped: 1}, res)
+
+	// links deleted before the retention cannot be restored
+	deletedAt := *trashed[0].DeletedAt
+	assert.ErrorIs(t, db.RestoreURL(ctx, "report", deletedAt.Add(time.Second)), database.ErrURLNotFound)
+
+	require.NoError(t, db.RestoreURL(ctx, "report", deletedAt.Add(-time.Hour)))
+
+	url, err := db.GetURl(ctx, "report")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/report", url)
+
+	assert.ErrorIs(t, db.RestoreURL(ctx, "kept", deletedAt.Add(-time.Hour)), database.ErrURLNotFound)
+
+	// purging removes only links deleted before the cutoff
+	_, err = db.DeleteURL(ctx, "report")
+	require.NoError(t, err)
+
+	purged, err := db.PurgeURLs(ctx, deletedAt.Add(-time.Hour), 10)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	purged, err = db.PurgeURLs(ctx, time.Now().Add(time.Hour), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	trashed, err = db.TrashedURLs(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+
+	// a purged alias is free again
+	require.NoError(t, db.SaveURL(ctx, "https://other.example.com", "report"))
+
+	_, err = db.PurgeURLs(ctx, time.Now(), 0)
+	assert.ErrorIs(t, err, database.ErrInvalidPage)
+}
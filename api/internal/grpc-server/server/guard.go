@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/grpc-server/shortener"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/ratelimiter"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	shortenerv1 "github.com/Pshimaf-Git/url-shortener/api/pkg/proto/shortener/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// method is how calls of a method are guarded.
+type method struct {
+	// group is the route group whose limits count the calls.
+	group string
+	// keyed methods are served only to calls carrying an API key.
+	keyed bool
+}
+
+// methods guards the calls changing links like the HTTP routes doing so,
+// except that they all need a key. Calls of other methods, e.g. health
+// checks, are let through.
+var methods = map[string]method{
+	shortenerv1.ShortenerService_Shorten_FullMethodName:      {group: config.RouteWrites, keyed: true},
+	shortenerv1.ShortenerService_Delete_FullMethodName:       {group: config.RouteWrites, keyed: true},
+	shortenerv1.ShortenerService_BatchShorten_FullMethodName: {group: config.RouteWrites, keyed: true},
+	shortenerv1.ShortenerService_BatchDelete_FullMethodName:  {group: config.RouteWrites, keyed: true},
+	shortenerv1.ShortenerService_Resolve_FullMethodName:      {group: config.RouteRedirects},
+	shortenerv1.ShortenerService_BatchResolve_FullMethodName: {group: config.RouteRedirects},
+}
+
+// guard rejects calls of keyed methods without one of the keys and counts
+// every call against the limits of its route group, batch calls once per
+// item, so batches get no more through than single calls. With no keys
+// configured every call of a keyed method is rejected.
+func guard(opts *options) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		m, ok := methods[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		client, known := apikey.Match(opts.keys, keyFromContext(ctx))
+		if m.keyed && !known {
+			return nil, shortener.Status(apierr.ErrUnauthorized).Err()
+		}
+		if known {
+			ctx = apikey.WithName(ctx, client.Name)
+		}
+
+		if opts.limiter != nil {
+			res, limited := ratelimiter.AllowGroup(ctx, opts.limiter, opts.limits, m.group, client, known, peerIP(ctx), items(req))
+			if limited && !res.Allowed {
+				secs := int64(math.Ceil(res.RetryAfter.Seconds()))
+				_ = grpc.SetTrailer(ctx, metadata.Pairs("retry-after", strconv.FormatInt(max(secs, 1), 10)))
+
+				return nil, shortener.Status(apierr.ErrTooManyRequests).Err()
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// keyFromContext returns the key sent in the `x-api-key` metadata or as an
+// `authorization: Bearer` token, like the HTTP API accepts it.
+func keyFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if v := md.Get(strings.ToLower(apikey.Header)); len(v) > 0 && strings.TrimSpace(v[0]) != "" {
+		return strings.TrimSpace(v[0])
+	}
+
+	const bearerPrefix = "bearer "
+	if v := md.Get("authorization"); len(v) > 0 && len(v[0]) > len(bearerPrefix) && strings.EqualFold(v[0][:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(v[0][len(bearerPrefix):])
+	}
+
+	return ""
+}
+
+// items returns the number of items of a batch call, at least one.
+func items(req any) int {
+	var n int
+
+	switch r := req.(type) {
+	case *shortenerv1.BatchShortenRequest:
+		n = len(r.GetRequests())
+	case *shortenerv1.BatchResolveRequest:
+		n = len(r.GetAliases())
+	case *shortenerv1.BatchDeleteRequest:
+		n = len(r.GetAliases())
+	}
+
+	return max(n, 1)
+}
@@ -0,0 +1,235 @@
+// Package server runs the gRPC API with health checking and reflection.
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/grpc-server/shortener"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/ratelimiter"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/domains"
+	shortenerv1 "github.com/Pshimaf-Git/url-shortener/api/pkg/proto/shortener/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// DomainHeader is the metadata naming the domain of the aliases of a call.
+const DomainHeader = "x-domain"
+
+type Server struct {
+	addr   string
+	serv   *grpc.Server
+	health *health.Server
+}
+
+type options struct {
+	keys    []config.APIKey
+	limiter ratelimiter.Limiter
+	limits  config.RateLimits
+	domains *domains.Registry
+}
+
+type OptFunc func(*options)
+
+// WithAPIKeys sets the keys accepted by the methods changing links.
+func WithAPIKeys(keys []config.APIKey) OptFunc {
+	return func(o *options) {
+		o.keys = keys
+	}
+}
+
+// WithLimits counts calls with limiter against the limits of the route
+// groups of the HTTP API.
+func WithLimits(limiter ratelimiter.Limiter, limits config.RateLimits) OptFunc {
+	return func(o *options) {
+		o.limiter, o.limits = limiter, limits
+	}
+}
+
+// WithDomains makes calls to the domains of r use their own aliases, like
+// requests to the HTTP API.
+func WithDomains(r *domains.Registry) OptFunc {
+	return func(o *options) {
+		o.domains = r
+	}
+}
+
+// New registers svc on a gRPC server together with the standard health
+// service and, when enabled, the reflection service. Calls changing links
+// need one of the keys set with WithAPIKeys.
+func New(cfg *config.GRPCConfig, svc shortenerv1.ShortenerServiceServer, log *slog.Logger, opts ...OptFunc) *Server {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	serv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			recoverer(log),
+			logger(log),
+			guard(o),
+			domain(o.domains),
+			actor(),
+		),
+	)
+
+	shortenerv1.RegisterShortenerServiceServer(serv, svc)
+
+	hs := health.NewServer()
+	hs.SetServingStatus(shortenerv1.ShortenerService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(serv, hs)
+
+	if cfg.Reflection {
+		reflection.Register(serv)
+	}
+
+	return &Server{
+		addr:   net.JoinHostPort(cfg.Host, cfg.Port),
+		serv:   serv,
+		health: hs,
+	}
+}
+
+func (s *Server) Addr() string { return s.addr }
+
+// Run listens on the configured address and serves until Shutdown.
+func (s *Server) Run() error {
+	const fn = "grpc.server.(*Server).Run"
+
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return wraper.Wrap(fn, err)
+	}
+
+	return s.Serve(lis)
+}
+
+// Serve serves on lis until Shutdown.
+func (s *Server) Serve(lis net.Listener) error {
+	return s.serv.Serve(lis)
+}
+
+// Shutdown reports NOT_SERVING to health checks and waits for running
+// calls, they are cancelled when ctx is done first.
+func (s *Server) Shutdown(ctx context.Context) {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.serv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.serv.Stop()
+	}
+}
+
+func logger(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		log.Info("grpc request completed",
+			slog.String("method", info.FullMethod),
+			slog.String("code", status.Code(err).String()),
+			slog.String("duration", time.Since(start).String()),
+		)
+
+		return resp, err
+	}
+}
+
+func recoverer(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error("panic in grpc handler",
+					slog.String("method", info.FullMethod),
+					slog.Any("panic", r),
+					slog.String("stack", string(debug.Stack())),
+				)
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// actor records the changes of a call in the audit log as done by the
+// address of the peer and the key it was authenticated with.
+func actor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		a := database.Actor{IP: peerIP(ctx)}
+		a.APIKey, _ = apikey.NameFromContext(ctx)
+
+		if a != (database.Actor{}) {
+			ctx = database.WithActor(ctx, a)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// domain scopes the aliases of a call to the domain named by its
+// `x-domain` metadata, or else to the one it is sent to, the :authority of
+// the call. Calls to unknown hosts use the default namespace, an unknown
+// `x-domain` is rejected rather than changing the default one.
+func domain(r *domains.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if r == nil {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		if v := md.Get(DomainHeader); len(v) > 0 && strings.TrimSpace(v[0]) != "" {
+			d, ok := r.Lookup(ctx, strings.TrimSpace(v[0]))
+			if !ok {
+				return nil, shortener.Status(apierr.ErrInvalidDomain).Err()
+			}
+			return handler(database.WithDomain(ctx, d), req)
+		}
+
+		if v := md.Get(":authority"); len(v) > 0 {
+			if d, ok := r.Lookup(ctx, v[0]); ok {
+				ctx = database.WithDomain(ctx, d)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// peerIP returns the address of the peer without its port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	ip, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return ip
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/ratelimiter"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/logger/discard"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/domains"
+	shortenerv1 "github.com/Pshimaf-Git/url-shortener/api/pkg/proto/shortener/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type panicking struct {
+	shortenerv1.UnimplementedShortenerServiceServer
+}
+
+func (panicking) Resolve(context.Context, *shortenerv1.ResolveRequest) (*shortenerv1.ResolveResponse, error) {
+	panic("boom")
+}
+
+func dial(t *testing.T, cfg *config.GRPCConfig) (*Server, *grpc.ClientConn) {
+	t.Helper()
+
+	srv := New(cfg, panicking{}, discard.NewDiscardLogger())
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis) //nolint:errcheck
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return srv, conn
+}
+
+func TestHealth(t *testing.T) {
+	srv, conn := dial(t, &config.GRPCConfig{})
+
+	client := healthpb.NewHealthClient(conn)
+
+	for _, service := range []string{"", shortenerv1.ShortenerService_ServiceDesc.ServiceName} {
+		res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus(), "service %q", service)
+	}
+
+	srv.Shutdown(context.Background())
+}
+
+func TestReflection(t *testing.T) {
+	list := func(t *testing.T, cfg *config.GRPCConfig) ([]string, error) {
+		srv, conn := dial(t, cfg)
+		defer srv.Shutdown(context.Background())
+
+		// cancelled before the graceful stop, which waits for open streams
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+		require.NoError(t, err)
+
+		err = stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		})
+		require.NoError(t, err)
+
+		res, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, s := range res.GetListServicesResponse().GetService() {
+			names = append(names, s.GetName())
+		}
+		return names, nil
+	}
+
+	names, err := list(t, &config.GRPCConfig{Reflection: true})
+	require.NoError(t, err)
+	assert.Contains(t, names, shortenerv1.ShortenerService_ServiceDesc.ServiceName)
+	assert.Contains(t, names, healthpb.Health_ServiceDesc.ServiceName)
+
+	_, err = list(t, &config.GRPCConfig{Reflection: false})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestRecoverer(t *testing.T) {
+	srv, conn := dial(t, &config.GRPCConfig{})
+	defer srv.Shutdown(context.Background())
+
+	_, err := shortenerv1.NewShortenerServiceClient(conn).Resolve(context.Background(), &shortenerv1.ResolveRequest{Alias: "a"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestActor(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51234},
+	})
+
+	var got database.Actor
+	_, err := actor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		got = database.ActorFrom(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, database.Actor{IP: "203.0.113.7"}, got)
+}
+
+func TestDomain(t *testing.T) {
+	registry := domains.New([]database.Domain{{Name: "go.brand-a.com"}}, nil, 0, nil)
+
+	testCases := []struct {
+		name     string
+		md       metadata.MD
+		want     string
+		wantCode codes.Code
+	}{
+		{name: "default domain", md: metadata.Pairs(":authority", "localhost:50051")},
+		{name: "sent to a domain", md: metadata.Pairs(":authority", "go.brand-a.com:50051"), want: "go.brand-a.com"},
+		{name: "named domain", md: metadata.Pairs(":authority", "localhost:50051", DomainHeader, "Go.Brand-A.com"), want: "go.brand-a.com"},
+		{name: "unknown named domain", md: metadata.Pairs(DomainHeader, "go.brand-b.com"), wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			got := "unset"
+			_, err := domain(registry)(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+				got = database.DomainFrom(ctx).Name
+				return nil, nil
+			})
+
+			if tt.wantCode != codes.OK {
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				assert.Equal(t, "unset", got)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGuard(t *testing.T) {
+	keys := []config.APIKey{{Name: "ops", Key: "ops-secret"}}
+	limits := config.RateLimits{
+		DefaultTier: config.TierFree,
+		Groups: map[string]config.RateLimitGroup{
+			config.RouteRedirects: {
+				Tiers: map[string]config.RateLimit{
+					config.TierFree: {Requests: 2, Window: time.Minute},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		opts     options
+		method   string
+		req      any
+		md       metadata.MD
+		wantCode codes.Code
+		wantKey  string
+	}{
+		{
+			name:     "mutating call without key",
+			opts:     options{keys: keys},
+			method:   shortenerv1.ShortenerService_Shorten_FullMethodName,
+			req:      &shortenerv1.ShortenRequest{},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "batch call with wrong key",
+			opts:     options{keys: keys},
+			method:   shortenerv1.ShortenerService_BatchDelete_FullMethodName,
+			req:      &shortenerv1.BatchDeleteRequest{},
+			md:       metadata.Pairs("x-api-key", "guess"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "no keys configured",
+			opts:     options{},
+			method:   shortenerv1.ShortenerService_Delete_FullMethodName,
+			req:      &shortenerv1.DeleteRequest{},
+			md:       metadata.Pairs("x-api-key", "ops-secret"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "bearer token",
+			opts:     options{keys: keys},
+			method:   shortenerv1.ShortenerService_BatchShorten_FullMethodName,
+			req:      &shortenerv1.BatchShortenRequest{},
+			md:       metadata.Pairs("authorization", "Bearer ops-secret"),
+			wantCode: codes.OK,
+			wantKey:  "ops",
+		},
+		{
+			name:     "resolve without key",
+			opts:     options{keys: keys, limiter: ratelimiter.NewLocalLimiter(), limits: limits},
+			method:   shortenerv1.ShortenerService_Resolve_FullMethodName,
+			req:      &shortenerv1.ResolveRequest{},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "batch counted per item",
+			opts:     options{keys: keys, limiter: ratelimiter.NewLocalLimiter(), limits: limits},
+			method:   shortenerv1.ShortenerService_BatchResolve_FullMethodName,
+			req:      &shortenerv1.BatchResolveRequest{Aliases: []string{"a", "b", "c"}},
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:     "other services",
+			opts:     options{},
+			method:   healthpb.Health_Check_FullMethodName,
+			req:      &healthpb.HealthCheckRequest{},
+			wantCode: codes.OK,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			var gotKey string
+			_, err := guard(&tt.opts)(ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(ctx context.Context, _ any) (any, error) {
+				gotKey, _ = apikey.NameFromContext(ctx)
+				return nil, nil
+			})
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantKey, gotKey)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	shortenerv1 "github.com/Pshimaf-Git/url-shortener/api/pkg/proto/shortener/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the google.rpc.ErrorInfo attached to errors.
+const ErrorDomain = "url-shortener"
+
+// grpcCodes maps the error codes of the HTTP API to gRPC codes, the rest
+// are Internal.
+var grpcCodes = map[string]codes.Code{
+	apierr.CodeNotFound:            codes.NotFound,
+	apierr.CodeEmptyAlias:          codes.InvalidArgument,
+	apierr.CodeEmptyURL:            codes.InvalidArgument,
+	apierr.CodeInvalidURL:          codes.InvalidArgument,
+	apierr.CodeInvalidPage:         codes.InvalidArgument,
+	apierr.CodeDestinationRejected: codes.InvalidArgument,
+	apierr.CodeUnsafeURL:           codes.PermissionDenied,
+	apierr.CodeReputationDown:      codes.Unavailable,
+	apierr.CodeLinkDisabled:        codes.FailedPrecondition,
+	apierr.CodeInvalidDomain:       codes.InvalidArgument,
+	apierr.CodeDomainTaken:         codes.AlreadyExists,
+	apierr.CodeInvalidOptions:      codes.InvalidArgument,
+	apierr.CodePasswordRequired:    codes.PermissionDenied,
+	apierr.CodeWrongPassword:       codes.PermissionDenied,
+	apierr.CodeLinkExhausted:       codes.FailedPrecondition,
+	apierr.CodeLinkNotActive:       codes.FailedPrecondition,
+	apierr.CodeLinkExpired:         codes.FailedPrecondition,
+	apierr.CodeAliasTaken:          codes.AlreadyExists,
+	apierr.CodeAliasGeneration:     codes.Unavailable,
+	apierr.CodeRateLimited:         codes.ResourceExhausted,
+	apierr.CodeUnauthorized:        codes.Unauthenticated,
+}
+
+// Code returns the gRPC code of err.
+func Code(err error) codes.Code {
+	if errors.Is(err, context.Canceled) {
+		return codes.Canceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return codes.DeadlineExceeded
+	}
+
+	if code, ok := grpcCodes[handlers.Classify(err).Code]; ok {
+		return code
+	}
+	return codes.Internal
+}
+
+// Status converts err to a status carrying the error code of the HTTP API
+// as the reason of a google.rpc.ErrorInfo. Internal errors keep their
+// message to themselves, like in the HTTP API.
+func Status(err error) *status.Status {
+	info := handlers.Classify(err)
+
+	code := Code(err)
+	msg := info.Err.Error()
+	if code == codes.Canceled || code == codes.DeadlineExceeded {
+		msg = err.Error()
+	}
+
+	st := status.New(code, msg)
+
+	detailed, derr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: info.Code,
+		Domain: ErrorDomain,
+	})
+	if derr != nil {
+		return st
+	}
+
+	return detailed
+}
+
+// itemError reports the failure of a batch item.
+func itemError(err error) *shortenerv1.Error {
+	info := handlers.Classify(err)
+	return &shortenerv1.Error{Code: info.Code, Message: info.Err.Error()}
+}
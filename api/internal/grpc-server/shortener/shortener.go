@@ -0,0 +1,213 @@
+// Package shortener implements the gRPC ShortenerService as an adapter over
+// the shortener service the HTTP handlers use, so both APIs behave the same.
+package shortener
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	shortenerv1 "github.com/Pshimaf-Git/url-shortener/api/pkg/proto/shortener/v1"
+	shortenersvc "github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// MaxBatchSize bounds the number of items of a batch call.
+	MaxBatchSize = 1000
+	// batchWorkers is the number of items of a batch processed at once.
+	batchWorkers = 8
+)
+
+var ErrBatchTooLarge = errors.New("batch too large")
+
+type Service struct {
+	shortenerv1.UnimplementedShortenerServiceServer
+
+	svc *shortenersvc.Shortener
+	log *slog.Logger
+}
+
+func New(svc *shortenersvc.Shortener, log *slog.Logger) *Service {
+	return &Service{svc: svc, log: log}
+}
+
+func (s *Service) Shorten(ctx context.Context, req *shortenerv1.ShortenRequest) (*shortenerv1.ShortenResponse, error) {
+	const fn = "grpc.shortener.(*Service).Shorten"
+
+	alias, err := s.shorten(ctx, req)
+	if err != nil {
+		return nil, s.fail(fn, err, slog.String("url", req.GetUrl()), slog.String("alias", req.GetAlias()))
+	}
+
+	return &shortenerv1.ShortenResponse{Alias: alias}, nil
+}
+
+func (s *Service) Resolve(ctx context.Context, req *shortenerv1.ResolveRequest) (*shortenerv1.ResolveResponse, error) {
+	const fn = "grpc.shortener.(*Service).Resolve"
+
+	url, err := s.resolve(ctx, req.GetAlias())
+	if err != nil {
+		return nil, s.fail(fn, err, slog.String("alias", req.GetAlias()))
+	}
+
+	return &shortenerv1.ResolveResponse{Url: url}, nil
+}
+
+func (s *Service) Delete(ctx context.Context, req *shortenerv1.DeleteRequest) (*shortenerv1.DeleteResponse, error) {
+	const fn = "grpc.shortener.(*Service).Delete"
+
+	if err := s.delete(ctx, req.GetAlias()); err != nil {
+		return nil, s.fail(fn, err, slog.String("alias", req.GetAlias()))
+	}
+
+	return &shortenerv1.DeleteResponse{}, nil
+}
+
+func (s *Service) BatchShorten(ctx context.Context, req *shortenerv1.BatchShortenRequest) (*shortenerv1.BatchShortenResponse, error) {
+	const fn = "grpc.shortener.(*Service).BatchShorten"
+
+	if err := checkBatch(len(req.GetRequests())); err != nil {
+		return nil, err
+	}
+
+	results := make([]*shortenerv1.ShortenResult, len(req.GetRequests()))
+
+	each(len(results), func(i int) {
+		item := req.GetRequests()[i]
+
+		alias, err := s.shorten(ctx, item)
+		if err != nil {
+			s.logItem(fn, err, slog.String("url", item.GetUrl()), slog.String("alias", item.GetAlias()))
+			results[i] = &shortenerv1.ShortenResult{Alias: item.GetAlias(), Error: itemError(err)}
+			return
+		}
+
+		results[i] = &shortenerv1.ShortenResult{Alias: alias}
+	})
+
+	return &shortenerv1.BatchShortenResponse{Results: results}, nil
+}
+
+func (s *Service) BatchResolve(ctx context.Context, req *shortenerv1.BatchResolveRequest) (*shortenerv1.BatchResolveResponse, error) {
+	const fn = "grpc.shortener.(*Service).BatchResolve"
+
+	if err := checkBatch(len(req.GetAliases())); err != nil {
+		return nil, err
+	}
+
+	results := make([]*shortenerv1.ResolveResult, len(req.GetAliases()))
+
+	each(len(results), func(i int) {
+		alias := req.GetAliases()[i]
+
+		url, err := s.resolve(ctx, alias)
+		if err != nil {
+			s.logItem(fn, err, slog.String("alias", alias))
+			results[i] = &shortenerv1.ResolveResult{Alias: alias, Error: itemError(err)}
+			return
+		}
+
+		results[i] = &shortenerv1.ResolveResult{Alias: alias, Url: url}
+	})
+
+	return &shortenerv1.BatchResolveResponse{Results: results}, nil
+}
+
+func (s *Service) BatchDelete(ctx context.Context, req *shortenerv1.BatchDeleteRequest) (*shortenerv1.BatchDeleteResponse, error) {
+	const fn = "grpc.shortener.(*Service).BatchDelete"
+
+	if err := checkBatch(len(req.GetAliases())); err != nil {
+		return nil, err
+	}
+
+	results := make([]*shortenerv1.DeleteResult, len(req.GetAliases()))
+
+	each(len(results), func(i int) {
+		alias := req.GetAliases()[i]
+
+		results[i] = &shortenerv1.DeleteResult{Alias: alias}
+
+		if err := s.delete(ctx, alias); err != nil {
+			s.logItem(fn, err, slog.String("alias", alias))
+			results[i].Error = itemError(err)
+		}
+	})
+
+	return &shortenerv1.BatchDeleteResponse{Results: results}, nil
+}
+
+func (s *Service) shorten(ctx context.Context, req *shortenerv1.ShortenRequest) (string, error) {
+	return s.svc.Shorten(ctx, req.GetUrl(), req.GetAlias())
+}
+
+// resolve looks alias up without counting a redirect, the caller does not
+// necessarily follow it.
+func (s *Service) resolve(ctx context.Context, alias string) (string, error) {
+	v, err := s.svc.Peek(ctx, alias, nil)
+	if err != nil {
+		return "", err
+	}
+	return v.URL, nil
+}
+
+func (s *Service) delete(ctx context.Context, alias string) error {
+	return s.svc.Delete(ctx, alias)
+}
+
+// fail logs err and converts it to a status error.
+func (s *Service) fail(fn string, err error, attrs ...any) error {
+	s.logItem(fn, err, attrs...)
+	return Status(err).Err()
+}
+
+// logItem logs expected failures, such as unknown aliases, at info level.
+func (s *Service) logItem(fn string, err error, attrs ...any) {
+	log := s.log.With(slog.String("fn", fn)).With(attrs...)
+
+	if Code(err) == codes.Internal {
+		log.Error("request failed", sl.Error(err))
+		return
+	}
+
+	if errors.Is(err, database.ErrURLNotFound) {
+		log.Info("url not found")
+		return
+	}
+
+	log.Info("request rejected", sl.Error(err))
+}
+
+func checkBatch(n int) error {
+	if n > MaxBatchSize {
+		return status.Errorf(codes.InvalidArgument, "%s: %d items, at most %d allowed", ErrBatchTooLarge, n, MaxBatchSize)
+	}
+	return nil
+}
+
+// each calls fn for every index in [0, n), batchWorkers at a time.
+func each(n int, fn func(i int)) {
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, batchWorkers)
+	)
+
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,334 @@
+package shortener_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	grpcserver "github.com/Pshimaf-Git/url-shortener/api/internal/grpc-server/server"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/grpc-server/shortener"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/logger/discard"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	shortenerv1 "github.com/Pshimaf-Git/url-shortener/api/pkg/proto/shortener/v1"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	stdAliasLen = 6
+	testKey     = "test-secret"
+)
+
+// newClient serves the service over an in-memory connection.
+func newClient(t *testing.T, db database.Database, c cache.Cache) shortenerv1.ShortenerServiceClient {
+	t.Helper()
+
+	log := discard.NewDiscardLogger()
+	h := handlers.New(db, c, &config.ServerConfig{StdAliasLen: stdAliasLen}, log)
+
+	srv := grpcserver.New(&config.GRPCConfig{}, shortener.New(h.Service(), log), log,
+		grpcserver.WithAPIKeys(config.APIKeys{{Name: "test", Key: testKey}}),
+	)
+
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis) //nolint:errcheck
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", testKey)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return shortenerv1.NewShortenerServiceClient(conn)
+}
+
+// reason returns the error code carried in the ErrorInfo detail.
+func reason(t *testing.T, err error) string {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	require.True(t, ok, "not a status error: %v", err)
+
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			assert.Equal(t, shortener.ErrorDomain, info.GetDomain())
+			return info.GetReason()
+		}
+	}
+
+	t.Fatalf("no ErrorInfo in %v", st)
+	return ""
+}
+
+func TestShorten(t *testing.T) {
+	testCases := []struct {
+		name       string
+		req        *shortenerv1.ShortenRequest
+		dbBehavior func(m *mocks.MockDatabase)
+		wantAlias  string
+		// wantLen is checked instead of wantAlias for generated aliases
+		wantLen    int
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{
+			name: "custom alias",
+			req:  &shortenerv1.ShortenRequest{Url: "https://google.com", Alias: "google"},
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), "https://google.com", "google").Return(nil)
+			},
+			wantAlias: "google",
+		},
+		{
+			name: "generated alias",
+			req:  &shortenerv1.ShortenRequest{Url: "https://google.com"},
+			dbBehavior: func(m *mocks.MockDatabase) {
+				gomock.InOrder(
+					m.EXPECT().SaveURL(gomock.Any(), "https://google.com", gomock.Any()).Return(database.ErrURLExist),
+					m.EXPECT().SaveURL(gomock.Any(), "https://google.com", gomock.Any()).Return(nil),
+				)
+			},
+			wantLen: stdAliasLen,
+		},
+		{
+			name: "alias taken",
+			req:  &shortenerv1.ShortenRequest{Url: "https://google.com", Alias: "google"},
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), "https://google.com", "google").Return(database.ErrURLExist)
+			},
+			wantCode:   codes.AlreadyExists,
+			wantReason: apierr.CodeAliasTaken,
+		},
+		{
+			name:       "invalid url",
+			req:        &shortenerv1.ShortenRequest{Url: "ftp://google.com"},
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantCode:   codes.InvalidArgument,
+			wantReason: apierr.CodeInvalidURL,
+		},
+		{
+			name: "database failure",
+			req:  &shortenerv1.ShortenRequest{Url: "https://google.com", Alias: "google"},
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("connection refused"))
+			},
+			wantCode:   codes.Internal,
+			wantReason: apierr.CodeInternal,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			db := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(db)
+
+			client := newClient(t, db, cachemock.NewMockCache(ctrl))
+
+			res, err := client.Shorten(context.Background(), tt.req)
+
+			if tt.wantCode != codes.OK {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				assert.Equal(t, tt.wantReason, reason(t, err))
+				assert.NotContains(t, err.Error(), "connection refused", "internal details leaked")
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.wantLen > 0 {
+				assert.Len(t, res.GetAlias(), tt.wantLen)
+				return
+			}
+			assert.Equal(t, tt.wantAlias, res.GetAlias())
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockDatabase(ctrl)
+	c := cachemock.NewMockCache(ctrl)
+
+	c.EXPECT().Get(gomock.Any(), "link:google").Return("https://google.com", nil)
+	c.EXPECT().Expire(gomock.Any(), "link:google").Return(nil)
+
+	c.EXPECT().Get(gomock.Any(), "link:unknown").Return("", cache.ErrKeyNotExist)
+	db.EXPECT().GetTarget(gomock.Any(), "unknown").Return(database.Target{}, database.ErrURLNotFound)
+
+	client := newClient(t, db, c)
+
+	res, err := client.Resolve(context.Background(), &shortenerv1.ResolveRequest{Alias: "google"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", res.GetUrl())
+
+	_, err = client.Resolve(context.Background(), &shortenerv1.ResolveRequest{Alias: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, apierr.CodeNotFound, reason(t, err))
+
+	_, err = client.Resolve(context.Background(), &shortenerv1.ResolveRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, apierr.CodeEmptyAlias, reason(t, err))
+}
+
+func TestResolve_ClickLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockDatabase(ctrl)
+	c := cachemock.NewMockCache(ctrl)
+
+	// lookups count no redirect, so the link is neither recorded nor
+	// evicted
+	c.EXPECT().Get(gomock.Any(), "link:report").Return(`{"url": "https://files.example.com/report.pdf", "max_clicks": 1}`, nil).Times(3)
+	c.EXPECT().Expire(gomock.Any(), "link:report").Return(nil).Times(3)
+	db.EXPECT().GetTarget(gomock.Any(), "report").Return(database.Target{
+		URL:     "https://files.example.com/report.pdf",
+		Options: database.Options{MaxClicks: 1},
+	}, nil).Times(3)
+
+	client := newClient(t, db, c)
+
+	for range 2 {
+		res, err := client.Resolve(context.Background(), &shortenerv1.ResolveRequest{Alias: "report"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://files.example.com/report.pdf", res.GetUrl())
+	}
+
+	res, err := client.BatchResolve(context.Background(), &shortenerv1.BatchResolveRequest{Aliases: []string{"report"}})
+	require.NoError(t, err)
+	require.Len(t, res.GetResults(), 1)
+	assert.Nil(t, res.GetResults()[0].GetError())
+}
+
+func TestDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockDatabase(ctrl)
+	c := cachemock.NewMockCache(ctrl)
+
+	db.EXPECT().DeleteURL(gomock.Any(), "google").Return(int64(1), nil)
+	c.EXPECT().Delete(gomock.Any(), "link:google").Return(nil)
+
+	client := newClient(t, db, c)
+
+	_, err := client.Delete(context.Background(), &shortenerv1.DeleteRequest{Alias: "google"})
+	assert.NoError(t, err)
+}
+
+func TestBatchShorten(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockDatabase(ctrl)
+	db.EXPECT().SaveURL(gomock.Any(), "https://google.com", "google").Return(nil)
+	db.EXPECT().SaveURL(gomock.Any(), "https://ya.ru", "taken").Return(database.ErrURLExist)
+
+	client := newClient(t, db, cachemock.NewMockCache(ctrl))
+
+	res, err := client.BatchShorten(context.Background(), &shortenerv1.BatchShortenRequest{
+		Requests: []*shortenerv1.ShortenRequest{
+			{Url: "https://google.com", Alias: "google"},
+			{Url: "https://ya.ru", Alias: "taken"},
+			{Url: "", Alias: "empty"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.GetResults(), 3)
+
+	assert.Equal(t, "google", res.GetResults()[0].GetAlias())
+	assert.Nil(t, res.GetResults()[0].GetError())
+
+	assert.Equal(t, "taken", res.GetResults()[1].GetAlias())
+	assert.Equal(t, apierr.CodeAliasTaken, res.GetResults()[1].GetError().GetCode())
+
+	assert.Equal(t, apierr.CodeEmptyURL, res.GetResults()[2].GetError().GetCode())
+}
+
+func TestBatchResolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockDatabase(ctrl)
+	c := cachemock.NewMockCache(ctrl)
+
+	c.EXPECT().Get(gomock.Any(), "link:google").Return("https://google.com", nil)
+	c.EXPECT().Expire(gomock.Any(), "link:google").Return(nil)
+	c.EXPECT().Get(gomock.Any(), "link:unknown").Return("", cache.ErrKeyNotExist)
+	db.EXPECT().GetTarget(gomock.Any(), "unknown").Return(database.Target{}, database.ErrURLNotFound)
+
+	client := newClient(t, db, c)
+
+	res, err := client.BatchResolve(context.Background(), &shortenerv1.BatchResolveRequest{
+		Aliases: []string{"google", "unknown"},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.GetResults(), 2)
+
+	assert.Equal(t, "https://google.com", res.GetResults()[0].GetUrl())
+	assert.Equal(t, "unknown", res.GetResults()[1].GetAlias())
+	assert.Equal(t, apierr.CodeNotFound, res.GetResults()[1].GetError().GetCode())
+}
+
+func TestBatchDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db := mocks.NewMockDatabase(ctrl)
+	c := cachemock.NewMockCache(ctrl)
+
+	db.EXPECT().DeleteURL(gomock.Any(), "google").Return(int64(1), nil)
+	c.EXPECT().Delete(gomock.Any(), "link:google").Return(nil)
+	db.EXPECT().DeleteURL(gomock.Any(), "unknown").Return(int64(0), database.ErrURLNotFound)
+
+	client := newClient(t, db, c)
+
+	res, err := client.BatchDelete(context.Background(), &shortenerv1.BatchDeleteRequest{
+		Aliases: []string{"google", "unknown"},
+	})
+	require.NoError(t, err)
+	require.Len(t, res.GetResults(), 2)
+
+	assert.Nil(t, res.GetResults()[0].GetError())
+	assert.Equal(t, apierr.CodeNotFound, res.GetResults()[1].GetError().GetCode())
+}
+
+func TestBatchTooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := newClient(t, mocks.NewMockDatabase(ctrl), cachemock.NewMockCache(ctrl))
+
+	_, err := client.BatchResolve(context.Background(), &shortenerv1.BatchResolveRequest{
+		Aliases: make([]string, shortener.MaxBatchSize+1),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
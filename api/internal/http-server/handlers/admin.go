@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+type LinkResponce struct {
+	resp.Response
+	Link database.Link `json:"link"`
+}
+
+type ListResponce struct {
+	resp.Response
+	URLs   []database.Link `json:"urls"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+type StatsResponce struct {
+	resp.Response
+	Stats database.Stats `json:"stats"`
+}
+
+func (h *Handler) NewInfo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Info"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		alias := c.GetParam("alias")
+		if strings.TrimSpace(alias) == "" {
+			log.Info("empty alias")
+			renderError(c, apierr.ErrEmptyAlias, fieldError("alias", apierr.FieldRequired, apierr.ErrEmptyAlias))
+			return
+		}
+
+		log = log.With(slog.String("alias", alias))
+
+		link, err := h.storage.GetLink(c.Context(), alias)
+		if err != nil {
+			if errors.Is(err, database.ErrURLNotFound) {
+				log.Info("url not found")
+			} else {
+				log.Error("failed to get link", sl.Error(err))
+			}
+
+			renderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, LinkResponce{
+			Response: resp.OK(),
+			Link:     link,
+		})
+	}
+}
+
+func (h *Handler) NewList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.List"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		limit, offset, fields := page(c)
+		if len(fields) > 0 {
+			log.Info("invalid page", slog.Any("fields", fields))
+			renderError(c, apierr.ErrInvalidPage, fields...)
+			return
+		}
+
+		links, err := h.storage.ListURLs(c.Context(), limit, offset)
+		if err != nil {
+			log.Error("failed to list urls", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, ListResponce{
+			Response: resp.OK(),
+			URLs:     links,
+			Limit:    limit,
+			Offset:   offset,
+		})
+	}
+}
+
+// NewBroken lists the links whose destination failed the last health
+// check, the most failures first.
+func (h *Handler) NewBroken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Broken"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		limit, offset, fields := page(c)
+		if len(fields) > 0 {
+			log.Info("invalid page", slog.Any("fields", fields))
+			renderError(c, apierr.ErrInvalidPage, fields...)
+			return
+		}
+
+		links, err := h.storage.BrokenLinks(c.Context(), limit, offset)
+		if err != nil {
+			log.Error("failed to list broken links", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, ListResponce{
+			Response: resp.OK(),
+			URLs:     links,
+			Limit:    limit,
+			Offset:   offset,
+		})
+	}
+}
+
+// NewEnable enables a link disabled by the health checks again.
+func (h *Handler) NewEnable() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Enable"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		alias := c.GetParam("alias")
+		if strings.TrimSpace(alias) == "" {
+			log.Info("empty alias")
+			renderError(c, apierr.ErrEmptyAlias, fieldError("alias", apierr.FieldRequired, apierr.ErrEmptyAlias))
+			return
+		}
+
+		log = log.With(slog.String("alias", alias))
+
+		if err := h.storage.EnableURL(c.Context(), alias); err != nil {
+			if errors.Is(err, database.ErrURLNotFound) {
+				log.Info("url not found")
+			} else {
+				log.Error("failed to enable link", sl.Error(err))
+			}
+
+			renderError(c, err)
+			return
+		}
+
+		log.Info("link enabled")
+
+		c.JSON(http.StatusOK, resp.OK())
+	}
+}
+
+func (h *Handler) NewStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Stats"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		stats, err := h.storage.Stats(c.Context())
+		if err != nil {
+			log.Error("failed to get stats", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, StatsResponce{
+			Response: resp.OK(),
+			Stats:    stats,
+		})
+	}
+}
+
+// page reads the `limit` and `offset` query parameters and reports the
+// invalid ones.
+func page(c *reqcontext.ReqContext) (limit int, offset int, fields []resp.FieldError) {
+	limit, offset = defaultPageLimit, 0
+
+	if raw := c.GetParam("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxPageLimit {
+			fields = append(fields, resp.FieldError{
+				Field:   "limit",
+				Code:    apierr.FieldInvalid,
+				Message: fmt.Sprintf("limit must be a number from 1 to %d", maxPageLimit),
+			})
+		}
+		limit = n
+	}
+
+	if raw := c.GetParam("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			fields = append(fields, resp.FieldError{
+				Field:   "offset",
+				Code:    apierr.FieldInvalid,
+				Message: "offset must be a non-negative number",
+			})
+		}
+		offset = n
+	}
+
+	return limit, offset, fields
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/httprate"
+)
+
+type AuditResponce struct {
+	resp.Response
+	Events []database.AuditEvent `json:"events"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+}
+
+// NewAudit lists the audit events matching the filter of the query, the
+// newest first.
+func (h *Handler) NewAudit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Audit"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		limit, offset, fields := page(c)
+		if len(fields) > 0 {
+			log.Info("invalid page", slog.Any("fields", fields))
+			renderError(c, apierr.ErrInvalidPage, fields...)
+			return
+		}
+
+		filter, fields := auditFilter(c)
+		if len(fields) > 0 {
+			log.Info("invalid filter", slog.Any("fields", fields))
+			renderError(c, apierr.ErrInvalidFilter, fields...)
+			return
+		}
+
+		events, err := h.storage.AuditEvents(c.Context(), filter, limit, offset)
+		if err != nil {
+			log.Error("failed to list audit events", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, AuditResponce{
+			Response: resp.OK(),
+			Events:   events,
+			Limit:    limit,
+			Offset:   offset,
+		})
+	}
+}
+
+// NewAuditExport streams every audit event matching the filter of the
+// query as NDJSON, the oldest first.
+func (h *Handler) NewAuditExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.AuditExport"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		filter, fields := auditFilter(c)
+		if len(fields) > 0 {
+			log.Info("invalid filter", slog.Any("fields", fields))
+			renderError(c, apierr.ErrInvalidFilter, fields...)
+			return
+		}
+
+		out := &countingWriter{w: c.ResponceWriter()}
+		enc := json.NewEncoder(out)
+
+		c.SetHeader("Content-Type", "application/x-ndjson")
+		c.SetHeader("Content-Disposition", `attachment; filename="audit.ndjson"`)
+
+		err := h.storage.ExportAudit(c.Context(), filter, func(e database.AuditEvent) error {
+			return enc.Encode(e)
+		})
+		if err != nil {
+			log.Error("export audit events", slog.Int64("bytes written", out.n), sl.Error(err))
+
+			if out.n == 0 {
+				c.Header().Del("Content-Disposition")
+				renderError(c, err)
+				return
+			}
+
+			// the status is already sent, break the connection so the
+			// client does not take a truncated export for a complete one
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// auditFilter reads the filter of the audit events from the query and
+// reports the invalid parameters.
+func auditFilter(c *reqcontext.ReqContext) (database.AuditFilter, []resp.FieldError) {
+	var (
+		f      database.AuditFilter
+		fields []resp.FieldError
+	)
+
+	f.Alias = strings.TrimSpace(c.GetParam("alias"))
+	f.APIKey = strings.TrimSpace(c.GetParam("api_key"))
+	f.IP = strings.TrimSpace(c.GetParam("ip"))
+	f.RequestID = strings.TrimSpace(c.GetParam("request_id"))
+
+	if raw := strings.TrimSpace(c.GetParam("action")); raw != "" {
+		action, err := database.ParseAuditAction(raw)
+		if err != nil {
+			fields = append(fields, resp.FieldError{
+				Field:   "action",
+				Code:    apierr.FieldInvalid,
+				Message: "action must be create, update, delete, restore or purge",
+			})
+		}
+		f.Action = action
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		raw := strings.TrimSpace(c.GetParam(p.name))
+		if raw == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			fields = append(fields, resp.FieldError{
+				Field:   p.name,
+				Code:    apierr.FieldInvalid,
+				Message: p.name + " must be an RFC 3339 time",
+			})
+			continue
+		}
+		*p.dst = &t
+	}
+
+	return f, fields
+}
+
+// recordActor makes the changes of a request be recorded with the name of
+// the API key it carries, its address and its id.
+func (h *Handler) recordActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := database.Actor{RequestID: middleware.GetReqID(r.Context())}
+		actor.IP, _ = httprate.KeyByRealIP(r)
+
+		if name, ok := apikey.NameFromContext(r.Context()); ok {
+			actor.APIKey = name
+		} else if key, ok := apikey.Identify(h.cfg.APIKeys, r); ok {
+			actor.APIKey = key.Name
+		}
+
+		next.ServeHTTP(w, r.WithContext(database.WithActor(r.Context(), actor)))
+	})
+}
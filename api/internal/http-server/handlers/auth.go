@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+)
+
+func (h *Handler) NewUnauthorized() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Unauthorized"
+
+		c := reqcontext.New(w, r)
+
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		log.Warn("request without valid api key",
+			slog.String("path", c.URL().Path),
+		)
+
+		c.SetHeader("WWW-Authenticate", `Bearer realm="url-shortener"`)
+		renderError(c, apierr.ErrUnauthorized)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/domains"
+)
+
+type DomainResponce struct {
+	resp.Response
+	Domain database.Domain `json:"domain"`
+}
+
+type DomainsResponce struct {
+	resp.Response
+	Domains []database.Domain `json:"domains"`
+}
+
+// DomainRequest is bound from JSON, XML or form bodies.
+type DomainRequest struct {
+	Name        string `json:"name" xml:"name" form:"name"`
+	AliasLength int    `json:"alias_length,omitempty" xml:"alias_length" form:"alias_length"`
+	NotFoundURL string `json:"not_found_url,omitempty" xml:"not_found_url" form:"not_found_url"`
+}
+
+// scopeDomain scopes the aliases of the request to the domain it is sent
+// to. Requests to unknown hosts use the default namespace.
+func (h *Handler) scopeDomain(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d, ok := h.domains.Lookup(r.Context(), r.Host); ok {
+			r = r.WithContext(database.WithDomain(r.Context(), d))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewDomains lists the configured and the registered domains.
+func (h *Handler) NewDomains() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Domains"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		list, err := h.domains.List(c.Context())
+		if err != nil {
+			log.Error("failed to list domains", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, DomainsResponce{
+			Response: resp.OK(),
+			Domains:  list,
+		})
+	}
+}
+
+// NewRegisterDomain registers a domain. Every instance serves it after the
+// refresh interval of its registry.
+func (h *Handler) NewRegisterDomain() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.RegisterDomain"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		var req DomainRequest
+
+		if err := c.Bind(&req); err != nil {
+			if errors.Is(err, reqcontext.ErrUnsupportedMediaType) {
+				log.Info("unsupported media type", sl.Error(err))
+			} else {
+				log.Error("decode req body", sl.Error(err))
+			}
+
+			renderError(c, err)
+			return
+		}
+
+		log = log.With(slog.String("domain", req.Name))
+
+		d, err := h.domains.Register(c.Context(), database.Domain{
+			Name:        req.Name,
+			AliasLength: req.AliasLength,
+			NotFoundURL: req.NotFoundURL,
+		})
+		if err != nil {
+			var invalid *domains.InvalidError
+
+			switch {
+			case errors.As(err, &invalid):
+				log.Info("invalid domain", sl.Error(err))
+				renderError(c, err, resp.FieldError{Field: invalid.Field, Code: apierr.FieldInvalid, Message: invalid.Error()})
+
+			case errors.Is(err, database.ErrDomainExist):
+				log.Info("domain already registered")
+				renderError(c, err, fieldError("name", apierr.FieldTaken, apierr.ErrDomainExist))
+
+			default:
+				log.Error("failed to register domain", sl.Error(err))
+				renderError(c, err)
+			}
+			return
+		}
+
+		log.Info("domain registered")
+
+		c.JSON(http.StatusCreated, DomainResponce{
+			Response: resp.OK(),
+			Domain:   d,
+		})
+	}
+}
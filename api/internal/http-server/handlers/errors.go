@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/linkio"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/domains"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/reputation"
+)
+
+// ErrorInfo is what the client is told about an error.
+type ErrorInfo struct {
+	Status int
+	Code   string
+	// Err is the public error, its message is sent to the client.
+	Err error
+}
+
+// Response returns the error response for the info.
+func (i ErrorInfo) Response(fields ...resp.FieldError) resp.Response {
+	return resp.ErrorCode(i.Code, i.Err, fields...)
+}
+
+type errorMapping struct {
+	target error
+	ErrorInfo
+}
+
+// errorTable maps the errors of the handlers and the layers below them to
+// responses. The first entry the error matches with errors.Is wins.
+var errorTable = []errorMapping{
+	{apierr.ErrURLNotFound, ErrorInfo{http.StatusNotFound, apierr.CodeNotFound, apierr.ErrURLNotFound}},
+	{apierr.ErrEmptyAlias, ErrorInfo{http.StatusBadRequest, apierr.CodeEmptyAlias, apierr.ErrEmptyAlias}},
+	{apierr.ErrEmptyURL, ErrorInfo{http.StatusBadRequest, apierr.CodeEmptyURL, apierr.ErrEmptyURL}},
+	{apierr.ErrInvalidURLFormat, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidURL, apierr.ErrInvalidURLFormat}},
+	{apierr.ErrAliasExist, ErrorInfo{http.StatusBadRequest, apierr.CodeAliasTaken, apierr.ErrAliasExist}},
+	{apierr.ErrCanNotGenAlias, ErrorInfo{http.StatusInternalServerError, apierr.CodeAliasGeneration, apierr.ErrCanNotGenAlias}},
+	{apierr.ErrTooManyRequests, ErrorInfo{http.StatusTooManyRequests, apierr.CodeRateLimited, apierr.ErrTooManyRequests}},
+	{apierr.ErrInvalidPage, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidPage, apierr.ErrInvalidPage}},
+	{apierr.ErrUnauthorized, ErrorInfo{http.StatusUnauthorized, apierr.CodeUnauthorized, apierr.ErrUnauthorized}},
+	{apierr.ErrUnknownFormat, ErrorInfo{http.StatusBadRequest, apierr.CodeUnknownFormat, apierr.ErrUnknownFormat}},
+	{apierr.ErrUnknownImportMode, ErrorInfo{http.StatusBadRequest, apierr.CodeUnknownImportMode, apierr.ErrUnknownImportMode}},
+	{apierr.ErrMalformedImport, ErrorInfo{http.StatusBadRequest, apierr.CodeMalformedImport, apierr.ErrMalformedImport}},
+	{apierr.ErrInvalidImport, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidImport, apierr.ErrInvalidImport}},
+	{apierr.ErrUnsupportedMediaType, ErrorInfo{http.StatusUnsupportedMediaType, apierr.CodeUnsupportedMediaType, apierr.ErrUnsupportedMediaType}},
+	{apierr.ErrNotAcceptable, ErrorInfo{http.StatusNotAcceptable, apierr.CodeNotAcceptable, apierr.ErrNotAcceptable}},
+	{apierr.ErrDestinationRejected, ErrorInfo{http.StatusBadRequest, apierr.CodeDestinationRejected, apierr.ErrDestinationRejected}},
+	{apierr.ErrUnsafeURL, ErrorInfo{http.StatusForbidden, apierr.CodeUnsafeURL, apierr.ErrUnsafeURL}},
+	{apierr.ErrReputationDown, ErrorInfo{http.StatusServiceUnavailable, apierr.CodeReputationDown, apierr.ErrReputationDown}},
+	{apierr.ErrLinkDisabled, ErrorInfo{http.StatusGone, apierr.CodeLinkDisabled, apierr.ErrLinkDisabled}},
+	{apierr.ErrInvalidDomain, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidDomain, apierr.ErrInvalidDomain}},
+	{apierr.ErrDomainExist, ErrorInfo{http.StatusBadRequest, apierr.CodeDomainTaken, apierr.ErrDomainExist}},
+	{apierr.ErrInvalidOptions, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidOptions, apierr.ErrInvalidOptions}},
+	{apierr.ErrPasswordRequired, ErrorInfo{http.StatusUnauthorized, apierr.CodePasswordRequired, apierr.ErrPasswordRequired}},
+	{apierr.ErrWrongPassword, ErrorInfo{http.StatusUnauthorized, apierr.CodeWrongPassword, apierr.ErrWrongPassword}},
+	{apierr.ErrLinkExhausted, ErrorInfo{http.StatusGone, apierr.CodeLinkExhausted, apierr.ErrLinkExhausted}},
+	{apierr.ErrLinkNotActive, ErrorInfo{http.StatusNotFound, apierr.CodeLinkNotActive, apierr.ErrLinkNotActive}},
+	{apierr.ErrLinkExpired, ErrorInfo{http.StatusGone, apierr.CodeLinkExpired, apierr.ErrLinkExpired}},
+	{apierr.ErrInvalidFilter, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidFilter, apierr.ErrInvalidFilter}},
+	{apierr.ErrInternalServer, ErrorInfo{http.StatusInternalServerError, apierr.CodeInternal, apierr.ErrInternalServer}},
+
+	{database.ErrURLNotFound, ErrorInfo{http.StatusNotFound, apierr.CodeNotFound, apierr.ErrURLNotFound}},
+	{database.ErrURLExist, ErrorInfo{http.StatusBadRequest, apierr.CodeAliasTaken, apierr.ErrAliasExist}},
+	{database.ErrMaxRetriesForGenerate, ErrorInfo{http.StatusInternalServerError, apierr.CodeAliasGeneration, apierr.ErrCanNotGenAlias}},
+	{database.ErrInvalidPage, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidPage, apierr.ErrInvalidPage}},
+	{database.ErrUnknownConflictMode, ErrorInfo{http.StatusBadRequest, apierr.CodeUnknownImportMode, apierr.ErrUnknownImportMode}},
+	{database.ErrLinkDisabled, ErrorInfo{http.StatusGone, apierr.CodeLinkDisabled, apierr.ErrLinkDisabled}},
+	{database.ErrLinkExhausted, ErrorInfo{http.StatusGone, apierr.CodeLinkExhausted, apierr.ErrLinkExhausted}},
+	{database.ErrDomainExist, ErrorInfo{http.StatusBadRequest, apierr.CodeDomainTaken, apierr.ErrDomainExist}},
+	{database.ErrUnknownAuditAction, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidFilter, apierr.ErrInvalidFilter}},
+
+	{reqcontext.ErrUnsupportedMediaType, ErrorInfo{http.StatusUnsupportedMediaType, apierr.CodeUnsupportedMediaType, apierr.ErrUnsupportedMediaType}},
+	{reqcontext.ErrNotAcceptable, ErrorInfo{http.StatusNotAcceptable, apierr.CodeNotAcceptable, apierr.ErrNotAcceptable}},
+
+	{policy.ErrRejected, ErrorInfo{http.StatusBadRequest, apierr.CodeDestinationRejected, apierr.ErrDestinationRejected}},
+	{reputation.ErrUnsafe, ErrorInfo{http.StatusForbidden, apierr.CodeUnsafeURL, apierr.ErrUnsafeURL}},
+	{reputation.ErrUnavailable, ErrorInfo{http.StatusServiceUnavailable, apierr.CodeReputationDown, apierr.ErrReputationDown}},
+	{domains.ErrInvalid, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidDomain, apierr.ErrInvalidDomain}},
+
+	{linkio.ErrUnknownFormat, ErrorInfo{http.StatusBadRequest, apierr.CodeUnknownFormat, apierr.ErrUnknownFormat}},
+	{linkio.ErrMalformed, ErrorInfo{http.StatusBadRequest, apierr.CodeMalformedImport, apierr.ErrMalformedImport}},
+	{errInvalidImportLink, ErrorInfo{http.StatusBadRequest, apierr.CodeInvalidImport, apierr.ErrInvalidImport}},
+}
+
+var internalError = ErrorInfo{http.StatusInternalServerError, apierr.CodeInternal, apierr.ErrInternalServer}
+
+// Classify maps err to the status, code and message of the response.
+// Unknown errors are internal, their message never reaches the client.
+func Classify(err error) ErrorInfo {
+	for _, m := range errorTable {
+		if errors.Is(err, m.target) {
+			return m.ErrorInfo
+		}
+	}
+	return internalError
+}
+
+// renderError answers with the response err maps to. Clients asking for
+// application/problem+json get a problem document with the request ID as
+// instance, the others the usual response in the type they accept.
+func renderError(c *reqcontext.ReqContext, err error, fields ...resp.FieldError) {
+	renderInfo(c, Classify(err), fields...)
+}
+
+// renderInfo writes the error response of info.
+func renderInfo(c *reqcontext.ReqContext, info ErrorInfo, fields ...resp.FieldError) {
+	if c.AcceptsProblem() {
+		c.Problem(info.Status, resp.NewProblem(
+			info.Status,
+			info.Code,
+			http.StatusText(info.Status),
+			info.Err,
+			c.RequestID(),
+			fields...,
+		))
+		return
+	}
+
+	if rerr := c.Render(info.Status, info.Response(fields...)); rerr != nil {
+		notAcceptable := Classify(apierr.ErrNotAcceptable)
+		c.JSON(notAcceptable.Status, notAcceptable.Response())
+	}
+}
+
+func fieldError(field, code string, err error) resp.FieldError {
+	return resp.FieldError{Field: field, Code: code, Message: err.Error()}
+}
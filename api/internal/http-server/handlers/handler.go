@@ -1,121 +1,131 @@
 package handlers
 
 import (
-	"context"
-	"errors"
 	"log/slog"
 	"net/http"
-	"net/url"
-	"strings"
 	"time"
 
 	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/ratelimiter"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/pages"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
-	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
-	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/domains"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/trash"
 )
 
-const setCacheTimeout = time.Second
-
 type Handler struct {
 	cache   cache.Cache
 	storage database.Database
+	svc     *shortener.Shortener
+	limiter ratelimiter.Limiter
+	domains *domains.Registry
+	pages   *pages.Pages
 	log     *slog.Logger
 	cfg     *config.ServerConfig
+
+	// retention is how long deleted links can be restored.
+	retention time.Duration
 }
 
 func New(storage database.Database, cache cache.Cache, cfg *config.ServerConfig, log *slog.Logger) *Handler {
+	svcCfg := shortener.Config{MaxRetries: maxRetries}
+	if cfg != nil {
+		svcCfg.AliasLength = cfg.StdAliasLen
+		svcCfg.AccessSecret = []byte(cfg.Passwords.CookieSecret)
+		svcCfg.AccessTTL = cfg.Passwords.CookieTTL
+	}
+
+	var (
+		store shortener.Store
+		c     shortener.Cache
+	)
+	if storage != nil {
+		store = storage
+	}
+	if cache != nil {
+		c = cache
+	}
+
 	return &Handler{
 		storage: storage,
 		cache:   cache,
+		svc:     shortener.New(store, c, svcCfg, log),
+		limiter: ratelimiter.NewLocalLimiter(),
+		domains: domains.New(nil, nil, 0, log),
+		pages:   pages.New(),
 		cfg:     cfg,
 		log:     log,
-	}
-}
-
-func (h *Handler) GetURLWithCache(ctx context.Context, alias string) (string, error) {
-	const fn = "handlers.handler.(*Handler).GetURLWithCache"
-
-	wp := wraper.New(fn)
-
-	url, err := h.cache.Get(ctx, alias)
-	if err == nil {
-		if err := h.cache.Expire(ctx, alias); err != nil {
-			h.log.Error("expire alias",
-				slog.String("key", alias),
-				slog.String("value", url),
-				sl.Error(err),
-			)
-		}
-		return url, nil
-	}
-
-	if !errors.Is(err, cache.ErrKeyNotExist) {
-		h.log.Error("get URL from cache",
-			slog.String("key", alias),
-			sl.Error(err),
-		)
-	}
 
-	url, err = h.storage.GetURl(ctx, alias)
-	if err != nil {
-		if errors.Is(err, database.ErrURLNotFound) {
-			return "", wp.Wrap(err)
-		}
-		return "", wp.Wrap(err)
+		retention: trash.DefaultRetention,
 	}
+}
 
-	h.log.Info("starting a setter goroutine", slog.String("alias", alias), slog.String("url", url))
-
-	setCtx, cancel := context.WithTimeout(context.Background(), setCacheTimeout)
+// Service returns the shortener the handlers are an adapter for.
+func (h *Handler) Service() *shortener.Shortener {
+	return h.svc
+}
 
-	go func() {
-		defer cancel()
+// UseLimiter makes the route group limits count requests with l instead of
+// local counters. Call it before InitRoutes.
+func (h *Handler) UseLimiter(l ratelimiter.Limiter) {
+	h.limiter = l
+}
 
-		if err := h.cache.Set(setCtx, alias, url); err != nil {
-			h.log.Error("cache URL",
-				slog.String("key", alias),
-				slog.String("value", url),
-				sl.Error(err),
-			)
-		}
-	}()
+// UsePolicy makes new and updated links point only to destinations p
+// accepts. Call it before InitRoutes and Service.
+func (h *Handler) UsePolicy(p shortener.Policy) {
+	h.svc = h.svc.WithPolicy(p)
+}
 
-	return url, nil
+// UseReputation makes new and updated links, and resolved ones when
+// onResolve is set, pass the reputation check of c. Call it before
+// InitRoutes and Service.
+func (h *Handler) UseReputation(c shortener.ReputationChecker, onResolve bool) {
+	h.svc = h.svc.WithReputation(c, onResolve)
 }
 
-func (h *Handler) DeleteURLWithCache(ctx context.Context, alias string) (int64, error) {
-	const fn = "handlers.handler.(*Handler).DeleteURLWithCache"
+// UseEnricher makes created and updated links be described by e in the
+// background. Call it before InitRoutes and Service.
+func (h *Handler) UseEnricher(e shortener.Enricher) {
+	h.svc = h.svc.WithEnricher(e)
+}
 
-	wp := wraper.New(fn)
+// UseDomains makes requests to the domains of r use their own aliases.
+// Without it every request uses the default namespace. Call it before
+// InitRoutes.
+func (h *Handler) UseDomains(r *domains.Registry) {
+	h.domains = r
+}
 
-	n, err := h.storage.DeleteURL(ctx, alias)
-	if err != nil {
-		if errors.Is(err, database.ErrURLNotFound) {
-			return 0, wp.Wrap(err)
-		}
-		return 0, wp.Wrap(err)
-	}
+// UseClickCounter makes click-limited links count their redirects with c,
+// shared by every instance, instead of in memory. Call it before
+// InitRoutes and Service.
+func (h *Handler) UseClickCounter(c shortener.ClickCounter) {
+	h.svc = h.svc.WithClickCounter(c)
+}
 
-	err = h.cache.Delete(ctx, alias)
-	if !errors.Is(err, cache.ErrKeyNotExist) {
-		h.log.Error("deleting URL from cache",
-			slog.String("key", alias),
-			sl.Error(err),
-		)
-	}
+// UseRetention lets deleted links be restored for d, the retention of
+// the trash purger. Call it before InitRoutes.
+func (h *Handler) UseRetention(d time.Duration) {
+	h.retention = d
+}
 
-	return n, nil
+// UsePages renders the pages shown to visitors with p instead of the
+// embedded ones.
+func (h *Handler) UsePages(p *pages.Pages) {
+	h.pages = p
 }
 
 func (h *Handler) Helthy(w http.ResponseWriter, r *http.Request) {
 	c := reqcontext.New(w, r)
 
 	if h.BadConfigurate() {
-		c.JSON(http.StatusInternalServerError, resp.Error(ErrInternalServer))
+		renderError(c, apierr.ErrInternalServer)
 		return
 	}
 
@@ -126,55 +136,71 @@ func (h *Handler) BadConfigurate() bool {
 	return h.cache == nil || h.storage == nil || h.log == nil || h.cfg == nil
 }
 
-func IsValidURL(urlToCheck string) bool {
-	const (
-		HTTP  = "http"
-		HTTPs = "https"
-	)
-
-	if urlToCheck == "" {
-		return false
+// render writes v in the media type the client accepts. Clients that accept
+// none of the supported types get a 406.
+func render(c *reqcontext.ReqContext, status int, v any) {
+	if err := c.Render(status, v); err != nil {
+		renderError(c, err)
 	}
+}
 
-	if strings.ContainsAny(urlToCheck, " \t\n\r\b\a") {
+// acceptable answers 406 unless the response can be encoded in a type the
+// client accepts. Handlers check it before changing anything.
+func acceptable(c *reqcontext.ReqContext) bool {
+	if _, err := c.Negotiate(reqcontext.Offers...); err != nil {
+		renderError(c, err)
 		return false
 	}
+	return true
+}
 
-	u, err := url.ParseRequestURI(urlToCheck)
-	if err != nil {
-		return false
-	}
+// IsValidURL reports whether urlToCheck is an absolute http(s) url.
+func IsValidURL(urlToCheck string) bool {
+	return shortener.IsValidURL(urlToCheck)
+}
 
-	if u.Scheme == "" || u.Host == "" {
-		return false
-	}
+// Request is bound from JSON, XML (any root element) or form bodies.
+type Request struct {
+	Alias string `json:"alias,omitempty" xml:"alias" form:"alias"`
+	URL   string `json:"url" xml:"url" form:"url"`
+
+	Passthrough  database.Passthrough `json:"passthrough,omitempty" xml:"passthrough" form:"passthrough"`
+	UTM          *database.UTM        `json:"utm,omitempty" xml:"utm" form:"utm"`
+	Interstitial bool                 `json:"interstitial,omitempty" xml:"interstitial" form:"interstitial"`
+	Rules        []database.Rule      `json:"rules,omitempty" xml:"rules>rule" form:"rules"`
+	Variants     []database.Variant   `json:"variants,omitempty" xml:"variants>variant" form:"variants"`
+	MaxClicks    int64                `json:"max_clicks,omitempty" xml:"max_clicks" form:"max_clicks"`
+	ActiveFrom   *time.Time           `json:"active_from,omitempty" xml:"active_from" form:"active_from"`
+	ActiveUntil  *time.Time           `json:"active_until,omitempty" xml:"active_until" form:"active_until"`
+
+	// Password protects the link unless empty.
+	Password string `json:"password,omitempty" xml:"password" form:"password"`
+}
 
-	if u.Scheme != HTTP && u.Scheme != HTTPs {
-		return false
-	}
+// LogValue leaves the password out of logs.
+func (r Request) LogValue() slog.Value {
+	type request Request
 
-	if strings.Contains(u.Host, "..") || strings.Contains(u.Host, " ") {
-		return false
+	logged := request(r)
+	if logged.Password != "" {
+		logged.Password = "[redacted]"
 	}
-
-	return true
+	return slog.AnyValue(logged)
 }
 
-type Request struct {
-	Alias string `json:"alias,omitempty"`
-	URL   string `json:"url"`
-}
-
-var (
-	ErrURLNotFound      = errors.New("url not found")
-	ErrEmptyAlias       = errors.New("url must not be empty")
-	ErrEmprtyURl        = errors.New("url must not be empty")
-	ErrInternalServer   = errors.New("internal server error")
-	ErrAliasExist       = errors.New("alias already exist")
-	ErrInvalidURLFormat = errors.New("invalid url format")
-	ErrTooManyRequests  = errors.New("too many requests")
-	ErrCanNotGenAlias   = errors.New("could not generate random unique alias, please try again")
-)
+// Options returns the options of the requested link.
+func (r Request) Options() database.Options {
+	return database.Options{
+		Passthrough:  r.Passthrough,
+		UTM:          r.UTM,
+		Interstitial: r.Interstitial,
+		Rules:        r.Rules,
+		Variants:     r.Variants,
+		MaxClicks:    r.MaxClicks,
+		ActiveFrom:   r.ActiveFrom,
+		ActiveUntil:  r.ActiveUntil,
+	}
+}
 
 const (
 	RequestID = "request_id"
@@ -4,11 +4,14 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/ratelimiter"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
-	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
 	"github.com/go-chi/httprate"
 )
 
+// NewLimit answers limited requests with 429 and, when the limiter passed
+// its decision along, the X-RateLimit-* and Retry-After headers.
 func (h *Handler) NewLimit() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.Limit"
@@ -26,6 +29,10 @@ func (h *Handler) NewLimit() http.HandlerFunc {
 			slog.String("ip", realIP),
 		)
 
-		c.JSON(http.StatusTooManyRequests, resp.Error(ErrTooManyRequests))
+		if res, ok := ratelimiter.ResultFromContext(r.Context()); ok {
+			ratelimiter.SetHeaders(w, res)
+		}
+
+		renderError(c, apierr.ErrTooManyRequests)
 	}
 }
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/openapi"
+)
+
+// OpenAPI serves the OpenAPI document of the API.
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	c := reqcontext.New(w, r)
+
+	c.SetHeader("Content-Type", reqcontext.MIMEJSON)
+	c.WriteHeader(http.StatusOK)
+	_, _ = c.Write(openapi.Spec)
+}
+
+// Docs serves the page rendering the OpenAPI document.
+func (h *Handler) Docs(w http.ResponseWriter, r *http.Request) {
+	c := reqcontext.New(w, r)
+
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.WriteHeader(http.StatusOK)
+	_, _ = c.Write(openapi.Docs)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+)
+
+// OptionsRequest is bound from JSON, XML or form bodies. Options left out
+// are reset.
+type OptionsRequest struct {
+	Passthrough  database.Passthrough `json:"passthrough,omitempty" xml:"passthrough" form:"passthrough"`
+	UTM          *database.UTM        `json:"utm,omitempty" xml:"utm" form:"utm"`
+	Interstitial bool                 `json:"interstitial,omitempty" xml:"interstitial" form:"interstitial"`
+	Rules        []database.Rule      `json:"rules,omitempty" xml:"rules>rule" form:"rules"`
+	Variants     []database.Variant   `json:"variants,omitempty" xml:"variants>variant" form:"variants"`
+	MaxClicks    int64                `json:"max_clicks,omitempty" xml:"max_clicks" form:"max_clicks"`
+	ActiveFrom   *time.Time           `json:"active_from,omitempty" xml:"active_from" form:"active_from"`
+	ActiveUntil  *time.Time           `json:"active_until,omitempty" xml:"active_until" form:"active_until"`
+}
+
+// Options returns the requested options.
+func (r OptionsRequest) Options() database.Options {
+	return database.Options{
+		Passthrough:  r.Passthrough,
+		UTM:          r.UTM,
+		Interstitial: r.Interstitial,
+		Rules:        r.Rules,
+		Variants:     r.Variants,
+		MaxClicks:    r.MaxClicks,
+		ActiveFrom:   r.ActiveFrom,
+		ActiveUntil:  r.ActiveUntil,
+	}
+}
+
+// NewOptions replaces the options of a link, the cached link is evicted.
+func (h *Handler) NewOptions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Options"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		if !acceptable(c) {
+			log.Info("not acceptable", slog.String("accept", r.Header.Get("Accept")))
+			return
+		}
+
+		alias := c.GetParam("alias")
+		if strings.TrimSpace(alias) == "" {
+			log.Info("empty alias")
+			renderError(c, apierr.ErrEmptyAlias, fieldError("alias", apierr.FieldRequired, apierr.ErrEmptyAlias))
+			return
+		}
+
+		log = log.With(slog.String("alias", alias))
+
+		var req OptionsRequest
+
+		if err := c.Bind(&req); err != nil {
+			if errors.Is(err, reqcontext.ErrUnsupportedMediaType) {
+				log.Info("unsupported media type", sl.Error(err))
+			} else {
+				log.Error("decode req body", sl.Error(err))
+			}
+
+			renderError(c, err)
+			return
+		}
+
+		err := h.svc.SetOptions(c.Context(), alias, req.Options())
+		if err != nil {
+			switch {
+			case destinationError(c, log, err, "options"):
+
+			case errors.Is(err, database.ErrURLNotFound):
+				log.Info("url not found")
+				renderError(c, err)
+
+			default:
+				log.Error("failed to set options", sl.Error(err))
+				renderError(c, err)
+			}
+			return
+		}
+
+		log.Info("options set")
+
+		render(c, http.StatusOK, Responce{
+			Response: resp.OK(),
+			Alias:    alias,
+		})
+	}
+}
+
+// optionsError returns the field error of an invalid option.
+func optionsError(err error) resp.FieldError {
+	var invalid *shortener.OptionsError
+	if errors.As(err, &invalid) {
+		return resp.FieldError{Field: invalid.Field, Code: apierr.FieldInvalid, Message: invalid.Error()}
+	}
+	return fieldError("options", apierr.FieldInvalid, err)
+}
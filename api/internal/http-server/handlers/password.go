@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/ratelimiter"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/pages"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+	"github.com/go-chi/httprate"
+)
+
+const (
+	unlockPath = "/api/v1/url/unlock"
+
+	accessCookiePrefix = "link_access_"
+)
+
+// UnlockRequest is bound from JSON, XML or form bodies, the password form
+// posts a form.
+type UnlockRequest struct {
+	Password string `json:"password" xml:"password" form:"password"`
+}
+
+// NewUnlock checks the password of a protected link. The right password
+// sets a cookie letting the visitor through until it expires and
+// redirects to the link. Attempts are limited per link and per IP.
+func (h *Handler) NewUnlock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Unlock"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		alias := c.GetParam("alias")
+		if strings.TrimSpace(alias) == "" {
+			log.Info("empty alias")
+			renderError(c, apierr.ErrEmptyAlias, fieldError("alias", apierr.FieldRequired, apierr.ErrEmptyAlias))
+			return
+		}
+
+		log = log.With(slog.String("alias", alias))
+
+		var req UnlockRequest
+		if err := c.Bind(&req); err != nil {
+			log.Info("decode req body", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		if res, ok := h.allowUnlock(c, alias); !ok {
+			log.Warn("too many password attempts")
+			ratelimiter.SetHeaders(w, res)
+			h.renderPassword(c, alias, apierr.ErrTooManyRequests)
+			return
+		}
+
+		token, err := h.svc.Unlock(c.Context(), alias, req.Password)
+		if err != nil {
+			switch {
+			case errors.Is(err, apierr.ErrWrongPassword):
+				log.Info("wrong password")
+				h.renderPassword(c, alias, err)
+				return
+
+			case errors.Is(err, database.ErrURLNotFound), errors.Is(err, database.ErrLinkDisabled):
+				log.Info("link not available", sl.Error(err))
+
+			default:
+				log.Error("failed to unlock link", sl.Error(err))
+			}
+
+			renderError(c, err)
+			return
+		}
+
+		http.SetCookie(w, h.accessCookie(c, alias, token))
+
+		query := r.URL.Query()
+		query.Del("alias")
+
+		url, err := h.svc.Redirect(shortener.WithAccess(c.Context(), token), alias, query)
+		if err != nil {
+			log.Error("failed to get URL", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		log.Info("link unlocked, redirecting", slog.String("url", url))
+
+		http.Redirect(w, r, url, http.StatusSeeOther)
+	}
+}
+
+// allowUnlock counts a password attempt for alias and for the IP of the
+// request. It returns the result of the limit denying the attempt.
+func (h *Handler) allowUnlock(c *reqcontext.ReqContext, alias string) (ratelimiter.Result, bool) {
+	cfg := h.cfg.Passwords
+	ip, _ := httprate.KeyByRealIP(c.Request())
+
+	limits := []struct {
+		key   string
+		limit int
+	}{
+		{"unlock:link:" + database.DomainFrom(c.Context()).Name + "\x00" + alias, cfg.LinkAttempts},
+		{"unlock:ip:" + ip, cfg.IPAttempts},
+	}
+
+	for _, l := range limits {
+		if l.limit <= 0 {
+			continue
+		}
+
+		if res := h.limiter.Allow(c.Context(), l.key, l.limit, cfg.AttemptWindow); !res.Allowed {
+			return res, false
+		}
+	}
+
+	return ratelimiter.Result{}, true
+}
+
+func (h *Handler) accessCookie(c *reqcontext.ReqContext, alias, token string) *http.Cookie {
+	return &http.Cookie{
+		Name:     accessCookieName(c.Context(), alias),
+		Value:    token,
+		Path:     "/api/v1/url",
+		MaxAge:   int(h.svc.Config().AccessTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request().TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// accessCookieName returns the name of the cookie of alias in the domain
+// of ctx. Aliases may hold characters cookie names must not, so the name
+// is derived from a hash.
+func accessCookieName(ctx context.Context, alias string) string {
+	sum := sha256.Sum256([]byte(database.DomainFrom(ctx).Name + "\x00" + alias))
+	return accessCookiePrefix + hex.EncodeToString(sum[:8])
+}
+
+// renderPassword answers browsers with the password form of alias,
+// telling why the password was not accepted when err is not
+// apierr.ErrPasswordRequired. Other clients get the error response.
+func (h *Handler) renderPassword(c *reqcontext.ReqContext, alias string, err error) {
+	if !prefersHTML(c) {
+		renderError(c, err)
+		return
+	}
+
+	var message string
+	switch {
+	case errors.Is(err, apierr.ErrWrongPassword):
+		message = "Wrong password, please try again."
+	case errors.Is(err, apierr.ErrTooManyRequests):
+		message = "Too many attempts, please try again later."
+	}
+
+	action := url.URL{Path: unlockPath, RawQuery: c.Query().Encode()}
+
+	h.renderPage(c, Classify(err).Status, pages.Password, pages.PasswordData{
+		Alias:   alias,
+		Action:  template.URL(action.String()),
+		Message: message,
+	})
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/pages"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/reputation"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+)
+
+// previewParam asks the redirect for the preview page instead.
+const previewParam = "preview"
+
+// NewPreview shows where the link of /{alias}+ goes instead of redirecting,
+// like the redirect with preview=1.
+func (h *Handler) NewPreview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Preview"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		alias, err := url.PathUnescape(c.GetChiParam("alias"))
+		if err != nil || strings.TrimSpace(alias) == "" {
+			log.Info("empty alias")
+			renderError(c, apierr.ErrEmptyAlias, fieldError("alias", apierr.FieldRequired, apierr.ErrEmptyAlias))
+			return
+		}
+
+		log = log.With(slog.String("alias", alias))
+
+		h.preview(c, log, alias, r.URL.Query())
+	}
+}
+
+// preview renders the preview page of alias for a visit with query.
+// Protected links ask for their password first. Previews do not count as
+// redirects of click-limited links.
+func (h *Handler) preview(c *reqcontext.ReqContext, log *slog.Logger, alias string, query url.Values) {
+	visit, err := h.svc.Peek(h.visitContext(c, alias), alias, query)
+	if err != nil {
+		h.visitError(c, log, alias, err)
+		return
+	}
+
+	log.Info("showing preview", slog.String("url", visit.URL))
+
+	reportVariant(c, visit)
+
+	h.renderLink(c, pages.Preview, alias, visit)
+}
+
+// visitContext returns the context of a visit of alias: the visitor the
+// rules and variants of the link pick the destination for and the access token of the cookie for
+// alias, if the visitor has one.
+func (h *Handler) visitContext(c *reqcontext.ReqContext, alias string) context.Context {
+	r := c.Request()
+
+	ctx := shortener.WithVisitor(c.Context(), shortener.Visitor{
+		UserAgent:      r.UserAgent(),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		Country:        h.country(r),
+		Header:         r.Header,
+		Key:            visitorKey(r),
+	})
+
+	cookie, err := r.Cookie(accessCookieName(ctx, alias))
+	if err != nil {
+		return ctx
+	}
+	return shortener.WithAccess(ctx, cookie.Value)
+}
+
+// country returns the country of the visitor the proxy in front of the
+// server tells in the configured header.
+func (h *Handler) country(r *http.Request) string {
+	if h.cfg.CountryHeader == "" {
+		return ""
+	}
+	return r.Header.Get(h.cfg.CountryHeader)
+}
+
+// visitError answers a visit of alias that cannot be redirected.
+func (h *Handler) visitError(c *reqcontext.ReqContext, log *slog.Logger, alias string, err error) {
+	switch {
+	case errors.Is(err, apierr.ErrPasswordRequired):
+		log.Info("password required")
+		h.renderPassword(c, alias, err)
+		return
+
+	case errors.Is(err, database.ErrURLNotFound):
+		if d := database.DomainFrom(c.Context()); d.NotFoundURL != "" {
+			log.Info("url not found, redirecting to the not found page of the domain")
+			http.Redirect(c.ResponceWriter(), c.Request(), d.NotFoundURL, http.StatusFound)
+			return
+		}
+
+		log.Info("url not found")
+
+	case errors.Is(err, database.ErrLinkDisabled):
+		log.Info("link disabled")
+
+	case errors.Is(err, apierr.ErrLinkNotActive):
+		h.renderNotActive(c, log, alias, err)
+		return
+
+	case errors.Is(err, apierr.ErrLinkExhausted):
+		log.Info("link exhausted")
+
+	case errors.Is(err, apierr.ErrLinkExpired):
+		log.Info("link expired")
+
+	case errors.Is(err, reputation.ErrUnsafe):
+		log.Warn("unsafe url not redirected", sl.Error(err))
+
+	default:
+		log.Error("failed to get URL", sl.Error(err))
+	}
+
+	renderError(c, err)
+}
+
+// renderLink renders the page name of alias for visit, described by the
+// metadata of the link when it is known.
+func (h *Handler) renderLink(c *reqcontext.ReqContext, name, alias string, visit shortener.Visit) {
+	data := pages.LinkData{Alias: alias, URL: visit.URL, CreatedAt: visit.CreatedAt}
+
+	if m := visit.Metadata; m != nil {
+		data.Title = cmp.Or(m.OGTitle, m.Title)
+		data.Description = m.OGDescription
+		data.Image = m.OGImage
+	}
+
+	h.renderPage(c, http.StatusOK, name, data)
+}
+
+// renderPage writes the page name rendered with data. Pages are never
+// cached, they depend on the link and on who visits it.
+func (h *Handler) renderPage(c *reqcontext.ReqContext, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := h.pages.Render(&buf, name, data); err != nil {
+		h.log.Error("render page", slog.String("page", name), sl.Error(err))
+		renderError(c, err)
+		return
+	}
+
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.SetHeader("Cache-Control", "no-store")
+	c.WriteHeader(status)
+	_, _ = c.Write(buf.Bytes())
+}
+
+// prefersHTML reports whether the client prefers HTML to the other types
+// the API answers with, like browsers do.
+func prefersHTML(c *reqcontext.ReqContext) bool {
+	mt, _ := c.Negotiate(slices.Concat(reqcontext.Offers, []string{reqcontext.MIMEHTML})...)
+	return mt == reqcontext.MIMEHTML
+}
@@ -3,6 +3,10 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/ratelimiter"
+
 	"github.com/go-chi/chi/v5"
 )
 
@@ -11,11 +15,68 @@ func (h *Handler) InitRoutes(middlewares ...func(http.Handler) http.Handler) *ch
 
 	router.Use(middlewares...)
 
-	router.Get("/helthy", h.Helthy)
+	// routes outside the route groups share the limit per client
+	router.Group(func(r chi.Router) {
+		r.Use(h.limitUngrouped)
+
+		r.Get("/helthy", h.Helthy)
+		r.Get("/openapi.json", h.OpenAPI)
+		r.Get("/docs", h.Docs)
+	})
+
+	// routes working with aliases are scoped to the domain of the request
+	router.With(h.scopeDomain, h.limitGroup(config.RouteRedirects)).Get("/api/v1/url", h.NewRedirect())
+	router.With(h.scopeDomain, h.limitGroup(config.RouteRedirects)).Post(unlockPath, h.NewUnlock())
+	router.With(h.scopeDomain, h.limitGroup(config.RouteRedirects)).Get("/{alias}+", h.NewPreview())
+
+	router.Group(func(r chi.Router) {
+		r.Use(h.scopeDomain)
+		r.Use(h.limitGroup(config.RouteWrites))
+		r.Use(h.recordActor)
+
+		r.Post("/api/v1/url", h.NewSave())
+		r.Delete("/api/v1/url", h.NewDelete())
+	})
+
+	// admin endpoints, limited before the key check to slow down guessing
+	router.Group(func(r chi.Router) {
+		r.Use(h.scopeDomain)
+		r.Use(h.limitGroup(config.RouteAdmin))
+		r.Use(apikey.New(h.cfg.APIKeys, h.NewUnauthorized()))
+		r.Use(h.recordActor)
+
+		r.Get("/api/v1/url/info", h.NewInfo())
+		r.Get("/api/v1/urls", h.NewList())
+		r.Get("/api/v1/urls/broken", h.NewBroken())
+		r.Post("/api/v1/url/enable", h.NewEnable())
+		r.Post("/api/v1/url/restore", h.NewRestore())
+		r.Get("/api/v1/trash", h.NewTrash())
+		r.Put("/api/v1/url/options", h.NewOptions())
+		r.Get("/api/v1/stats", h.NewStats())
 
-	router.Get("/api/v1/url", h.NewRedirect())
-	router.Post("/api/v1/url", h.NewSave())
-	router.Delete("/api/v1/url", h.NewDelete())
+		r.Get("/api/v1/domains", h.NewDomains())
+		r.Post("/api/v1/domains", h.NewRegisterDomain())
+
+		r.Post("/api/v1/import", h.NewImport())
+		r.Get("/api/v1/export", h.NewExport())
+
+		r.Get("/api/v1/audit", h.NewAudit())
+		r.Get("/api/v1/audit/export", h.NewAuditExport())
+	})
 
 	return router
 }
+
+// limitUngrouped applies the limit of the routes outside the route groups.
+func (h *Handler) limitUngrouped(next http.Handler) http.Handler {
+	if h.cfg.RequesLimit <= 0 {
+		return next
+	}
+	return ratelimiter.NewWithLimiter(h.limiter, h.cfg.RequesLimit, h.cfg.WindowLength, h.NewLimit())(next)
+}
+
+// limitGroup applies the configured limits of a route group, or
+// request_limit when the group is not configured.
+func (h *Handler) limitGroup(name string) func(http.Handler) http.Handler {
+	return ratelimiter.NewGroup(h.limiter, name, h.cfg.GroupLimits(), h.cfg.APIKeys, h.NewLimit())
+}
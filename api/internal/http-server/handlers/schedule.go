@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/pages"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+)
+
+// renderNotActive answers a visit of alias before its active window, as
+// configured: with a redirect to the configured url, the not active page
+// for browsers or the error response. Retry-After tells when the link
+// becomes active.
+func (h *Handler) renderNotActive(c *reqcontext.ReqContext, log *slog.Logger, alias string, err error) {
+	cfg := h.cfg.NotActive
+
+	if cfg.URL != "" {
+		log.Info("link not active, redirecting to the not active page")
+		http.Redirect(c.ResponceWriter(), c.Request(), cfg.URL, http.StatusFound)
+		return
+	}
+
+	info := Classify(err)
+	if cfg.Status >= 400 && cfg.Status <= 599 {
+		info.Status = cfg.Status
+	}
+
+	var notActive *shortener.NotActiveError
+	if errors.As(err, &notActive) {
+		if wait := time.Until(notActive.From); wait > 0 {
+			c.SetHeader("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		}
+	}
+
+	log.Info("link not active")
+
+	if prefersHTML(c) && notActive != nil {
+		h.renderPage(c, info.Status, pages.NotActive, pages.NotActiveData{Alias: alias, From: notActive.From})
+		return
+	}
+
+	renderInfo(c, info)
+}
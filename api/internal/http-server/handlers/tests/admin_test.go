@@ -0,0 +1,399 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestInfo(t *testing.T) {
+	created := time.Date(2025, 6, 25, 15, 15, 17, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		alias      string
+		dbBehavior func(m *mocks.MockDatabase, alias string)
+		wantStatus int
+	}{
+		{
+			name:  "happy path",
+			alias: "google",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().GetLink(gomock.Any(), alias).Return(database.Link{
+					ID: 1, URL: "https://google.com", Alias: alias, CreatedAt: created, UpdatedAt: created,
+					Metadata: &database.Metadata{Title: "Google", FetchedAt: created},
+				}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "empty alias",
+			alias:      "",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "not found",
+			alias: "unknown",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().GetLink(gomock.Any(), alias).Return(database.Link{}, database.ErrURLNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:  "database error",
+			alias: "alias",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().GetLink(gomock.Any(), alias).Return(database.Link{}, ErrInternal)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock, tt.alias)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodGet, path+"?alias="+tt.alias, nil)
+			w := httptest.NewRecorder()
+
+			h.NewInfo()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK {
+				var body LinkResponce
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, resp.StatusOK, body.Status)
+				assert.Equal(t, tt.alias, body.Link.Alias)
+				assert.True(t, created.Equal(body.Link.CreatedAt))
+				require.NotNil(t, body.Link.Metadata)
+				assert.Equal(t, "Google", body.Link.Metadata.Title)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	testCases := []struct {
+		name       string
+		query      string
+		dbBehavior func(m *mocks.MockDatabase)
+		wantStatus int
+		wantLimit  int
+		wantOffset int
+	}{
+		{
+			name:  "default page",
+			query: "",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().ListURLs(gomock.Any(), 100, 0).Return([]database.Link{{Alias: "a"}}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantLimit:  100,
+		},
+		{
+			name:  "custom page",
+			query: "?limit=10&offset=20",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().ListURLs(gomock.Any(), 10, 20).Return([]database.Link{}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantLimit:  10,
+			wantOffset: 20,
+		},
+		{
+			name:       "limit is not a number",
+			query:      "?limit=ten",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "limit too big",
+			query:      "?limit=100000",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "negative offset",
+			query:      "?offset=-1",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "database error",
+			query: "",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().ListURLs(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, ErrInternal)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodGet, path+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.NewList()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK {
+				var body ListResponce
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, tt.wantLimit, body.Limit)
+				assert.Equal(t, tt.wantOffset, body.Offset)
+				assert.NotNil(t, body.URLs)
+			}
+		})
+	}
+}
+
+func TestBroken(t *testing.T) {
+	status := http.StatusNotFound
+	checked := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		query      string
+		dbBehavior func(m *mocks.MockDatabase)
+		wantStatus int
+	}{
+		{
+			name:  "happy path",
+			query: "?limit=10",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().BrokenLinks(gomock.Any(), 10, 0).Return([]database.Link{{
+					Alias: "a", LastStatus: &status, LastCheckedAt: &checked, CheckFailures: 3,
+				}}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid page",
+			query:      "?offset=x",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "database error",
+			query: "",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().BrokenLinks(gomock.Any(), 100, 0).Return(nil, ErrInternal)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodGet, path+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.NewBroken()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK {
+				var body ListResponce
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				require.Len(t, body.URLs, 1)
+				assert.Equal(t, 3, body.URLs[0].CheckFailures)
+				require.NotNil(t, body.URLs[0].LastStatus)
+				assert.Equal(t, status, *body.URLs[0].LastStatus)
+			}
+		})
+	}
+}
+
+func TestEnable(t *testing.T) {
+	testCases := []struct {
+		name       string
+		alias      string
+		dbBehavior func(m *mocks.MockDatabase, alias string)
+		wantStatus int
+	}{
+		{
+			name:  "happy path",
+			alias: "google",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().EnableURL(gomock.Any(), alias).Return(nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "empty alias",
+			alias:      "",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "not found",
+			alias: "unknown",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().EnableURL(gomock.Any(), alias).Return(database.ErrURLNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock, tt.alias)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodPost, path+"?alias="+tt.alias, nil)
+			w := httptest.NewRecorder()
+
+			h.NewEnable()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	t.Run("happy_path", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dbMock := mocks.NewMockDatabase(ctrl)
+		dbMock.EXPECT().Stats(gomock.Any()).Return(database.Stats{Total: 42, CreatedLast24: 2}, nil)
+
+		h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		h.NewStats()(w, r)
+
+		var body StatsResponce
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, int64(42), body.Stats.Total)
+		assert.Equal(t, int64(2), body.Stats.CreatedLast24)
+	})
+
+	t.Run("database_error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dbMock := mocks.NewMockDatabase(ctrl)
+		dbMock.EXPECT().Stats(gomock.Any()).Return(database.Stats{}, ErrInternal)
+
+		h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		h.NewStats()(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestAdminRoutes_APIKey(t *testing.T) {
+	cfg := &config.ServerConfig{
+		APIKeys: config.APIKeys{{Name: "ops", Key: "secret"}},
+	}
+
+	testCases := []struct {
+		name       string
+		key        string
+		dbBehavior func(m *mocks.MockDatabase)
+		wantStatus int
+	}{
+		{
+			name: "valid key",
+			key:  "secret",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().Stats(gomock.Any()).Return(database.Stats{}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid key",
+			key:        "guess",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "without key",
+			key:        "",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			router := New(dbMock, cachemock.NewMockCache(ctrl), cfg, discardLogger).InitRoutes()
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/stats", nil)
+			if tt.key != "" {
+				r.Header.Set(apikey.Header, tt.key)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusUnauthorized {
+				var body resp.Response
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, resp.ErrorCode(apierr.CodeUnauthorized, apierr.ErrUnauthorized), body)
+			}
+		})
+	}
+}
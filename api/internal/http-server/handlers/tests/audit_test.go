@@ -0,0 +1,242 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestAudit(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	event := database.AuditEvent{
+		ID:     7,
+		Action: database.AuditDelete,
+		Alias:  "google",
+		Actor:  database.Actor{APIKey: "ops", IP: "203.0.113.7", RequestID: "host/abc-000001"},
+		Before: &database.Link{Alias: "google", URL: "https://www.google.com"},
+	}
+
+	testCases := []struct {
+		name       string
+		query      string
+		dbBehavior func(m *mocks.MockDatabase)
+		wantStatus int
+		wantField  string
+	}{
+		{
+			name:  "happy path",
+			query: "?alias=google&action=delete&api_key=ops&since=2026-10-01T00:00:00Z&limit=10",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().AuditEvents(gomock.Any(), database.AuditFilter{
+					Alias:  "google",
+					Action: database.AuditDelete,
+					APIKey: "ops",
+					Since:  &since,
+				}, 10, 0).Return([]database.AuditEvent{event}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unknown action",
+			query:      "?action=rename",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantField:  "action",
+		},
+		{
+			name:       "invalid time",
+			query:      "?until=yesterday",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantField:  "until",
+		},
+		{
+			name:       "invalid page",
+			query:      "?offset=-1",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantField:  "offset",
+		},
+		{
+			name:  "database error",
+			query: "",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().AuditEvents(gomock.Any(), database.AuditFilter{}, 100, 0).Return(nil, ErrInternal)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodGet, path+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.NewAudit()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK {
+				var body AuditResponce
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, []database.AuditEvent{event}, body.Events)
+				assert.Equal(t, 10, body.Limit)
+			}
+
+			if tt.wantField != "" {
+				var body resp.Response
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				require.Len(t, body.Fields, 1)
+				assert.Equal(t, tt.wantField, body.Fields[0].Field)
+			}
+		})
+	}
+}
+
+func TestAuditExport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	events := []database.AuditEvent{
+		{ID: 1, Action: database.AuditCreate, Alias: "google", After: &database.Link{Alias: "google"}},
+		{ID: 2, Action: database.AuditDelete, Alias: "google", Before: &database.Link{Alias: "google"}},
+	}
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	dbMock.EXPECT().ExportAudit(gomock.Any(), database.AuditFilter{Alias: "google"}, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ database.AuditFilter, each func(database.AuditEvent) error) error {
+			for _, e := range events {
+				if err := each(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+	r := httptest.NewRequest(http.MethodGet, path+"?alias=google", nil)
+	w := httptest.NewRecorder()
+
+	h.NewAuditExport()(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	var got []database.AuditEvent
+	sc := bufio.NewScanner(w.Body)
+	for sc.Scan() {
+		var e database.AuditEvent
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &e))
+		got = append(got, e)
+	}
+	assert.Equal(t, events, got)
+}
+
+func TestRecordActor(t *testing.T) {
+	cfg := &config.ServerConfig{APIKeys: config.APIKeys{{Name: "ops", Key: "ops-secret"}}}
+
+	testCases := []struct {
+		name       string
+		method     string
+		target     string
+		key        string
+		dbBehavior func(m *mocks.MockDatabase, got *database.Actor)
+		wantActor  database.Actor
+	}{
+		{
+			name:   "admin route",
+			method: http.MethodPost,
+			target: "/api/v1/url/enable?alias=google",
+			key:    "ops-secret",
+			dbBehavior: func(m *mocks.MockDatabase, got *database.Actor) {
+				m.EXPECT().EnableURL(gomock.Any(), "google").DoAndReturn(func(ctx context.Context, _ string) error {
+					*got = database.ActorFrom(ctx)
+					return nil
+				})
+			},
+			wantActor: database.Actor{APIKey: "ops", IP: "203.0.113.7"},
+		},
+		{
+			name:   "write route without a key",
+			method: http.MethodDelete,
+			target: "/api/v1/url?alias=google",
+			dbBehavior: func(m *mocks.MockDatabase, got *database.Actor) {
+				m.EXPECT().DeleteURL(gomock.Any(), "google").DoAndReturn(func(ctx context.Context, _ string) (int64, error) {
+					*got = database.ActorFrom(ctx)
+					return 1, nil
+				})
+			},
+			wantActor: database.Actor{IP: "203.0.113.7"},
+		},
+		{
+			name:   "write route with a key",
+			method: http.MethodDelete,
+			target: "/api/v1/url?alias=google",
+			key:    "ops-secret",
+			dbBehavior: func(m *mocks.MockDatabase, got *database.Actor) {
+				m.EXPECT().DeleteURL(gomock.Any(), "google").DoAndReturn(func(ctx context.Context, _ string) (int64, error) {
+					*got = database.ActorFrom(ctx)
+					return 1, nil
+				})
+			},
+			wantActor: database.Actor{APIKey: "ops", IP: "203.0.113.7"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var got database.Actor
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock, &got)
+
+			cacheMock := cachemock.NewMockCache(ctrl)
+			cacheMock.EXPECT().Delete(gomock.Any(), "link:google").Return(nil).AnyTimes()
+
+			router := New(dbMock, cacheMock, cfg, discardLogger).InitRoutes(middleware.RequestID)
+
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			r.RemoteAddr = "203.0.113.7:51234"
+			if tt.key != "" {
+				r.Header.Set(apikey.Header, tt.key)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, r)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.NotEmpty(t, got.RequestID)
+			got.RequestID = ""
+			assert.Equal(t, tt.wantActor, got)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestRedirect_ClickLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Get(gomock.Any(), "link:report").Return(`{"url": "https://files.example.com/report.pdf", "max_clicks": 2}`, nil).Times(3)
+	cacheMock.EXPECT().Expire(gomock.Any(), "link:report").Return(nil).Times(3)
+	// the last redirect and the refused one evict the link
+	cacheMock.EXPECT().Delete(gomock.Any(), "link:report").Return(nil).Times(2)
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	// the counter starts at the clicks of the store
+	dbMock.EXPECT().GetTarget(gomock.Any(), "report").Return(database.Target{
+		URL:     "https://files.example.com/report.pdf",
+		Options: database.Options{MaxClicks: 2},
+	}, nil)
+	dbMock.EXPECT().RecordClicks(gomock.Any(), "report", gomock.Any()).Return(nil).AnyTimes()
+
+	h := New(dbMock, cacheMock, discardCfg, discardLogger)
+	router := h.InitRoutes()
+
+	for _, want := range []int{http.StatusFound, http.StatusFound, http.StatusGone} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=report", nil))
+
+		require.Equal(t, want, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	cacheMock.EXPECT().Get(gomock.Any(), "link:report").Return("", cache.ErrKeyNotExist)
+	dbMock.EXPECT().GetTarget(gomock.Any(), "report").Return(database.Target{}, database.ErrLinkExhausted)
+
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=report", nil))
+
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Contains(t, w.Body.String(), apierr.CodeLinkExhausted)
+}
+
+func TestPreview_ClickLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Get(gomock.Any(), "link:report").Return(`{"url": "https://files.example.com/report.pdf", "max_clicks": 1}`, nil).Times(4)
+	cacheMock.EXPECT().Expire(gomock.Any(), "link:report").Return(nil).Times(4)
+	// the redirect and the refused one evict the link
+	cacheMock.EXPECT().Delete(gomock.Any(), "link:report").Return(nil).Times(2)
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	// previews read the clicks of the store until a redirect starts the
+	// counter
+	dbMock.EXPECT().GetTarget(gomock.Any(), "report").Return(database.Target{
+		URL:     "https://files.example.com/report.pdf",
+		Options: database.Options{MaxClicks: 1},
+	}, nil).Times(3)
+	dbMock.EXPECT().RecordClicks(gomock.Any(), "report", int64(1)).Return(nil).AnyTimes()
+
+	h := New(dbMock, cacheMock, discardCfg, discardLogger)
+	router := h.InitRoutes()
+
+	for _, target := range []string{"/report+", "/api/v1/url?alias=report&preview=1"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		require.Equal(t, http.StatusOK, w.Code, target)
+	}
+
+	for _, want := range []int{http.StatusFound, http.StatusGone} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=report", nil))
+
+		require.Equal(t, want, w.Code)
+	}
+}
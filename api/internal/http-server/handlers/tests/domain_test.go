@@ -0,0 +1,232 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/domains"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+var brandA = database.Domain{Name: "go.brand-a.com", AliasLength: 4, NotFoundURL: "https://brand-a.com/links"}
+
+func TestRedirect_Domains(t *testing.T) {
+	testCases := []struct {
+		name          string
+		host          string
+		alias         string
+		dbBehavior    func(m *mocks.MockDatabase, alias string)
+		cacheBehavior func(c *cachemock.MockCache, alias string)
+		wantStatus    int
+		wantLocation  string
+	}{
+		{
+			name:  "domain alias",
+			host:  "go.brand-a.com",
+			alias: "docs",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().GetTarget(gomock.Any(), gomock.Any()).Times(0)
+			},
+			cacheBehavior: func(c *cachemock.MockCache, alias string) {
+				// cached apart from the alias of the default namespace
+				c.EXPECT().Get(gomock.Any(), "link:go.brand-a.com\x00"+alias).Return("https://brand-a.com/docs", nil)
+				c.EXPECT().Expire(gomock.Any(), "link:go.brand-a.com\x00"+alias).Return(nil)
+			},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://brand-a.com/docs",
+		},
+		{
+			name:  "domain not found page",
+			host:  "GO.BRAND-A.COM:8080",
+			alias: "unknown",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().GetTarget(gomock.Any(), alias).
+					Do(func(ctx context.Context, _ string) {
+						assert.Equal(t, brandA, database.DomainFrom(ctx))
+					}).
+					Return(database.Target{}, database.ErrURLNotFound)
+			},
+			cacheBehavior: func(c *cachemock.MockCache, alias string) {
+				c.EXPECT().Get(gomock.Any(), gomock.Any()).Return("", cache.ErrKeyNotExist)
+			},
+			wantStatus:   http.StatusFound,
+			wantLocation: brandA.NotFoundURL,
+		},
+		{
+			name:  "other host",
+			host:  "sho.rt",
+			alias: "unknown",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().GetTarget(gomock.Any(), alias).
+					Do(func(ctx context.Context, _ string) {
+						assert.Equal(t, database.DefaultDomain, database.DomainFrom(ctx).Name)
+					}).
+					Return(database.Target{}, database.ErrURLNotFound)
+			},
+			cacheBehavior: func(c *cachemock.MockCache, alias string) {
+				c.EXPECT().Get(gomock.Any(), "link:"+alias).Return("", cache.ErrKeyNotExist)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			cacheMock := cachemock.NewMockCache(ctrl)
+			tt.dbBehavior(dbMock, tt.alias)
+			tt.cacheBehavior(cacheMock, tt.alias)
+
+			h := New(dbMock, cacheMock, discardCfg, discardLogger)
+			h.UseDomains(domains.New([]database.Domain{brandA}, nil, 0, nil))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/url?alias="+tt.alias, nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+
+			h.InitRoutes().ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestDomains(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	dbMock.EXPECT().ListDomains(gomock.Any()).Return([]database.Domain{{Name: "go.brand-b.com"}}, nil)
+
+	h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+	h.UseDomains(domains.New([]database.Domain{brandA}, dbMock, 0, nil))
+
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+
+	h.NewDomains()(w, r)
+
+	var body DomainsResponce
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []database.Domain{brandA, {Name: "go.brand-b.com"}}, body.Domains)
+}
+
+func TestRegisterDomain(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		dbBehavior func(m *mocks.MockDatabase)
+		wantStatus int
+		wantCode   string
+		wantField  string
+	}{
+		{
+			name: "happy path",
+			body: `{"name": "Go.Brand-B.com", "alias_length": 8}`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveDomain(gomock.Any(), database.Domain{Name: "go.brand-b.com", AliasLength: 8}).Return(nil)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "invalid name",
+			body:       `{"name": "brand"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierr.CodeInvalidDomain,
+			wantField:  "name",
+		},
+		{
+			name:       "invalid not found url",
+			body:       `{"name": "go.brand-b.com", "not_found_url": "ftp://brand-b.com"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierr.CodeInvalidDomain,
+			wantField:  "not_found_url",
+		},
+		{
+			name:       "configured",
+			body:       `{"name": "go.brand-a.com"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierr.CodeDomainTaken,
+			wantField:  "name",
+		},
+		{
+			name: "registered",
+			body: `{"name": "go.brand-b.com"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveDomain(gomock.Any(), gomock.Any()).Return(database.ErrDomainExist)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apierr.CodeDomainTaken,
+			wantField:  "name",
+		},
+		{
+			name: "database error",
+			body: `{"name": "go.brand-b.com"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveDomain(gomock.Any(), gomock.Any()).Return(ErrInternal)
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   apierr.CodeInternal,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+			h.UseDomains(domains.New([]database.Domain{brandA}, dbMock, 0, nil))
+
+			r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			h.NewRegisterDomain()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusCreated {
+				var body DomainResponce
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, "go.brand-b.com", body.Domain.Name)
+				return
+			}
+
+			var body resp.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantCode, body.Code)
+
+			if tt.wantField != "" {
+				require.Len(t, body.Fields, 1)
+				assert.Equal(t, tt.wantField, body.Fields[0].Field)
+			}
+		})
+	}
+}
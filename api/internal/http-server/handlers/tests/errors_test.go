@@ -0,0 +1,212 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/linkio"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want ErrorInfo
+	}{
+		{
+			name: "handler error",
+			err:  apierr.ErrEmptyAlias,
+			want: ErrorInfo{Status: http.StatusBadRequest, Code: apierr.CodeEmptyAlias, Err: apierr.ErrEmptyAlias},
+		},
+		{
+			name: "wrapped database error",
+			err:  fmt.Errorf("save: %w", database.ErrURLExist),
+			want: ErrorInfo{Status: http.StatusBadRequest, Code: apierr.CodeAliasTaken, Err: apierr.ErrAliasExist},
+		},
+		{
+			name: "alias generation",
+			err:  database.ErrMaxRetriesForGenerate,
+			want: ErrorInfo{Status: http.StatusInternalServerError, Code: apierr.CodeAliasGeneration, Err: apierr.ErrCanNotGenAlias},
+		},
+		{
+			name: "media type",
+			err:  fmt.Errorf("%w %q", reqcontext.ErrUnsupportedMediaType, "text/csv"),
+			want: ErrorInfo{Status: http.StatusUnsupportedMediaType, Code: apierr.CodeUnsupportedMediaType, Err: apierr.ErrUnsupportedMediaType},
+		},
+		{
+			name: "malformed import",
+			err:  fmt.Errorf("line 3: %w", linkio.ErrMalformed),
+			want: ErrorInfo{Status: http.StatusBadRequest, Code: apierr.CodeMalformedImport, Err: apierr.ErrMalformedImport},
+		},
+		{
+			name: "unknown error is internal",
+			err:  errors.New("connection refused"),
+			want: ErrorInfo{Status: http.StatusInternalServerError, Code: apierr.CodeInternal, Err: apierr.ErrInternalServer},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.err))
+		})
+	}
+}
+
+func TestErrorByCode(t *testing.T) {
+	assert.Equal(t, apierr.ErrAliasExist, apierr.ByCode(apierr.CodeAliasTaken))
+	assert.Equal(t, apierr.ErrTooManyRequests, apierr.ByCode(apierr.CodeRateLimited))
+	assert.Nil(t, apierr.ByCode("no_such_code"))
+}
+
+// Error messages are what clients used to match on before codes, they must
+// stay distinct.
+func TestErrorMessagesUnique(t *testing.T) {
+	errs := []error{
+		apierr.ErrURLNotFound, apierr.ErrEmptyAlias, apierr.ErrEmptyURL, apierr.ErrInternalServer, apierr.ErrAliasExist,
+		apierr.ErrInvalidURLFormat, apierr.ErrTooManyRequests, apierr.ErrCanNotGenAlias, apierr.ErrInvalidPage,
+		apierr.ErrUnauthorized, apierr.ErrUnknownFormat, apierr.ErrUnknownImportMode, apierr.ErrMalformedImport,
+		apierr.ErrInvalidImport, apierr.ErrUnsupportedMediaType, apierr.ErrNotAcceptable,
+	}
+
+	seen := make(map[string]error, len(errs))
+	for _, err := range errs {
+		if prev, ok := seen[err.Error()]; ok {
+			t.Errorf("%q is the message of two errors: %v", err.Error(), prev)
+		}
+		seen[err.Error()] = err
+	}
+}
+
+func TestSave_FieldErrors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		dbBehavior func(m *mocks.MockDatabase)
+		wantStatus int
+		want       resp.Response
+	}{
+		{
+			name:       "empty url",
+			body:       `{"alias":"google"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			want: resp.ErrorCode(apierr.CodeEmptyURL, apierr.ErrEmptyURL, resp.FieldError{
+				Field: "url", Code: apierr.FieldRequired, Message: apierr.ErrEmptyURL.Error(),
+			}),
+		},
+		{
+			name:       "invalid url",
+			body:       `{"url":"ftp://google.com"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			want: resp.ErrorCode(apierr.CodeInvalidURL, apierr.ErrInvalidURLFormat, resp.FieldError{
+				Field: "url", Code: apierr.FieldInvalid, Message: apierr.ErrInvalidURLFormat.Error(),
+			}),
+		},
+		{
+			name: "alias taken",
+			body: `{"url":"https://google.com","alias":"google"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), "https://google.com", "google").Return(database.ErrURLExist)
+			},
+			wantStatus: http.StatusBadRequest,
+			want: resp.ErrorCode(apierr.CodeAliasTaken, apierr.ErrAliasExist, resp.FieldError{
+				Field: "alias", Code: apierr.FieldTaken, Message: apierr.ErrAliasExist.Error(),
+			}),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.NewSave()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			var got resp.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestList_FieldErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	h := New(mocks.NewMockDatabase(ctrl), cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+	r := httptest.NewRequest(http.MethodGet, path+"?limit=5000&offset=-1", nil)
+	w := httptest.NewRecorder()
+
+	h.NewList()(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var got resp.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, apierr.CodeInvalidPage, got.Code)
+	require.Len(t, got.Fields, 2)
+	assert.Equal(t, "limit", got.Fields[0].Field)
+	assert.Equal(t, "offset", got.Fields[1].Field)
+}
+
+func TestRedirect_Problem(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	dbMock.EXPECT().GetTarget(gomock.Any(), "unknown").Return(database.Target{}, database.ErrURLNotFound)
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Get(gomock.Any(), "link:unknown").Return("", cache.ErrKeyNotExist)
+
+	h := New(dbMock, cacheMock, discardCfg, discardLogger)
+
+	r := httptest.NewRequest(http.MethodGet, path+"?alias=unknown", nil)
+	r.Header.Set("Accept", "application/json, application/problem+json")
+	w := httptest.NewRecorder()
+
+	middleware.RequestID(h.NewRedirect()).ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, reqcontext.MIMEProblemJSON, w.Header().Get("Content-Type"))
+
+	var got resp.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+
+	assert.Equal(t, resp.ProblemTypePrefix+apierr.CodeNotFound, got.Type)
+	assert.Equal(t, "Not Found", got.Title)
+	assert.Equal(t, http.StatusNotFound, got.Status)
+	assert.Equal(t, apierr.ErrURLNotFound.Error(), got.Detail)
+	assert.Equal(t, apierr.CodeNotFound, got.Code)
+	assert.NotEmpty(t, got.Instance, "request id")
+}
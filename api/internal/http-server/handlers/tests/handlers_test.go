@@ -17,6 +17,7 @@ import (
 	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/logger/discard"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httprate"
 	"github.com/golang/mock/gomock"
@@ -225,7 +226,7 @@ func Test_helthy(t *testing.T) {
 		err = json.Unmarshal(body, &responce)
 		require.NoError(t, err, "json unmarshaling")
 
-		assert.Equal(t, resp.Error(ErrInternalServer), responce)
+		assert.Equal(t, resp.ErrorCode(apierr.CodeInternal, apierr.ErrInternalServer), responce)
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 }
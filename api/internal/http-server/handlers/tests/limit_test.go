@@ -4,13 +4,17 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
 	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/ratelimiter"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,7 +37,7 @@ func TestNewLimit(t *testing.T) {
 
 		limiter(w, r)
 
-		expectedBody := resp.Error(ErrTooManyRequests)
+		expectedBody := resp.ErrorCode(apierr.CodeRateLimited, apierr.ErrTooManyRequests)
 		var body resp.Response
 		err := json.Unmarshal(w.Body.Bytes(), &body)
 		require.NoError(t, err)
@@ -62,11 +66,134 @@ func TestNewLimit(t *testing.T) {
 			w.WriteHeader(http.StatusOK)
 		})).ServeHTTP(w, r)
 
-		expectedBody := resp.Error(ErrTooManyRequests)
+		expectedBody := resp.ErrorCode(apierr.CodeRateLimited, apierr.ErrTooManyRequests)
 		var body resp.Response
 		err := json.Unmarshal(w.Body.Bytes(), &body)
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusTooManyRequests, w.Code)
 		assert.Equal(t, expectedBody, body)
 	})
+
+	t.Run("with_limiter_result", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		h := New(
+			mocks.NewMockDatabase(ctrl), cachemock.NewMockCache(ctrl),
+			discardCfg, discardLogger,
+		)
+
+		mw := ratelimiter.NewWithLimiter(ratelimiter.NewLocalLimiter(), 0, time.Minute, h.NewLimit())
+
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})).ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+		assert.NotEmpty(t, w.Header().Get("X-RateLimit-Reset"))
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+}
+
+func TestInitRoutes_RateLimits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := &config.ServerConfig{
+		RequesLimit:  1,
+		WindowLength: time.Minute,
+		RateLimits: config.RateLimits{
+			DefaultTier: config.TierFree,
+			Groups: map[string]config.RateLimitGroup{
+				config.RouteRedirects: {
+					Tiers: map[string]config.RateLimit{
+						config.TierFree: {Requests: 10, Window: time.Minute},
+					},
+				},
+				config.RouteWrites: {
+					Tiers: map[string]config.RateLimit{
+						config.TierFree: {Requests: 1, Window: time.Minute},
+					},
+				},
+			},
+		},
+	}
+
+	router := New(
+		mocks.NewMockDatabase(ctrl), cachemock.NewMockCache(ctrl),
+		cfg, discardLogger,
+	).InitRoutes()
+
+	save := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/url", strings.NewReader(`{"url":""}`))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, save().Code)
+
+	w := save()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// the redirects have their own budget, not capped by request_limit
+	for range 3 {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/url", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	// routes outside the groups share request_limit
+	helthy := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/helthy", nil))
+		return w.Code
+	}
+	assert.NotEqual(t, http.StatusTooManyRequests, helthy())
+	assert.Equal(t, http.StatusTooManyRequests, helthy())
+}
+
+func TestInitRoutes_RateLimitsWithoutGroups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// configs from before the route groups only have request_limit
+	cfg := &config.ServerConfig{
+		RequesLimit:  2,
+		WindowLength: time.Minute,
+		RateLimits:   config.RateLimits{DefaultTier: config.TierFree},
+	}
+
+	router := New(
+		mocks.NewMockDatabase(ctrl), cachemock.NewMockCache(ctrl),
+		cfg, discardLogger,
+	).InitRoutes()
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/v1/url", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// every group gets its own request_limit
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		for range 2 {
+			assert.Equal(t, http.StatusBadRequest, serve(method, `{"url":""}`).Code, method)
+		}
+
+		w := serve(method, `{"url":""}`)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code, method)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	}
 }
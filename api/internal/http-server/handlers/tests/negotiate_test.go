@@ -0,0 +1,193 @@
+package handlers_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestSave_ContentNegotiation(t *testing.T) {
+	testCases := []struct {
+		name            string
+		contentType     string
+		accept          string
+		body            string
+		dbBehavior      func(m *mocks.MockDatabase)
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:        "html form, text response",
+			contentType: "application/x-www-form-urlencoded",
+			accept:      "text/plain",
+			body:        "url=https%3A%2F%2Fgoogle.com&alias=google&submit=Shorten",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), "https://google.com", "google").Return(nil)
+			},
+			wantStatus:      http.StatusCreated,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "alias: google\nstatus: OK\n",
+		},
+		{
+			name:        "xml client",
+			contentType: "text/xml",
+			accept:      "application/xml",
+			body:        `<request><url>https://google.com</url><alias>google</alias></request>`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), "https://google.com", "google").Return(nil)
+			},
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/xml",
+			wantBody:        `<response><status>OK</status><alias>google</alias></response>`,
+		},
+		{
+			name:        "form response",
+			contentType: "application/json",
+			accept:      "application/x-www-form-urlencoded",
+			body:        `{"url":"https://google.com","alias":"google"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), "https://google.com", "google").Return(nil)
+			},
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/x-www-form-urlencoded",
+			wantBody:        "alias=google&status=OK",
+		},
+		{
+			name:            "unsupported content type",
+			contentType:     "text/csv",
+			body:            "https://google.com,google",
+			dbBehavior:      func(m *mocks.MockDatabase) {},
+			wantStatus:      http.StatusUnsupportedMediaType,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "not acceptable, nothing saved",
+			contentType:     "application/json",
+			accept:          "image/png",
+			body:            `{"url":"https://google.com","alias":"google"}`,
+			dbBehavior:      func(m *mocks.MockDatabase) {},
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: "application/json",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			h.NewSave()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDelete_NotAcceptable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// nothing is deleted when the response can not be encoded
+	h := New(mocks.NewMockDatabase(ctrl), cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+	r := httptest.NewRequest(http.MethodDelete, path+"?alias=google", nil)
+	r.Header.Set("Accept", "image/png")
+	w := httptest.NewRecorder()
+
+	h.NewDelete()(w, r)
+
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
+
+func TestOptions_Negotiation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the options are not changed when the response can not be encoded
+	h := New(mocks.NewMockDatabase(ctrl), cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+	r := httptest.NewRequest(http.MethodPut, path+"?alias=google", strings.NewReader(`{"passthrough": "destination"}`))
+	r.Header.Set("Accept", "image/png")
+	w := httptest.NewRecorder()
+
+	h.NewOptions()(w, r)
+
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	dbMock.EXPECT().SetOptions(gomock.Any(), "google", gomock.Any()).Return(nil)
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Delete(gomock.Any(), "link:google").Return(nil)
+
+	h = New(dbMock, cacheMock, discardCfg, discardLogger)
+
+	r = httptest.NewRequest(http.MethodPut, path+"?alias=google", strings.NewReader(`{"passthrough": "destination"}`))
+	r.Header.Set("Accept", "application/xml")
+	w = httptest.NewRecorder()
+
+	h.NewOptions()(w, r)
+
+	var got Responce
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "google", got.Alias)
+}
+
+func TestRedirect_ErrorNegotiation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	dbMock.EXPECT().GetTarget(gomock.Any(), "unknown").Return(database.Target{}, database.ErrURLNotFound)
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Get(gomock.Any(), "link:unknown").Return("", cache.ErrKeyNotExist)
+
+	h := New(dbMock, cacheMock, discardCfg, discardLogger)
+
+	r := httptest.NewRequest(http.MethodGet, path+"?alias=unknown", nil)
+	r.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	w := httptest.NewRecorder()
+
+	h.NewRedirect()(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/xml", w.Header().Get("Content-Type"))
+
+	var body resp.Response
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, resp.StatusError, body.Status)
+	assert.Equal(t, apierr.ErrURLNotFound.Error(), body.Error)
+}
@@ -0,0 +1,328 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/openapi"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+// schemaTypes are the Go types behind the component schemas of the spec.
+var schemaTypes = map[string]any{
+	"Response":        resp.Response{},
+	"FieldError":      resp.FieldError{},
+	"Problem":         resp.Problem{},
+	"Request":         Request{},
+	"Responce":        Responce{},
+	"Link":            database.Link{},
+	"Metadata":        database.Metadata{},
+	"LinkResponce":    LinkResponce{},
+	"ListResponce":    ListResponce{},
+	"Stats":           database.Stats{},
+	"StatsResponce":   StatsResponce{},
+	"ImportResult":    database.ImportResult{},
+	"ImportError":     ImportError{},
+	"ImportResponce":  ImportResponce{},
+	"Domain":          database.Domain{},
+	"DomainRequest":   DomainRequest{},
+	"DomainResponce":  DomainResponce{},
+	"DomainsResponce": DomainsResponce{},
+	"UTM":             database.UTM{},
+	"OptionsRequest":  OptionsRequest{},
+	"UnlockRequest":   UnlockRequest{},
+	"Rule":            database.Rule{},
+	"HeaderCondition": database.HeaderCondition{},
+	"Variant":         database.Variant{},
+	"Actor":           database.Actor{},
+	"AuditEvent":      database.AuditEvent{},
+	"AuditResponce":   AuditResponce{},
+}
+
+type spec map[string]any
+
+func loadSpec(t *testing.T) spec {
+	t.Helper()
+
+	var s spec
+	require.NoError(t, json.Unmarshal(openapi.Spec, &s), "openapi.json is not valid JSON")
+	require.True(t, strings.HasPrefix(s["openapi"].(string), "3."), "not an OpenAPI 3 document")
+
+	return s
+}
+
+// lookup resolves a local reference such as `#/components/schemas/Link`.
+func (s spec) lookup(ref string) (map[string]any, bool) {
+	var node any = map[string]any(s)
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if node, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	m, ok := node.(map[string]any)
+	return m, ok
+}
+
+func (s spec) resolve(t *testing.T, schema map[string]any) map[string]any {
+	t.Helper()
+
+	for schema["$ref"] != nil {
+		ref := schema["$ref"].(string)
+		target, ok := s.lookup(ref)
+		require.True(t, ok, "unresolved reference %s", ref)
+		schema = target
+	}
+	return schema
+}
+
+// properties returns the type of every property of an object schema,
+// merging the parts of allOf.
+func (s spec) properties(t *testing.T, schema map[string]any) map[string]string {
+	t.Helper()
+
+	schema = s.resolve(t, schema)
+	props := make(map[string]string)
+
+	if parts, ok := schema["allOf"].([]any); ok {
+		for _, part := range parts {
+			for k, v := range s.properties(t, part.(map[string]any)) {
+				props[k] = v
+			}
+		}
+	}
+
+	for name, raw := range asMap(schema["properties"]) {
+		props[name] = s.typeOf(t, raw.(map[string]any))
+	}
+
+	return props
+}
+
+func (s spec) typeOf(t *testing.T, schema map[string]any) string {
+	schema = s.resolve(t, schema)
+	if _, ok := schema["allOf"]; ok {
+		return "object"
+	}
+	typ, _ := schema["type"].(string)
+	return typ
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// goProperties returns the JSON type of every field of t the way
+// encoding/json sees it, with embedded structs flattened.
+func goProperties(t reflect.Type) map[string]string {
+	props := make(map[string]string)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range goProperties(f.Type) {
+				props[k] = v
+			}
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		props[name] = jsonType(f.Type)
+	}
+
+	return props
+}
+
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func TestOpenAPI_Routes(t *testing.T) {
+	s := loadSpec(t)
+
+	h := New(nil, nil, discardCfg, discardLogger)
+
+	var registered []string
+	err := chi.Walk(h.InitRoutes(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		registered = append(registered, method+" "+route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var documented []string
+	for path, item := range asMap(s["paths"]) {
+		for method := range asMap(item) {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+
+	assert.Equal(t, registered, documented, "routes of InitRoutes and paths of openapi.json differ")
+}
+
+func TestOpenAPI_Schemas(t *testing.T) {
+	s := loadSpec(t)
+
+	schemas := asMap(asMap(s["components"])["schemas"])
+
+	for name := range schemas {
+		_, ok := schemaTypes[name]
+		assert.True(t, ok, "schema %s has no Go type in schemaTypes", name)
+	}
+
+	for name, v := range schemaTypes {
+		t.Run(name, func(t *testing.T) {
+			schema, ok := schemas[name].(map[string]any)
+			require.True(t, ok, "schema %s is missing from openapi.json", name)
+
+			assert.Equal(t, goProperties(reflect.TypeOf(v)), s.properties(t, schema))
+		})
+	}
+}
+
+// TestOpenAPI_References checks that every $ref of the document resolves.
+func TestOpenAPI_References(t *testing.T) {
+	s := loadSpec(t)
+
+	var walk func(node any)
+	walk = func(node any) {
+		switch n := node.(type) {
+		case map[string]any:
+			if ref, ok := n["$ref"].(string); ok {
+				_, found := s.lookup(ref)
+				assert.True(t, found, "unresolved reference %s", ref)
+			}
+			for _, v := range n {
+				walk(v)
+			}
+		case []any:
+			for _, v := range n {
+				walk(v)
+			}
+		}
+	}
+
+	walk(map[string]any(s))
+}
+
+func TestOpenAPI_ErrorCodes(t *testing.T) {
+	s := loadSpec(t)
+
+	response, ok := s.lookup("#/components/schemas/Response")
+	require.True(t, ok)
+
+	var documented []string
+	for _, code := range asMap(asMap(response["properties"])["code"])["enum"].([]any) {
+		documented = append(documented, code.(string))
+		assert.NotNil(t, apierr.ByCode(code.(string)), "documented code %s is never sent", code)
+	}
+
+	errs := []error{
+		apierr.ErrURLNotFound, apierr.ErrEmptyAlias, apierr.ErrEmptyURL, apierr.ErrInternalServer, apierr.ErrAliasExist,
+		apierr.ErrInvalidURLFormat, apierr.ErrTooManyRequests, apierr.ErrCanNotGenAlias, apierr.ErrInvalidPage,
+		apierr.ErrUnauthorized, apierr.ErrUnknownFormat, apierr.ErrUnknownImportMode, apierr.ErrMalformedImport,
+		apierr.ErrInvalidImport, apierr.ErrUnsupportedMediaType, apierr.ErrNotAcceptable, apierr.ErrDestinationRejected,
+		apierr.ErrUnsafeURL, apierr.ErrReputationDown, apierr.ErrLinkDisabled, apierr.ErrInvalidDomain, apierr.ErrDomainExist,
+		apierr.ErrInvalidOptions, apierr.ErrPasswordRequired, apierr.ErrWrongPassword, apierr.ErrLinkExhausted,
+		apierr.ErrLinkNotActive, apierr.ErrLinkExpired, apierr.ErrInvalidFilter,
+	}
+	for _, err := range errs {
+		assert.Contains(t, documented, Classify(err).Code, "code of %q is not documented", err)
+	}
+}
+
+func TestOpenAPI_FieldCodes(t *testing.T) {
+	s := loadSpec(t)
+
+	fieldError, ok := s.lookup("#/components/schemas/FieldError")
+	require.True(t, ok)
+
+	var documented []string
+	for _, code := range asMap(asMap(fieldError["properties"])["code"])["enum"].([]any) {
+		documented = append(documented, code.(string))
+	}
+
+	codes := append([]string{apierr.FieldRequired, apierr.FieldInvalid, apierr.FieldTaken}, policy.Reasons...)
+	assert.ElementsMatch(t, codes, documented)
+}
+
+func TestOpenAPI_Serve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	h := New(mocks.NewMockDatabase(ctrl), cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+	router := h.InitRoutes()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, string(openapi.Spec), w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "openapi.json")
+}
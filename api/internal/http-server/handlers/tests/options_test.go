@@ -0,0 +1,184 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestSave_Options(t *testing.T) {
+	testCases := []struct {
+		name       string
+		body       string
+		dbBehavior func(m *mocks.MockDatabase)
+		wantStatus int
+		wantField  string
+	}{
+		{
+			name: "happy path",
+			body: `{"url": "https://www.google.com", "alias": "google", "passthrough": "incoming", "utm": {"source": "newsletter"}}`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveTarget(gomock.Any(), "google", database.Target{
+					URL: "https://www.google.com",
+					Options: database.Options{
+						Passthrough: database.PassthroughIncoming,
+						UTM:         &database.UTM{Source: "newsletter"},
+					},
+				}).Return(nil)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "empty options",
+			body: `{"url": "https://www.google.com", "alias": "google", "utm": {}}`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), "https://www.google.com", "google").Return(nil)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "unknown passthrough",
+			body:       `{"url": "https://www.google.com", "passthrough": "always"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantField:  "passthrough",
+		},
+		{
+			name:       "long utm",
+			body:       `{"url": "https://www.google.com", "utm": {"campaign": "` + strings.Repeat("x", 257) + `"}}`,
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantField:  "utm.campaign",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.NewSave()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantField != "" {
+				var body resp.Response
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, apierr.CodeInvalidOptions, body.Code)
+				require.Len(t, body.Fields, 1)
+				assert.Equal(t, tt.wantField, body.Fields[0].Field)
+			}
+		})
+	}
+}
+
+func TestRedirect_Passthrough(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Get(gomock.Any(), "link:sale").
+		Return(`{"url": "https://shop.example.com/sale?ref=short#top", "passthrough": "destination", "utm": {"source": "newsletter"}}`, nil)
+	cacheMock.EXPECT().Expire(gomock.Any(), "link:sale").Return(nil)
+
+	h := New(mocks.NewMockDatabase(ctrl), cacheMock, discardCfg, discardLogger)
+
+	r := httptest.NewRequest(http.MethodGet, path+"?alias=sale&ref=ad&utm_source=twitter&q=a+b", nil)
+	w := httptest.NewRecorder()
+
+	h.NewRedirect()(w, r)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://shop.example.com/sale?ref=short&utm_source=newsletter&q=a+b#top", w.Header().Get("Location"))
+}
+
+func TestOptions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		alias         string
+		body          string
+		dbBehavior    func(m *mocks.MockDatabase, alias string)
+		cacheBehavior func(c *cachemock.MockCache, alias string)
+		wantStatus    int
+	}{
+		{
+			name:  "happy path",
+			alias: "google",
+			body:  `{"passthrough": "destination"}`,
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().SetOptions(gomock.Any(), alias, database.Options{Passthrough: database.PassthroughDestination}).Return(nil)
+			},
+			cacheBehavior: func(c *cachemock.MockCache, alias string) {
+				c.EXPECT().Delete(gomock.Any(), "link:"+alias).Return(cache.ErrKeyNotExist)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "empty alias",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid options",
+			alias:      "google",
+			body:       `{"passthrough": "both"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "not found",
+			alias: "unknown",
+			body:  `{}`,
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().SetOptions(gomock.Any(), alias, database.Options{}).Return(database.ErrURLNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			cacheMock := cachemock.NewMockCache(ctrl)
+			if tt.dbBehavior != nil {
+				tt.dbBehavior(dbMock, tt.alias)
+			}
+			if tt.cacheBehavior != nil {
+				tt.cacheBehavior(cacheMock, tt.alias)
+			}
+
+			h := New(dbMock, cacheMock, discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodPut, path+"?alias="+tt.alias, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.NewOptions()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+const browserAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+// protectedHandler returns a handler serving the link wiki protected by
+// the password hunter2 from the cache.
+func protectedHandler(t *testing.T, cfg *config.ServerConfig) http.Handler {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Get(gomock.Any(), "link:wiki").
+		Return(fmt.Sprintf(`{"url": "https://wiki.example.com", "passthrough": "incoming", "password_hash": %q}`, hash), nil).
+		AnyTimes()
+	cacheMock.EXPECT().Expire(gomock.Any(), "link:wiki").Return(nil).AnyTimes()
+
+	return New(mocks.NewMockDatabase(ctrl), cacheMock, cfg, discardLogger).InitRoutes()
+}
+
+func TestRedirect_Password(t *testing.T) {
+	h := protectedHandler(t, discardCfg)
+
+	t.Run("browser", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=wiki&ref=ad", nil)
+		r.Header.Set("Accept", browserAccept)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, w.Body.String(), `action="/api/v1/url/unlock?alias=wiki&amp;ref=ad"`)
+		assert.Empty(t, w.Header().Get("Location"))
+	})
+
+	t.Run("api client", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=wiki", nil)
+		r.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		var body resp.Response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, apierr.CodePasswordRequired, body.Code)
+	})
+}
+
+func unlock(h http.Handler, query, password string) *httptest.ResponseRecorder {
+	form := url.Values{"password": {password}}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/url/unlock?"+query, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", browserAccept)
+	r.RemoteAddr = "203.0.113.7:4242"
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestUnlock(t *testing.T) {
+	h := protectedHandler(t, discardCfg)
+
+	w := unlock(h, "alias=wiki&page=home", "hunter2")
+
+	require.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "https://wiki.example.com?page=home", w.Header().Get("Location"))
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+
+	// the cookie lets the visitor through
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=wiki", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://wiki.example.com", w.Header().Get("Location"))
+
+	// a wrong password shows the form again
+	w = unlock(h, "alias=wiki", "hunter3")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Wrong password")
+	assert.Empty(t, w.Result().Cookies())
+
+	w = unlock(h, "", "hunter2")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUnlock_Attempts(t *testing.T) {
+	testCases := []struct {
+		name      string
+		passwords config.PasswordsConfig
+	}{
+		{
+			name:      "per ip",
+			passwords: config.PasswordsConfig{IPAttempts: 2, AttemptWindow: time.Minute},
+		},
+		{
+			name:      "per link",
+			passwords: config.PasswordsConfig{LinkAttempts: 2, AttemptWindow: time.Minute},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			h := protectedHandler(t, &config.ServerConfig{Passwords: tt.passwords})
+
+			for range 2 {
+				assert.Equal(t, http.StatusUnauthorized, unlock(h, "alias=wiki", "guess").Code)
+			}
+
+			// the right password is refused as well once the limit is hit
+			w := unlock(h, "alias=wiki", "hunter2")
+
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Contains(t, w.Body.String(), "Too many attempts")
+			assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		})
+	}
+}
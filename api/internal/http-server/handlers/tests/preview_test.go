@@ -0,0 +1,185 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/pages"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestPreview(t *testing.T) {
+	testCases := []struct {
+		name   string
+		target string
+	}{
+		{
+			name:   "path",
+			target: "/docs+?ref=ad",
+		},
+		{
+			name:   "query",
+			target: "/api/v1/url?alias=docs&preview=1&ref=ad",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheMock := cachemock.NewMockCache(ctrl)
+			// the link is described by the cache, the store is not read
+			cacheMock.EXPECT().Get(gomock.Any(), "link:docs").Return(`{"url": "https://example.com/docs", "passthrough": "incoming",
+				"created_at": "2026-10-18T12:00:00Z", "metadata": {"title": "Docs", "og_title": "The docs"}}`, nil)
+			cacheMock.EXPECT().Expire(gomock.Any(), "link:docs").Return(nil)
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+
+			h := New(dbMock, cacheMock, discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			w := httptest.NewRecorder()
+
+			h.InitRoutes().ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+			assert.Empty(t, w.Header().Get("Location"))
+
+			body := w.Body.String()
+			assert.Contains(t, body, "The docs")
+			assert.Contains(t, body, "18 October 2026")
+			// the preview parameter is not passed through
+			assert.Contains(t, body, `href="https://example.com/docs?ref=ad"`)
+		})
+	}
+}
+
+func TestPreview_WithoutDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	// links cached as a bare url have no details
+	cacheMock.EXPECT().Get(gomock.Any(), "link:docs").Return("https://example.com/docs", nil)
+	cacheMock.EXPECT().Expire(gomock.Any(), "link:docs").Return(nil)
+
+	h := New(mocks.NewMockDatabase(ctrl), cacheMock, discardCfg, discardLogger)
+
+	r := httptest.NewRequest(http.MethodGet, "/docs+", nil)
+	w := httptest.NewRecorder()
+
+	h.InitRoutes().ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `href="https://example.com/docs"`)
+}
+
+func TestPreview_CachesDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cached := make(chan string, 1)
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Get(gomock.Any(), "link:docs").Return("", cache.ErrKeyNotExist)
+	cacheMock.EXPECT().Set(gomock.Any(), "link:docs", gomock.Any()).DoAndReturn(func(_ any, _ string, value any) error {
+		cached <- value.(string)
+		return nil
+	})
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	dbMock.EXPECT().GetTarget(gomock.Any(), "docs").Return(database.Target{
+		URL:       "https://example.com/docs",
+		CreatedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Metadata:  &database.Metadata{Title: "Docs", OGTitle: "The docs"},
+	}, nil)
+
+	h := New(dbMock, cacheMock, discardCfg, discardLogger)
+
+	r := httptest.NewRequest(http.MethodGet, "/docs+", nil)
+	w := httptest.NewRecorder()
+
+	h.InitRoutes().ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "The docs")
+
+	// the next previews read the details from the cache
+	select {
+	case value := <-cached:
+		assert.Contains(t, value, `"og_title":"The docs"`)
+		assert.Contains(t, value, `"created_at":"2026-10-18T12:00:00Z"`)
+	case <-time.After(time.Second):
+		t.Fatal("link was not cached")
+	}
+}
+
+func TestRedirect_Interstitial(t *testing.T) {
+	testCases := []struct {
+		name       string
+		accept     string
+		wantStatus int
+	}{
+		{
+			name:       "browser",
+			accept:     browserAccept,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "api client",
+			accept:     "*/*",
+			wantStatus: http.StatusFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheMock := cachemock.NewMockCache(ctrl)
+			cacheMock.EXPECT().Get(gomock.Any(), "link:docs").Return(`{"url": "https://example.com/docs", "interstitial": true}`, nil)
+			cacheMock.EXPECT().Expire(gomock.Any(), "link:docs").Return(nil)
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, pages.Interstitial), []byte(`leaving for {{.URL}}`), 0o600))
+
+			p, err := pages.Load(dir)
+			require.NoError(t, err)
+
+			h := New(dbMock, cacheMock, discardCfg, discardLogger)
+			h.UsePages(p)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=docs", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+
+			h.InitRoutes().ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "leaving for https://example.com/docs", w.Body.String())
+				assert.Empty(t, w.Header().Get("Location"))
+			} else {
+				assert.Equal(t, "https://example.com/docs", w.Header().Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestRedirect_Rules(t *testing.T) {
+	const target = `{"url": "https://app.example.com", "rules": [
+		{"url": "https://apps.apple.com/app/id1", "platforms": ["ios"]},
+		{"url": "https://play.google.com/store/apps/details?id=app", "platforms": ["android"]},
+		{"url": "https://app.example.com/de", "countries": ["DE"], "languages": ["de"]}
+	]}`
+
+	testCases := []struct {
+		name      string
+		headers   map[string]string
+		wantedURL string
+	}{
+		{
+			name:      "iphone",
+			headers:   map[string]string{"User-Agent": "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"},
+			wantedURL: "https://apps.apple.com/app/id1",
+		},
+		{
+			name:      "android",
+			headers:   map[string]string{"User-Agent": "Mozilla/5.0 (Linux; Android 14; Pixel 8)"},
+			wantedURL: "https://play.google.com/store/apps/details?id=app",
+		},
+		{
+			name:      "country and language",
+			headers:   map[string]string{"CF-IPCountry": "DE", "Accept-Language": "de-DE,de;q=0.9,en;q=0.5"},
+			wantedURL: "https://app.example.com/de",
+		},
+		{
+			name:      "no rule",
+			headers:   map[string]string{"User-Agent": "curl/8.4.0", "CF-IPCountry": "DE"},
+			wantedURL: "https://app.example.com",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheMock := cachemock.NewMockCache(ctrl)
+			cacheMock.EXPECT().Get(gomock.Any(), "link:app").Return(target, nil)
+			cacheMock.EXPECT().Expire(gomock.Any(), "link:app").Return(nil)
+
+			h := New(mocks.NewMockDatabase(ctrl), cacheMock, &config.ServerConfig{CountryHeader: "CF-IPCountry"}, discardLogger)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=app", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			h.InitRoutes().ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tt.wantedURL, w.Header().Get("Location"))
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestRedirect_Schedule(t *testing.T) {
+	launch := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	ended := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	early := fmt.Sprintf(`{"url": "https://shop.example.com/launch", "active_from": %q}`, launch.Format(time.RFC3339))
+	late := fmt.Sprintf(`{"url": "https://shop.example.com/launch", "active_until": %q}`, ended.Format(time.RFC3339))
+
+	testCases := []struct {
+		name         string
+		target       string
+		cfg          config.NotActiveConfig
+		accept       string
+		wantStatus   int
+		wantCode     string
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:       "not active",
+			target:     early,
+			wantStatus: http.StatusNotFound,
+			wantCode:   apierr.CodeLinkNotActive,
+		},
+		{
+			name:       "configured status",
+			target:     early,
+			cfg:        config.NotActiveConfig{Status: http.StatusForbidden},
+			wantStatus: http.StatusForbidden,
+			wantCode:   apierr.CodeLinkNotActive,
+		},
+		{
+			name:       "browser",
+			target:     early,
+			accept:     browserAccept,
+			wantStatus: http.StatusNotFound,
+			wantBody:   "This link is not available yet",
+		},
+		{
+			name:         "configured url",
+			target:       early,
+			cfg:          config.NotActiveConfig{URL: "https://shop.example.com/coming-soon"},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://shop.example.com/coming-soon",
+		},
+		{
+			name:       "expired",
+			target:     late,
+			wantStatus: http.StatusGone,
+			wantCode:   apierr.CodeLinkExpired,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheMock := cachemock.NewMockCache(ctrl)
+			cacheMock.EXPECT().Get(gomock.Any(), "link:launch").Return(tt.target, nil)
+			cacheMock.EXPECT().Expire(gomock.Any(), "link:launch").Return(nil)
+
+			h := New(mocks.NewMockDatabase(ctrl), cacheMock, &config.ServerConfig{NotActive: tt.cfg}, discardLogger)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=launch", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			h.InitRoutes().ServeHTTP(w, r)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantCode)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+			assert.Equal(t, tt.wantLocation, w.Header().Get("Location"))
+
+			if tt.target == early && tt.cfg.URL == "" {
+				retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+				require.NoError(t, err)
+				assert.InDelta(t, 48*time.Hour.Seconds(), retry, 60)
+			}
+		})
+	}
+}
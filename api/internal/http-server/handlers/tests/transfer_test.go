@@ -0,0 +1,326 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+// drain reads the links like the storage does and returns what it got.
+func drain(r database.LinkReader) ([]database.Link, error) {
+	var links []database.Link
+	for {
+		link, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return links, nil
+		}
+		if err != nil {
+			return links, err
+		}
+		links = append(links, link)
+	}
+}
+
+func TestImport(t *testing.T) {
+	const csvBody = "alias,url\ngoogle,https://google.com\nbroken,not a url\nya,https://ya.ru\n"
+
+	testCases := []struct {
+		name          string
+		query         string
+		body          string
+		dbBehavior    func(m *mocks.MockDatabase)
+		cacheBehavior func(m *cachemock.MockCache)
+		wantStatus    int
+		wantError     error
+		wantInvalid   int64
+	}{
+		{
+			name:  "skip invalid links",
+			query: "?format=csv&mode=skip",
+			body:  csvBody,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().ImportURLs(gomock.Any(), gomock.Any(), database.ConflictSkip).
+					DoAndReturn(func(_ any, r database.LinkReader, _ database.ConflictMode) (database.ImportResult, error) {
+						links, err := drain(r)
+						if err != nil {
+							return database.ImportResult{}, err
+						}
+						return database.ImportResult{Created: int64(len(links))}, nil
+					})
+			},
+			wantStatus:  http.StatusOK,
+			wantInvalid: 1,
+		},
+		{
+			name:  "overwrite evicts cache",
+			query: "?format=ndjson&mode=overwrite",
+			body:  `{"alias":"google","url":"https://google.com"}`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().ImportURLs(gomock.Any(), gomock.Any(), database.ConflictOverwrite).
+					Return(database.ImportResult{Updated: 1, Overwritten: []database.Link{{Alias: "google"}}}, nil)
+			},
+			cacheBehavior: func(m *cachemock.MockCache) {
+				m.EXPECT().Delete(gomock.Any(), "link:google").Return(cache.ErrKeyNotExist)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "fail on invalid link",
+			query: "?mode=fail",
+			body:  csvBody,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().ImportURLs(gomock.Any(), gomock.Any(), database.ConflictFail).
+					DoAndReturn(func(_ any, r database.LinkReader, _ database.ConflictMode) (database.ImportResult, error) {
+						_, err := drain(r)
+						return database.ImportResult{}, err
+					})
+			},
+			wantStatus:  http.StatusBadRequest,
+			wantError:   apierr.ErrInvalidImport,
+			wantInvalid: 1,
+		},
+		{
+			name:  "fail on existing alias",
+			query: "?mode=fail",
+			body:  csvBody,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().ImportURLs(gomock.Any(), gomock.Any(), database.ConflictFail).
+					Return(database.ImportResult{}, database.ErrURLExist)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  apierr.ErrAliasExist,
+		},
+		{
+			name:  "malformed body",
+			query: "?format=bitly",
+			body:  `"links"`,
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().ImportURLs(gomock.Any(), gomock.Any(), database.ConflictSkip).
+					DoAndReturn(func(_ any, r database.LinkReader, _ database.ConflictMode) (database.ImportResult, error) {
+						_, err := drain(r)
+						return database.ImportResult{}, err
+					})
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  apierr.ErrMalformedImport,
+		},
+		{
+			name:       "unknown format",
+			query:      "?format=xml",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantError:  apierr.ErrUnknownFormat,
+		},
+		{
+			name:       "unknown mode",
+			query:      "?mode=merge",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+			wantError:  apierr.ErrUnknownImportMode,
+		},
+		{
+			name:  "database error",
+			body:  csvBody,
+			query: "",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().ImportURLs(gomock.Any(), gomock.Any(), gomock.Any()).Return(database.ImportResult{}, ErrInternal)
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  apierr.ErrInternalServer,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			cacheMock := cachemock.NewMockCache(ctrl)
+			if tt.cacheBehavior != nil {
+				tt.cacheBehavior(cacheMock)
+			}
+
+			h := New(dbMock, cacheMock, discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodPost, path+tt.query, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.NewImport()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			var body ImportResponce
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+
+			if tt.wantError != nil {
+				assert.Equal(t, tt.wantError.Error(), body.Error)
+			} else {
+				assert.Equal(t, resp.StatusOK, body.Status)
+			}
+
+			assert.Equal(t, tt.wantInvalid, body.Invalid)
+			assert.Len(t, body.Errors, int(tt.wantInvalid))
+		})
+	}
+}
+
+func TestImport_DestinationPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	const body = "alias,url\n" +
+		"google,https://google.com\n" +
+		"local,http://127.0.0.1/admin\n" +
+		"meta,http://169.254.169.254/latest/meta-data\n" +
+		"denied,https://evil.example/x\n" +
+		"chain,https://bit.ly/abc\n"
+
+	var imported []database.Link
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	dbMock.EXPECT().ImportURLs(gomock.Any(), gomock.Any(), database.ConflictSkip).
+		DoAndReturn(func(_ any, r database.LinkReader, _ database.ConflictMode) (database.ImportResult, error) {
+			links, err := drain(r)
+			imported = links
+			return database.ImportResult{Created: int64(len(links))}, err
+		})
+
+	h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+	h.UsePolicy(policy.New(policy.Config{
+		Deny:       []string{"evil.example"},
+		Shorteners: []string{"bit.ly"},
+	}, nil))
+
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.NewImport()(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res ImportResponce
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	require.Len(t, imported, 1)
+	assert.Equal(t, "google", imported[0].Alias)
+
+	assert.Equal(t, int64(4), res.Invalid)
+	require.Len(t, res.Errors, 4)
+	for i, alias := range []string{"local", "meta", "denied", "chain"} {
+		assert.Equal(t, alias, res.Errors[i].Alias)
+		assert.Equal(t, i+3, res.Errors[i].Line)
+		assert.Equal(t, apierr.CodeDestinationRejected, res.Errors[i].Code)
+	}
+}
+
+func TestExport(t *testing.T) {
+	links := []database.Link{
+		{ID: 1, Alias: "google", URL: "https://google.com"},
+		{ID: 2, Alias: "ya", URL: "https://ya.ru"},
+	}
+
+	export := func(_ any, each func(database.Link) error) error {
+		for _, link := range links {
+			if err := each(link); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	t.Run("ndjson", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dbMock := mocks.NewMockDatabase(ctrl)
+		dbMock.EXPECT().ExportURLs(gomock.Any(), gomock.Any()).DoAndReturn(export)
+
+		h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+		r := httptest.NewRequest(http.MethodGet, path+"?format=ndjson", nil)
+		w := httptest.NewRecorder()
+
+		h.NewExport()(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "links.ndjson")
+
+		dec := json.NewDecoder(w.Body)
+		for _, want := range links {
+			var got database.Link
+			require.NoError(t, dec.Decode(&got))
+			assert.Equal(t, want, got)
+		}
+	})
+
+	t.Run("csv by default", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dbMock := mocks.NewMockDatabase(ctrl)
+		dbMock.EXPECT().ExportURLs(gomock.Any(), gomock.Any()).DoAndReturn(export)
+
+		h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		h.NewExport()(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "id,alias,url,created_at,updated_at\n1,google,https://google.com,,\n2,ya,https://ya.ru,,\n", w.Body.String())
+	})
+
+	t.Run("error before the first link", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		dbMock := mocks.NewMockDatabase(ctrl)
+		dbMock.EXPECT().ExportURLs(gomock.Any(), gomock.Any()).Return(ErrInternal)
+
+		h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+
+		h.NewExport()(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Disposition"))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		h := New(mocks.NewMockDatabase(ctrl), cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+		r := httptest.NewRequest(http.MethodGet, path+"?format=xml", nil)
+		w := httptest.NewRecorder()
+
+		h.NewExport()(w, r)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
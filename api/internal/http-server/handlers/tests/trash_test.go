@@ -0,0 +1,159 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestTrash(t *testing.T) {
+	deleted := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		query      string
+		dbBehavior func(m *mocks.MockDatabase)
+		wantStatus int
+	}{
+		{
+			name:  "happy path",
+			query: "?limit=10&offset=5",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().TrashedURLs(gomock.Any(), 10, 5).Return([]database.Link{{
+					Alias: "report", URL: "https://example.com/report", DeletedAt: &deleted,
+				}}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid page",
+			query:      "?limit=0",
+			dbBehavior: func(m *mocks.MockDatabase) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "database error",
+			query: "",
+			dbBehavior: func(m *mocks.MockDatabase) {
+				m.EXPECT().TrashedURLs(gomock.Any(), 100, 0).Return(nil, ErrInternal)
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodGet, path+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			h.NewTrash()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK {
+				var body ListResponce
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+				require.Len(t, body.URLs, 1)
+				require.NotNil(t, body.URLs[0].DeletedAt)
+				assert.True(t, deleted.Equal(*body.URLs[0].DeletedAt))
+			}
+		})
+	}
+}
+
+func TestRestore(t *testing.T) {
+	testCases := []struct {
+		name       string
+		alias      string
+		dbBehavior func(m *mocks.MockDatabase, alias string)
+		wantStatus int
+	}{
+		{
+			name:  "happy path",
+			alias: "report",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().RestoreURL(gomock.Any(), alias, gomock.Any()).Return(nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "empty alias",
+			alias:      "",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "not in trash",
+			alias: "unknown",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().RestoreURL(gomock.Any(), alias, gomock.Any()).Return(database.ErrURLNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			tt.dbBehavior(dbMock, tt.alias)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+
+			r := httptest.NewRequest(http.MethodPost, path+"?alias="+tt.alias, nil)
+			w := httptest.NewRecorder()
+
+			h.NewRestore()(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestRestore_Retention(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var since time.Time
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	dbMock.EXPECT().RestoreURL(gomock.Any(), "report", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, s time.Time) error {
+			since = s
+			return nil
+		})
+
+	h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+	h.UseRetention(7 * 24 * time.Hour)
+
+	r := httptest.NewRequest(http.MethodPost, path+"?alias=report", nil)
+	w := httptest.NewRecorder()
+
+	h.NewRestore()(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	// only links deleted within the retention are restored
+	assert.WithinDuration(t, time.Now().Add(-7*24*time.Hour), since, time.Minute)
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"sync"
 	"testing"
 
@@ -16,6 +17,11 @@ import (
 	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/reputation"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -91,7 +97,7 @@ func TestSave(t *testing.T) {
 			name:    "invalid request body",
 			request: Request{URL: "http://invalid"},
 			dbBehavior: func(m *mocks.MockDatabase, req Request) {
-				m.EXPECT().SaveGeneratedURl(gomock.Any(), req.URL, gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().SaveURL(gomock.Any(), req.URL, gomock.Any()).Times(0)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -113,37 +119,37 @@ func TestSave(t *testing.T) {
 		},
 
 		{
-			name:    "empty alias, happy path(SaveGeneratedURl)",
+			name:    "empty alias, happy path(generated alias)",
 			request: Request{URL: "http://url.without.alias"},
 			dbBehavior: func(m *mocks.MockDatabase, req Request) {
 				m.EXPECT().
-					SaveGeneratedURl(gomock.Any(), req.URL, gomock.Any(), gomock.Any()).
+					SaveURL(gomock.Any(), req.URL, gomock.Any()).
 					Times(1).
-					Return("http://good", nil)
+					Return(nil)
 			},
 			expectedStatus: http.StatusCreated,
 		},
 
 		{
-			name:    "empty alias, max retries error(SaveGeneratedURl)",
+			name:    "empty alias, max retries error(generated alias)",
 			request: Request{URL: "http://url.without.alias"},
 			dbBehavior: func(m *mocks.MockDatabase, req Request) {
 				m.EXPECT().
-					SaveGeneratedURl(gomock.Any(), req.URL, gomock.Any(), gomock.Any()).
-					Times(1).
-					Return("", database.ErrMaxRetriesForGenerate)
+					SaveURL(gomock.Any(), req.URL, gomock.Any()).
+					Times(shortener.DefaultMaxRetries).
+					Return(database.ErrURLExist)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
 
 		{
-			name:    "empty alias, interal database error(SaveGeneratedURl)",
+			name:    "empty alias, interal database error(generated alias)",
 			request: Request{URL: "http://url.without.alias"},
 			dbBehavior: func(m *mocks.MockDatabase, req Request) {
 				m.EXPECT().
-					SaveGeneratedURl(gomock.Any(), req.URL, gomock.Any(), gomock.Any()).
+					SaveURL(gomock.Any(), req.URL, gomock.Any()).
 					Times(1).
-					Return("", ErrInternal)
+					Return(ErrInternal)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -192,6 +198,163 @@ func TestSave(t *testing.T) {
 	}
 }
 
+func TestSave_DestinationPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dbMock := mocks.NewMockDatabase(ctrl)
+	dbMock.EXPECT().SaveURL(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+	h.UsePolicy(policy.New(policy.Config{}, nil))
+
+	body, err := json.Marshal(Request{URL: "http://169.254.169.254/latest/meta-data", Alias: "meta"})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.NewSave()(w, r)
+
+	var got resp.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, apierr.CodeDestinationRejected, got.Code)
+	require.Len(t, got.Fields, 1)
+	assert.Equal(t, "url", got.Fields[0].Field)
+	assert.Equal(t, policy.ReasonPrivateAddress, got.Fields[0].Code)
+}
+
+// reputationFunc is a ReputationChecker answering with a function.
+type reputationFunc func(url string) error
+
+func (f reputationFunc) CheckURL(_ context.Context, url string) error { return f(url) }
+
+func TestSave_Reputation(t *testing.T) {
+	testCases := []struct {
+		name         string
+		checkErr     error
+		expectedCode string
+		expected     int
+	}{
+		{
+			name:         "unsafe url",
+			checkErr:     &reputation.ThreatError{Threats: []string{"MALWARE"}},
+			expectedCode: apierr.CodeUnsafeURL,
+			expected:     http.StatusForbidden,
+		},
+		{
+			name:         "checker unavailable",
+			checkErr:     reputation.ErrUnavailable,
+			expectedCode: apierr.CodeReputationDown,
+			expected:     http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			dbMock.EXPECT().SaveURL(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+			h.UseReputation(reputationFunc(func(string) error { return tt.checkErr }), false)
+
+			body, err := json.Marshal(Request{URL: "http://phish.example/login", Alias: "phish"})
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			h.NewSave()(w, r)
+
+			var got resp.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Equal(t, tt.expectedCode, got.Code)
+		})
+	}
+}
+
+func TestOptions_Destinations(t *testing.T) {
+	testCases := []struct {
+		name         string
+		configure    func(h *Handler)
+		body         string
+		expected     int
+		expectedCode string
+	}{
+		{
+			name:         "rejected by policy",
+			configure:    func(h *Handler) { h.UsePolicy(policy.New(policy.Config{}, nil)) },
+			body:         `{"variants": [{"url": "http://169.254.169.254/latest/meta-data", "weight": 1}, {"url": "http://169.254.169.254/", "weight": 1}]}`,
+			expected:     http.StatusBadRequest,
+			expectedCode: apierr.CodeDestinationRejected,
+		},
+		{
+			name: "unsafe url",
+			configure: func(h *Handler) {
+				h.UseReputation(reputationFunc(func(string) error {
+					return &reputation.ThreatError{Threats: []string{"MALWARE"}}
+				}), false)
+			},
+			body:         `{"rules": [{"url": "http://phish.example/login", "countries": ["DE"]}]}`,
+			expected:     http.StatusForbidden,
+			expectedCode: apierr.CodeUnsafeURL,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dbMock := mocks.NewMockDatabase(ctrl)
+			dbMock.EXPECT().SetOptions(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+			h := New(dbMock, cachemock.NewMockCache(ctrl), discardCfg, discardLogger)
+			tt.configure(h)
+
+			r := httptest.NewRequest(http.MethodPut, path+"?alias=sale", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			h.NewOptions()(w, r)
+
+			var got resp.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Equal(t, tt.expectedCode, got.Code)
+		})
+	}
+}
+
+func TestRedirect_Reputation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := cachemock.NewMockCache(ctrl)
+	mockCache.EXPECT().Get(gomock.Any(), "link:phish").Return("http://phish.example/login", nil)
+	mockCache.EXPECT().Expire(gomock.Any(), "link:phish").Return(nil)
+
+	h := New(mocks.NewMockDatabase(ctrl), mockCache, discardCfg, discardLogger)
+	h.UseReputation(reputationFunc(func(string) error {
+		return &reputation.ThreatError{Threats: []string{"SOCIAL_ENGINEERING"}}
+	}), true)
+
+	r := httptest.NewRequest(http.MethodGet, "/?alias=phish", nil)
+	w := httptest.NewRecorder()
+
+	h.NewRedirect()(w, r)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
 func TestDelete(t *testing.T) {
 	testCases := []struct {
 		name           string
@@ -207,7 +370,7 @@ func TestDelete(t *testing.T) {
 				m.EXPECT().DeleteURL(gomock.Any(), alias).Return(hasDel, nil)
 			},
 			caheBehavior: func(c *cachemock.MockCache, alias string) {
-				c.EXPECT().Delete(gomock.Any(), alias).
+				c.EXPECT().Delete(gomock.Any(), "link:"+alias).
 					Times(1).
 					Return(nil)
 			},
@@ -221,7 +384,7 @@ func TestDelete(t *testing.T) {
 				m.EXPECT().DeleteURL(gomock.Any(), alias).Times(1).Return(noDel, database.ErrURLNotFound)
 			},
 			caheBehavior: func(c *cachemock.MockCache, alias string) {
-				c.EXPECT().Delete(gomock.Any(), alias).Times(0)
+				c.EXPECT().Delete(gomock.Any(), "link:"+alias).Times(0)
 			},
 			expectedStatus: http.StatusNotFound,
 		},
@@ -233,7 +396,7 @@ func TestDelete(t *testing.T) {
 				m.EXPECT().DeleteURL(gomock.Any(), alias).Return(noDel, ErrInternal)
 			},
 			caheBehavior: func(c *cachemock.MockCache, alias string) {
-				c.EXPECT().Delete(gomock.Any(), alias).Times(0)
+				c.EXPECT().Delete(gomock.Any(), "link:"+alias).Times(0)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -245,7 +408,7 @@ func TestDelete(t *testing.T) {
 				m.EXPECT().DeleteURL(gomock.Any(), alias).Return(hasDel, nil)
 			},
 			caheBehavior: func(c *cachemock.MockCache, alias string) {
-				c.EXPECT().Delete(gomock.Any(), alias).Times(1).Return(ErrInternal)
+				c.EXPECT().Delete(gomock.Any(), "link:"+alias).Times(1).Return(ErrInternal)
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -257,7 +420,7 @@ func TestDelete(t *testing.T) {
 				m.EXPECT().DeleteURL(gomock.Any(), alias).Return(hasDel, nil)
 			},
 			caheBehavior: func(c *cachemock.MockCache, alias string) {
-				c.EXPECT().Delete(gomock.Any(), alias).Return(cache.ErrKeyNotExist)
+				c.EXPECT().Delete(gomock.Any(), "link:"+alias).Return(cache.ErrKeyNotExist)
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -309,14 +472,14 @@ func TestRedirect(t *testing.T) {
 			name:  "happy path - cache miss, db hit",
 			alias: "valid",
 			dbBehavior: func(m *mocks.MockDatabase, alias string) {
-				m.EXPECT().GetURl(gomock.Any(), alias).Return("http://something.com", nil)
+				m.EXPECT().GetTarget(gomock.Any(), alias).Return(database.Target{URL: "http://something.com"}, nil)
 			},
 			cacheBehavior: func(c *cachemock.MockCache, alias string, wg *sync.WaitGroup) {
 				wg.Add(1)
 
-				c.EXPECT().Get(gomock.Any(), alias).Return("", cache.ErrKeyNotExist)
+				c.EXPECT().Get(gomock.Any(), "link:"+alias).Return("", cache.ErrKeyNotExist)
 				c.EXPECT().Expire(gomock.Any(), gomock.Any()).Times(0)
-				c.EXPECT().Set(gomock.Any(), alias, "http://something.com").
+				c.EXPECT().Set(gomock.Any(), "link:"+alias, "http://something.com").
 					Do(func(_ context.Context, _ string, _ any) {
 						defer wg.Done()
 					}).
@@ -329,10 +492,10 @@ func TestRedirect(t *testing.T) {
 			name:  "happy path - cache hit",
 			alias: "cached",
 			dbBehavior: func(m *mocks.MockDatabase, alias string) {
-				m.EXPECT().GetURl(gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().GetTarget(gomock.Any(), gomock.Any()).Times(0)
 			},
 			cacheBehavior: func(c *cachemock.MockCache, alias string, wg *sync.WaitGroup) {
-				c.EXPECT().Get(gomock.Any(), alias).Return("http://cached.com", nil)
+				c.EXPECT().Get(gomock.Any(), "link:"+alias).Return("http://cached.com", nil)
 				c.EXPECT().Expire(gomock.Any(), gomock.Any()).Times(1).Return(nil)
 				c.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 			},
@@ -343,7 +506,7 @@ func TestRedirect(t *testing.T) {
 			name:  "empty alias",
 			alias: "",
 			dbBehavior: func(m *mocks.MockDatabase, alias string) {
-				m.EXPECT().GetURl(gomock.Any(), gomock.Any()).Times(0)
+				m.EXPECT().GetTarget(gomock.Any(), gomock.Any()).Times(0)
 			},
 			cacheBehavior: func(c *cachemock.MockCache, alias string, wg *sync.WaitGroup) {
 				c.EXPECT().Get(gomock.Any(), gomock.Any()).Times(0)
@@ -358,23 +521,36 @@ func TestRedirect(t *testing.T) {
 			name:  "url not found",
 			alias: "notfound",
 			dbBehavior: func(m *mocks.MockDatabase, alias string) {
-				m.EXPECT().GetURl(gomock.Any(), alias).Return("", database.ErrURLNotFound)
+				m.EXPECT().GetTarget(gomock.Any(), alias).Return(database.Target{}, database.ErrURLNotFound)
 			},
 			cacheBehavior: func(m *cachemock.MockCache, alias string, wg *sync.WaitGroup) {
-				m.EXPECT().Get(gomock.Any(), alias).Return("", cache.ErrKeyNotExist)
+				m.EXPECT().Get(gomock.Any(), "link:"+alias).Return("", cache.ErrKeyNotExist)
 				m.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 			},
 			wantStatus:   http.StatusNotFound,
 			wantRedirect: false,
 		},
+		{
+			name:  "link disabled",
+			alias: "disabled",
+			dbBehavior: func(m *mocks.MockDatabase, alias string) {
+				m.EXPECT().GetTarget(gomock.Any(), alias).Return(database.Target{}, database.ErrLinkDisabled)
+			},
+			cacheBehavior: func(m *cachemock.MockCache, alias string, wg *sync.WaitGroup) {
+				m.EXPECT().Get(gomock.Any(), "link:"+alias).Return("", cache.ErrKeyNotExist)
+				m.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus:   http.StatusGone,
+			wantRedirect: false,
+		},
 		{
 			name:  "database error",
 			alias: "dberror",
 			dbBehavior: func(m *mocks.MockDatabase, alias string) {
-				m.EXPECT().GetURl(gomock.Any(), alias).Return("", errors.New("db error"))
+				m.EXPECT().GetTarget(gomock.Any(), alias).Return(database.Target{}, errors.New("db error"))
 			},
 			cacheBehavior: func(m *cachemock.MockCache, alias string, wg *sync.WaitGroup) {
-				m.EXPECT().Get(gomock.Any(), alias).Return("", cache.ErrKeyNotExist)
+				m.EXPECT().Get(gomock.Any(), "link:"+alias).Return("", cache.ErrKeyNotExist)
 				m.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 			},
 			wantStatus:   http.StatusInternalServerError,
@@ -460,7 +636,7 @@ func TestHandlers_SaveRedirectDelete_HappyPath(t *testing.T) {
 			cacheMock.EXPECT().Expire(gomock.Any(), gomock.Any()).Times(1).Return(nil)
 			cacheMock.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
 
-			dbMock.EXPECT().GetURl(gomock.Any(), gomock.Any()).Times(0)
+			dbMock.EXPECT().GetTarget(gomock.Any(), gomock.Any()).Times(0)
 
 			redirector(w, r)
 
@@ -548,9 +724,9 @@ func TestHandlers_SaveRedirectDelete_BadRequests(t *testing.T) {
 				// Don't save so it won't exist for redirect
 			},
 			redirectBehavior: func(m *mocks.MockDatabase, c *cachemock.MockCache, alias string) {
-				c.EXPECT().Get(gomock.Any(), alias).Return("", cache.ErrKeyNotExist)
+				c.EXPECT().Get(gomock.Any(), "link:"+alias).Return("", cache.ErrKeyNotExist)
 				c.EXPECT().Expire(gomock.Any(), gomock.Any()).Times(0)
-				m.EXPECT().GetURl(gomock.Any(), alias).Return("", database.ErrURLNotFound)
+				m.EXPECT().GetTarget(gomock.Any(), alias).Return(database.Target{}, database.ErrURLNotFound)
 			},
 			expectedRedirectStatus: http.StatusNotFound,
 		},
@@ -584,9 +760,9 @@ func TestHandlers_SaveRedirectDelete_BadRequests(t *testing.T) {
 				m.EXPECT().SaveURL(gomock.Any(), req.URL, req.Alias).Return(nil)
 			},
 			redirectBehavior: func(m *mocks.MockDatabase, c *cachemock.MockCache, alias string) {
-				c.EXPECT().Get(gomock.Any(), alias).Return("", cache.ErrKeyNotExist)
+				c.EXPECT().Get(gomock.Any(), "link:"+alias).Return("", cache.ErrKeyNotExist)
 				c.EXPECT().Expire(gomock.Any(), gomock.Any()).Times(0)
-				m.EXPECT().GetURl(gomock.Any(), alias).Return("", ErrInternal)
+				m.EXPECT().GetTarget(gomock.Any(), alias).Return(database.Target{}, ErrInternal)
 			},
 			expectedSaveStatus:     http.StatusCreated,
 			expectedRedirectStatus: http.StatusInternalServerError,
@@ -0,0 +1,91 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+)
+
+func TestRedirect_Variants(t *testing.T) {
+	const target = `{"url": "https://example.com", "variants": [
+		{"url": "https://example.com/a", "weight": 70},
+		{"url": "https://example.com/b", "weight": 30}
+	]}`
+
+	variants := []string{"https://example.com/a", "https://example.com/b"}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Get(gomock.Any(), "link:landing").Return(target, nil).AnyTimes()
+	cacheMock.EXPECT().Expire(gomock.Any(), "link:landing").Return(nil).AnyTimes()
+
+	h := New(mocks.NewMockDatabase(ctrl), cacheMock, discardCfg, discardLogger)
+	router := h.InitRoutes()
+
+	visit := func(t *testing.T, ua string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=landing", nil)
+		r.Header.Set("User-Agent", ua)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusFound, w.Code)
+		return w
+	}
+
+	w := visit(t, "Mozilla/5.0 (X11; Linux x86_64)")
+
+	variant, err := strconv.Atoi(w.Header().Get(VariantHeader))
+	require.NoError(t, err)
+	require.Less(t, variant, len(variants))
+	assert.Equal(t, variants[variant], w.Header().Get("Location"))
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "link_visitor", cookies[0].Name)
+
+	// visitors without the cookie are told apart by IP and User-Agent
+	again := visit(t, "Mozilla/5.0 (X11; Linux x86_64)")
+	assert.Equal(t, w.Header().Get("Location"), again.Header().Get("Location"))
+
+	// the cookie keeps the variant
+	for _, ua := range []string{"curl/8.4.0", "Mozilla/5.0 (iPhone)", "Go-http-client/1.1"} {
+		w := visit(t, ua, cookies[0])
+		assert.Equal(t, strconv.Itoa(variant), w.Header().Get(VariantHeader))
+		assert.Empty(t, w.Result().Cookies(), "cookie set again")
+	}
+}
+
+func TestRedirect_WithoutVariants(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheMock := cachemock.NewMockCache(ctrl)
+	cacheMock.EXPECT().Get(gomock.Any(), "link:docs").Return("https://example.com/docs", nil)
+	cacheMock.EXPECT().Expire(gomock.Any(), "link:docs").Return(nil)
+
+	h := New(mocks.NewMockDatabase(ctrl), cacheMock, discardCfg, discardLogger)
+
+	w := httptest.NewRecorder()
+	h.InitRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/url?alias=docs", nil))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Empty(t, w.Header().Get(VariantHeader))
+	assert.Empty(t, w.Result().Cookies())
+}
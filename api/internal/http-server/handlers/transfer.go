@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/linkio"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+)
+
+// maxImportErrors bounds the number of rejected links listed in the
+// import response, the rest are only counted.
+const maxImportErrors = 100
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Alias string `json:"alias,omitempty"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+type ImportResponce struct {
+	resp.Response
+	Result  database.ImportResult `json:"result"`
+	Invalid int64                 `json:"invalid"`
+	Errors  []ImportError         `json:"errors,omitempty"`
+}
+
+// NewImport streams links from the request body into the database. The
+// `format` query parameter selects the layout (csv by default) and `mode`
+// what happens to existing aliases (skip by default).
+func (h *Handler) NewImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Import"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		format, err := linkio.ParseFormat(paramOr(c, "format", string(linkio.FormatCSV)))
+		if err != nil {
+			log.Info("unknown format", slog.String("format", c.GetParam("format")))
+			renderError(c, apierr.ErrUnknownFormat, fieldError("format", apierr.FieldInvalid, apierr.ErrUnknownFormat))
+			return
+		}
+
+		mode, err := database.ParseConflictMode(paramOr(c, "mode", string(database.ConflictSkip)))
+		if err != nil {
+			log.Info("unknown import mode", slog.String("mode", c.GetParam("mode")))
+			renderError(c, apierr.ErrUnknownImportMode, fieldError("mode", apierr.FieldInvalid, apierr.ErrUnknownImportMode))
+			return
+		}
+
+		log = log.With(slog.String("format", string(format)), slog.String("mode", string(mode)))
+
+		reader, err := linkio.NewReader(c.Body(), format)
+		if err != nil {
+			log.Error("create reader", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		ir := &importReader{ctx: c.Context(), r: reader, svc: h.svc, mode: mode}
+
+		result, err := h.storage.ImportURLs(c.Context(), ir, mode)
+		if err != nil {
+			switch {
+			case errors.Is(err, database.ErrURLExist):
+				log.Info("import stopped on existing alias", sl.Error(err))
+			case errors.Is(err, errInvalidImportLink):
+				log.Info("import stopped on invalid link", sl.Error(err))
+			case errors.Is(err, linkio.ErrMalformed):
+				log.Info("malformed import", sl.Error(err))
+			default:
+				log.Error("import urls", sl.Error(err))
+			}
+
+			info := Classify(err)
+
+			c.JSON(info.Status, ImportResponce{
+				Response: info.Response(),
+				Invalid:  ir.invalid,
+				Errors:   ir.errors,
+			})
+			return
+		}
+
+		for _, link := range result.Overwritten {
+			ctx := database.WithDomain(c.Context(), database.Domain{Name: link.Domain})
+			h.svc.Evict(ctx, link.Alias)
+		}
+
+		log.Info("urls imported",
+			slog.Int64("created", result.Created),
+			slog.Int64("updated", result.Updated),
+			slog.Int64("skipped", result.Skipped),
+			slog.Int64("invalid", ir.invalid),
+		)
+
+		c.JSON(http.StatusOK, ImportResponce{
+			Response: resp.OK(),
+			Result:   result,
+			Invalid:  ir.invalid,
+			Errors:   ir.errors,
+		})
+	}
+}
+
+// NewExport streams every link in the format given by the `format` query
+// parameter, csv by default.
+func (h *Handler) NewExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Export"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		format, err := linkio.ParseFormat(paramOr(c, "format", string(linkio.FormatCSV)))
+		if err != nil {
+			log.Info("unknown format", slog.String("format", c.GetParam("format")))
+			renderError(c, apierr.ErrUnknownFormat, fieldError("format", apierr.FieldInvalid, apierr.ErrUnknownFormat))
+			return
+		}
+
+		out := &countingWriter{w: c.ResponceWriter()}
+
+		writer, err := linkio.NewWriter(out, format)
+		if err != nil {
+			log.Error("create writer", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		c.SetHeader("Content-Type", linkio.ContentType(format))
+		c.SetHeader("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, linkio.FileName(format)))
+
+		err = h.storage.ExportURLs(c.Context(), writer.Write)
+		if err == nil {
+			err = writer.Close()
+		}
+
+		if err != nil {
+			log.Error("export urls", slog.Int64("bytes written", out.n), sl.Error(err))
+
+			if out.n == 0 {
+				c.Header().Del("Content-Disposition")
+				renderError(c, err)
+				return
+			}
+
+			// the status is already sent, break the connection so the
+			// client does not take a truncated export for a complete one
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+func paramOr(c *reqcontext.ReqContext, key, def string) string {
+	if v := strings.TrimSpace(c.GetParam(key)); v != "" {
+		return strings.ToLower(v)
+	}
+	return def
+}
+
+var errInvalidImportLink = errors.New("invalid link")
+
+// importReader validates the decoded links and checks their urls like the
+// service checks new links. Invalid links are collected and skipped, or
+// stop the import in the fail mode.
+type importReader struct {
+	ctx     context.Context
+	r       linkio.Reader
+	svc     *shortener.Shortener
+	mode    database.ConflictMode
+	invalid int64
+	errors  []ImportError
+}
+
+func (ir *importReader) Read() (database.Link, error) {
+	for {
+		link, err := ir.r.Read()
+		if err != nil {
+			return database.Link{}, err
+		}
+
+		reason := ir.check(link)
+		if reason == nil {
+			return link, nil
+		}
+
+		ir.invalid++
+		if len(ir.errors) < maxImportErrors {
+			ir.errors = append(ir.errors, ImportError{
+				Line:  ir.r.Line(),
+				Alias: link.Alias,
+				Code:  Classify(reason).Code,
+				Error: reason.Error(),
+			})
+		}
+
+		if ir.mode == database.ConflictFail {
+			return database.Link{}, fmt.Errorf("%w: line %d: %s", errInvalidImportLink, ir.r.Line(), reason)
+		}
+	}
+}
+
+// check returns why link must not be imported, the url is rejected like
+// by NewSave: for its format, by the destination policy or for its
+// reputation.
+func (ir *importReader) check(link database.Link) error {
+	if strings.TrimSpace(link.Alias) == "" {
+		return apierr.ErrEmptyAlias
+	}
+
+	return ir.svc.CheckURL(ir.ctx, link.URL)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+)
+
+// NewTrash lists the deleted links, the most recently deleted first.
+func (h *Handler) NewTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Trash"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		limit, offset, fields := page(c)
+		if len(fields) > 0 {
+			log.Info("invalid page", slog.Any("fields", fields))
+			renderError(c, apierr.ErrInvalidPage, fields...)
+			return
+		}
+
+		links, err := h.storage.TrashedURLs(c.Context(), limit, offset)
+		if err != nil {
+			log.Error("failed to list deleted links", sl.Error(err))
+			renderError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, ListResponce{
+			Response: resp.OK(),
+			URLs:     links,
+			Limit:    limit,
+			Offset:   offset,
+		})
+	}
+}
+
+// NewRestore takes a deleted link out of the trash, unless it was deleted
+// longer than the retention ago.
+func (h *Handler) NewRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const fn = "handlers.Restore"
+
+		c := reqcontext.New(w, r)
+		log := h.log.With(
+			slog.String("fn", fn),
+			slog.String(RequestID, c.RequestID()),
+		)
+
+		alias := c.GetParam("alias")
+		if strings.TrimSpace(alias) == "" {
+			log.Info("empty alias")
+			renderError(c, apierr.ErrEmptyAlias, fieldError("alias", apierr.FieldRequired, apierr.ErrEmptyAlias))
+			return
+		}
+
+		log = log.With(slog.String("alias", alias))
+
+		if err := h.storage.RestoreURL(c.Context(), alias, time.Now().Add(-h.retention)); err != nil {
+			if errors.Is(err, database.ErrURLNotFound) {
+				log.Info("url not in trash")
+			} else {
+				log.Error("failed to restore link", sl.Error(err))
+			}
+
+			renderError(c, err)
+			return
+		}
+
+		log.Info("link restored")
+
+		c.JSON(http.StatusOK, resp.OK())
+	}
+}
@@ -4,17 +4,22 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/pages"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/api/resp"
 	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/reputation"
 )
 
 type Responce struct {
 	resp.Response
-	Alias string `json:"alias"`
+	Alias string `json:"alias" xml:"alias"`
 }
 
 const maxRetries int = 10
@@ -29,100 +34,94 @@ func (h *Handler) NewSave() http.HandlerFunc {
 			slog.String(RequestID, c.RequestID()),
 		)
 
-		var req Request
-
-		log.Info("decoding request body")
-
-		if err := c.DecodeJSON(&req); err != nil {
-			log.Error("decode req body", sl.Error(err))
-
-			c.JSON(http.StatusInternalServerError, resp.Error(ErrInternalServer))
+		if !acceptable(c) {
+			log.Info("not acceptable", slog.String("accept", r.Header.Get("Accept")))
 			return
 		}
 
-		log.Info("decoded requst body", slog.Any("request body", req))
-
-		userProvaidedAlias := req.Alias
-		url := req.URL
-		stdLength := h.cfg.StdAliasLen
-
-		if strings.EqualFold(url, "") {
-			log.Info("request without url")
-
-			c.JSON(http.StatusBadRequest, resp.Error(ErrEmprtyURl))
-			return
-		}
+		var req Request
 
-		if !IsValidURL(url) {
-			h.log.Error("invalid URL format", slog.String("url", req.URL))
+		log.Info("decoding request body", slog.String("content type", c.ContentType()))
 
-			c.JSON(http.StatusBadRequest, resp.Error(ErrInvalidURLFormat))
+		if err := c.Bind(&req); err != nil {
+			if errors.Is(err, reqcontext.ErrUnsupportedMediaType) {
+				log.Info("unsupported media type", sl.Error(err))
+			} else {
+				log.Error("decode req body", sl.Error(err))
+			}
 
+			renderError(c, err)
 			return
 		}
 
-		var (
-			finalAlias string
-			err        error
-		)
-
-		if strings.EqualFold(userProvaidedAlias, "") {
-			finalAlias, err = h.storage.SaveGeneratedURl(c.Context(), url, stdLength, maxRetries)
-			if err != nil {
-				if errors.Is(err, database.ErrMaxRetriesForGenerate) {
-					log.Error("generate randim alias",
-						slog.Int("max retries", maxRetries),
-						slog.Int("standart alias length", stdLength),
-						sl.Error(err),
-					)
-
-					c.JSON(http.StatusInternalServerError, resp.Error(ErrCanNotGenAlias))
-
-					return
-				}
-
-				log.Error("saving URl with generated alias", slog.String("url", url), sl.Error(err))
-
-				c.JSON(http.StatusInternalServerError, resp.Error(ErrInternalServer))
+		log.Info("decoded requst body", slog.Any("request body", req))
 
-				return
-			}
+		log = log.With(slog.String("url", req.URL), slog.String("alias", req.Alias))
 
-			log.Info("added alias", slog.String("url", url), slog.String("alias", finalAlias))
+		alias, err := h.svc.ShortenWith(c.Context(), req.URL, req.Alias, req.Options(), req.Password)
+		if err != nil {
+			switch {
+			case errors.Is(err, apierr.ErrEmptyURL):
+				log.Info("request without url")
+				renderError(c, err, fieldError("url", apierr.FieldRequired, apierr.ErrEmptyURL))
 
-			c.JSON(http.StatusCreated, Responce{
-				Response: resp.OK(),
-				Alias:    finalAlias,
-			})
+			case errors.Is(err, apierr.ErrInvalidURLFormat):
+				log.Info("invalid URL format")
+				renderError(c, err, fieldError("url", apierr.FieldInvalid, apierr.ErrInvalidURLFormat))
 
-		} else {
-			log = log.With(slog.String("url", url), slog.String("alias", userProvaidedAlias))
+			case destinationError(c, log, err, "url"):
 
-			err := h.storage.SaveURL(c.Context(), url, userProvaidedAlias)
-			if err != nil {
-				if errors.Is(err, database.ErrURLExist) {
-					log.Info("alias already exist")
+			case errors.Is(err, database.ErrURLExist):
+				log.Info("alias already exist")
+				renderError(c, err, fieldError("alias", apierr.FieldTaken, apierr.ErrAliasExist))
 
-					c.JSON(http.StatusBadRequest, resp.Error(ErrAliasExist))
-					return
-				}
+			case errors.Is(err, database.ErrMaxRetriesForGenerate):
+				log.Error("generate randim alias",
+					slog.Int("max retries", h.svc.Config().MaxRetries),
+					slog.Int("standart alias length", h.svc.Config().AliasLength),
+					sl.Error(err),
+				)
+				renderError(c, err)
 
+			default:
 				log.Error("url saver", sl.Error(err))
-
-				c.JSON(http.StatusInternalServerError, resp.Error(ErrInternalServer))
-				return
+				renderError(c, err)
 			}
+			return
+		}
 
-			log.Info("url added")
+		log.Info("url added", slog.String("saved alias", alias))
 
-			c.JSON(http.StatusCreated, Responce{
-				Response: resp.OK(),
-				Alias:    userProvaidedAlias,
-			})
-		}
+		render(c, http.StatusCreated, Responce{
+			Response: resp.OK(),
+			Alias:    alias,
+		})
 	}
 }
 
+// destinationError renders the errors of links that are not saved because
+// of their options or destinations, field names the rejected url. It
+// reports whether err was one of them.
+func destinationError(c *reqcontext.ReqContext, log *slog.Logger, err error, field string) bool {
+	switch {
+	case errors.Is(err, apierr.ErrInvalidOptions):
+		log.Info("invalid options", sl.Error(err))
+		renderError(c, err, optionsError(err))
+
+	case errors.Is(err, policy.ErrRejected):
+		log.Info("destination rejected", sl.Error(err))
+		renderError(c, err, fieldError(field, policy.Reason(err), err))
+
+	case errors.Is(err, reputation.ErrUnsafe):
+		log.Warn("unsafe url rejected", sl.Error(err))
+		renderError(c, err)
+
+	default:
+		return false
+	}
+	return true
+}
+
 func (h *Handler) NewDelete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const fn = "handlers.Delete"
@@ -133,12 +132,17 @@ func (h *Handler) NewDelete() http.HandlerFunc {
 			slog.String(RequestID, c.RequestID()),
 		)
 
+		if !acceptable(c) {
+			log.Info("not acceptable", slog.String("accept", r.Header.Get("Accept")))
+			return
+		}
+
 		alias := c.GetParam("alias")
 
 		if strings.EqualFold(alias, "") {
 			log.Info("empty alias")
 
-			c.JSON(http.StatusBadRequest, resp.Error(ErrEmptyAlias))
+			renderError(c, apierr.ErrEmptyAlias, fieldError("alias", apierr.FieldRequired, apierr.ErrEmptyAlias))
 
 			return
 		}
@@ -147,21 +151,18 @@ func (h *Handler) NewDelete() http.HandlerFunc {
 
 		log.Info("decoded requst body")
 
-		_, err := h.DeleteURLWithCache(c.Context(), alias)
-		if err != nil {
-			switch {
-			case errors.Is(err, database.ErrURLNotFound):
+		if err := h.svc.Delete(c.Context(), alias); err != nil {
+			if errors.Is(err, database.ErrURLNotFound) {
 				log.Info("url not found")
-				c.JSON(http.StatusNotFound, resp.Error(ErrURLNotFound))
-				return
-			default:
+			} else {
 				log.Error("failed to delete URL", sl.Error(err))
-				c.JSON(http.StatusInternalServerError, resp.Error(ErrInternalServer))
-				return
 			}
+
+			renderError(c, err)
+			return
 		}
 
-		c.JSON(http.StatusOK, Responce{
+		render(c, http.StatusOK, Responce{
 			Response: resp.OK(),
 			Alias:    alias,
 		})
@@ -181,35 +182,46 @@ func (h *Handler) NewRedirect() http.HandlerFunc {
 		alias := c.GetParam("alias")
 		if strings.TrimSpace(alias) == "" {
 			log.Info("empty alias")
-			c.JSON(http.StatusBadRequest, resp.Error(ErrEmptyAlias))
+			renderError(c, apierr.ErrEmptyAlias, fieldError("alias", apierr.FieldRequired, apierr.ErrEmptyAlias))
 			return
 		}
 
 		log = h.log.With("alias", alias)
 
-		url, err := h.GetURLWithCache(c.Context(), alias)
+		// everything but the alias is the query of the visit
+		query := r.URL.Query()
+		query.Del("alias")
+
+		if preview, _ := strconv.ParseBool(query.Get(previewParam)); preview {
+			query.Del(previewParam)
+			h.preview(c, log, alias, query)
+			return
+		}
+
+		visit, err := h.svc.Visit(h.visitContext(c, alias), alias, query)
 		if err != nil {
-			switch {
-			case errors.Is(err, database.ErrURLNotFound):
-				log.Info("url not found")
-				c.JSON(http.StatusNotFound, resp.Error(ErrURLNotFound))
-				return
+			h.visitError(c, log, alias, err)
+			return
+		}
 
-			default:
-				log.Error("failed to get URL", sl.Error(err))
-				c.JSON(http.StatusInternalServerError, resp.Error(ErrInternalServer))
-				return
-			}
+		reportVariant(c, visit)
+
+		if visit.Interstitial && prefersHTML(c) {
+			log.Info("showing interstitial", slog.String("url", visit.URL))
+			h.renderLink(c, pages.Interstitial, alias, visit)
+			return
 		}
 
 		log.Info("redirecting",
-			slog.String("url", url),
+			slog.String("url", visit.URL),
+			slog.Int("rule", visit.Rule),
+			slog.Int("variant", visit.Variant),
 		)
 
 		http.Redirect(
 			c.ResponceWriter(),
 			c.Request(),
-			url,
+			visit.URL,
 			http.StatusFound,
 		)
 	}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/reqcontext"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+	"github.com/go-chi/httprate"
+)
+
+const (
+	// VariantHeader tells the index of the variant a visit was sent to.
+	VariantHeader = "X-Variant"
+
+	// visitorCookieName is the cookie keeping the variants of a visitor.
+	visitorCookieName = "link_visitor"
+	visitorCookieTTL  = 365 * 24 * time.Hour
+	visitorKeyLength  = 32
+)
+
+// visitorKey returns the key of the visitor of r: the one of its cookie or,
+// for visitors without one, a hash of the IP and User-Agent.
+func visitorKey(r *http.Request) string {
+	if cookie, err := r.Cookie(visitorCookieName); err == nil && validVisitorKey(cookie.Value) {
+		return cookie.Value
+	}
+
+	ip, _ := httprate.KeyByRealIP(r)
+	sum := sha256.Sum256([]byte(ip + "\x00" + r.UserAgent()))
+	return hex.EncodeToString(sum[:])[:visitorKeyLength]
+}
+
+func validVisitorKey(key string) bool {
+	if len(key) != visitorKeyLength {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+// reportVariant tells the client the variant of visit and keeps it with the
+// visitor cookie, so it stays when the IP changes.
+func reportVariant(c *reqcontext.ReqContext, visit shortener.Visit) {
+	if visit.Variant < 0 {
+		return
+	}
+
+	w, r := c.ResponceWriter(), c.Request()
+	w.Header().Set(VariantHeader, strconv.Itoa(visit.Variant))
+
+	if _, err := r.Cookie(visitorCookieName); err == nil {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookieName,
+		Value:    visitorKey(r),
+		Path:     "/",
+		MaxAge:   int(visitorCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,96 @@
+// Package apikey authenticates requests by a static API key sent in the
+// `X-API-Key` header or as an `Authorization: Bearer` token.
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+)
+
+const (
+	Header       = "X-API-Key"
+	bearerPrefix = "Bearer "
+)
+
+type ctxKey struct{}
+
+// New returns a middleware that lets through only requests carrying one of
+// the given keys and calls unauthorized for the rest. With no keys
+// configured every request is rejected.
+func New(keys []config.APIKey, unauthorized http.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			k, ok := lookup(keys, FromRequest(r))
+			if !ok {
+				unauthorized(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithName(r.Context(), k.Name)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// FromRequest returns the raw key sent by the client or an empty string.
+func FromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get(Header)); key != "" {
+		return key
+	}
+
+	auth := r.Header.Get("Authorization")
+	if len(auth) > len(bearerPrefix) && strings.EqualFold(auth[:len(bearerPrefix)], bearerPrefix) {
+		return strings.TrimSpace(auth[len(bearerPrefix):])
+	}
+
+	return ""
+}
+
+// Identify returns the configured key the request carries, if any, without
+// rejecting anything. It serves routes open to everyone that still treat
+// known clients differently.
+func Identify(keys []config.APIKey, r *http.Request) (config.APIKey, bool) {
+	return lookup(keys, FromRequest(r))
+}
+
+// Match returns the configured key equal to key, if any. It serves
+// transports other than HTTP.
+func Match(keys []config.APIKey, key string) (config.APIKey, bool) {
+	return lookup(keys, key)
+}
+
+// WithName returns a copy of ctx carrying the name of the authenticated key.
+func WithName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, name)
+}
+
+// NameFromContext returns the name of the key the request was authenticated with.
+func NameFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(ctxKey{}).(string)
+	return name, ok
+}
+
+func lookup(keys []config.APIKey, key string) (config.APIKey, bool) {
+	if key == "" {
+		return config.APIKey{}, false
+	}
+
+	var (
+		match config.APIKey
+		found bool
+	)
+
+	// compare with every key to keep the timing independent of the match
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(k.Key), []byte(key)) == 1 {
+			match, found = k, true
+		}
+	}
+
+	return match, found
+}
@@ -0,0 +1,119 @@
+package apikey_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	keys := []config.APIKey{
+		{Name: "ops", Key: "ops-secret"},
+		{Name: "ci", Key: "ci-secret"},
+	}
+
+	testCases := []struct {
+		name       string
+		keys       []config.APIKey
+		header     http.Header
+		wantStatus int
+		wantName   string
+	}{
+		{
+			name:       "x-api-key header",
+			keys:       keys,
+			header:     http.Header{apikey.Header: {"ci-secret"}},
+			wantStatus: http.StatusOK,
+			wantName:   "ci",
+		},
+		{
+			name:       "bearer token",
+			keys:       keys,
+			header:     http.Header{"Authorization": {"Bearer ops-secret"}},
+			wantStatus: http.StatusOK,
+			wantName:   "ops",
+		},
+		{
+			name:       "wrong key",
+			keys:       keys,
+			header:     http.Header{apikey.Header: {"guess"}},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "without key",
+			keys:       keys,
+			header:     http.Header{},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no keys configured",
+			keys:       nil,
+			header:     http.Header{},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "no keys configured with a key sent",
+			keys:       nil,
+			header:     http.Header{apikey.Header: {"ops-secret"}},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	unauthorized := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotName string
+
+			handler := apikey.New(tt.keys, unauthorized)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotName, _ = apikey.NameFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.header {
+				r.Header.Set(k, v[0])
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantName, gotName)
+		})
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, apikey.FromRequest(r))
+
+	r.Header.Set("Authorization", "bearer token")
+	assert.Equal(t, "token", apikey.FromRequest(r))
+
+	r.Header.Set(apikey.Header, "key")
+	assert.Equal(t, "key", apikey.FromRequest(r))
+}
+
+func TestIdentify(t *testing.T) {
+	keys := []config.APIKey{{Name: "acme", Key: "acme-secret", Tier: config.TierPartner}}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := apikey.Identify(keys, r)
+	assert.False(t, ok)
+
+	r.Header.Set(apikey.Header, "guess")
+	_, ok = apikey.Identify(keys, r)
+	assert.False(t, ok)
+
+	r.Header.Set(apikey.Header, "acme-secret")
+	k, ok := apikey.Identify(keys, r)
+	assert.True(t, ok)
+	assert.Equal(t, keys[0], k)
+}
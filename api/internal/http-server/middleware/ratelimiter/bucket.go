@@ -0,0 +1,46 @@
+package ratelimiter
+
+import (
+	"math"
+	"time"
+)
+
+// bucket is a token bucket kept in memory.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket is full again and can be dropped.
+	full time.Time
+}
+
+// refill returns the tokens of a bucket elapsed after it held tokens.
+func refill(tokens float64, elapsed time.Duration, limit int, window time.Duration, burst int) float64 {
+	if elapsed <= 0 {
+		return tokens
+	}
+	return min(float64(burst), tokens+float64(limit)*float64(elapsed)/float64(window))
+}
+
+// untilTokens returns how long refilling n tokens takes.
+func untilTokens(n float64, limit int, window time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(n * float64(window) / float64(limit)))
+}
+
+// newBucketResult decides about a request from the tokens left after it.
+func newBucketResult(allowed bool, limit int, window time.Duration, burst int, tokens float64, now time.Time) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     now.Add(untilTokens(float64(burst)-tokens, limit, window)),
+	}
+
+	if !allowed {
+		res.RetryAfter = untilTokens(1-tokens, limit, window)
+	}
+
+	return res
+}
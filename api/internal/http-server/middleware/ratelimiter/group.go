@@ -0,0 +1,79 @@
+package ratelimiter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/go-chi/httprate"
+)
+
+// NewGroup limits the requests of the route group name as configured in
+// limits. A client gets the limit of the tier of its API key, clients
+// without a known key or with a key without a tier the one of the default
+// tier. Groups without tiers get limits.Fallback for every client, tiers
+// without a limit are not limited.
+//
+// Limited requests are passed to limitHandler like NewWithLimiter does.
+func NewGroup(limiter Limiter, name string, limits config.RateLimits, keys []config.APIKey, limitHandler http.HandlerFunc) func(http.Handler) http.Handler {
+	if _, ok := configured(limits, name); !ok && !isLimit(limits.Fallback) {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client, known := apikey.Identify(keys, r)
+			ip, _ := httprate.KeyByRealIP(r)
+
+			res, limited := AllowGroup(r.Context(), limiter, limits, name, client, known, ip, 1)
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			serve(w, r, res, next, limitHandler)
+		})
+	}
+}
+
+// AllowGroup counts n requests of a client, known by its API key or else
+// by its ip, against the limits of the route group name. limited is false
+// when the group or the tier of the client has no limit.
+func AllowGroup(ctx context.Context, limiter Limiter, limits config.RateLimits, name string, client config.APIKey, known bool, ip string, n int) (res Result, limited bool) {
+	tier := limits.DefaultTier
+	if known && client.Tier != "" {
+		tier = client.Tier
+	}
+
+	group, ok := configured(limits, name)
+	limit := limits.Fallback
+	if ok {
+		limit = group.Tiers[tier]
+	} else {
+		// the fallback is the same for every tier, like request_limit was
+		tier = "fallback"
+	}
+
+	if !isLimit(limit) {
+		return Result{}, false
+	}
+
+	key := name + ":" + tier + ":ip:" + ip
+	if known && group.By == config.KeyByAPIKey {
+		key = name + ":" + tier + ":key:" + client.Name
+	}
+
+	return limiter.AllowN(ctx, key, n, limit.Requests, limit.Window, limit.Burst), true
+}
+
+// configured returns the group name of limits unless it has no tiers.
+func configured(limits config.RateLimits, name string) (config.RateLimitGroup, bool) {
+	group, ok := limits.Groups[name]
+	return group, ok && len(group.Tiers) > 0
+}
+
+// isLimit reports whether l limits anything.
+func isLimit(l config.RateLimit) bool {
+	return l.Requests > 0 && l.Window > 0
+}
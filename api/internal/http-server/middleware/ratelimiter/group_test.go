@@ -0,0 +1,96 @@
+package ratelimiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/middleware/apikey"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGroup(t *testing.T) {
+	limits := config.RateLimits{
+		DefaultTier: config.TierFree,
+		Groups: map[string]config.RateLimitGroup{
+			config.RouteWrites: {
+				By: config.KeyByAPIKey,
+				Tiers: map[string]config.RateLimit{
+					config.TierFree:    {Requests: 1, Window: time.Minute},
+					config.TierPartner: {Requests: 60, Window: time.Minute, Burst: 2},
+				},
+			},
+		},
+	}
+	keys := []config.APIKey{
+		{Name: "acme", Key: "acme-secret", Tier: config.TierPartner},
+		{Name: "ops", Key: "ops-secret", Tier: config.TierInternal},
+	}
+
+	clk := newClock(0)
+	l := NewLocalLimiter()
+	l.now = clk.now
+
+	limited := func(w http.ResponseWriter, r *http.Request) {
+		if res, ok := ResultFromContext(r.Context()); ok {
+			SetHeaders(w, res)
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	writes := NewGroup(l, config.RouteWrites, limits, keys, limited)(ok)
+	admin := NewGroup(l, config.RouteAdmin, limits, keys, limited)(ok)
+
+	request := func(h http.Handler, ip, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = ip + ":12345"
+		if key != "" {
+			r.Header.Set(apikey.Header, key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// clients without a key are counted by IP in the default tier
+	assert.Equal(t, http.StatusOK, request(writes, "10.0.0.1", "").Code)
+	w := request(writes, "10.0.0.1", "guess")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, request(writes, "10.0.0.2", "").Code)
+
+	// a partner is counted by its key from every IP, with a burst
+	w = request(writes, "10.0.0.1", "acme-secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, request(writes, "10.0.0.3", "acme-secret").Code)
+	w = request(writes, "10.0.0.4", "acme-secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// tiers without a limit and groups without config or fallback are not
+	// limited
+	for range 3 {
+		assert.Equal(t, http.StatusOK, request(writes, "10.0.0.1", "ops-secret").Code)
+		w = request(admin, "10.0.0.1", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+	}
+
+	// groups without config get the fallback, whatever the tier
+	limits.Fallback = config.RateLimit{Requests: 1, Window: time.Minute}
+	admin = NewGroup(l, config.RouteAdmin, limits, keys, limited)(ok)
+
+	assert.Equal(t, http.StatusOK, request(admin, "10.0.0.5", "acme-secret").Code)
+	w = request(admin, "10.0.0.5", "ops-secret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, http.StatusOK, request(admin, "10.0.0.6", "").Code)
+}
@@ -0,0 +1,269 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/httprate"
+)
+
+// Limiter counts requests per key with a sliding window counter: the count
+// of the previous window, weighted by how much of it still overlaps the
+// sliding window, plus the count of the current one. Limits with a burst
+// are counted by a token bucket instead.
+type Limiter interface {
+	// Allow counts a request of key unless it exceeds limit requests per
+	// window.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) Result
+	// AllowBurst takes a token from the bucket of key holding up to burst
+	// tokens, refilled at limit tokens per window.
+	AllowBurst(ctx context.Context, key string, limit int, window time.Duration, burst int) Result
+	// AllowN counts n requests of key at once, all of them or none, from a
+	// token bucket when burst is positive. Batch calls are counted per item
+	// with it.
+	AllowN(ctx context.Context, key string, n, limit int, window time.Duration, burst int) Result
+}
+
+// Result is the decision about one request.
+type Result struct {
+	Allowed bool
+	// Limit is the limit per window, or the size of the bucket.
+	Limit     int
+	Remaining int
+	// Reset is the end of the current window, or when the bucket is full
+	// again.
+	Reset time.Time
+	// RetryAfter is how long a limited client has to wait.
+	RetryAfter time.Duration
+}
+
+// newResult decides about a request from the counts of the current and the
+// previous window, now being elapsed into the current window.
+func newResult(allowed bool, limit, curr, prev int, start time.Time, elapsed, window time.Duration) Result {
+	res := Result{
+		Allowed: allowed,
+		Limit:   limit,
+		Reset:   start.Add(window),
+	}
+
+	rate := slidingRate(curr, prev, elapsed, window)
+	res.Remaining = max(limit-int(math.Ceil(rate)), 0)
+
+	if !allowed {
+		res.RetryAfter = retryAfter(limit, curr, prev, elapsed, window)
+	}
+
+	return res
+}
+
+func slidingRate(curr, prev int, elapsed, window time.Duration) float64 {
+	overlap := float64(window-elapsed) / float64(window)
+	return float64(prev)*overlap + float64(curr)
+}
+
+// allows reports whether n more requests fit into limit.
+func allows(n, limit, curr, prev int, elapsed, window time.Duration) bool {
+	return slidingRate(curr, prev, elapsed, window)+float64(n) <= float64(limit)
+}
+
+// retryAfter returns the time until the sliding rate has dropped enough
+// for one more request.
+func retryAfter(limit, curr, prev int, elapsed, window time.Duration) time.Duration {
+	need := float64(limit - 1)
+	if need < 0 {
+		return window
+	}
+
+	// the weight of the previous window falls in this one
+	if float64(curr) <= need && prev > 0 {
+		at := float64(window) * (1 - (need-float64(curr))/float64(prev))
+		return max(time.Duration(at)-elapsed, 0)
+	}
+
+	// the current window has to become the previous one
+	rest := window - elapsed
+	if curr == 0 {
+		return rest
+	}
+	at := float64(window) * (1 - need/float64(curr))
+	return rest + max(time.Duration(at), 0)
+}
+
+// LocalLimiter keeps the counters in memory, so every instance limits on
+// its own.
+type LocalLimiter struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type counter struct {
+	window time.Duration
+	start  time.Time
+	curr   int
+	prev   int
+}
+
+// sweepInterval is how often counters of idle keys are dropped.
+const sweepInterval = time.Minute
+
+func NewLocalLimiter() *LocalLimiter {
+	return &LocalLimiter{
+		counters: make(map[string]*counter),
+		buckets:  make(map[string]*bucket),
+		now:      time.Now,
+	}
+}
+
+func (l *LocalLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) Result {
+	return l.AllowN(ctx, key, 1, limit, window, 0)
+}
+
+func (l *LocalLimiter) AllowBurst(ctx context.Context, key string, limit int, window time.Duration, burst int) Result {
+	return l.AllowN(ctx, key, 1, limit, window, burst)
+}
+
+func (l *LocalLimiter) AllowN(_ context.Context, key string, n, limit int, window time.Duration, burst int) Result {
+	if limit > 0 && burst > 0 {
+		return l.take(key, n, limit, window, burst)
+	}
+
+	now := l.now()
+	start := now.Truncate(window)
+	elapsed := now.Sub(start)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	c, ok := l.counters[key]
+	if !ok || c.window != window {
+		c = &counter{window: window, start: start}
+		l.counters[key] = c
+	}
+
+	switch {
+	case c.start.Equal(start):
+	case c.start.Add(window).Equal(start):
+		c.start, c.prev, c.curr = start, c.curr, 0
+	default:
+		c.start, c.prev, c.curr = start, 0, 0
+	}
+
+	allowed := allows(n, limit, c.curr, c.prev, elapsed, window)
+	if allowed {
+		c.curr += n
+	}
+
+	return newResult(allowed, limit, c.curr, c.prev, start, elapsed, window)
+}
+
+// take takes n tokens from the bucket of key.
+func (l *LocalLimiter) take(key string, n, limit int, window time.Duration, burst int) Result {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = refill(b.tokens, now.Sub(b.last), limit, window, burst)
+	b.last = now
+
+	allowed := b.tokens >= float64(n)
+	if allowed {
+		b.tokens -= float64(n)
+	}
+	b.full = now.Add(untilTokens(float64(burst)-b.tokens, limit, window))
+
+	return newBucketResult(allowed, limit, window, burst, b.tokens, now)
+}
+
+// sweep drops the counters that no longer affect any window and the
+// buckets that are full again.
+func (l *LocalLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, c := range l.counters {
+		if now.Sub(c.start) >= 2*c.window {
+			delete(l.counters, key)
+		}
+	}
+
+	for key, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// NewWithLimiter limits every client, keyed by its real IP, to
+// requestLimit requests per windowLength. The X-RateLimit-* headers are set
+// like httprate does. Limited requests are passed to limitHandler with the
+// Result in their context, it is expected to set the headers with
+// SetHeaders.
+func NewWithLimiter(limiter Limiter, requestLimit int, windowLength time.Duration, limitHandler http.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, _ := httprate.KeyByRealIP(r)
+
+			res := limiter.Allow(r.Context(), key, requestLimit, windowLength)
+			serve(w, r, res, next, limitHandler)
+		})
+	}
+}
+
+// serve passes the request on to next or, when limited, to limitHandler.
+func serve(w http.ResponseWriter, r *http.Request, res Result, next http.Handler, limitHandler http.HandlerFunc) {
+	if !res.Allowed {
+		limitHandler(w, r.WithContext(WithResult(r.Context(), res)))
+		return
+	}
+
+	SetHeaders(w, res)
+	next.ServeHTTP(w, r)
+}
+
+type ctxKey struct{}
+
+// WithResult returns a copy of ctx carrying res.
+func WithResult(ctx context.Context, res Result) context.Context {
+	return context.WithValue(ctx, ctxKey{}, res)
+}
+
+// ResultFromContext returns the decision that limited the request.
+func ResultFromContext(ctx context.Context) (Result, bool) {
+	res, ok := ctx.Value(ctxKey{}).(Result)
+	return res, ok
+}
+
+// SetHeaders sets the X-RateLimit-* headers and, for a limited request,
+// Retry-After.
+func SetHeaders(w http.ResponseWriter, res Result) {
+	h := w.Header()
+
+	h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(res.Reset.Unix(), 10))
+
+	if !res.Allowed {
+		secs := int64(math.Ceil(res.RetryAfter.Seconds()))
+		h.Set("Retry-After", strconv.FormatInt(max(secs, 1), 10))
+	}
+}
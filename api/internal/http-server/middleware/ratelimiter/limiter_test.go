@@ -0,0 +1,203 @@
+package ratelimiter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a settable time source.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time            { return c.t }
+func (c *clock) advance(d time.Duration)   { c.t = c.t.Add(d) }
+func newClock(offset time.Duration) *clock { return &clock{t: testStart.Add(offset)} }
+
+// testStart is the start of a minute window.
+var testStart = time.Date(2025, 6, 25, 15, 15, 0, 0, time.UTC)
+
+func TestRetryAfter(t *testing.T) {
+	const window = time.Minute
+
+	testCases := []struct {
+		name     string
+		limit    int
+		curr     int
+		prev     int
+		elapsed  time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "current window full",
+			limit:    3,
+			curr:     3,
+			elapsed:  10 * time.Second,
+			expected: 50*time.Second + 20*time.Second,
+		},
+		{
+			name:     "previous window fades",
+			limit:    3,
+			curr:     1,
+			prev:     4,
+			elapsed:  15 * time.Second,
+			expected: 45*time.Second - 15*time.Second,
+		},
+		{
+			name:     "zero limit",
+			limit:    0,
+			expected: window,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := retryAfter(tt.limit, tt.curr, tt.prev, tt.elapsed, window)
+			assert.Equal(t, tt.expected, got)
+
+			// one more request fits after the wait
+			if tt.limit > 0 && got < window-tt.elapsed {
+				assert.True(t, allows(1, tt.limit, tt.curr, tt.prev, tt.elapsed+got, window))
+			}
+		})
+	}
+}
+
+func TestLocalLimiter(t *testing.T) {
+	ctx := context.Background()
+	clk := newClock(30 * time.Second)
+
+	l := NewLocalLimiter()
+	l.now = clk.now
+
+	for i := range 3 {
+		res := l.Allow(ctx, "a", 3, time.Minute)
+		require.True(t, res.Allowed, "request %d", i)
+		assert.Equal(t, 2-i, res.Remaining)
+		assert.Equal(t, testStart.Add(time.Minute), res.Reset)
+	}
+
+	res := l.Allow(ctx, "a", 3, time.Minute)
+	assert.False(t, res.Allowed)
+	assert.Zero(t, res.Remaining)
+	assert.Equal(t, 30*time.Second+time.Minute/3, res.RetryAfter)
+
+	// other keys have their own counters
+	assert.True(t, l.Allow(ctx, "b", 3, time.Minute).Allowed)
+
+	// half of the previous window still counts: 1.5 + 1 fits, 2.5 + 1 not
+	clk.advance(time.Minute)
+	assert.True(t, l.Allow(ctx, "a", 3, time.Minute).Allowed)
+	assert.False(t, l.Allow(ctx, "a", 3, time.Minute).Allowed)
+
+	// idle counters are dropped
+	clk.advance(3 * time.Minute)
+	l.Allow(ctx, "c", 3, time.Minute)
+	assert.Len(t, l.counters, 1)
+}
+
+func TestLocalLimiter_AllowBurst(t *testing.T) {
+	ctx := context.Background()
+	clk := newClock(0)
+
+	l := NewLocalLimiter()
+	l.now = clk.now
+
+	// 60 per minute refill a token every second, 3 may come at once
+	for i := range 3 {
+		res := l.AllowBurst(ctx, "a", 60, time.Minute, 3)
+		require.True(t, res.Allowed, "request %d", i)
+		assert.Equal(t, 3, res.Limit)
+		assert.Equal(t, 2-i, res.Remaining)
+		assert.Equal(t, testStart.Add(time.Duration(i+1)*time.Second), res.Reset)
+	}
+
+	res := l.AllowBurst(ctx, "a", 60, time.Minute, 3)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	clk.advance(1500 * time.Millisecond)
+	res = l.AllowBurst(ctx, "a", 60, time.Minute, 3)
+	assert.True(t, res.Allowed)
+	assert.Zero(t, res.Remaining)
+	assert.False(t, l.AllowBurst(ctx, "a", 60, time.Minute, 3).Allowed)
+
+	// the bucket never holds more than the burst
+	clk.advance(time.Hour)
+	for range 3 {
+		assert.True(t, l.AllowBurst(ctx, "a", 60, time.Minute, 3).Allowed)
+	}
+	assert.False(t, l.AllowBurst(ctx, "a", 60, time.Minute, 3).Allowed)
+
+	// full buckets are dropped
+	clk.advance(time.Hour)
+	l.AllowBurst(ctx, "b", 60, time.Minute, 3)
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestLocalLimiter_AllowN(t *testing.T) {
+	ctx := context.Background()
+	clk := newClock(0)
+
+	l := NewLocalLimiter()
+	l.now = clk.now
+
+	// a batch counts all of its items or none
+	assert.True(t, l.AllowN(ctx, "a", 3, 5, time.Minute, 0).Allowed)
+	assert.False(t, l.AllowN(ctx, "a", 3, 5, time.Minute, 0).Allowed)
+	res := l.AllowN(ctx, "a", 2, 5, time.Minute, 0)
+	assert.True(t, res.Allowed)
+	assert.Zero(t, res.Remaining)
+
+	assert.True(t, l.AllowN(ctx, "b", 3, 60, time.Minute, 3).Allowed)
+	assert.False(t, l.AllowN(ctx, "b", 1, 60, time.Minute, 3).Allowed)
+	assert.False(t, l.AllowN(ctx, "c", 4, 60, time.Minute, 3).Allowed)
+}
+
+func TestNewWithLimiter(t *testing.T) {
+	clk := newClock(0)
+
+	l := NewLocalLimiter()
+	l.now = clk.now
+
+	var limited int
+	mw := NewWithLimiter(l, 1, time.Minute, func(w http.ResponseWriter, r *http.Request) {
+		limited++
+		res, ok := ResultFromContext(r.Context())
+		require.True(t, ok)
+		SetHeaders(w, res)
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = ip + ":12345"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := request("10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, strconv.FormatInt(testStart.Add(time.Minute).Unix(), 10), w.Header().Get("X-RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = request("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+	assert.Equal(t, 1, limited)
+
+	w = request("10.0.0.2")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,222 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// keyPrefix namespaces the counters in Redis.
+	keyPrefix = "ratelimit:"
+	// redisTimeout bounds a single check, a slow Redis must not hold up
+	// every request.
+	redisTimeout = 250 * time.Millisecond
+	// redisRetryInterval is how long the local counters are used after
+	// Redis failed before Redis is tried again.
+	redisRetryInterval = 5 * time.Second
+)
+
+// slidingWindow checks and counts a request in one step, so concurrent
+// instances never let more requests through than the limit.
+//
+// KEYS[1] counter of the current window, KEYS[2] of the previous one
+// ARGV[1] limit, ARGV[2] window length and ARGV[3] time elapsed in the
+// current window, both in milliseconds, and ARGV[4] the number of requests
+//
+// It returns {allowed, current count, previous count}.
+var slidingWindow = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+
+local curr = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+
+if prev * (window - elapsed) / window + curr + n > limit then
+	return {0, curr, prev}
+end
+
+curr = redis.call('INCRBY', KEYS[1], n)
+if curr == n then
+	redis.call('PEXPIRE', KEYS[1], window * 2)
+end
+
+return {1, curr, prev}
+`)
+
+// tokenBucket takes tokens from a bucket in one step. The bucket is a
+// hash of its tokens and the time they were counted at, it expires once
+// it would be full again.
+//
+// KEYS[1] bucket
+// ARGV[1] limit, ARGV[2] window length in milliseconds, ARGV[3] burst,
+// ARGV[4] the current unix time in milliseconds and ARGV[5] the number of
+// tokens to take
+//
+// It returns {allowed, tokens left in thousandths}.
+var tokenBucket = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+local now = tonumber(ARGV[4])
+local n = tonumber(ARGV[5])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+
+-- instances with clocks behind must not take refilled tokens back
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * limit / window)
+	ts = now
+end
+
+local allowed = 0
+if tokens >= n then
+	tokens = tokens - n
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', ts)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) * window / limit) + 1)
+
+return {allowed, math.floor(tokens * 1000)}
+`)
+
+// RedisLimiter shares the counters of all instances in Redis. While Redis
+// is unreachable every instance falls back to its local counters.
+type RedisLimiter struct {
+	rdb   redis.Scripter
+	local *LocalLimiter
+	log   *slog.Logger
+	now   func() time.Time
+
+	// downUntil is the unix nano time until which Redis is skipped.
+	downUntil atomic.Int64
+}
+
+func NewRedisLimiter(rdb redis.Scripter, log *slog.Logger) *RedisLimiter {
+	return &RedisLimiter{
+		rdb:   rdb,
+		local: NewLocalLimiter(),
+		log:   log,
+		now:   time.Now,
+	}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) Result {
+	return l.AllowN(ctx, key, 1, limit, window, 0)
+}
+
+func (l *RedisLimiter) AllowBurst(ctx context.Context, key string, limit int, window time.Duration, burst int) Result {
+	return l.AllowN(ctx, key, 1, limit, window, burst)
+}
+
+func (l *RedisLimiter) AllowN(ctx context.Context, key string, n, limit int, window time.Duration, burst int) Result {
+	if limit <= 0 || burst <= 0 {
+		burst = 0
+	}
+
+	now := l.now()
+
+	if now.UnixNano() < l.downUntil.Load() {
+		return l.local.AllowN(ctx, key, n, limit, window, burst)
+	}
+
+	var (
+		res Result
+		err error
+	)
+	if burst > 0 {
+		res, err = l.allowBurst(ctx, key, n, limit, window, burst, now)
+	} else {
+		res, err = l.allow(ctx, key, n, limit, window, now)
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			l.markDown(now, err)
+		}
+		return l.local.AllowN(ctx, key, n, limit, window, burst)
+	}
+
+	if l.downUntil.Swap(0) != 0 {
+		l.log.Info("rate limiter uses redis again")
+	}
+
+	return res
+}
+
+func (l *RedisLimiter) allowBurst(ctx context.Context, key string, n, limit int, window time.Duration, burst int, now time.Time) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	out, err := tokenBucket.Run(ctx, l.rdb, []string{bucketKey(key)}, limit, window.Milliseconds(), burst, now.UnixMilli(), n).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newBucketResult(out[0] == 1, limit, window, burst, float64(out[1])/1000, now), nil
+}
+
+func (l *RedisLimiter) allow(ctx context.Context, key string, n, limit int, window time.Duration, now time.Time) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+
+	start := now.Truncate(window)
+	elapsed := now.Sub(start)
+
+	keys := []string{
+		counterKey(key, start),
+		counterKey(key, start.Add(-window)),
+	}
+
+	out, err := slidingWindow.Run(ctx, l.rdb, keys, limit, window.Milliseconds(), elapsed.Milliseconds(), n).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(out[0] == 1, limit, int(out[1]), int(out[2]), start, elapsed, window), nil
+}
+
+func (l *RedisLimiter) markDown(now time.Time, err error) {
+	if l.downUntil.Swap(now.Add(redisRetryInterval).UnixNano()) == 0 {
+		l.log.Error("rate limiter falls back to local counters", sl.Error(err))
+	}
+}
+
+func counterKey(key string, start time.Time) string {
+	return keyPrefix + key + ":" + strconv.FormatInt(start.UnixMilli(), 10)
+}
+
+func bucketKey(key string) string {
+	return keyPrefix + "bucket:" + key
+}
+
+// Stores of the request counters.
+const (
+	StoreRedis = "redis"
+	StoreLocal = "local"
+)
+
+var ErrUnknownStore = errors.New("unknown rate limit store, use redis or local")
+
+// NewLimiter returns the limiter keeping its counters in store.
+func NewLimiter(store string, rdb redis.Scripter, log *slog.Logger) (Limiter, error) {
+	switch store {
+	case StoreRedis:
+		return NewRedisLimiter(rdb, log), nil
+	case StoreLocal:
+		return NewLocalLimiter(), nil
+	default:
+		return nil, wraper.Wrap("ratelimiter.NewLimiter", ErrUnknownStore)
+	}
+}
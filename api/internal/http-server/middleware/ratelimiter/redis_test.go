@@ -0,0 +1,171 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/logger/discard"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedisLimiter returns a limiter with its own connection to mr.
+func newRedisLimiter(t *testing.T, mr *miniredis.Miniredis, clk *clock) *RedisLimiter {
+	t.Helper()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+
+	l := NewRedisLimiter(rdb, discard.NewDiscardLogger())
+	l.now = clk.now
+	l.local.now = clk.now
+	return l
+}
+
+func TestRedisLimiter(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	clk := newClock(30 * time.Second)
+
+	// two instances share the counters
+	a := newRedisLimiter(t, mr, clk)
+	b := newRedisLimiter(t, mr, clk)
+
+	for i := range 4 {
+		l := a
+		if i%2 == 1 {
+			l = b
+		}
+		res := l.Allow(ctx, "10.0.0.1", 4, time.Minute)
+		require.True(t, res.Allowed, "request %d", i)
+		assert.Equal(t, 3-i, res.Remaining)
+	}
+
+	res := b.Allow(ctx, "10.0.0.1", 4, time.Minute)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, testStart.Add(time.Minute), res.Reset)
+	assert.Positive(t, res.RetryAfter)
+	assert.False(t, a.Allow(ctx, "10.0.0.1", 4, time.Minute).Allowed)
+
+	key := counterKey("10.0.0.1", testStart)
+	assert.Equal(t, "4", mustGet(t, mr, key), "denied requests must not be counted")
+	assert.Equal(t, 2*time.Minute, mr.TTL(key))
+
+	// half of the previous window still counts: 2 + 2 fit, 2 + 3 not
+	clk.advance(time.Minute)
+	assert.True(t, a.Allow(ctx, "10.0.0.1", 4, time.Minute).Allowed)
+	assert.True(t, b.Allow(ctx, "10.0.0.1", 4, time.Minute).Allowed)
+	assert.False(t, a.Allow(ctx, "10.0.0.1", 4, time.Minute).Allowed)
+}
+
+func TestRedisLimiter_AllowBurst(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	clk := newClock(0)
+
+	a := newRedisLimiter(t, mr, clk)
+	b := newRedisLimiter(t, mr, clk)
+
+	for i := range 3 {
+		l := a
+		if i%2 == 1 {
+			l = b
+		}
+		res := l.AllowBurst(ctx, "key:acme", 60, time.Minute, 3)
+		require.True(t, res.Allowed, "request %d", i)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res := b.AllowBurst(ctx, "key:acme", 60, time.Minute, 3)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second+time.Millisecond, mr.TTL(bucketKey("key:acme")))
+
+	clk.advance(time.Second)
+	assert.True(t, a.AllowBurst(ctx, "key:acme", 60, time.Minute, 3).Allowed)
+	assert.False(t, b.AllowBurst(ctx, "key:acme", 60, time.Minute, 3).Allowed)
+}
+
+func TestRedisLimiter_AllowN(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	clk := newClock(30 * time.Second)
+
+	l := newRedisLimiter(t, mr, clk)
+
+	assert.True(t, l.AllowN(ctx, "10.0.0.1", 3, 5, time.Minute, 0).Allowed)
+	assert.False(t, l.AllowN(ctx, "10.0.0.1", 3, 5, time.Minute, 0).Allowed)
+	assert.Equal(t, "3", mustGet(t, mr, counterKey("10.0.0.1", testStart)))
+
+	assert.True(t, l.AllowN(ctx, "key:acme", 3, 60, time.Minute, 3).Allowed)
+	assert.False(t, l.AllowN(ctx, "key:acme", 1, 60, time.Minute, 3).Allowed)
+}
+
+func TestRedisLimiter_Fallback(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	clk := newClock(0)
+
+	l := newRedisLimiter(t, mr, clk)
+
+	require.True(t, l.Allow(ctx, "10.0.0.1", 2, time.Minute).Allowed)
+
+	mr.Close()
+
+	// the local counters limit while redis is down
+	assert.True(t, l.Allow(ctx, "10.0.0.1", 2, time.Minute).Allowed)
+	assert.True(t, l.Allow(ctx, "10.0.0.1", 2, time.Minute).Allowed)
+	assert.False(t, l.Allow(ctx, "10.0.0.1", 2, time.Minute).Allowed)
+	assert.NotZero(t, l.downUntil.Load())
+
+	require.NoError(t, mr.Restart())
+
+	// redis is skipped until the retry interval passed
+	clk.advance(redisRetryInterval / 2)
+	l.Allow(ctx, "10.0.0.2", 2, time.Minute)
+	assert.False(t, mr.Exists(counterKey("10.0.0.2", testStart)))
+
+	clk.advance(redisRetryInterval)
+	assert.True(t, l.Allow(ctx, "10.0.0.3", 2, time.Minute).Allowed)
+	assert.True(t, mr.Exists(counterKey("10.0.0.3", testStart)))
+	assert.Zero(t, l.downUntil.Load())
+}
+
+func TestRedisLimiter_Cancelled(t *testing.T) {
+	mr := miniredis.RunT(t)
+	l := newRedisLimiter(t, mr, newClock(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	l.Allow(ctx, "10.0.0.1", 2, time.Minute)
+	assert.Zero(t, l.downUntil.Load(), "a cancelled request is no redis failure")
+}
+
+func TestNewLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	l, err := NewLimiter(StoreRedis, rdb, discard.NewDiscardLogger())
+	require.NoError(t, err)
+	assert.IsType(t, &RedisLimiter{}, l)
+
+	l, err = NewLimiter(StoreLocal, nil, discard.NewDiscardLogger())
+	require.NoError(t, err)
+	assert.IsType(t, &LocalLimiter{}, l)
+
+	_, err = NewLimiter("memcached", nil, discard.NewDiscardLogger())
+	assert.ErrorIs(t, err, ErrUnknownStore)
+}
+
+func mustGet(t *testing.T, mr *miniredis.Miniredis, key string) string {
+	t.Helper()
+
+	v, err := mr.Get(key)
+	require.NoError(t, err)
+	return v
+}
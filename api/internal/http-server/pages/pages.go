@@ -0,0 +1,144 @@
+// Package pages renders the HTML pages shown to visitors of links. The
+// templates are embedded, each of them can be replaced by a file of the
+// same name in a directory.
+package pages
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+)
+
+// The pages, named like their templates.
+const (
+	// Password asks for the password of a protected link, see
+	// PasswordData.
+	Password = "password.html"
+	// Preview shows where a link goes, see LinkData.
+	Preview = "preview.html"
+	// Interstitial warns visitors before they leave for the url of a link,
+	// see LinkData.
+	Interstitial = "interstitial.html"
+	// NotActive tells visitors a link is not active yet, see
+	// NotActiveData.
+	NotActive = "not_active.html"
+)
+
+var names = []string{Password, Preview, Interstitial, NotActive}
+
+//go:embed templates/*.html
+var embedded embed.FS
+
+// PasswordData is rendered by the Password page.
+type PasswordData struct {
+	Alias string
+	// Action is where the form posts the password.
+	Action template.URL
+	// Message tells why the last password was not accepted, if it was
+	// not.
+	Message string
+}
+
+// LinkData is rendered by the Preview and Interstitial pages. The fields
+// describing the page of the link are empty when unknown.
+type LinkData struct {
+	Alias string
+	// URL is where the visit goes.
+	URL         string
+	Title       string
+	Description string
+	Image       string
+	CreatedAt   time.Time
+}
+
+// NotActiveData is rendered by the NotActive page.
+type NotActiveData struct {
+	Alias string
+	// From is when the link becomes active.
+	From time.Time
+}
+
+// Pages are the parsed templates of the pages. They are safe for
+// concurrent use.
+type Pages struct {
+	templates map[string]*template.Template
+}
+
+// New returns the embedded pages.
+func New() *Pages {
+	p, err := parse(embedded, "templates")
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Load returns the pages, the embedded ones replaced by the templates of
+// the same name in dir. Other files of dir are ignored. An empty dir
+// returns the embedded pages.
+func Load(dir string) (*Pages, error) {
+	const fn = "pages.Load"
+
+	p := New()
+	if dir == "" {
+		return p, nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, wraper.Wrap(fn, err)
+	}
+	if !info.IsDir() {
+		return nil, wraper.Wrapf(fn, errors.New("not a directory"), "templates %s", dir)
+	}
+
+	overrides, err := parse(os.DirFS(dir), ".")
+	if err != nil {
+		return nil, wraper.Wrap(fn, err)
+	}
+
+	for name, tmpl := range overrides.templates {
+		p.templates[name] = tmpl
+	}
+
+	return p, nil
+}
+
+// parse parses the templates of the pages found in dir of fsys.
+func parse(fsys fs.FS, dir string) (*Pages, error) {
+	p := &Pages{templates: make(map[string]*template.Template, len(names))}
+
+	for _, name := range names {
+		b, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, name)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := template.New(name).Parse(string(b))
+		if err != nil {
+			return nil, err
+		}
+		p.templates[name] = tmpl
+	}
+
+	return p, nil
+}
+
+// Render writes the page name rendered with data to w.
+func (p *Pages) Render(w io.Writer, name string, data any) error {
+	tmpl, ok := p.templates[name]
+	if !ok {
+		return errors.New("unknown page " + name)
+	}
+	return tmpl.Execute(w, data)
+}
@@ -0,0 +1,81 @@
+package pages
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	p := New()
+
+	data := LinkData{
+		Alias:     "docs",
+		URL:       "https://example.com/docs?a=1&b=<2>",
+		Title:     "Docs",
+		CreatedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, p.Render(&buf, Preview, data))
+	assert.Contains(t, buf.String(), "Created on 18 October 2026")
+	assert.Contains(t, buf.String(), `href="https://example.com/docs?a=1&amp;b=%3c2%3e"`)
+	assert.NotContains(t, buf.String(), "<2>", "url is not escaped")
+
+	buf.Reset()
+	require.NoError(t, p.Render(&buf, Interstitial, data))
+	assert.Contains(t, buf.String(), "You are leaving")
+
+	buf.Reset()
+	require.NoError(t, p.Render(&buf, Password, PasswordData{Alias: "docs", Message: "Wrong password"}))
+	assert.Contains(t, buf.String(), "Wrong password")
+
+	buf.Reset()
+	require.NoError(t, p.Render(&buf, NotActive, NotActiveData{Alias: "launch", From: time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)}))
+	assert.Contains(t, buf.String(), "opens on 1 November 2026 at 09:00 UTC")
+
+	assert.Error(t, p.Render(&buf, "unknown.html", nil))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, Preview), []byte(`<p>{{.Alias}} goes to {{.URL}}</p>`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`{{`), 0o600))
+
+	p, err := Load(dir)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, p.Render(&buf, Preview, LinkData{Alias: "docs", URL: "https://example.com"}))
+	assert.Equal(t, `<p>docs goes to https://example.com</p>`, buf.String())
+
+	// pages without a template in dir stay embedded
+	buf.Reset()
+	require.NoError(t, p.Render(&buf, Interstitial, LinkData{Alias: "docs", URL: "https://example.com"}))
+	assert.Contains(t, buf.String(), "You are leaving")
+
+	// and the embedded pages are not changed
+	buf.Reset()
+	require.NoError(t, New().Render(&buf, Preview, LinkData{Alias: "docs", URL: "https://example.com"}))
+	assert.Contains(t, buf.String(), "Continue")
+
+	p, err = Load("")
+	require.NoError(t, err)
+	assert.NotNil(t, p)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, Interstitial), []byte(`{{.URL`), 0o600))
+
+	_, err = Load(dir)
+	assert.Error(t, err)
+}
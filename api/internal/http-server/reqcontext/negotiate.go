@@ -0,0 +1,324 @@
+package reqcontext
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ajg/form"
+)
+
+const (
+	MIMEJSON      = "application/json"
+	MIMEXML       = "application/xml"
+	MIMETextXML   = "text/xml"
+	MIMEForm      = "application/x-www-form-urlencoded"
+	MIMEMultipart = "multipart/form-data"
+	MIMEText      = "text/plain"
+	MIMEHTML      = "text/html"
+	// MIMEProblemJSON is the RFC 7807 problem details format.
+	MIMEProblemJSON = "application/problem+json"
+)
+
+// Offers are the media types Render can produce, in the order of preference
+// used when the client accepts several of them equally.
+var Offers = []string{MIMEJSON, MIMEXML, MIMEForm, MIMEText, MIMETextXML}
+
+// maxMultipartMemory is the part of a multipart form kept in memory, the
+// rest is stored in temporary files.
+const maxMultipartMemory = 1 << 20
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrNotAcceptable        = errors.New("not acceptable")
+)
+
+// ContentType returns the media type of the request body without
+// parameters, or "" when the header is missing.
+func (c *ReqContext) ContentType() string {
+	raw := c.r.Header.Get("Content-Type")
+	if raw == "" {
+		return ""
+	}
+
+	mt, _, err := mime.ParseMediaType(raw)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(raw))
+	}
+
+	return mt
+}
+
+// Bind decodes the request body into v according to its Content-Type.
+// Bodies without Content-Type are decoded as JSON. Unknown form keys are
+// ignored, since HTML forms also post e.g. the name of the submit button.
+func (c *ReqContext) Bind(v any) error {
+	switch mt := c.ContentType(); {
+	case mt == "", mt == MIMEJSON, strings.HasSuffix(mt, "+json"):
+		return c.DecodeJSON(v)
+
+	case mt == MIMEXML, mt == MIMETextXML, strings.HasSuffix(mt, "+xml"):
+		return c.DecodeXML(v)
+
+	case mt == MIMEForm:
+		defer c.CloseBody() //nolint:errcheck
+
+		dec := form.NewDecoder(c.Body())
+		dec.IgnoreUnknownKeys(true)
+		return dec.Decode(v)
+
+	case mt == MIMEMultipart:
+		defer c.CloseBody() //nolint:errcheck
+
+		if err := c.r.ParseMultipartForm(maxMultipartMemory); err != nil {
+			return err
+		}
+
+		dec := form.NewDecoder(nil)
+		dec.IgnoreUnknownKeys(true)
+		return dec.DecodeValues(v, c.r.MultipartForm.Value)
+
+	default:
+		return fmt.Errorf("%w %q", ErrUnsupportedMediaType, mt)
+	}
+}
+
+// Negotiate picks the offer the client prefers according to the Accept
+// header. Requests without Accept get the first offer.
+func (c *ReqContext) Negotiate(offers ...string) (string, error) {
+	if len(offers) == 0 {
+		offers = Offers
+	}
+
+	accept := c.r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return offers[0], nil
+	}
+
+	ranges := parseAccept(strings.Join(accept, ","))
+	if len(ranges) == 0 {
+		return offers[0], nil
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := quality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	if best == "" {
+		return "", ErrNotAcceptable
+	}
+
+	return best, nil
+}
+
+// AcceptsProblem reports whether the client names application/problem+json
+// in Accept with a quality not below the one of application/json. Wildcards
+// do not count, problem documents are only sent to clients asking for them.
+func (c *ReqContext) AcceptsProblem() bool {
+	ranges := parseAccept(strings.Join(c.r.Header.Values("Accept"), ","))
+
+	for _, r := range ranges {
+		if r.typ+"/"+r.subtype == MIMEProblemJSON && r.q > 0 {
+			return r.q >= quality(ranges, MIMEJSON)
+		}
+	}
+
+	return false
+}
+
+// Problem writes v as application/problem+json.
+func (c *ReqContext) Problem(status int, v any) {
+	c.writeJSON(status, v, MIMEProblemJSON)
+}
+
+// Render writes v in the media type negotiated from the Accept header. When
+// no offer is acceptable nothing is written and ErrNotAcceptable returned.
+//
+// Form and text bodies are built from the JSON representation of v, so all
+// formats use the same field names.
+func (c *ReqContext) Render(status int, v any) error {
+	mt, err := c.Negotiate(Offers...)
+	if err != nil {
+		return err
+	}
+
+	c.Header().Add("Vary", "Accept")
+
+	switch mt {
+	case MIMEXML, MIMETextXML:
+		c.writeXML(status, v, mt)
+	case MIMEForm:
+		c.writeValues(status, v, MIMEForm, encodeForm)
+	case MIMEText:
+		c.Text(status, v)
+	default:
+		c.JSON(status, v)
+	}
+
+	return nil
+}
+
+// Text writes v as plain text: a fmt.Stringer as is, anything else as
+// `key: value` lines of its JSON representation.
+func (c *ReqContext) Text(status int, v any) {
+	if s, ok := v.(fmt.Stringer); ok {
+		c.Header().Set("Content-Type", MIMEText+"; charset=utf-8")
+		c.WriteHeader(status)
+		_, _ = c.Write([]byte(s.String() + "\n"))
+		return
+	}
+
+	c.writeValues(status, v, MIMEText+"; charset=utf-8", encodeText)
+}
+
+func (c *ReqContext) writeXML(status int, v any, contentType string) {
+	buf := &bytes.Buffer{}
+	if err := xml.NewEncoder(buf).Encode(v); err != nil {
+		http.Error(c.w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	c.Header().Set("Content-Type", contentType)
+	c.WriteHeader(status)
+	_, _ = c.Write(buf.Bytes())
+}
+
+func (c *ReqContext) writeValues(status int, v any, contentType string, encode func(map[string]any) ([]byte, error)) {
+	body, err := flatten(v)
+	if err == nil {
+		var out []byte
+		if out, err = encode(body); err == nil {
+			c.Header().Set("Content-Type", contentType)
+			c.WriteHeader(status)
+			_, _ = c.Write(out)
+			return
+		}
+	}
+
+	http.Error(c.w, err.Error(), http.StatusInternalServerError)
+}
+
+// flatten converts v to the generic form of its JSON encoding.
+func flatten(v any) (map[string]any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		// not an object, e.g. a bare string
+		var scalar any
+		if err := json.Unmarshal(raw, &scalar); err != nil {
+			return nil, err
+		}
+		return map[string]any{"value": scalar}, nil
+	}
+
+	return m, nil
+}
+
+func encodeForm(m map[string]any) ([]byte, error) {
+	s, err := form.EncodeToString(m)
+	return []byte(s), err
+}
+
+func encodeText(m map[string]any) ([]byte, error) {
+	values, err := form.EncodeToValues(m)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		for _, v := range values[k] {
+			fmt.Fprintf(&buf, "%s: %s\n", k, v)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mt, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			// `*` alone is sent by some old clients
+			if strings.HasPrefix(part, "*") {
+				mt = "*/*"
+			} else {
+				continue
+			}
+		}
+
+		typ, subtype, ok := strings.Cut(mt, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				q = parsed
+			}
+		}
+
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+
+	return ranges
+}
+
+// quality returns the q value of the most specific range matching offer.
+func quality(ranges []mediaRange, offer string) float64 {
+	typ, subtype, _ := strings.Cut(offer, "/")
+
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 2
+		case r.typ == typ && r.subtype == "*":
+			s = 1
+		case r.typ == "*" && r.subtype == "*":
+			s = 0
+		default:
+			continue
+		}
+
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+
+	return q
+}
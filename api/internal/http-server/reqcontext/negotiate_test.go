@@ -0,0 +1,209 @@
+package reqcontext
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindTarget struct {
+	URL   string `json:"url" xml:"url" form:"url"`
+	Alias string `json:"alias" xml:"alias" form:"alias"`
+}
+
+func TestBind(t *testing.T) {
+	want := bindTarget{URL: "https://google.com", Alias: "google"}
+
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     error
+	}{
+		{name: "without content type", body: `{"url":"https://google.com","alias":"google"}`},
+		{name: "json", contentType: "application/json; charset=utf-8", body: `{"url":"https://google.com","alias":"google"}`},
+		{name: "json suffix", contentType: "application/vnd.api+json", body: `{"url":"https://google.com","alias":"google"}`},
+		{name: "xml", contentType: "application/xml", body: `<request><url>https://google.com</url><alias>google</alias></request>`},
+		{name: "text xml", contentType: "text/xml", body: `<request><url>https://google.com</url><alias>google</alias></request>`},
+		{name: "form", contentType: "application/x-www-form-urlencoded", body: "url=https%3A%2F%2Fgoogle.com&alias=google&submit=Shorten"},
+		{name: "unsupported", contentType: "text/csv", body: "https://google.com,google", wantErr: ErrUnsupportedMediaType},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var got bindTarget
+			err := New(httptest.NewRecorder(), r).Bind(&got)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	t.Run("multipart", func(t *testing.T) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		require.NoError(t, mw.WriteField("url", "https://google.com"))
+		require.NoError(t, mw.WriteField("alias", "google"))
+		require.NoError(t, mw.Close())
+
+		r := httptest.NewRequest(http.MethodPost, "/", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+
+		var got bindTarget
+		require.NoError(t, New(httptest.NewRecorder(), r).Bind(&got))
+		assert.Equal(t, want, got)
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		accept  []string
+		want    string
+		wantErr error
+	}{
+		{name: "no accept", want: MIMEJSON},
+		{name: "any", accept: []string{"*/*"}, want: MIMEJSON},
+		{name: "bare star", accept: []string{"*"}, want: MIMEJSON},
+		{name: "xml", accept: []string{"application/xml"}, want: MIMEXML},
+		{name: "text xml", accept: []string{"text/xml"}, want: MIMETextXML},
+		{name: "any text", accept: []string{"text/*"}, want: MIMEText},
+		{name: "form", accept: []string{"application/x-www-form-urlencoded"}, want: MIMEForm},
+		{name: "quality", accept: []string{"application/json;q=0.5, text/plain"}, want: MIMEText},
+		{name: "several headers", accept: []string{"text/html", "application/xml;q=0.9"}, want: MIMEXML},
+		{name: "browser", accept: []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"}, want: MIMEXML},
+		{name: "excluded by q=0", accept: []string{"*/*, application/json;q=0"}, want: MIMEXML},
+		{name: "nothing acceptable", accept: []string{"image/png"}, wantErr: ErrNotAcceptable},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, a := range tt.accept {
+				r.Header.Add("Accept", a)
+			}
+
+			got, err := New(httptest.NewRecorder(), r).Negotiate()
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAcceptsProblem(t *testing.T) {
+	testCases := []struct {
+		name   string
+		accept string
+		want   bool
+	}{
+		{name: "no accept"},
+		{name: "any", accept: "*/*"},
+		{name: "json", accept: "application/json"},
+		{name: "problem", accept: "application/problem+json", want: true},
+		{name: "problem and json", accept: "application/json, application/problem+json", want: true},
+		{name: "json preferred", accept: "application/json, application/problem+json;q=0.5"},
+		{name: "problem excluded", accept: "application/problem+json;q=0"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			assert.Equal(t, tt.want, New(httptest.NewRecorder(), r).AcceptsProblem())
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	type body struct {
+		Status string `json:"status" xml:"status"`
+		Alias  string `json:"alias" xml:"alias"`
+	}
+
+	v := body{Status: "OK", Alias: "google"}
+
+	testCases := []struct {
+		name            string
+		accept          string
+		wantContentType string
+		wantBody        string
+		wantErr         error
+	}{
+		{
+			name:            "json",
+			accept:          "application/json",
+			wantContentType: "application/json",
+			wantBody:        `{"status":"OK","alias":"google"}`,
+		},
+		{
+			name:            "xml",
+			accept:          "application/xml",
+			wantContentType: "application/xml",
+			wantBody:        `<body><status>OK</status><alias>google</alias></body>`,
+		},
+		{
+			name:            "form",
+			accept:          "application/x-www-form-urlencoded",
+			wantContentType: "application/x-www-form-urlencoded",
+			wantBody:        "alias=google&status=OK",
+		},
+		{
+			name:            "text",
+			accept:          "text/plain",
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "alias: google\nstatus: OK",
+		},
+		{
+			name:    "not acceptable",
+			accept:  "image/png",
+			wantErr: ErrNotAcceptable,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+
+			err := New(w, r).Render(http.StatusCreated, v)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, w.Body.String())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, tt.wantContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", w.Header().Get("Vary"))
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
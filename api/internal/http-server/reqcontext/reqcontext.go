@@ -39,6 +39,10 @@ func (c *ReqContext) GetChiParamFromCtx(key string) string {
 }
 
 func (c *ReqContext) JSON(status int, v any) {
+	c.writeJSON(status, v, MIMEJSON)
+}
+
+func (c *ReqContext) writeJSON(status int, v any, contentType string) {
 	buf := &bytes.Buffer{}
 	enc := json.NewEncoder(buf)
 	enc.SetEscapeHTML(true)
@@ -47,22 +51,13 @@ func (c *ReqContext) JSON(status int, v any) {
 		return
 	}
 
-	c.Header().Set("Content-Type", "application/json")
+	c.Header().Set("Content-Type", contentType)
 	c.WriteHeader(status)
 	_, _ = c.Write(buf.Bytes())
 }
 
 func (c *ReqContext) XML(status int, v any) {
-	buf := &bytes.Buffer{}
-	enc := xml.NewEncoder(buf)
-	if err := enc.Encode(v); err != nil {
-		http.Error(c.w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	c.Header().Set("Content-Type", "application/xml")
-	c.WriteHeader(status)
-	_, _ = c.Write(buf.Bytes())
+	c.writeXML(status, v, MIMEXML)
 }
 
 func (c *ReqContext) FORM(status int, v any) {
@@ -0,0 +1,30 @@
+package resp
+
+// ProblemTypePrefix prefixes the error code in the type of a problem. The
+// URN identifies the problem, it is not meant to be dereferenced.
+const ProblemTypePrefix = "urn:url-shortener:problem:"
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem returns the problem for an error with the given status and
+// code. Instance identifies the failed request, e.g. by its request ID.
+func NewProblem(status int, code, title string, err error, instance string, fields ...FieldError) Problem {
+	return Problem{
+		Type:     ProblemTypePrefix + code,
+		Title:    title,
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: instance,
+		Code:     code,
+		Errors:   fields,
+	}
+}
@@ -1,11 +1,25 @@
 package resp
 
+import (
+	"encoding/xml"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+)
+
 // Response is a structure that includes common server response fields.
+// Structures embedding it are encoded to XML as <response>.
 type Response struct {
-	Status string `json:"status"`          // should be `OK` or `error` only
-	Error  string `json:"error,omitempty"` // error message
+	XMLName xml.Name     `json:"-" xml:"response"`
+	Status  string       `json:"status" xml:"status"`                    // should be `OK` or `error` only
+	Code    string       `json:"code,omitempty" xml:"code,omitempty"`    // machine-readable error code
+	Error   string       `json:"error,omitempty" xml:"error,omitempty"`  // error message
+	Fields  []FieldError `json:"fields,omitempty" xml:"field,omitempty"` // invalid request fields
 }
 
+// FieldError describes why a single request field was rejected. It is
+// shared with the clients of the API.
+type FieldError = apierr.FieldError
+
 const (
 	StatusOK    = "OK"
 	StatusError = "error"
@@ -25,3 +39,11 @@ func Error(err error) Response {
 		Error:  err.Error(),
 	}
 }
+
+// ErrorCode is Error with a machine-readable code and the rejected fields.
+func ErrorCode(code string, err error, fields ...FieldError) Response {
+	r := Error(err)
+	r.Code = code
+	r.Fields = fields
+	return r
+}
@@ -50,3 +50,29 @@ func TestError(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorCode(t *testing.T) {
+	field := FieldError{Field: "url", Code: "required", Message: "url must not be empty"}
+
+	got := ErrorCode("empty_url", errors.New("url must not be empty"), field)
+
+	assert.Equal(t, Response{
+		Status: StatusError,
+		Code:   "empty_url",
+		Error:  "url must not be empty",
+		Fields: []FieldError{field},
+	}, got)
+}
+
+func TestNewProblem(t *testing.T) {
+	got := NewProblem(404, "not_found", "Not Found", errors.New("url not found"), "req-1")
+
+	assert.Equal(t, Problem{
+		Type:     ProblemTypePrefix + "not_found",
+		Title:    "Not Found",
+		Status:   404,
+		Detail:   "url not found",
+		Instance: "req-1",
+		Code:     "not_found",
+	}, got)
+}
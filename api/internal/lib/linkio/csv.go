@@ -0,0 +1,226 @@
+package linkio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+)
+
+const yourlsTimeLayout = "2006-01-02 15:04:05"
+
+// csvLayout describes a CSV flavour: which columns hold the link fields and
+// how times are written.
+type csvLayout struct {
+	url, alias, created string
+	timeLayout          string
+	// positional are the columns of files without a header row, nil means
+	// the header is required.
+	positional []string
+}
+
+var (
+	csvFormat = csvLayout{
+		url:        "url",
+		alias:      "alias",
+		created:    "created_at",
+		timeLayout: time.RFC3339,
+	}
+
+	yourlsFormat = csvLayout{
+		url:        "url",
+		alias:      "keyword",
+		created:    "timestamp",
+		timeLayout: yourlsTimeLayout,
+		positional: []string{"keyword", "url", "title", "timestamp", "ip", "clicks"},
+	}
+)
+
+type csvReader struct {
+	r      *csv.Reader
+	layout csvLayout
+	cols   map[string]int
+	line   int
+}
+
+func newCSVReader(r io.Reader) *csvReader    { return newLayoutReader(r, csvFormat) }
+func newYOURLSReader(r io.Reader) *csvReader { return newLayoutReader(r, yourlsFormat) }
+
+func newLayoutReader(r io.Reader, layout csvLayout) *csvReader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	return &csvReader{r: cr, layout: layout}
+}
+
+func (c *csvReader) Line() int { return c.line }
+
+func (c *csvReader) Read() (database.Link, error) {
+	record, err := c.next()
+	if err != nil {
+		return database.Link{}, err
+	}
+
+	if c.cols == nil {
+		isHeader, err := c.readHeader(record)
+		if err != nil {
+			return database.Link{}, err
+		}
+
+		if isHeader {
+			if record, err = c.next(); err != nil {
+				return database.Link{}, err
+			}
+		}
+	}
+
+	return c.link(record)
+}
+
+func (c *csvReader) next() ([]string, error) {
+	record, err := c.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+
+	c.line, _ = c.r.FieldPos(0)
+
+	if err != nil {
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			c.line = perr.Line
+		}
+		return nil, malformed(c.line, err)
+	}
+
+	return record, nil
+}
+
+// readHeader sets up the column indexes from the first row and reports
+// whether the row is a header rather than a link.
+func (c *csvReader) readHeader(record []string) (bool, error) {
+	cols := make(map[string]int, len(record))
+	for i, name := range record {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := cols[c.layout.url]; ok {
+		c.cols = cols
+		return true, nil
+	}
+
+	if c.layout.positional == nil {
+		return false, malformed(c.line, fmt.Errorf("header has no %q column", c.layout.url))
+	}
+
+	c.cols = make(map[string]int, len(c.layout.positional))
+	for i, name := range c.layout.positional {
+		c.cols[name] = i
+	}
+
+	return false, nil
+}
+
+func (c *csvReader) link(record []string) (database.Link, error) {
+	field := func(name string) string {
+		i, ok := c.cols[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	link := database.Link{
+		URL:   field(c.layout.url),
+		Alias: field(c.layout.alias),
+	}
+
+	if raw := field(c.layout.created); raw != "" {
+		t, err := time.ParseInLocation(c.layout.timeLayout, raw, time.UTC)
+		if err != nil {
+			return database.Link{}, malformed(c.line, err)
+		}
+		link.CreatedAt = t.UTC()
+	}
+
+	return link, nil
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header []string
+	row    func(database.Link) []string
+	wrote  bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{
+		w:      csv.NewWriter(w),
+		header: []string{"id", "alias", "url", "created_at", "updated_at"},
+		row: func(l database.Link) []string {
+			return []string{
+				strconv.FormatInt(l.ID, 10),
+				l.Alias,
+				l.URL,
+				formatTime(l.CreatedAt, time.RFC3339),
+				formatTime(l.UpdatedAt, time.RFC3339),
+			}
+		},
+	}
+}
+
+func newYOURLSWriter(w io.Writer) *csvWriter {
+	return &csvWriter{
+		w:      csv.NewWriter(w),
+		header: yourlsFormat.positional,
+		row: func(l database.Link) []string {
+			return []string{l.Alias, l.URL, "", formatTime(l.CreatedAt, yourlsTimeLayout), "", "0"}
+		},
+	}
+}
+
+func (c *csvWriter) Write(link database.Link) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.w.Write(c.row(link))
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.wrote {
+		return nil
+	}
+	c.wrote = true
+
+	return c.w.Write(c.header)
+}
+
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
+
+// malformed reports a broken record, the result matches ErrMalformed.
+func malformed(line int, err error) error {
+	return fmt.Errorf("%w: line %d: %v", ErrMalformed, line, err)
+}
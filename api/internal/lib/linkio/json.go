@@ -0,0 +1,242 @@
+package linkio
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+)
+
+// maxLineSize bounds a single NDJSON line.
+const maxLineSize = 1 << 20
+
+// bitlyTimeLayout is how Bitly writes timestamps, e.g. 2021-03-04T05:06:07+0000.
+const bitlyTimeLayout = "2006-01-02T15:04:05-0700"
+
+type ndjsonReader struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+
+	return &ndjsonReader{sc: sc}
+}
+
+func (n *ndjsonReader) Line() int { return n.line }
+
+func (n *ndjsonReader) Read() (database.Link, error) {
+	for n.sc.Scan() {
+		n.line++
+
+		raw := strings.TrimSpace(n.sc.Text())
+		if raw == "" {
+			continue
+		}
+
+		var link database.Link
+		if err := json.Unmarshal([]byte(raw), &link); err != nil {
+			return database.Link{}, malformed(n.line, err)
+		}
+
+		return link, nil
+	}
+
+	if err := n.sc.Err(); err != nil {
+		return database.Link{}, malformed(n.line+1, err)
+	}
+
+	return database.Link{}, io.EOF
+}
+
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	buf := bufio.NewWriter(w)
+	return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (n *ndjsonWriter) Write(link database.Link) error { return n.enc.Encode(link) }
+func (n *ndjsonWriter) Close() error                   { return n.buf.Flush() }
+
+// bitlink is an entry of the Bitly v4 `links` array. Only the fields needed
+// to recreate the link are kept.
+type bitlink struct {
+	ID        string `json:"id"`
+	Link      string `json:"link,omitempty"`
+	LongURL   string `json:"long_url"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// bitlyReader reads either the `{"links": [...]}` object returned by Bitly
+// or a bare array of bitlinks.
+type bitlyReader struct {
+	dec     *json.Decoder
+	started bool
+	done    bool
+	n       int
+}
+
+func newBitlyReader(r io.Reader) *bitlyReader {
+	return &bitlyReader{dec: json.NewDecoder(r)}
+}
+
+func (b *bitlyReader) Line() int { return b.n }
+
+func (b *bitlyReader) Read() (database.Link, error) {
+	if !b.started {
+		b.started = true
+		if err := b.start(); err != nil {
+			return database.Link{}, err
+		}
+	}
+
+	if b.done || !b.dec.More() {
+		b.done = true
+		return database.Link{}, io.EOF
+	}
+
+	b.n++
+
+	var bl bitlink
+	if err := b.dec.Decode(&bl); err != nil {
+		return database.Link{}, malformed(b.n, err)
+	}
+
+	link := database.Link{
+		URL:   bl.LongURL,
+		Alias: bitlyAlias(bl),
+	}
+
+	if bl.CreatedAt != "" {
+		t, err := time.Parse(bitlyTimeLayout, bl.CreatedAt)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, bl.CreatedAt)
+		}
+		if err != nil {
+			return database.Link{}, malformed(b.n, err)
+		}
+		link.CreatedAt = t.UTC()
+	}
+
+	return link, nil
+}
+
+// start positions the decoder at the first element of the links array.
+func (b *bitlyReader) start() error {
+	tok, err := b.dec.Token()
+	if errors.Is(err, io.EOF) {
+		b.done = true
+		return nil
+	}
+	if err != nil {
+		return malformed(0, err)
+	}
+
+	switch tok {
+	case json.Delim('['):
+		return nil
+	case json.Delim('{'):
+	default:
+		return malformed(0, fmt.Errorf("unexpected %v, want an object or array", tok))
+	}
+
+	for b.dec.More() {
+		tok, err := b.dec.Token()
+		if err != nil {
+			return malformed(0, err)
+		}
+
+		if tok != "links" {
+			var skip json.RawMessage
+			if err := b.dec.Decode(&skip); err != nil {
+				return malformed(0, err)
+			}
+			continue
+		}
+
+		tok, err = b.dec.Token()
+		if err != nil {
+			return malformed(0, err)
+		}
+		if tok != json.Delim('[') {
+			return malformed(0, errors.New(`"links" is not an array`))
+		}
+
+		return nil
+	}
+
+	// an object without links
+	b.done = true
+	return nil
+}
+
+// bitlyAlias returns the back-half of the bitlink, e.g. `abc` of `bit.ly/abc`.
+func bitlyAlias(bl bitlink) string {
+	id := bl.ID
+	if id == "" && bl.Link != "" {
+		if u, err := url.Parse(bl.Link); err == nil {
+			id = u.Path
+		}
+	}
+
+	id = strings.TrimRight(id, "/")
+	if i := strings.LastIndexByte(id, '/'); i >= 0 {
+		id = id[i+1:]
+	}
+
+	return id
+}
+
+type bitlyWriter struct {
+	buf   *bufio.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newBitlyWriter(w io.Writer) *bitlyWriter {
+	buf := bufio.NewWriter(w)
+	return &bitlyWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (b *bitlyWriter) Write(link database.Link) error {
+	sep := ","
+	if b.count == 0 {
+		sep = `{"links":[`
+	}
+	b.count++
+
+	if _, err := b.buf.WriteString(sep); err != nil {
+		return err
+	}
+
+	return b.enc.Encode(bitlink{
+		ID:        link.Alias,
+		LongURL:   link.URL,
+		CreatedAt: formatTime(link.CreatedAt, bitlyTimeLayout),
+	})
+}
+
+func (b *bitlyWriter) Close() error {
+	trailer := "]}\n"
+	if b.count == 0 {
+		trailer = `{"links":[]}` + "\n"
+	}
+
+	if _, err := b.buf.WriteString(trailer); err != nil {
+		return err
+	}
+
+	return b.buf.Flush()
+}
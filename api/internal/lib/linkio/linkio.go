@@ -0,0 +1,110 @@
+// Package linkio reads and writes links in the formats supported by the
+// import and export endpoints. Readers and writers work on one link at a
+// time, so arbitrarily large files are processed in constant memory.
+package linkio
+
+import (
+	"errors"
+	"io"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+)
+
+type Format string
+
+const (
+	// CSV with a header row, columns are matched by name.
+	FormatCSV Format = "csv"
+	// NDJSON is one JSON encoded link per line.
+	FormatNDJSON Format = "ndjson"
+	// Bitly is the JSON layout of the Bitly v4 `bitlinks` listing.
+	FormatBitly Format = "bitly"
+	// YOURLS is the CSV dump of the YOURLS `yourls_url` table.
+	FormatYOURLS Format = "yourls"
+)
+
+var Formats = []Format{FormatCSV, FormatNDJSON, FormatBitly, FormatYOURLS}
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrMalformed     = errors.New("malformed input")
+)
+
+// Reader yields links one by one and returns io.EOF after the last one.
+type Reader interface {
+	Read() (database.Link, error)
+	// Line returns the line or record number of the last link read.
+	Line() int
+}
+
+// Writer encodes links one by one. Close writes the trailer of the format
+// and flushes buffered data, it does not close the underlying writer.
+type Writer interface {
+	Write(link database.Link) error
+	Close() error
+}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", ErrUnknownFormat
+}
+
+func NewReader(r io.Reader, f Format) (Reader, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVReader(r), nil
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	case FormatBitly:
+		return newBitlyReader(r), nil
+	case FormatYOURLS:
+		return newYOURLSReader(r), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatBitly:
+		return newBitlyWriter(w), nil
+	case FormatYOURLS:
+		return newYOURLSWriter(w), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ContentType returns the media type of the format.
+func ContentType(f Format) string {
+	switch f {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatBitly:
+		return "application/json"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// FileName returns the default name of an export in the format.
+func FileName(f Format) string {
+	switch f {
+	case FormatNDJSON:
+		return "links.ndjson"
+	case FormatBitly:
+		return "links.bitly.json"
+	case FormatYOURLS:
+		return "links.yourls.csv"
+	default:
+		return "links.csv"
+	}
+}
@@ -0,0 +1,216 @@
+package linkio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, r Reader) ([]database.Link, error) {
+	t.Helper()
+
+	var links []database.Link
+	for {
+		link, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return links, nil
+		}
+		if err != nil {
+			return links, err
+		}
+		links = append(links, link)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		got, err := ParseFormat(string(f))
+		require.NoError(t, err)
+		assert.Equal(t, f, got)
+	}
+
+	_, err := ParseFormat("xml")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestReader(t *testing.T) {
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		format  Format
+		input   string
+		want    []database.Link
+		wantErr error
+	}{
+		{
+			name:   "csv",
+			format: FormatCSV,
+			input:  "\ufeffURL,Alias,created_at\nhttps://google.com,google,2021-03-04T05:06:07Z\nhttps://ya.ru,,\n",
+			want: []database.Link{
+				{URL: "https://google.com", Alias: "google", CreatedAt: created},
+				{URL: "https://ya.ru"},
+			},
+		},
+		{
+			name:    "csv without url column",
+			format:  FormatCSV,
+			input:   "alias\ngoogle\n",
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "csv with bad time",
+			format:  FormatCSV,
+			input:   "url,alias,created_at\nhttps://google.com,google,yesterday\n",
+			wantErr: ErrMalformed,
+		},
+		{
+			name:   "ndjson",
+			format: FormatNDJSON,
+			input:  `{"url":"https://google.com","alias":"google","created_at":"2021-03-04T05:06:07Z"}` + "\n\n" + `{"url":"https://ya.ru","alias":"ya"}`,
+			want: []database.Link{
+				{URL: "https://google.com", Alias: "google", CreatedAt: created},
+				{URL: "https://ya.ru", Alias: "ya"},
+			},
+		},
+		{
+			name:    "broken ndjson",
+			format:  FormatNDJSON,
+			input:   `{"url":`,
+			wantErr: ErrMalformed,
+		},
+		{
+			name:   "bitly",
+			format: FormatBitly,
+			input: `{"pagination":{"total":2},"links":[
+				{"id":"bit.ly/google","long_url":"https://google.com","created_at":"2021-03-04T05:06:07+0000","tags":[]},
+				{"link":"https://bit.ly/ya","long_url":"https://ya.ru"}
+			]}`,
+			want: []database.Link{
+				{URL: "https://google.com", Alias: "google", CreatedAt: created},
+				{URL: "https://ya.ru", Alias: "ya"},
+			},
+		},
+		{
+			name:   "bitly array",
+			format: FormatBitly,
+			input:  `[{"id":"bit.ly/google","long_url":"https://google.com"}]`,
+			want:   []database.Link{{URL: "https://google.com", Alias: "google"}},
+		},
+		{
+			name:   "bitly without links",
+			format: FormatBitly,
+			input:  `{"pagination":{}}`,
+		},
+		{
+			name:    "bitly links is not an array",
+			format:  FormatBitly,
+			input:   `{"links":{}}`,
+			wantErr: ErrMalformed,
+		},
+		{
+			name:   "yourls with header",
+			format: FormatYOURLS,
+			input:  "keyword,url,title,timestamp,ip,clicks\ngoogle,https://google.com,Google,2021-03-04 05:06:07,127.0.0.1,42\n",
+			want:   []database.Link{{URL: "https://google.com", Alias: "google", CreatedAt: created}},
+		},
+		{
+			name:   "yourls without header",
+			format: FormatYOURLS,
+			input:  "google,https://google.com,Google,2021-03-04 05:06:07,127.0.0.1,42\nya,https://ya.ru,,,,\n",
+			want: []database.Link{
+				{URL: "https://google.com", Alias: "google", CreatedAt: created},
+				{URL: "https://ya.ru", Alias: "ya"},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tt.input), tt.format)
+			require.NoError(t, err)
+
+			got, err := readAll(t, r)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	links := []database.Link{
+		{ID: 1, URL: "https://google.com", Alias: "google", CreatedAt: created, UpdatedAt: created},
+		{ID: 2, URL: "https://ya.ru/?q=a,b", Alias: "ya", CreatedAt: created, UpdatedAt: created},
+	}
+
+	for _, f := range Formats {
+		t.Run(string(f), func(t *testing.T) {
+			var buf bytes.Buffer
+
+			w, err := NewWriter(&buf, f)
+			require.NoError(t, err)
+
+			for _, link := range links {
+				require.NoError(t, w.Write(link))
+			}
+			require.NoError(t, w.Close())
+
+			r, err := NewReader(&buf, f)
+			require.NoError(t, err)
+
+			got, err := readAll(t, r)
+			require.NoError(t, err)
+			require.Len(t, got, len(links))
+
+			for i, link := range got {
+				assert.Equal(t, links[i].URL, link.URL)
+				assert.Equal(t, links[i].Alias, link.Alias)
+				assert.True(t, links[i].CreatedAt.Equal(link.CreatedAt))
+			}
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		for _, f := range Formats {
+			var buf bytes.Buffer
+
+			w, err := NewWriter(&buf, f)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			r, err := NewReader(&buf, f)
+			require.NoError(t, err)
+
+			got, err := readAll(t, r)
+			require.NoError(t, err, f)
+			assert.Empty(t, got, f)
+		}
+	})
+}
+
+func TestReader_Line(t *testing.T) {
+	r, err := NewReader(strings.NewReader("url,alias\nhttps://google.com,google\n\nhttps://ya.ru,ya\n"), FormatCSV)
+	require.NoError(t, err)
+
+	_, err = r.Read()
+	require.NoError(t, err)
+	assert.Equal(t, 2, r.Line())
+
+	_, err = r.Read()
+	require.NoError(t, err)
+	assert.Equal(t, 4, r.Line())
+}
@@ -0,0 +1,17 @@
+// Package openapi embeds the OpenAPI 3 document of the HTTP API and the page
+// rendering it. The document is written by hand; the handler tests fail when
+// it drifts from the registered routes or the response types.
+package openapi
+
+import _ "embed"
+
+// Spec is the OpenAPI document in JSON.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Docs is a self-contained HTML page that loads `openapi.json` relative to
+// its own URL and renders it with a form to try every operation.
+//
+//go:embed docs.html
+var Docs []byte
@@ -1 +1 @@
-DROP TABLE IF EXIST urls;
\ No newline at end of file
+DROP TABLE IF EXISTS urls;
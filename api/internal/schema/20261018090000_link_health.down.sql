@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_urls_broken;
+DROP INDEX IF EXISTS idx_urls_last_checked_at;
+
+ALTER TABLE urls
+  DROP COLUMN IF EXISTS disabled_at,
+  DROP COLUMN IF EXISTS check_failures,
+  DROP COLUMN IF EXISTS last_checked_at,
+  DROP COLUMN IF EXISTS last_status;
@@ -0,0 +1,8 @@
+ALTER TABLE urls
+  ADD COLUMN IF NOT EXISTS last_status     INTEGER,
+  ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS check_failures  INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS disabled_at     TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_urls_last_checked_at ON urls(last_checked_at NULLS FIRST);
+CREATE INDEX IF NOT EXISTS idx_urls_broken ON urls(check_failures) WHERE check_failures > 0;
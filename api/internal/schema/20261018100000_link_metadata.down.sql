@@ -0,0 +1,6 @@
+ALTER TABLE urls
+  DROP COLUMN IF EXISTS metadata_fetched_at,
+  DROP COLUMN IF EXISTS og_image,
+  DROP COLUMN IF EXISTS og_description,
+  DROP COLUMN IF EXISTS og_title,
+  DROP COLUMN IF EXISTS title;
@@ -0,0 +1,6 @@
+ALTER TABLE urls
+  ADD COLUMN IF NOT EXISTS title               TEXT,
+  ADD COLUMN IF NOT EXISTS og_title            TEXT,
+  ADD COLUMN IF NOT EXISTS og_description      TEXT,
+  ADD COLUMN IF NOT EXISTS og_image            TEXT,
+  ADD COLUMN IF NOT EXISTS metadata_fetched_at TIMESTAMPTZ;
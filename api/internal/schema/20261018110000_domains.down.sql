@@ -0,0 +1,8 @@
+-- fails while two domains share an alias
+DROP INDEX IF EXISTS idx_urls_domain_alias;
+ALTER TABLE urls ADD CONSTRAINT urls_alias_key UNIQUE (alias);
+CREATE INDEX IF NOT EXISTS idx_urls_alias ON urls(alias);
+
+ALTER TABLE urls DROP COLUMN IF EXISTS domain;
+
+DROP TABLE IF EXISTS domains;
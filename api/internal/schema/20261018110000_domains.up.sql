@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS domains (
+  name          TEXT PRIMARY KEY,
+  alias_length  INTEGER NOT NULL DEFAULT 0,
+  not_found_url TEXT,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- links of hosts that are no domain keep the empty default namespace
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS domain TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_alias_key;
+DROP INDEX IF EXISTS idx_urls_alias;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_urls_domain_alias ON urls(domain, alias);
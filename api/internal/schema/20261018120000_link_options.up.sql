@@ -0,0 +1,4 @@
+ALTER TABLE urls
+  ADD COLUMN IF NOT EXISTS passthrough TEXT NOT NULL DEFAULT ''
+    CHECK (passthrough IN ('', 'incoming', 'destination')),
+  ADD COLUMN IF NOT EXISTS utm         JSONB;
@@ -0,0 +1 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS interstitial BOOLEAN NOT NULL DEFAULT false;
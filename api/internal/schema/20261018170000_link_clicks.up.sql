@@ -0,0 +1,2 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS max_clicks BIGINT;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE urls
+  ADD COLUMN IF NOT EXISTS active_from  TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS active_until TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- links in the trash would be served again without the column
+DELETE FROM urls WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_urls_deleted_at;
+
+ALTER TABLE urls
+  DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+  id         BIGSERIAL PRIMARY KEY,
+  action     TEXT NOT NULL,
+  alias      TEXT NOT NULL,
+  domain     TEXT NOT NULL DEFAULT '',
+  api_key    TEXT,
+  ip         TEXT,
+  request_id TEXT,
+  before     JSONB,
+  after      JSONB,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_domain_alias ON audit_log(domain, alias);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
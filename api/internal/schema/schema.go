@@ -0,0 +1,133 @@
+// Package schema embeds the SQL migrations of the service into the binary.
+//
+// Every migration is a pair of files named `<version>_<name>.up.sql` and
+// `<version>_<name>.down.sql` (the layout used by golang-migrate), so the
+// same directory can still be applied by the external `migrate` CLI.
+package schema
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+const (
+	upSuffix   = ".up.sql"
+	downSuffix = ".down.sql"
+)
+
+// Migration is a single schema change with its rollback.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+var (
+	ErrBadFileName      = errors.New("bad migration file name")
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+	ErrMissingUp        = errors.New("migration without up script")
+)
+
+// Load returns the embedded migrations sorted by version.
+func Load() ([]Migration, error) {
+	return Parse(files)
+}
+
+// Parse reads the migrations from the root of fsys and returns them
+// sorted by version.
+func Parse(fsys fs.FS) ([]Migration, error) {
+	const fn = "schema.Parse"
+
+	wp := wraper.New(fn)
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	byVersion := make(map[uint64]*Migration, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		version, name, up, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, wp.Wrap(err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, wp.WrapMsg(entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+
+		if m.Name != name {
+			return nil, wp.Wrapf(ErrDuplicateVersion, "version=%d", version)
+		}
+
+		if up {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, wp.Wrapf(ErrMissingUp, "version=%d", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// parseFileName splits `20250625151517_init.up.sql` into its parts.
+func parseFileName(name string) (version uint64, title string, up bool, err error) {
+	var rest string
+
+	switch {
+	case strings.HasSuffix(name, upSuffix):
+		rest, up = strings.TrimSuffix(name, upSuffix), true
+	case strings.HasSuffix(name, downSuffix):
+		rest, up = strings.TrimSuffix(name, downSuffix), false
+	default:
+		return 0, "", false, fmt.Errorf("%w: %s", ErrBadFileName, name)
+	}
+
+	rawVersion, title, ok := strings.Cut(rest, "_")
+	if !ok || title == "" {
+		return 0, "", false, fmt.Errorf("%w: %s", ErrBadFileName, name)
+	}
+
+	version, err = strconv.ParseUint(rawVersion, 10, 64)
+	if err != nil || version == 0 {
+		return 0, "", false, fmt.Errorf("%w: %s", ErrBadFileName, name)
+	}
+
+	return version, title, up, nil
+}
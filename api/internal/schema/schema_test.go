@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("embedded_migrations", func(t *testing.T) {
+		migrations, err := Load()
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+
+		for i, m := range migrations {
+			assert.NotEmpty(t, m.Up, "version %d", m.Version)
+			assert.NotEmpty(t, m.Down, "version %d", m.Version)
+
+			if i > 0 {
+				assert.Greater(t, m.Version, migrations[i-1].Version)
+			}
+		}
+
+		assert.Equal(t, uint64(20250625151517), migrations[0].Version)
+		assert.Equal(t, "init", migrations[0].Name)
+	})
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fsys     fstest.MapFS
+		want     []Migration
+		wantErr  error
+		wantNone bool
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"2_second.up.sql":   {Data: []byte("B")},
+				"2_second.down.sql": {Data: []byte("b")},
+				"1_first.up.sql":    {Data: []byte("A")},
+				"1_first.down.sql":  {Data: []byte("a")},
+				"README.md":         {Data: []byte("ignored")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "first", Up: "A", Down: "a"},
+				{Version: 2, Name: "second", Up: "B", Down: "b"},
+			},
+		},
+		{
+			name: "without down script",
+			fsys: fstest.MapFS{
+				"1_first.up.sql": {Data: []byte("A")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "first", Up: "A"},
+			},
+		},
+		{
+			name:     "empty dir",
+			fsys:     fstest.MapFS{},
+			wantNone: true,
+		},
+		{
+			name: "bad file name",
+			fsys: fstest.MapFS{
+				"first.up.sql": {Data: []byte("A")},
+			},
+			wantErr: ErrBadFileName,
+		},
+		{
+			name: "bad suffix",
+			fsys: fstest.MapFS{
+				"1_first.sql": {Data: []byte("A")},
+			},
+			wantErr: ErrBadFileName,
+		},
+		{
+			name: "zero version",
+			fsys: fstest.MapFS{
+				"0_first.up.sql": {Data: []byte("A")},
+			},
+			wantErr: ErrBadFileName,
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"1_first.up.sql":  {Data: []byte("A")},
+				"1_second.up.sql": {Data: []byte("B")},
+			},
+			wantErr: ErrDuplicateVersion,
+		},
+		{
+			name: "missing up script",
+			fsys: fstest.MapFS{
+				"1_first.down.sql": {Data: []byte("a")},
+			},
+			wantErr: ErrMissingUp,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.fsys)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			if tt.wantNone {
+				assert.Empty(t, got)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package apierr holds the errors of the url shortener API and the
+// machine-readable codes they are reported with. The server and its
+// clients share it, so a client maps a code back to the error with ByCode
+// and callers match on it with errors.Is.
+package apierr
+
+import "errors"
+
+// Error codes are part of the API contract. Clients match on them, the
+// messages may change.
+const (
+	CodeInternal             = "internal"
+	CodeNotFound             = "not_found"
+	CodeEmptyAlias           = "empty_alias"
+	CodeEmptyURL             = "empty_url"
+	CodeInvalidURL           = "invalid_url"
+	CodeAliasTaken           = "alias_taken"
+	CodeAliasGeneration      = "alias_generation_failed"
+	CodeRateLimited          = "rate_limited"
+	CodeInvalidPage          = "invalid_page"
+	CodeUnauthorized         = "unauthorized"
+	CodeUnknownFormat        = "unknown_format"
+	CodeUnknownImportMode    = "unknown_import_mode"
+	CodeMalformedImport      = "malformed_import"
+	CodeInvalidImport        = "invalid_import"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeNotAcceptable        = "not_acceptable"
+	CodeDestinationRejected  = "destination_rejected"
+	CodeUnsafeURL            = "unsafe_url"
+	CodeReputationDown       = "reputation_unavailable"
+	CodeLinkDisabled         = "link_disabled"
+	CodeInvalidDomain        = "invalid_domain"
+	CodeDomainTaken          = "domain_taken"
+	CodeInvalidOptions       = "invalid_options"
+	CodePasswordRequired     = "password_required"
+	CodeWrongPassword        = "wrong_password"
+	CodeLinkExhausted        = "link_exhausted"
+	CodeLinkNotActive        = "link_not_active"
+	CodeLinkExpired          = "link_expired"
+	CodeInvalidFilter        = "invalid_filter"
+)
+
+// Field error codes, used next to the error code of the response. A
+// rejected destination carries the reason code of the policy instead.
+const (
+	FieldRequired = "required"
+	FieldInvalid  = "invalid"
+	FieldTaken    = "taken"
+)
+
+// The public errors, their messages are sent to clients.
+var (
+	ErrURLNotFound          = errors.New("url not found")
+	ErrEmptyAlias           = errors.New("alias must not be empty")
+	ErrEmptyURL             = errors.New("url must not be empty")
+	ErrInternalServer       = errors.New("internal server error")
+	ErrAliasExist           = errors.New("alias already exist")
+	ErrInvalidURLFormat     = errors.New("invalid url format")
+	ErrTooManyRequests      = errors.New("too many requests")
+	ErrCanNotGenAlias       = errors.New("could not generate random unique alias, please try again")
+	ErrInvalidPage          = errors.New("invalid limit or offset")
+	ErrUnauthorized         = errors.New("missing or invalid api key")
+	ErrUnknownFormat        = errors.New("unknown format, use csv, ndjson, bitly or yourls")
+	ErrUnknownImportMode    = errors.New("unknown import mode, use skip, overwrite or fail")
+	ErrMalformedImport      = errors.New("malformed import data")
+	ErrInvalidImport        = errors.New("import contains an invalid link")
+	ErrUnsupportedMediaType = errors.New("unsupported content type, use json, xml or form")
+	ErrNotAcceptable        = errors.New("none of the accepted types is supported, use json, xml, form or text")
+	ErrDestinationRejected  = errors.New("links to this destination are not allowed")
+	ErrUnsafeURL            = errors.New("url is listed as unsafe")
+	ErrReputationDown       = errors.New("url reputation cannot be checked, please try again")
+	ErrLinkDisabled         = errors.New("link is disabled")
+	ErrInvalidDomain        = errors.New("invalid domain")
+	ErrDomainExist          = errors.New("domain already registered")
+	ErrInvalidOptions       = errors.New("invalid link options")
+	ErrPasswordRequired     = errors.New("link is protected by a password")
+	ErrWrongPassword        = errors.New("wrong password")
+	ErrLinkExhausted        = errors.New("link reached its click limit")
+	ErrLinkNotActive        = errors.New("link is not active yet")
+	ErrLinkExpired          = errors.New("link is no longer active")
+	ErrInvalidFilter        = errors.New("invalid audit filter")
+)
+
+// byCode maps every code to the error reported with it.
+var byCode = map[string]error{
+	CodeInternal:             ErrInternalServer,
+	CodeNotFound:             ErrURLNotFound,
+	CodeEmptyAlias:           ErrEmptyAlias,
+	CodeEmptyURL:             ErrEmptyURL,
+	CodeInvalidURL:           ErrInvalidURLFormat,
+	CodeAliasTaken:           ErrAliasExist,
+	CodeAliasGeneration:      ErrCanNotGenAlias,
+	CodeRateLimited:          ErrTooManyRequests,
+	CodeInvalidPage:          ErrInvalidPage,
+	CodeUnauthorized:         ErrUnauthorized,
+	CodeUnknownFormat:        ErrUnknownFormat,
+	CodeUnknownImportMode:    ErrUnknownImportMode,
+	CodeMalformedImport:      ErrMalformedImport,
+	CodeInvalidImport:        ErrInvalidImport,
+	CodeUnsupportedMediaType: ErrUnsupportedMediaType,
+	CodeNotAcceptable:        ErrNotAcceptable,
+	CodeDestinationRejected:  ErrDestinationRejected,
+	CodeUnsafeURL:            ErrUnsafeURL,
+	CodeReputationDown:       ErrReputationDown,
+	CodeLinkDisabled:         ErrLinkDisabled,
+	CodeInvalidDomain:        ErrInvalidDomain,
+	CodeDomainTaken:          ErrDomainExist,
+	CodeInvalidOptions:       ErrInvalidOptions,
+	CodePasswordRequired:     ErrPasswordRequired,
+	CodeWrongPassword:        ErrWrongPassword,
+	CodeLinkExhausted:        ErrLinkExhausted,
+	CodeLinkNotActive:        ErrLinkNotActive,
+	CodeLinkExpired:          ErrLinkExpired,
+	CodeInvalidFilter:        ErrInvalidFilter,
+}
+
+// ByCode returns the error reported with code, or nil.
+func ByCode(code string) error {
+	return byCode[code]
+}
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field" xml:"name"`
+	Code    string `json:"code" xml:"code"`
+	Message string `json:"message" xml:"message"`
+}
@@ -0,0 +1,17 @@
+package apierr_test
+
+import (
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByCode(t *testing.T) {
+	assert.Equal(t, apierr.ErrAliasExist, apierr.ByCode(apierr.CodeAliasTaken))
+	assert.Equal(t, apierr.ErrInvalidURLFormat, apierr.ByCode(apierr.CodeInvalidURL))
+	assert.Equal(t, apierr.ErrInternalServer, apierr.ByCode(apierr.CodeInternal))
+
+	assert.Nil(t, apierr.ByCode(""))
+	assert.Nil(t, apierr.ByCode("unknown"))
+}
@@ -0,0 +1,273 @@
+// Package client is the Go client of the url shortener HTTP API.
+//
+//	c, err := client.New(client.Config{BaseURL: "https://short.example.com"})
+//	if err != nil {
+//		return err
+//	}
+//
+//	alias, err := c.ShortenWithAlias(ctx, "https://www.google.com", "google")
+//	if errors.Is(err, client.ErrAliasExist) {
+//		// pick another alias
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	urlPath   = "/api/v1/url"
+	statsPath = "/api/v1/stats"
+
+	apiKeyHeader = "X-API-Key"
+
+	// maxErrorBody caps how much of an error response is read.
+	maxErrorBody = 64 << 10
+)
+
+var (
+	ErrEmptyBaseURL   = errors.New("base url must not be empty")
+	ErrInvalidBaseURL = errors.New("base url must be an absolute http(s) url")
+	// ErrNoLocation means the server answered the redirect endpoint
+	// without a Location header.
+	ErrNoLocation = errors.New("redirect response has no location")
+)
+
+// Stats is an aggregate over all stored links.
+type Stats struct {
+	Total         int64      `json:"total"`
+	CreatedLast24 int64      `json:"created_last_24h"`
+	LastCreatedAt *time.Time `json:"last_created_at,omitempty"`
+}
+
+// The bodies of the requests and responses, kept here so the client does
+// not depend on the server.
+type (
+	shortenRequest struct {
+		Alias string `json:"alias,omitempty"`
+		URL   string `json:"url"`
+	}
+
+	shortenResponse struct {
+		Alias string `json:"alias"`
+	}
+
+	statsResponse struct {
+		Stats Stats `json:"stats"`
+	}
+)
+
+// Config configures a Client.
+type Config struct {
+	// BaseURL is the address of the server, e.g. https://short.example.com.
+	BaseURL string
+	// APIKey is sent with every request, it is needed for Stats.
+	APIKey string
+	// HTTPClient sends the requests, http.DefaultClient when nil. Its
+	// redirect policy is ignored, redirects are never followed.
+	HTTPClient *http.Client
+	Retry      RetryPolicy
+}
+
+// Client calls the url shortener API. It is safe for concurrent use.
+type Client struct {
+	base   string
+	apiKey string
+	http   *http.Client
+	retry  RetryPolicy
+}
+
+// New returns a client for the server at cfg.BaseURL.
+func New(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, ErrEmptyBaseURL
+	}
+
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidBaseURL
+	}
+
+	hc := http.DefaultClient
+	if cfg.HTTPClient != nil {
+		hc = cfg.HTTPClient
+	}
+
+	// the redirect endpoint answers with 302, which is the result itself
+	noRedirect := *hc
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Client{
+		base:   strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey: cfg.APIKey,
+		http:   &noRedirect,
+		retry:  cfg.Retry.withDefaults(),
+	}, nil
+}
+
+// Shorten saves longURL under a generated alias and returns the alias.
+//
+// A 5xx answer is retried like any other, so a link saved before the server
+// failed to answer may be saved a second time under another alias.
+func (c *Client) Shorten(ctx context.Context, longURL string) (string, error) {
+	return c.ShortenWithAlias(ctx, longURL, "")
+}
+
+// ShortenWithAlias saves longURL under alias and returns the alias. An empty
+// alias works like Shorten.
+func (c *Client) ShortenWithAlias(ctx context.Context, longURL, alias string) (string, error) {
+	const fn = "client.(*Client).ShortenWithAlias"
+
+	body, err := json.Marshal(shortenRequest{URL: longURL, Alias: alias})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", fn, err)
+	}
+
+	res, err := c.do(ctx, http.MethodPost, urlPath, nil, body)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var out shortenResponse
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("%s: decode response: %w", fn, err)
+	}
+
+	return out.Alias, nil
+}
+
+// Resolve returns the url alias redirects to. The redirect is not followed.
+func (c *Client) Resolve(ctx context.Context, alias string) (string, error) {
+	res, err := c.do(ctx, http.MethodGet, urlPath, url.Values{"alias": {alias}}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	location := res.Header.Get("Location")
+	if location == "" {
+		return "", ErrNoLocation
+	}
+
+	return location, nil
+}
+
+// Delete moves the link with alias to the trash.
+func (c *Client) Delete(ctx context.Context, alias string) error {
+	res, err := c.do(ctx, http.MethodDelete, urlPath, url.Values{"alias": {alias}}, nil)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Stats returns the statistics of the service. It needs an API key.
+func (c *Client) Stats(ctx context.Context) (Stats, error) {
+	const fn = "client.(*Client).Stats"
+
+	res, err := c.do(ctx, http.MethodGet, statsPath, nil, nil)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer res.Body.Close()
+
+	var out statsResponse
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return Stats{}, fmt.Errorf("%s: decode response: %w", fn, err)
+	}
+
+	return out.Stats, nil
+}
+
+// ShortURL returns the address that redirects to the link with alias.
+func (c *Client) ShortURL(alias string) string {
+	return c.base + urlPath + "?" + url.Values{"alias": {alias}}.Encode()
+}
+
+// do sends the request, retrying it according to the retry policy, and
+// returns the response if its status is below 400. Error responses are
+// returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	const fn = "client.(*Client).do"
+
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for retry := 0; ; retry++ {
+		res, err := c.send(ctx, method, target, body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+
+		if res.StatusCode < http.StatusBadRequest {
+			return res, nil
+		}
+
+		apiErr := decodeError(res)
+		if !apiErr.Temporary() || retry >= c.retry.MaxRetries {
+			return nil, apiErr
+		}
+
+		wait := c.retry.backoff(retry)
+		if apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			// the retry would be cancelled anyway
+			return nil, apiErr
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, target string, body []byte) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, r)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
+
+	return c.http.Do(req)
+}
+
+// decodeError reads the error response and closes its body.
+func decodeError(res *http.Response) *Error {
+	defer res.Body.Close()
+
+	var body errorResponse
+
+	raw, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	_ = json.Unmarshal(raw, &body)
+
+	return newError(res.StatusCode, body, retryAfter(res.Header, time.Now()))
+}
@@ -0,0 +1,402 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache/cachemock"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database/mocks"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/http-server/handlers"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/logger/discard"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "secret"
+
+var errDB = errors.New("database is down")
+
+// fastRetry keeps the tests quick.
+var fastRetry = client.RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+type env struct {
+	db    *mocks.MockDatabase
+	cache *cachemock.MockCache
+	srv   *httptest.Server
+	// calls counts the requests that reached the server
+	calls atomic.Int32
+}
+
+// newEnv serves the real router backed by mocks. wrap, if not nil, is put
+// in front of the router.
+func newEnv(t *testing.T, wrap func(http.Handler) http.Handler) *env {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+
+	e := &env{
+		db:    mocks.NewMockDatabase(ctrl),
+		cache: cachemock.NewMockCache(ctrl),
+	}
+
+	cfg := &config.ServerConfig{StdAliasLen: 6, APIKeys: config.APIKeys{{Name: "test", Key: testKey}}}
+	var router http.Handler = handlers.New(e.db, e.cache, cfg, discard.NewDiscardLogger()).InitRoutes()
+	if wrap != nil {
+		router = wrap(router)
+	}
+
+	e.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.calls.Add(1)
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(e.srv.Close)
+
+	return e
+}
+
+func (e *env) client(t *testing.T, cfg client.Config) *client.Client {
+	t.Helper()
+
+	cfg.BaseURL = e.srv.URL
+	c, err := client.New(cfg)
+	require.NoError(t, err)
+	return c
+}
+
+func TestNew(t *testing.T) {
+	_, err := client.New(client.Config{})
+	assert.ErrorIs(t, err, client.ErrEmptyBaseURL)
+
+	for _, base := range []string{"localhost:8000", "ftp://host", "http://"} {
+		_, err := client.New(client.Config{BaseURL: base})
+		assert.ErrorIs(t, err, client.ErrInvalidBaseURL, base)
+	}
+
+	c, err := client.New(client.Config{BaseURL: "https://short.example.com/"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://short.example.com/api/v1/url?alias=google", c.ShortURL("google"))
+}
+
+func TestShorten(t *testing.T) {
+	const longURL = "https://www.google.com"
+
+	e := newEnv(t, nil)
+	c := e.client(t, client.Config{Retry: fastRetry})
+
+	var saved string
+	e.db.EXPECT().SaveURL(gomock.Any(), longURL, gomock.Any()).DoAndReturn(func(_ context.Context, _, alias string) error {
+		saved = alias
+		return nil
+	})
+
+	alias, err := c.Shorten(context.Background(), longURL)
+	require.NoError(t, err)
+	assert.Len(t, alias, 6)
+	assert.Equal(t, saved, alias)
+}
+
+func TestShortenWithAlias(t *testing.T) {
+	const longURL = "https://www.google.com"
+
+	testCases := []struct {
+		name      string
+		url       string
+		alias     string
+		dbBehavor func(m *mocks.MockDatabase)
+		wantErr   error
+		wantCode  string
+		wantField string
+	}{
+		{
+			name:  "happy path",
+			url:   longURL,
+			alias: "google",
+			dbBehavor: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), longURL, "google").Return(nil)
+			},
+		},
+		{
+			name:  "alias taken",
+			url:   longURL,
+			alias: "google",
+			dbBehavor: func(m *mocks.MockDatabase) {
+				m.EXPECT().SaveURL(gomock.Any(), longURL, "google").Return(database.ErrURLExist)
+			},
+			wantErr:   client.ErrAliasExist,
+			wantCode:  apierr.CodeAliasTaken,
+			wantField: "alias",
+		},
+		{
+			name:      "invalid url",
+			url:       "not a url",
+			alias:     "google",
+			dbBehavor: func(m *mocks.MockDatabase) {},
+			wantErr:   client.ErrInvalidURLFormat,
+			wantCode:  apierr.CodeInvalidURL,
+			wantField: "url",
+		},
+		{
+			name:      "empty url",
+			alias:     "google",
+			dbBehavor: func(m *mocks.MockDatabase) {},
+			wantErr:   client.ErrEmptyURL,
+			wantCode:  apierr.CodeEmptyURL,
+			wantField: "url",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newEnv(t, nil)
+			c := e.client(t, client.Config{Retry: fastRetry})
+
+			tc.dbBehavor(e.db)
+
+			alias, err := c.ShortenWithAlias(context.Background(), tc.url, tc.alias)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				assert.Equal(t, tc.alias, alias)
+				return
+			}
+
+			assert.ErrorIs(t, err, tc.wantErr)
+
+			var apiErr *client.Error
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+			assert.Equal(t, tc.wantCode, apiErr.Code)
+			require.Len(t, apiErr.Fields, 1)
+			assert.Equal(t, tc.wantField, apiErr.Fields[0].Field)
+
+			// client errors are not retried
+			assert.Equal(t, int32(1), e.calls.Load())
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	const longURL = "https://www.google.com"
+
+	t.Run("cached", func(t *testing.T) {
+		e := newEnv(t, nil)
+		c := e.client(t, client.Config{Retry: fastRetry})
+
+		e.cache.EXPECT().Get(gomock.Any(), "link:google").Return(longURL, nil)
+		e.cache.EXPECT().Expire(gomock.Any(), "link:google").Return(nil)
+
+		got, err := c.Resolve(context.Background(), "google")
+		require.NoError(t, err)
+		assert.Equal(t, longURL, got)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		e := newEnv(t, nil)
+		c := e.client(t, client.Config{Retry: fastRetry})
+
+		e.cache.EXPECT().Get(gomock.Any(), "link:unknown").Return("", cache.ErrKeyNotExist)
+		e.db.EXPECT().GetTarget(gomock.Any(), "unknown").Return(database.Target{}, database.ErrURLNotFound)
+
+		_, err := c.Resolve(context.Background(), "unknown")
+		assert.ErrorIs(t, err, client.ErrURLNotFound)
+	})
+
+	t.Run("empty alias", func(t *testing.T) {
+		e := newEnv(t, nil)
+		c := e.client(t, client.Config{Retry: fastRetry})
+
+		_, err := c.Resolve(context.Background(), "")
+		assert.ErrorIs(t, err, client.ErrEmptyAlias)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	e := newEnv(t, nil)
+	c := e.client(t, client.Config{Retry: fastRetry})
+
+	gomock.InOrder(
+		e.db.EXPECT().DeleteURL(gomock.Any(), "google").Return(int64(1), nil),
+		e.db.EXPECT().DeleteURL(gomock.Any(), "google").Return(int64(0), database.ErrURLNotFound),
+	)
+	e.cache.EXPECT().Delete(gomock.Any(), "link:google").Return(nil)
+
+	require.NoError(t, c.Delete(context.Background(), "google"))
+	assert.ErrorIs(t, c.Delete(context.Background(), "google"), client.ErrURLNotFound)
+}
+
+func TestStats(t *testing.T) {
+	e := newEnv(t, nil)
+
+	last := time.Date(2025, 6, 25, 15, 15, 17, 0, time.UTC)
+	e.db.EXPECT().Stats(gomock.Any()).Return(database.Stats{Total: 3, CreatedLast24: 1, LastCreatedAt: &last}, nil)
+
+	stats, err := e.client(t, client.Config{APIKey: testKey}).Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Total)
+	assert.Equal(t, int64(1), stats.CreatedLast24)
+	require.NotNil(t, stats.LastCreatedAt)
+	assert.True(t, last.Equal(*stats.LastCreatedAt))
+
+	_, err = e.client(t, client.Config{APIKey: "wrong"}).Stats(context.Background())
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+func TestRetry(t *testing.T) {
+	t.Run("server error", func(t *testing.T) {
+		e := newEnv(t, nil)
+		c := e.client(t, client.Config{APIKey: testKey, Retry: fastRetry})
+
+		gomock.InOrder(
+			e.db.EXPECT().Stats(gomock.Any()).Return(database.Stats{}, errDB).Times(2),
+			e.db.EXPECT().Stats(gomock.Any()).Return(database.Stats{Total: 1}, nil),
+		)
+
+		stats, err := c.Stats(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), stats.Total)
+		assert.Equal(t, int32(3), e.calls.Load())
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		e := newEnv(t, nil)
+		policy := fastRetry
+		policy.MaxRetries = 2
+		c := e.client(t, client.Config{APIKey: testKey, Retry: policy})
+
+		e.db.EXPECT().Stats(gomock.Any()).Return(database.Stats{}, errDB).Times(3)
+
+		_, err := c.Stats(context.Background())
+		assert.ErrorIs(t, err, client.ErrInternalServer)
+
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusInternalServerError, apiErr.Status)
+		assert.Equal(t, apierr.CodeInternal, apiErr.Code)
+		assert.Equal(t, int32(3), e.calls.Load())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		e := newEnv(t, nil)
+		c := e.client(t, client.Config{APIKey: testKey, Retry: client.RetryPolicy{MaxRetries: -1}})
+
+		e.db.EXPECT().Stats(gomock.Any()).Return(database.Stats{}, errDB)
+
+		_, err := c.Stats(context.Background())
+		assert.ErrorIs(t, err, client.ErrInternalServer)
+		assert.Equal(t, int32(1), e.calls.Load())
+	})
+
+	t.Run("post body is sent again", func(t *testing.T) {
+		e := newEnv(t, nil)
+		c := e.client(t, client.Config{Retry: fastRetry})
+
+		gomock.InOrder(
+			e.db.EXPECT().SaveURL(gomock.Any(), "https://www.google.com", "google").Return(errDB),
+			e.db.EXPECT().SaveURL(gomock.Any(), "https://www.google.com", "google").Return(nil),
+		)
+
+		alias, err := c.ShortenWithAlias(context.Background(), "https://www.google.com", "google")
+		require.NoError(t, err)
+		assert.Equal(t, "google", alias)
+	})
+}
+
+// limitOnce answers the first request with 429 and the given Retry-After.
+func limitOnce(retryAfter string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		var limited atomic.Bool
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limited.CompareAndSwap(false, true) {
+				w.Header().Set("Retry-After", retryAfter)
+				handlers.New(nil, nil, &config.ServerConfig{}, discard.NewDiscardLogger()).NewLimit()(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Run("honoured", func(t *testing.T) {
+		e := newEnv(t, limitOnce("1"))
+		c := e.client(t, client.Config{APIKey: testKey, Retry: fastRetry})
+
+		e.db.EXPECT().Stats(gomock.Any()).Return(database.Stats{Total: 1}, nil)
+
+		start := time.Now()
+		_, err := c.Stats(context.Background())
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+		assert.Equal(t, int32(2), e.calls.Load())
+	})
+
+	t.Run("http date", func(t *testing.T) {
+		e := newEnv(t, limitOnce(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)))
+		c := e.client(t, client.Config{APIKey: testKey, Retry: fastRetry})
+
+		e.db.EXPECT().Stats(gomock.Any()).Return(database.Stats{Total: 1}, nil)
+
+		_, err := c.Stats(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), e.calls.Load())
+	})
+
+	t.Run("beyond deadline", func(t *testing.T) {
+		e := newEnv(t, limitOnce("60"))
+		c := e.client(t, client.Config{APIKey: testKey, Retry: fastRetry})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		_, err := c.Stats(ctx)
+		assert.ErrorIs(t, err, client.ErrTooManyRequests)
+		assert.Less(t, time.Since(start), time.Second)
+
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusTooManyRequests, apiErr.Status)
+		assert.Equal(t, time.Minute, apiErr.RetryAfter)
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		e := newEnv(t, limitOnce("60"))
+		c := e.client(t, client.Config{APIKey: testKey, Retry: fastRetry})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		_, err := c.Stats(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestErrorWithoutCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c, err := client.New(client.Config{BaseURL: srv.URL, Retry: client.RetryPolicy{MaxRetries: -1}})
+	require.NoError(t, err)
+
+	err = c.Delete(context.Background(), "google")
+	assert.ErrorIs(t, err, client.ErrInternalServer)
+
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadGateway, apiErr.Status)
+	assert.Empty(t, apiErr.Code)
+	assert.True(t, apiErr.Temporary())
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+)
+
+// The errors of the server. Every *Error unwraps to the one matching its
+// code, so callers can use errors.Is(err, client.ErrAliasExist).
+var (
+	ErrURLNotFound          = apierr.ErrURLNotFound
+	ErrEmptyAlias           = apierr.ErrEmptyAlias
+	ErrEmptyURL             = apierr.ErrEmptyURL
+	ErrInvalidURLFormat     = apierr.ErrInvalidURLFormat
+	ErrAliasExist           = apierr.ErrAliasExist
+	ErrCanNotGenAlias       = apierr.ErrCanNotGenAlias
+	ErrTooManyRequests      = apierr.ErrTooManyRequests
+	ErrInvalidPage          = apierr.ErrInvalidPage
+	ErrUnauthorized         = apierr.ErrUnauthorized
+	ErrUnsupportedMediaType = apierr.ErrUnsupportedMediaType
+	ErrNotAcceptable        = apierr.ErrNotAcceptable
+	ErrInternalServer       = apierr.ErrInternalServer
+	// ErrDestinationRejected carries the policy reason as the code of its
+	// url field.
+	ErrDestinationRejected = apierr.ErrDestinationRejected
+	ErrUnsafeURL           = apierr.ErrUnsafeURL
+	ErrReputationDown      = apierr.ErrReputationDown
+	ErrLinkDisabled        = apierr.ErrLinkDisabled
+	ErrInvalidDomain       = apierr.ErrInvalidDomain
+	ErrDomainExist         = apierr.ErrDomainExist
+	ErrInvalidOptions      = apierr.ErrInvalidOptions
+	ErrPasswordRequired    = apierr.ErrPasswordRequired
+	ErrWrongPassword       = apierr.ErrWrongPassword
+	ErrLinkExhausted       = apierr.ErrLinkExhausted
+	ErrLinkNotActive       = apierr.ErrLinkNotActive
+	ErrLinkExpired         = apierr.ErrLinkExpired
+)
+
+// Error is an error response of the server.
+type Error struct {
+	Status int
+	// Code is the machine-readable error code, empty when the server
+	// sent none.
+	Code    string
+	Message string
+	Fields  []apierr.FieldError
+	// RetryAfter is the wait the server asked for, if any.
+	RetryAfter time.Duration
+
+	err error
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("url-shortener: %d: %s", e.Status, e.Message)
+	}
+	return fmt.Sprintf("url-shortener: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// Unwrap returns the server error the code stands for, or nil.
+func (e *Error) Unwrap() error { return e.err }
+
+// Temporary reports whether the request may succeed when retried.
+func (e *Error) Temporary() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= http.StatusInternalServerError
+}
+
+// errorResponse is the body of an error response.
+type errorResponse struct {
+	Code   string              `json:"code"`
+	Error  string              `json:"error"`
+	Fields []apierr.FieldError `json:"fields"`
+}
+
+func newError(status int, body errorResponse, retryAfter time.Duration) *Error {
+	e := &Error{
+		Status:     status,
+		Code:       body.Code,
+		Message:    body.Error,
+		Fields:     body.Fields,
+		RetryAfter: retryAfter,
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(status)
+	}
+
+	e.err = apierr.ByCode(body.Code)
+	if e.err == nil {
+		switch {
+		case status == http.StatusTooManyRequests:
+			e.err = ErrTooManyRequests
+		case status == http.StatusUnauthorized:
+			e.err = ErrUnauthorized
+		case status >= http.StatusInternalServerError:
+			e.err = ErrInternalServer
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,301 @@
+// Package domains knows the hosts links are served on. Each domain has
+// its own aliases, alias length and not-found page; requests on other
+// hosts use the default namespace. Domains are configured or registered
+// at runtime, registered ones are shared by every instance through the
+// store.
+//
+//	reg := domains.New([]database.Domain{{Name: "go.brand-a.com"}}, db, 0, log)
+//	if d, ok := reg.Lookup(ctx, r.Host); ok {
+//		ctx = database.WithDomain(ctx, d)
+//	}
+package domains
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+)
+
+const (
+	// DefaultRefresh is how long registered domains are cached without a
+	// configured interval.
+	DefaultRefresh = 30 * time.Second
+	// MaxAliasLength bounds the alias length of a domain.
+	MaxAliasLength = 64
+)
+
+var (
+	// ErrInvalid is matched by every InvalidError.
+	ErrInvalid = errors.New("invalid domain")
+	// ErrReadOnly means domains cannot be registered without a store.
+	ErrReadOnly = errors.New("domains cannot be registered without a store")
+)
+
+// InvalidError tells which field of a domain is invalid.
+type InvalidError struct {
+	Field  string
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrInvalid, e.Field, e.Reason)
+}
+
+func (e *InvalidError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Store keeps the registered domains. database.Database implementations
+// satisfy it.
+type Store interface {
+	SaveDomain(ctx context.Context, d database.Domain) error
+	ListDomains(ctx context.Context) ([]database.Domain, error)
+}
+
+// Registry looks domains up by host. It is safe for concurrent use.
+type Registry struct {
+	static  map[string]database.Domain
+	store   Store
+	refresh time.Duration
+	log     *slog.Logger
+	now     func() time.Time
+
+	mu     sync.Mutex
+	stored map[string]database.Domain
+	loaded time.Time
+}
+
+// New returns a Registry of the static domains and the ones registered in
+// store. store may be nil, then no domains can be registered; registered
+// domains are read again after refresh, DefaultRefresh when zero; log may
+// be nil. Static domains must be valid, see Validate.
+func New(static []database.Domain, store Store, refresh time.Duration, log *slog.Logger) *Registry {
+	if refresh <= 0 {
+		refresh = DefaultRefresh
+	}
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	r := &Registry{
+		static:  make(map[string]database.Domain, len(static)),
+		store:   store,
+		refresh: refresh,
+		log:     log,
+		now:     time.Now,
+	}
+
+	for _, d := range static {
+		d.Name = Normalize(d.Name)
+		r.static[d.Name] = d
+	}
+
+	return r
+}
+
+// Lookup returns the domain served on host, which may carry a port.
+func (r *Registry) Lookup(ctx context.Context, host string) (database.Domain, bool) {
+	name := Normalize(host)
+	if name == "" {
+		return database.Domain{}, false
+	}
+
+	if d, ok := r.static[name]; ok {
+		return d, true
+	}
+
+	d, ok := r.registered(ctx)[name]
+	return d, ok
+}
+
+// Serves reports whether host is one of the domains. It lets a
+// policy.Policy reject links to the short links of every domain.
+func (r *Registry) Serves(ctx context.Context, host string) bool {
+	_, ok := r.Lookup(ctx, host)
+	return ok
+}
+
+// List returns every domain ordered by name.
+func (r *Registry) List(ctx context.Context) ([]database.Domain, error) {
+	const fn = "domains.(*Registry).List"
+
+	list := make([]database.Domain, 0, len(r.static))
+	for _, d := range r.static {
+		list = append(list, d)
+	}
+
+	if r.store != nil {
+		stored, err := r.store.ListDomains(ctx)
+		if err != nil {
+			return nil, wraper.Wrap(fn, err)
+		}
+		list = append(list, stored...)
+	}
+
+	slices.SortFunc(list, func(a, b database.Domain) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return list, nil
+}
+
+// Register validates and stores d. It returns database.ErrDomainExist for
+// configured and registered domains.
+func (r *Registry) Register(ctx context.Context, d database.Domain) (database.Domain, error) {
+	const fn = "domains.(*Registry).Register"
+
+	wp := wraper.New(fn)
+
+	if r.store == nil {
+		return database.Domain{}, wp.Wrap(ErrReadOnly)
+	}
+
+	d.Name = Normalize(d.Name)
+	d.CreatedAt = nil
+
+	if err := Validate(d); err != nil {
+		return database.Domain{}, err
+	}
+
+	if _, ok := r.static[d.Name]; ok {
+		return database.Domain{}, wp.Wrap(database.ErrDomainExist)
+	}
+
+	if err := r.store.SaveDomain(ctx, d); err != nil {
+		return database.Domain{}, wp.Wrap(err)
+	}
+
+	// read again on the next lookup, so that this instance serves it now
+	r.mu.Lock()
+	r.loaded = time.Time{}
+	r.mu.Unlock()
+
+	return d, nil
+}
+
+// registered returns the registered domains, read from the store when
+// the cached ones are stale. On errors the stale ones are kept.
+func (r *Registry) registered(ctx context.Context) map[string]database.Domain {
+	if r.store == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if !r.loaded.IsZero() && now.Sub(r.loaded) < r.refresh {
+		return r.stored
+	}
+
+	// retry after refresh on errors too, a broken store is not hit by
+	// every request
+	r.loaded = now
+
+	list, err := r.store.ListDomains(ctx)
+	if err != nil {
+		r.log.Error("failed to load domains", sl.Error(err))
+		return r.stored
+	}
+
+	stored := make(map[string]database.Domain, len(list))
+	for _, d := range list {
+		stored[d.Name] = d
+	}
+	r.stored = stored
+
+	return stored
+}
+
+// Normalize returns host without port and trailing dot, in lower case.
+func Normalize(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(host, ".")
+}
+
+// Validate reports the first invalid field of d as an *InvalidError. The
+// name is expected to be normalized.
+func Validate(d database.Domain) error {
+	if !validName(d.Name) {
+		return &InvalidError{Field: "name", Reason: "must be a host name such as go.example.com"}
+	}
+
+	if d.AliasLength < 0 || d.AliasLength > MaxAliasLength {
+		return &InvalidError{Field: "alias_length", Reason: fmt.Sprintf("must be from 0 to %d", MaxAliasLength)}
+	}
+
+	if d.NotFoundURL != "" {
+		u, err := url.Parse(d.NotFoundURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &InvalidError{Field: "not_found_url", Reason: "must be an http(s) url"}
+		}
+	}
+
+	return nil
+}
+
+// validName reports whether name is a host name of at least two labels.
+func validName(name string) bool {
+	if len(name) > 253 || !strings.Contains(name, ".") {
+		return false
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, c := range label {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Config lists the domains served besides the ones registered in the
+// store.
+type Config struct {
+	// Domains are normalized and validated by Load.
+	Domains []database.Domain
+	// Refresh is how long registered domains are cached, DefaultRefresh
+	// when zero.
+	Refresh time.Duration
+}
+
+// Load returns the Registry of the configured domains and the ones
+// registered in store. It fails on invalid configured domains.
+func Load(cfg Config, store Store, log *slog.Logger) (*Registry, error) {
+	const fn = "domains.Load"
+
+	static := make([]database.Domain, 0, len(cfg.Domains))
+	for _, d := range cfg.Domains {
+		name := d.Name
+		d.Name = Normalize(d.Name)
+
+		if err := Validate(d); err != nil {
+			return nil, wraper.Wrapf(fn, err, "domain %q", name)
+		}
+		static = append(static, d)
+	}
+
+	return New(static, store, cfg.Refresh, log), nil
+}
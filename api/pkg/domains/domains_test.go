@@ -0,0 +1,226 @@
+package domains
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	mu      sync.Mutex
+	domains []database.Domain
+	lists   int
+	err     error
+}
+
+func (s *memStore) SaveDomain(ctx context.Context, d database.Domain) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, have := range s.domains {
+		if have.Name == d.Name {
+			return database.ErrDomainExist
+		}
+	}
+	s.domains = append(s.domains, d)
+	return nil
+}
+
+func (s *memStore) ListDomains(ctx context.Context) ([]database.Domain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lists++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return append([]database.Domain(nil), s.domains...), nil
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"go.example.com":      "go.example.com",
+		"Go.Example.COM:8080": "go.example.com",
+		"go.example.com.":     "go.example.com",
+		" go.example.com ":    "go.example.com",
+		"[::1]:8080":          "::1",
+		"localhost":           "localhost",
+		"":                    "",
+	}
+
+	for host, want := range tests {
+		assert.Equal(t, want, Normalize(host), host)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain database.Domain
+		field  string
+	}{
+		{name: "valid", domain: database.Domain{Name: "go.brand-a.com", AliasLength: 4, NotFoundURL: "https://brand-a.com/404"}},
+		{name: "single label", domain: database.Domain{Name: "localhost"}, field: "name"},
+		{name: "empty label", domain: database.Domain{Name: "go..com"}, field: "name"},
+		{name: "hyphen", domain: database.Domain{Name: "-go.com"}, field: "name"},
+		{name: "invalid character", domain: database.Domain{Name: "go_links.com"}, field: "name"},
+		{name: "negative alias length", domain: database.Domain{Name: "go.com", AliasLength: -1}, field: "alias_length"},
+		{name: "long alias length", domain: database.Domain{Name: "go.com", AliasLength: MaxAliasLength + 1}, field: "alias_length"},
+		{name: "not found url", domain: database.Domain{Name: "go.com", NotFoundURL: "javascript:alert(1)"}, field: "not_found_url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.domain)
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var invalid *InvalidError
+			require.ErrorAs(t, err, &invalid)
+			assert.Equal(t, tt.field, invalid.Field)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	store := &memStore{domains: []database.Domain{{Name: "go.brand-b.com", AliasLength: 8}}}
+	r := New([]database.Domain{{Name: "Go.Brand-A.com", AliasLength: 4}}, store, time.Minute, nil)
+
+	d, ok := r.Lookup(context.Background(), "go.brand-a.com:443")
+	require.True(t, ok)
+	assert.Equal(t, database.Domain{Name: "go.brand-a.com", AliasLength: 4}, d)
+
+	d, ok = r.Lookup(context.Background(), "GO.BRAND-B.COM")
+	require.True(t, ok)
+	assert.Equal(t, 8, d.AliasLength)
+
+	_, ok = r.Lookup(context.Background(), "short.example.com")
+	assert.False(t, ok)
+
+	// registered domains are cached until refresh
+	assert.Equal(t, 1, store.lists)
+}
+
+func TestRegistry_Refresh(t *testing.T) {
+	store := &memStore{}
+	r := New(nil, store, time.Minute, nil)
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	_, ok := r.Lookup(context.Background(), "go.brand-b.com")
+	assert.False(t, ok)
+
+	// registered by another instance
+	store.domains = append(store.domains, database.Domain{Name: "go.brand-b.com"})
+
+	_, ok = r.Lookup(context.Background(), "go.brand-b.com")
+	assert.False(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = r.Lookup(context.Background(), "go.brand-b.com")
+	assert.True(t, ok)
+
+	// stale domains are served while the store fails
+	store.err = errors.New("connection refused")
+	now = now.Add(time.Minute)
+
+	_, ok = r.Lookup(context.Background(), "go.brand-b.com")
+	assert.True(t, ok)
+	assert.Equal(t, 3, store.lists)
+
+	// and the store is not asked again before refresh
+	_, ok = r.Lookup(context.Background(), "go.brand-b.com")
+	assert.True(t, ok)
+	assert.Equal(t, 3, store.lists)
+}
+
+func TestRegistry_Register(t *testing.T) {
+	store := &memStore{}
+	r := New([]database.Domain{{Name: "go.brand-a.com"}}, store, time.Hour, nil)
+
+	// cache the empty list
+	_, ok := r.Lookup(context.Background(), "go.brand-b.com")
+	require.False(t, ok)
+
+	now := time.Now()
+	d, err := r.Register(context.Background(), database.Domain{Name: "GO.brand-b.com.", AliasLength: 5, CreatedAt: &now})
+	require.NoError(t, err)
+	assert.Equal(t, database.Domain{Name: "go.brand-b.com", AliasLength: 5}, d)
+
+	// served at once by the registering instance
+	_, ok = r.Lookup(context.Background(), "go.brand-b.com")
+	assert.True(t, ok)
+
+	_, err = r.Register(context.Background(), database.Domain{Name: "go.brand-b.com"})
+	assert.ErrorIs(t, err, database.ErrDomainExist)
+
+	_, err = r.Register(context.Background(), database.Domain{Name: "go.brand-a.com"})
+	assert.ErrorIs(t, err, database.ErrDomainExist)
+
+	_, err = r.Register(context.Background(), database.Domain{Name: "brand"})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	list, err := r.List(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []database.Domain{
+		{Name: "go.brand-a.com"},
+		{Name: "go.brand-b.com", AliasLength: 5},
+	}, list)
+}
+
+func TestRegistry_Serves(t *testing.T) {
+	ctx := context.Background()
+
+	r := New([]database.Domain{{Name: "go.brand-a.com"}}, &memStore{}, time.Hour, nil)
+	p := policy.New(policy.Config{SelfHosts: []string{"sho.rt"}}, nil).WithSelfHosts(r.Serves)
+
+	assert.Equal(t, policy.ReasonSelfReference, policy.Reason(p.Check(ctx, "https://sho.rt/abc")))
+	assert.Equal(t, policy.ReasonSelfReference, policy.Reason(p.Check(ctx, "https://go.brand-a.com/abc")))
+	assert.NoError(t, p.Check(ctx, "https://go.brand-b.com/abc"))
+
+	// domains registered at runtime are rejected at once
+	_, err := r.Register(ctx, database.Domain{Name: "go.brand-b.com"})
+	require.NoError(t, err)
+
+	assert.True(t, r.Serves(ctx, "GO.brand-b.com:443"))
+	assert.Equal(t, policy.ReasonSelfReference, policy.Reason(p.Check(ctx, "https://go.brand-b.com/abc")))
+}
+
+func TestRegistry_ReadOnly(t *testing.T) {
+	r := New([]database.Domain{{Name: "go.brand-a.com"}}, nil, 0, nil)
+
+	_, err := r.Register(context.Background(), database.Domain{Name: "go.brand-b.com"})
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	_, ok := r.Lookup(context.Background(), "go.brand-a.com")
+	assert.True(t, ok)
+
+	list, err := r.List(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestLoad(t *testing.T) {
+	r, err := Load(Config{
+		Domains: []database.Domain{{Name: "Go.Brand-A.com", AliasLength: 4, NotFoundURL: "https://brand-a.com"}},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	d, ok := r.Lookup(context.Background(), "go.brand-a.com")
+	require.True(t, ok)
+	assert.Equal(t, "https://brand-a.com", d.NotFoundURL)
+
+	_, err = Load(Config{Domains: []database.Domain{{Name: "brand"}}}, nil, nil)
+	assert.ErrorIs(t, err, ErrInvalid)
+}
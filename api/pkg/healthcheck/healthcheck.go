@@ -0,0 +1,336 @@
+// Package healthcheck checks link destinations in the background. Every
+// run picks the least recently checked links, requests them with bounded
+// concurrency and a delay between requests to the same host, and records
+// the outcome on the link. Links failing too often can be disabled.
+//
+//	checker := healthcheck.New(db, svc, nil, healthcheck.Config{DisableAfter: 5}, log)
+//	go checker.Run(ctx)
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+)
+
+const (
+	DefaultInterval    = time.Minute
+	DefaultRecheck     = 24 * time.Hour
+	DefaultBatchSize   = 100
+	DefaultConcurrency = 8
+	DefaultHostDelay   = time.Second
+	DefaultTimeout     = 10 * time.Second
+	DefaultUserAgent   = "url-shortener-healthcheck/1.0"
+
+	// maxDrainSize bounds the body read of GET responses, so that the
+	// connection can be reused.
+	maxDrainSize = 64 << 10
+)
+
+// Store keeps the links and their check results. database.Database
+// implementations satisfy it.
+type Store interface {
+	LinksToCheck(ctx context.Context, before time.Time, limit int) ([]database.Link, error)
+	RecordCheck(ctx context.Context, id int64, res database.CheckResult, disableAfter int) (bool, error)
+}
+
+// Evicter drops cached urls, so that disabled links stop redirecting.
+// shortener.Shortener satisfies it.
+type Evicter interface {
+	Evict(ctx context.Context, aliases ...string)
+}
+
+// Config tunes a Checker. The zero value uses the defaults and never
+// disables links.
+type Config struct {
+	// Interval is how often Run checks a batch.
+	Interval time.Duration
+	// Recheck is how long a checked link is left alone.
+	Recheck time.Duration
+	// BatchSize is the most links checked by one run.
+	BatchSize int
+	// Concurrency is the most requests in flight.
+	Concurrency int
+	// HostDelay is the least time between two requests to the same host,
+	// none when negative.
+	HostDelay time.Duration
+	// Timeout bounds the check of one link.
+	Timeout time.Duration
+	// DisableAfter disables links failing that many checks in a row,
+	// never when zero.
+	DisableAfter int
+	UserAgent    string
+}
+
+// Checker checks links. It is safe for concurrent use, though runs are
+// meant to follow each other.
+type Checker struct {
+	store   Store
+	evicter Evicter
+	client  *http.Client
+	cfg     Config
+	log     *slog.Logger
+	now     func() time.Time
+}
+
+// New returns a Checker over store. evicter may be nil when nothing is
+// cached; client may be nil, then policy.NewClient is used, which refuses
+// internal addresses; log may be nil.
+func New(store Store, evicter Evicter, client *http.Client, cfg Config, log *slog.Logger) *Checker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Recheck <= 0 {
+		cfg.Recheck = DefaultRecheck
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultConcurrency
+	}
+	if cfg.HostDelay == 0 {
+		cfg.HostDelay = DefaultHostDelay
+	}
+	if cfg.HostDelay < 0 {
+		cfg.HostDelay = 0
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent
+	}
+	if client == nil {
+		client = policy.NewClient()
+	}
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	return &Checker{
+		store:   store,
+		evicter: evicter,
+		client:  client,
+		cfg:     cfg,
+		log:     log,
+		now:     time.Now,
+	}
+}
+
+// Run checks a batch every Interval until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			c.log.Error("link health check", sl.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce checks one batch of links due for a check and returns how many
+// were checked.
+func (c *Checker) RunOnce(ctx context.Context) (int, error) {
+	const fn = "healthcheck.(*Checker).RunOnce"
+
+	wp := wraper.New(fn)
+
+	links, err := c.store.LinksToCheck(ctx, c.now().Add(-c.cfg.Recheck), c.cfg.BatchSize)
+	if err != nil {
+		return 0, wp.Wrap(err)
+	}
+
+	var (
+		jobs  = make(chan database.Link)
+		hosts = newHosts(c.cfg.HostDelay, c.now)
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		done  int
+		errs  []error
+	)
+
+	for range min(c.cfg.Concurrency, len(links)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for link := range jobs {
+				err := c.checkLink(ctx, hosts, link)
+
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else {
+					done++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+send:
+	for _, link := range links {
+		select {
+		case jobs <- link:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return done, wp.Wrap(err)
+	}
+
+	return done, wp.Wrap(errors.Join(errs...))
+}
+
+// checkLink checks the destination of link and records the outcome.
+func (c *Checker) checkLink(ctx context.Context, hosts *hosts, link database.Link) error {
+	if err := hosts.wait(ctx, host(link.URL)); err != nil {
+		return err
+	}
+
+	status, err := c.Check(ctx, link.URL)
+	res := database.CheckResult{
+		Status:    status,
+		CheckedAt: c.now(),
+		Failed:    err != nil || status >= http.StatusBadRequest,
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		c.log.Debug("destination unreachable",
+			slog.String("alias", link.Alias),
+			slog.String("url", link.URL),
+			sl.Error(err),
+		)
+	}
+
+	disabled, err := c.store.RecordCheck(ctx, link.ID, res, c.cfg.DisableAfter)
+	if err != nil {
+		return err
+	}
+
+	if disabled {
+		c.log.Warn("link disabled after failed health checks",
+			slog.String("alias", link.Alias),
+			slog.String("url", link.URL),
+			slog.Int("failures", link.CheckFailures+1),
+			slog.Int("status", status),
+		)
+
+		if c.evicter != nil {
+			c.evicter.Evict(database.WithDomain(ctx, database.Domain{Name: link.Domain}), link.Alias)
+		}
+	}
+
+	return nil
+}
+
+// Check requests url with HEAD, and with GET when HEAD fails, as some
+// servers do not support it. It returns the status of the last response.
+func (c *Checker) Check(ctx context.Context, url string) (int, error) {
+	status, err := c.request(ctx, http.MethodHead, url)
+	if err == nil && status < http.StatusBadRequest {
+		return status, nil
+	}
+
+	if ctx.Err() != nil {
+		return status, err
+	}
+
+	return c.request(ctx, http.MethodGet, url)
+}
+
+func (c *Checker) request(ctx context.Context, method, url string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", c.cfg.UserAgent)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(res.Body, maxDrainSize)) //nolint:errcheck
+
+	return res.StatusCode, nil
+}
+
+// host returns the key requests are spaced by.
+func host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// hosts spaces the requests to every host by a delay.
+type hosts struct {
+	delay time.Duration
+	now   func() time.Time
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newHosts(delay time.Duration, now func() time.Time) *hosts {
+	return &hosts{delay: delay, now: now, next: make(map[string]time.Time)}
+}
+
+// wait blocks until a request to host may be sent, reserving the slot.
+func (h *hosts) wait(ctx context.Context, host string) error {
+	h.mu.Lock()
+	now := h.now()
+	at := h.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	h.next[host] = at.Add(h.delay)
+	h.mu.Unlock()
+
+	if !at.After(now) {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
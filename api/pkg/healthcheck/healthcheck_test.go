@@ -0,0 +1,230 @@
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore keeps links in memory, disabling them like the database does.
+type memStore struct {
+	mu       sync.Mutex
+	links    []database.Link
+	disabled map[int64]bool
+	results  map[int64]database.CheckResult
+}
+
+func newMemStore(urls ...string) *memStore {
+	s := &memStore{
+		disabled: make(map[int64]bool),
+		results:  make(map[int64]database.CheckResult),
+	}
+	for i, url := range urls {
+		s.links = append(s.links, database.Link{ID: int64(i + 1), URL: url, Alias: "alias" + string(rune('a'+i))})
+	}
+	return s
+}
+
+func (s *memStore) LinksToCheck(_ context.Context, _ time.Time, limit int) ([]database.Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var links []database.Link
+	for _, link := range s.links {
+		if !s.disabled[link.ID] && len(links) < limit {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (s *memStore) RecordCheck(_ context.Context, id int64, res database.CheckResult, disableAfter int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results[id] = res
+
+	link := &s.links[id-1]
+	if !res.Failed {
+		link.CheckFailures = 0
+		return false, nil
+	}
+
+	link.CheckFailures++
+	if disableAfter > 0 && link.CheckFailures >= disableAfter && !s.disabled[id] {
+		s.disabled[id] = true
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *memStore) result(id int64) database.CheckResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.results[id]
+}
+
+type evicter struct {
+	mu      sync.Mutex
+	aliases []string
+}
+
+func (e *evicter) Evict(_ context.Context, aliases ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.aliases = append(e.aliases, aliases...)
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChecker_RunOnce(t *testing.T) {
+	srv := newTestServer(t)
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	store := newMemStore(srv.URL+"/ok", srv.URL+"/no-head", srv.URL+"/moved", srv.URL+"/gone", closed.URL+"/")
+
+	c := New(store, nil, srv.Client(), Config{HostDelay: -1}, nil)
+
+	n, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	tests := []struct {
+		id     int64
+		status int
+		failed bool
+	}{
+		{id: 1, status: http.StatusOK},
+		{id: 2, status: http.StatusOK},
+		{id: 3, status: http.StatusOK},
+		{id: 4, status: http.StatusGone, failed: true},
+		{id: 5, status: 0, failed: true},
+	}
+
+	for _, tt := range tests {
+		res := store.result(tt.id)
+		assert.Equal(t, tt.status, res.Status, "link %d", tt.id)
+		assert.Equal(t, tt.failed, res.Failed, "link %d", tt.id)
+		assert.False(t, res.CheckedAt.IsZero(), "link %d", tt.id)
+	}
+}
+
+func TestChecker_DisableAfter(t *testing.T) {
+	srv := newTestServer(t)
+
+	store := newMemStore(srv.URL+"/gone", srv.URL+"/ok")
+	ev := &evicter{}
+
+	c := New(store, ev, srv.Client(), Config{HostDelay: -1, DisableAfter: 2}, nil)
+
+	_, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, ev.aliases)
+
+	_, err = c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aliasa"}, ev.aliases)
+
+	// disabled links are not checked anymore
+	n, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestChecker_Concurrency(t *testing.T) {
+	var inFlight, most atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	urls := make([]string, 12)
+	for i := range urls {
+		urls[i] = srv.URL
+	}
+
+	c := New(newMemStore(urls...), nil, srv.Client(), Config{HostDelay: -1, Concurrency: 3}, nil)
+
+	n, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, len(urls), n)
+	assert.LessOrEqual(t, most.Load(), int32(3))
+	assert.Greater(t, most.Load(), int32(1))
+}
+
+func TestChecker_HostDelay(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	const delay = 30 * time.Millisecond
+
+	c := New(newMemStore(srv.URL, srv.URL, srv.URL), nil, srv.Client(), Config{HostDelay: delay, Concurrency: 3}, nil)
+
+	_, err := c.RunOnce(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, times, 3)
+	for i := 1; i < len(times); i++ {
+		// a little slack for the timer
+		assert.GreaterOrEqual(t, times[i].Sub(times[i-1]), delay-5*time.Millisecond)
+	}
+}
+
+func TestChecker_Cancel(t *testing.T) {
+	srv := newTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := New(newMemStore(srv.URL+"/ok"), nil, srv.Client(), Config{}, nil)
+
+	_, err := c.RunOnce(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
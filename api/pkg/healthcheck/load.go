@@ -0,0 +1,21 @@
+package healthcheck
+
+import (
+	"log/slog"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+)
+
+// Load returns the Checker configured by cfg. evicter and log may be nil.
+func Load(cfg *config.HealthCheckConfig, store Store, evicter Evicter, log *slog.Logger) *Checker {
+	return New(store, evicter, nil, Config{
+		Interval:     cfg.Interval,
+		Recheck:      cfg.Recheck,
+		BatchSize:    cfg.BatchSize,
+		Concurrency:  cfg.Concurrency,
+		HostDelay:    cfg.HostDelay,
+		Timeout:      cfg.Timeout,
+		DisableAfter: cfg.DisableAfter,
+		UserAgent:    cfg.UserAgent,
+	}, log)
+}
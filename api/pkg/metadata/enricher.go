@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+)
+
+const (
+	DefaultWorkers   = 2
+	DefaultQueueSize = 1000
+)
+
+// Store keeps the metadata of links. database.Database implementations
+// satisfy it.
+type Store interface {
+	SaveMetadata(ctx context.Context, alias, url string, m database.Metadata) error
+}
+
+// Evictor drops cached links. shortener.Shortener satisfies it.
+type Evictor interface {
+	Evict(ctx context.Context, aliases ...string)
+}
+
+type job struct {
+	domain database.Domain
+	alias  string
+	url    string
+}
+
+// Enricher fetches the metadata of links in the background. Enqueue never
+// blocks, so saving a link does not wait for its page.
+type Enricher struct {
+	store   Store
+	fetcher *Fetcher
+	workers int
+	queue   chan job
+	evictor Evictor
+	log     *slog.Logger
+}
+
+// NewEnricher returns an Enricher storing what fetcher fetches in store.
+// log may be nil.
+func NewEnricher(store Store, fetcher *Fetcher, cfg Config, log *slog.Logger) *Enricher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	return &Enricher{
+		store:   store,
+		fetcher: fetcher,
+		workers: cfg.Workers,
+		queue:   make(chan job, cfg.QueueSize),
+		log:     log,
+	}
+}
+
+// UseEvictor makes e evict the links it saved the metadata of from the
+// cache of v, so that their pages show it. Call it before Run.
+func (e *Enricher) UseEvictor(v Evictor) {
+	e.evictor = v
+}
+
+// Enqueue schedules fetching the page of the link saved under alias in the
+// domain of ctx. The link is dropped when the queue is full.
+func (e *Enricher) Enqueue(ctx context.Context, alias, url string) {
+	select {
+	case e.queue <- job{domain: database.DomainFrom(ctx), alias: alias, url: url}:
+	default:
+		e.log.Warn("metadata queue is full, link dropped", slog.String("alias", alias))
+	}
+}
+
+// Run fetches enqueued links until ctx is done.
+func (e *Enricher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for range e.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-e.queue:
+					e.enrich(ctx, j)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (e *Enricher) enrich(ctx context.Context, j job) {
+	log := e.log.With(slog.String("alias", j.alias), slog.String("url", j.url))
+
+	m, err := e.fetcher.Fetch(ctx, j.url)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Debug("failed to fetch metadata", sl.Error(err))
+		}
+		return
+	}
+
+	ctx = database.WithDomain(ctx, j.domain)
+
+	if err := e.store.SaveMetadata(ctx, j.alias, j.url, m); err != nil {
+		// links updated or deleted meanwhile are not found
+		log.Debug("failed to save metadata", sl.Error(err))
+		return
+	}
+
+	if e.evictor != nil {
+		e.evictor.Evict(ctx, j.alias)
+	}
+
+	log.Debug("metadata saved", slog.String("title", m.Title))
+}
+
+// Load returns the Enricher configured by cfg. log may be nil.
+func Load(cfg *config.MetadataConfig, store Store, log *slog.Logger) *Enricher {
+	c := Config{
+		Timeout:     cfg.Timeout,
+		MaxBodySize: cfg.MaxBodySize,
+		UserAgent:   cfg.UserAgent,
+		Workers:     cfg.Workers,
+		QueueSize:   cfg.QueueSize,
+	}
+
+	return NewEnricher(store, NewFetcher(c, nil), c, log)
+}
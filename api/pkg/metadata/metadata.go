@@ -0,0 +1,236 @@
+// Package metadata describes the pages links point to. Fetcher reads the
+// title and the Open Graph tags of a page with a size and time limited
+// client that refuses internal addresses; Enricher fetches them in the
+// background and stores them with the link.
+//
+//	enricher := metadata.NewEnricher(db, metadata.NewFetcher(metadata.Config{}, nil), metadata.Config{}, log)
+//	go enricher.Run(ctx)
+//	svc := shortener.New(store, cache, shortener.Config{Enricher: enricher}, log)
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"golang.org/x/net/html"
+)
+
+const (
+	DefaultTimeout     = 5 * time.Second
+	DefaultMaxBodySize = 512 << 10
+	DefaultUserAgent   = "url-shortener-metadata/1.0"
+
+	maxTitleLength       = 512
+	maxDescriptionLength = 1024
+	maxImageLength       = 2048
+)
+
+var ErrUnexpectedStatus = errors.New("unexpected status of the page")
+
+// Config tunes fetching. The zero value uses the defaults.
+type Config struct {
+	// Timeout bounds the fetch of one page.
+	Timeout time.Duration
+	// MaxBodySize is the most bytes read from a page. The head of a page
+	// is expected within it.
+	MaxBodySize int64
+	UserAgent   string
+	// Workers is how many pages an Enricher fetches at once.
+	Workers int
+	// QueueSize is how many links an Enricher holds before it drops new
+	// ones.
+	QueueSize int
+}
+
+// Fetcher fetches the metadata of pages. It is safe for concurrent use.
+type Fetcher struct {
+	client *http.Client
+	cfg    Config
+}
+
+// NewFetcher returns a Fetcher. client may be nil, then policy.NewClient is
+// used, which refuses internal addresses.
+func NewFetcher(cfg Config, client *http.Client) *Fetcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = DefaultMaxBodySize
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent
+	}
+	if client == nil {
+		client = policy.NewClient()
+	}
+
+	return &Fetcher{client: client, cfg: cfg}
+}
+
+// Fetch returns the metadata of the page at url. Pages that are no HTML
+// have empty metadata.
+func (f *Fetcher) Fetch(ctx context.Context, url string) (database.Metadata, error) {
+	const fn = "metadata.(*Fetcher).Fetch"
+
+	wp := wraper.New(fn)
+
+	ctx, cancel := context.WithTimeout(ctx, f.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return database.Metadata{}, wp.Wrap(err)
+	}
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return database.Metadata{}, wp.Wrap(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return database.Metadata{}, wp.Wrap(fmt.Errorf("%w: %s", ErrUnexpectedStatus, res.Status))
+	}
+
+	m := database.Metadata{FetchedAt: time.Now()}
+
+	if !isHTML(res.Header.Get("Content-Type")) {
+		return m, nil
+	}
+
+	parse(io.LimitReader(res.Body, f.cfg.MaxBodySize), &m)
+
+	// relative images are relative to the page after redirects
+	m.OGImage = absolute(res.Request.URL, m.OGImage)
+
+	return m, nil
+}
+
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// parse reads the title and the Open Graph tags from the head of a page.
+// Broken markup ends the parse, what was read so far is kept.
+func parse(r io.Reader, m *database.Metadata) {
+	z := html.NewTokenizer(r)
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+
+			switch string(name) {
+			case "body":
+				return
+			case "title":
+				if m.Title == "" && z.Next() == html.TextToken {
+					m.Title = clean(string(z.Text()), maxTitleLength)
+				}
+			case "meta":
+				if hasAttr {
+					meta(z, m)
+				}
+			}
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return
+			}
+		}
+	}
+}
+
+// meta reads an Open Graph tag. Pages use both property and name.
+func meta(z *html.Tokenizer, m *database.Metadata) {
+	var key, content string
+
+	for {
+		k, v, more := z.TagAttr()
+
+		switch string(k) {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(string(v))
+			}
+		case "content":
+			content = string(v)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	switch key {
+	case "og:title":
+		if m.OGTitle == "" {
+			m.OGTitle = clean(content, maxTitleLength)
+		}
+	case "og:description":
+		if m.OGDescription == "" {
+			m.OGDescription = clean(content, maxDescriptionLength)
+		}
+	case "og:image", "og:image:url", "og:image:secure_url":
+		if m.OGImage == "" {
+			m.OGImage = strings.TrimSpace(content)
+		}
+	}
+}
+
+// clean collapses white space and cuts s to at most max bytes of valid
+// UTF-8.
+func clean(s string, max int) string {
+	s = strings.Join(strings.Fields(strings.ToValidUTF8(s, "")), " ")
+	if len(s) <= max {
+		return s
+	}
+
+	s = s[:max]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// absolute resolves ref against base and keeps only http(s) urls.
+func absolute(base *neturl.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	s := u.String()
+	if len(s) > maxImageLength {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,177 @@
+package metadata_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/database"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/metadata"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const page = `<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>
+    Go &amp; you
+  </title>
+  <meta property="og:title" content="The Go Programming Language">
+  <meta name="og:description" content="Build simple, secure, scalable systems.">
+  <meta property="og:image" content="/images/gopher.png">
+</head>
+<body>
+  <meta property="og:title" content="ignored">
+</body>
+</html>`
+
+func newTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.7"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><!--" + strings.Repeat("x", 4096) + "--><title>late</title></head></html>"))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	srv := newTestServer(t)
+	f := metadata.NewFetcher(metadata.Config{MaxBodySize: 1024}, srv.Client())
+
+	for _, path := range []string{"/page", "/moved"} {
+		m, err := f.Fetch(context.Background(), srv.URL+path)
+		require.NoError(t, err, path)
+
+		assert.Equal(t, "Go & you", m.Title, path)
+		assert.Equal(t, "The Go Programming Language", m.OGTitle, path)
+		assert.Equal(t, "Build simple, secure, scalable systems.", m.OGDescription, path)
+		assert.Equal(t, srv.URL+"/images/gopher.png", m.OGImage, path)
+		assert.False(t, m.FetchedAt.IsZero(), path)
+	}
+}
+
+func TestFetcher_NoMetadata(t *testing.T) {
+	srv := newTestServer(t)
+	f := metadata.NewFetcher(metadata.Config{MaxBodySize: 1024}, srv.Client())
+
+	// pages without metadata are fetched all the same
+	for _, path := range []string{"/pdf", "/large"} {
+		m, err := f.Fetch(context.Background(), srv.URL+path)
+		require.NoError(t, err, path)
+		assert.Equal(t, database.Metadata{FetchedAt: m.FetchedAt}, m, path)
+	}
+
+	_, err := f.Fetch(context.Background(), srv.URL+"/missing")
+	assert.ErrorIs(t, err, metadata.ErrUnexpectedStatus)
+}
+
+func TestFetcher_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	f := metadata.NewFetcher(metadata.Config{Timeout: 20 * time.Millisecond}, srv.Client())
+
+	_, err := f.Fetch(context.Background(), srv.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFetcher_RefusesPrivateAddresses(t *testing.T) {
+	srv := newTestServer(t)
+
+	// the default client, the test server listens on a loopback address
+	f := metadata.NewFetcher(metadata.Config{}, nil)
+
+	_, err := f.Fetch(context.Background(), srv.URL+"/page")
+	assert.ErrorIs(t, err, policy.ErrRejected)
+	assert.Equal(t, policy.ReasonPrivateAddress, policy.Reason(err))
+}
+
+type memStore struct {
+	mu    sync.Mutex
+	saved map[string]database.Metadata
+	// evicted are the links evicted from the cache
+	evicted []string
+}
+
+func (s *memStore) SaveMetadata(ctx context.Context, alias, url string, m database.Metadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saved[database.DomainFrom(ctx).Name+" "+alias+" "+url] = m
+	return nil
+}
+
+func (s *memStore) Evict(ctx context.Context, aliases ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, alias := range aliases {
+		s.evicted = append(s.evicted, database.DomainFrom(ctx).Name+" "+alias)
+	}
+}
+
+func (s *memStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.saved)
+}
+
+func TestEnricher(t *testing.T) {
+	srv := newTestServer(t)
+
+	store := &memStore{saved: make(map[string]database.Metadata)}
+	cfg := metadata.Config{QueueSize: 2}
+	e := metadata.NewEnricher(store, metadata.NewFetcher(cfg, srv.Client()), cfg, nil)
+	e.UseEvictor(store)
+
+	brand := database.WithDomain(context.Background(), database.Domain{Name: "go.brand-a.com"})
+
+	e.Enqueue(context.Background(), "go", srv.URL+"/page")
+	e.Enqueue(brand, "pdf", srv.URL+"/pdf")
+	// the queue is full, Enqueue drops rather than blocks
+	e.Enqueue(context.Background(), "dropped", srv.URL+"/page")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return store.len() == 2 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+
+	assert.Equal(t, "Go & you", store.saved[" go "+srv.URL+"/page"].Title)
+	// the domain of the link is kept
+	assert.Contains(t, store.saved, "go.brand-a.com pdf "+srv.URL+"/pdf")
+	// cached links are read again with their metadata
+	assert.ElementsMatch(t, []string{" go", "go.brand-a.com pdf"}, store.evicted)
+}
@@ -0,0 +1,53 @@
+package policy
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// maxRedirects bounds the redirects followed by NewClient.
+const maxRedirects = 5
+
+// Control refuses connections to addresses that are not public. Set as
+// the Control of a net.Dialer it guards clients fetching destinations,
+// including redirects and names resolving to internal addresses after
+// the link was created.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &RejectionError{Reason: ReasonInvalidURL, Host: address}
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isPublic(addr) {
+		return &RejectionError{Reason: ReasonPrivateAddress, Host: host}
+	}
+
+	return nil
+}
+
+// NewClient returns a client for requesting destinations. It connects only
+// to public addresses, ignores proxies and follows a few redirects.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: Control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+)
+
+// LoadDomains reads a domain list file: one domain per line, blank lines
+// and lines starting with # are skipped. An empty path is an empty list.
+func LoadDomains(path string) ([]string, error) {
+	const fn = "policy.LoadDomains"
+
+	wp := wraper.New(fn)
+
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+	defer f.Close()
+
+	var list []string
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, wp.WrapMsg(path, err)
+	}
+
+	return list, nil
+}
+
+// Load returns the policy configured by cfg, reading its domain list
+// files.
+func Load(cfg Config, resolver Resolver) (*Policy, error) {
+	const fn = "policy.Load"
+
+	wp := wraper.New(fn)
+
+	deny, err := LoadDomains(cfg.DenyListFile)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	allow, err := LoadDomains(cfg.AllowListFile)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	cfg.Deny = append(slices.Clip(cfg.Deny), deny...)
+	cfg.Allow = append(slices.Clip(cfg.Allow), allow...)
+
+	return New(cfg, resolver), nil
+}
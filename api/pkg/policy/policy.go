@@ -0,0 +1,326 @@
+// Package policy decides which destinations links may point to. It keeps
+// links away from internal addresses (SSRF), from denied domains and from
+// this and other shorteners, which would make redirect loops and chains.
+//
+//	p := policy.New(policy.Config{SelfHosts: []string{"sho.rt"}}, nil)
+//	svc := shortener.New(store, cache, shortener.Config{Policy: p}, log)
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// DefaultMaxURLLength is the longest url accepted without a configured
+// limit.
+const DefaultMaxURLLength = 2048
+
+// Reasons a destination is rejected for. They are part of the API
+// contract, clients match on them.
+const (
+	ReasonTooLong          = "url_too_long"
+	ReasonInvalidURL       = "invalid_url"
+	ReasonPrivateAddress   = "private_address"
+	ReasonInternalHost     = "internal_host"
+	ReasonUnresolvableHost = "unresolvable_host"
+	ReasonDenied           = "domain_denied"
+	ReasonNotAllowed       = "domain_not_allowed"
+	ReasonSelfReference    = "self_reference"
+	ReasonShortener        = "shortener_chain"
+)
+
+// Reasons lists every reason code.
+var Reasons = []string{
+	ReasonTooLong,
+	ReasonInvalidURL,
+	ReasonPrivateAddress,
+	ReasonInternalHost,
+	ReasonUnresolvableHost,
+	ReasonDenied,
+	ReasonNotAllowed,
+	ReasonSelfReference,
+	ReasonShortener,
+}
+
+// ErrRejected is matched by every RejectionError.
+var ErrRejected = errors.New("destination rejected by policy")
+
+// RejectionError tells why a destination was rejected.
+type RejectionError struct {
+	Reason string
+	Host   string
+}
+
+func (e *RejectionError) Error() string {
+	if e.Host == "" {
+		return fmt.Sprintf("%s: %s", ErrRejected, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s: %s", ErrRejected, e.Reason, e.Host)
+}
+
+func (e *RejectionError) Is(target error) bool {
+	return target == ErrRejected
+}
+
+// Reason returns the reason code of a rejection, or an empty string for
+// other errors.
+func Reason(err error) string {
+	var rerr *RejectionError
+	if errors.As(err, &rerr) {
+		return rerr.Reason
+	}
+	return ""
+}
+
+// Resolver looks up the addresses of a host. *net.Resolver satisfies it.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// DefaultShorteners are well known shorteners links must not point to.
+var DefaultShorteners = []string{
+	"bit.ly", "bitly.com", "buff.ly", "cutt.ly", "goo.gl", "is.gd",
+	"ow.ly", "rb.gy", "rebrand.ly", "shorturl.at", "t.co", "t.ly",
+	"tiny.cc", "tinyurl.com", "v.gd",
+}
+
+// Config tunes a Policy. Domains match themselves and their subdomains.
+type Config struct {
+	// MaxURLLength is the longest accepted url, DefaultMaxURLLength when
+	// zero.
+	MaxURLLength int
+	// Resolve checks the addresses a host name resolves to as well,
+	// not only addresses written as the host.
+	Resolve bool
+	// Deny lists rejected domains.
+	Deny []string
+	// Allow, when not empty, lists the only accepted domains.
+	Allow []string
+	// DenyListFile and AllowListFile are read by Load, their domains are
+	// added to Deny and Allow.
+	DenyListFile  string
+	AllowListFile string
+	// SelfHosts are the domains this shortener is served on.
+	SelfHosts []string
+	// Shorteners are other shorteners, DefaultShorteners when empty.
+	Shorteners []string
+}
+
+// Policy checks destinations. It is safe for concurrent use.
+type Policy struct {
+	maxLen     int
+	resolver   Resolver
+	deny       domains
+	allow      domains
+	self       domains
+	shorteners domains
+	// isSelf reports further domains this shortener is served on.
+	isSelf func(ctx context.Context, host string) bool
+}
+
+// New returns the policy configured by cfg. resolver is used when
+// cfg.Resolve is set, net.DefaultResolver when nil.
+func New(cfg Config, resolver Resolver) *Policy {
+	if cfg.MaxURLLength <= 0 {
+		cfg.MaxURLLength = DefaultMaxURLLength
+	}
+	if len(cfg.Shorteners) == 0 {
+		cfg.Shorteners = DefaultShorteners
+	}
+
+	p := &Policy{
+		maxLen:     cfg.MaxURLLength,
+		deny:       newDomains(cfg.Deny),
+		allow:      newDomains(cfg.Allow),
+		self:       newDomains(cfg.SelfHosts),
+		shorteners: newDomains(cfg.Shorteners),
+	}
+
+	if cfg.Resolve {
+		p.resolver = resolver
+		if p.resolver == nil {
+			p.resolver = net.DefaultResolver
+		}
+	}
+
+	return p
+}
+
+// WithSelfHosts returns a copy of p that treats the hosts isSelf reports
+// like the configured self hosts. It is asked on every check, so domains
+// added at runtime, e.g. to a domains.Registry, are rejected as soon as
+// they are served.
+func (p *Policy) WithSelfHosts(isSelf func(ctx context.Context, host string) bool) *Policy {
+	c := *p
+	c.isSelf = isSelf
+	return &c
+}
+
+// Check returns a *RejectionError when rawURL must not be a destination.
+// It expects a url that passed the syntax check of the shortener.
+func (p *Policy) Check(ctx context.Context, rawURL string) error {
+	if len(rawURL) > p.maxLen {
+		return &RejectionError{Reason: ReasonTooLong}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return &RejectionError{Reason: ReasonInvalidURL}
+	}
+
+	host := normalize(u.Hostname())
+
+	switch {
+	case p.self.match(host), p.isSelf != nil && p.isSelf(ctx, host):
+		return &RejectionError{Reason: ReasonSelfReference, Host: host}
+	case p.shorteners.match(host):
+		return &RejectionError{Reason: ReasonShortener, Host: host}
+	case p.deny.match(host):
+		return &RejectionError{Reason: ReasonDenied, Host: host}
+	case len(p.allow) > 0 && !p.allow.match(host):
+		return &RejectionError{Reason: ReasonNotAllowed, Host: host}
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !isPublic(addr) {
+			return &RejectionError{Reason: ReasonPrivateAddress, Host: host}
+		}
+		return nil
+	}
+
+	if isInternalName(host) {
+		return &RejectionError{Reason: ReasonInternalHost, Host: host}
+	}
+
+	if p.resolver == nil {
+		return nil
+	}
+
+	addrs, err := p.resolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return &RejectionError{Reason: ReasonUnresolvableHost, Host: host}
+	}
+
+	// one internal address is enough, the client may pick any of them
+	for _, a := range addrs {
+		addr, ok := netip.AddrFromSlice(a.IP)
+		if !ok || !isPublic(addr) {
+			return &RejectionError{Reason: ReasonPrivateAddress, Host: host}
+		}
+	}
+
+	return nil
+}
+
+// nonPublic are the ranges isPublic rejects on top of the ones the netip
+// methods know.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, broadcast
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("fec0::/10"),       // site-local
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2002:a00::/24"),   // 6to4 of 10.0.0.0/8
+	netip.MustParsePrefix("2002:7f00::/24"),  // 6to4 of 127.0.0.0/8
+	netip.MustParsePrefix("2002:a9fe::/32"),  // 6to4 of 169.254.0.0/16
+	netip.MustParsePrefix("2002:ac10::/28"),  // 6to4 of 172.16.0.0/12
+	netip.MustParsePrefix("2002:c0a8::/32"),  // 6to4 of 192.168.0.0/16
+	netip.MustParsePrefix("2001::/32"),       // Teredo
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4-translated
+}
+
+// isPublic reports whether addr is reachable on the internet, and not a
+// private, loopback, link-local, multicast or otherwise special address.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+
+	for _, p := range nonPublic {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// internalSuffixes are names that only resolve inside a network.
+var internalSuffixes = []string{
+	".localhost", ".local", ".internal", ".intranet", ".lan", ".home.arpa",
+	".corp", ".private",
+}
+
+// isInternalName reports whether host is a name of the local network:
+// single labels such as `db`, reserved suffixes and numeric hosts that
+// are no valid address but which resolvers read as one, e.g. `2130706433`
+// or `0x7f.1`.
+func isInternalName(host string) bool {
+	if !strings.Contains(host, ".") || host == "localhost" {
+		return true
+	}
+
+	for _, suffix := range internalSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	return isNumeric(host)
+}
+
+func isNumeric(host string) bool {
+	last := host[strings.LastIndexByte(host, '.')+1:]
+	if last == "" {
+		return false
+	}
+
+	// a top-level domain never starts with a digit
+	return last[0] >= '0' && last[0] <= '9'
+}
+
+// domains matches hosts against a set of domains and their subdomains.
+type domains map[string]struct{}
+
+func newDomains(list []string) domains {
+	d := make(domains, len(list))
+	for _, domain := range list {
+		if domain = normalize(domain); domain != "" {
+			d[domain] = struct{}{}
+		}
+	}
+	return d
+}
+
+func (d domains) match(host string) bool {
+	for {
+		if _, ok := d[host]; ok {
+			return true
+		}
+
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
+
+func normalize(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,144 @@
+package policy_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resolver answers from a fixed table.
+type resolver map[string][]string
+
+func (r resolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	addrs := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func TestPolicy_Check(t *testing.T) {
+	p := policy.New(policy.Config{
+		MaxURLLength: 64,
+		Deny:         []string{"evil.com"},
+		SelfHosts:    []string{"sho.rt"},
+	}, nil)
+
+	testCases := []struct {
+		name   string
+		url    string
+		reason string
+	}{
+		{name: "public host", url: "https://www.google.com/search?q=go"},
+		{name: "public ip", url: "http://8.8.8.8/"},
+		{name: "public ipv6", url: "http://[2606:4700:4700::1111]/"},
+		{name: "too long", url: "https://google.com/" + strings.Repeat("a", 64), reason: policy.ReasonTooLong},
+		{name: "loopback", url: "http://127.0.0.1:8080/admin", reason: policy.ReasonPrivateAddress},
+		{name: "cloud metadata", url: "http://169.254.169.254/latest", reason: policy.ReasonPrivateAddress},
+		{name: "private", url: "http://10.1.2.3", reason: policy.ReasonPrivateAddress},
+		{name: "carrier-grade nat", url: "http://100.64.0.1", reason: policy.ReasonPrivateAddress},
+		{name: "unspecified", url: "http://0.0.0.0", reason: policy.ReasonPrivateAddress},
+		{name: "ipv6 loopback", url: "http://[::1]/", reason: policy.ReasonPrivateAddress},
+		{name: "ipv6 unique local", url: "http://[fd00::1]/", reason: policy.ReasonPrivateAddress},
+		{name: "ipv4-mapped loopback", url: "http://[::ffff:127.0.0.1]/", reason: policy.ReasonPrivateAddress},
+		{name: "localhost", url: "http://localhost:6379", reason: policy.ReasonInternalHost},
+		{name: "single label", url: "http://postgres:5432", reason: policy.ReasonInternalHost},
+		{name: "internal suffix", url: "http://grafana.svc.internal", reason: policy.ReasonInternalHost},
+		{name: "decimal ip", url: "http://2130706433/", reason: policy.ReasonInternalHost},
+		{name: "hex ip", url: "http://0x7f.0x0.0x0.0x1/", reason: policy.ReasonInternalHost},
+		{name: "self", url: "https://sho.rt/abc", reason: policy.ReasonSelfReference},
+		{name: "self subdomain", url: "https://WWW.Sho.Rt./abc", reason: policy.ReasonSelfReference},
+		{name: "other shortener", url: "https://bit.ly/abc", reason: policy.ReasonShortener},
+		{name: "denied", url: "https://login.evil.com", reason: policy.ReasonDenied},
+		{name: "denied lookalike is fine", url: "https://notevil.com"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Check(context.Background(), tt.url)
+			if tt.reason == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, policy.ErrRejected)
+			assert.Equal(t, tt.reason, policy.Reason(err))
+		})
+	}
+}
+
+func TestPolicy_Allow(t *testing.T) {
+	p := policy.New(policy.Config{Allow: []string{"example.com"}}, nil)
+
+	assert.NoError(t, p.Check(context.Background(), "https://docs.example.com"))
+	assert.Equal(t, policy.ReasonNotAllowed, policy.Reason(p.Check(context.Background(), "https://google.com")))
+}
+
+func TestPolicy_Resolve(t *testing.T) {
+	ctx := context.Background()
+
+	r := resolver{
+		"www.google.com":    {"142.250.74.36", "2a00:1450:4001:82b::2004"},
+		"rebind.attack.com": {"93.184.216.34", "127.0.0.1"},
+		"metadata.evil.com": {"169.254.169.254"},
+	}
+
+	p := policy.New(policy.Config{Resolve: true}, r)
+
+	assert.NoError(t, p.Check(ctx, "https://www.google.com"))
+	assert.Equal(t, policy.ReasonPrivateAddress, policy.Reason(p.Check(ctx, "https://rebind.attack.com")))
+	assert.Equal(t, policy.ReasonPrivateAddress, policy.Reason(p.Check(ctx, "https://metadata.evil.com")))
+	assert.Equal(t, policy.ReasonUnresolvableHost, policy.Reason(p.Check(ctx, "https://nxdomain.example.org")))
+
+	// literal addresses are not resolved
+	assert.NoError(t, p.Check(ctx, "http://8.8.8.8"))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, p.Check(cancelled, "https://nxdomain.example.org"), context.Canceled)
+
+	// without Resolve host names are not looked up
+	p = policy.New(policy.Config{}, r)
+	assert.NoError(t, p.Check(ctx, "https://metadata.evil.com"))
+}
+
+func TestControl(t *testing.T) {
+	assert.NoError(t, policy.Control("tcp4", "142.250.74.36:443", nil))
+	assert.NoError(t, policy.Control("tcp6", "[2a00:1450:4001:82b::2004]:443", nil))
+
+	for _, address := range []string{"127.0.0.1:80", "10.0.0.1:443", "169.254.169.254:80", "[::1]:80", "[::ffff:127.0.0.1]:80"} {
+		assert.Equal(t, policy.ReasonPrivateAddress, policy.Reason(policy.Control("tcp", address, nil)), address)
+	}
+
+	assert.ErrorIs(t, policy.Control("tcp", "no port", nil), policy.ErrRejected)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	deny := filepath.Join(dir, "deny.txt")
+	require.NoError(t, os.WriteFile(deny, []byte("# phishing\nevil.com\n\n  Bad.Example  \n"), 0o600))
+
+	list, err := policy.LoadDomains(deny)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"evil.com", "Bad.Example"}, list)
+
+	p, err := policy.Load(policy.Config{DenyListFile: deny}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, policy.ReasonDenied, policy.Reason(p.Check(context.Background(), "https://www.bad.example")))
+
+	_, err = policy.Load(policy.Config{AllowListFile: filepath.Join(dir, "missing.txt")}, nil)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,172 @@
+// Package reputation tells whether urls are known to be malicious, e.g.
+// phishing or malware. Checker caches the verdicts of a Lookuper such as
+// SafeBrowsing and decides what happens while it is unavailable.
+//
+//	sb := reputation.NewSafeBrowsing(reputation.SafeBrowsingConfig{APIKey: key}, nil)
+//	checker := reputation.NewChecker(sb, reputation.Config{FailOpen: true}, log)
+//	svc := shortener.New(store, cache, shortener.Config{Reputation: checker}, log)
+package reputation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+)
+
+const (
+	// DefaultCacheTTL is how long verdicts are cached without a
+	// configured TTL.
+	DefaultCacheTTL = 30 * time.Minute
+	// DefaultMaxEntries bounds the cache without a configured size.
+	DefaultMaxEntries = 100_000
+)
+
+var (
+	// ErrUnsafe is matched by every ThreatError.
+	ErrUnsafe = errors.New("url is listed as unsafe")
+	// ErrUnavailable means the reputation of a url could not be checked.
+	ErrUnavailable = errors.New("url reputation service unavailable")
+)
+
+// ThreatError lists the threats a url is known for.
+type ThreatError struct {
+	Threats []string
+}
+
+func (e *ThreatError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUnsafe, strings.Join(e.Threats, ", "))
+}
+
+func (e *ThreatError) Is(target error) bool {
+	return target == ErrUnsafe
+}
+
+// Lookuper looks up the threats a url is listed for, none for safe urls.
+type Lookuper interface {
+	Lookup(ctx context.Context, url string) ([]string, error)
+}
+
+// Config tunes a Checker. The zero value uses the defaults and fails
+// closed.
+type Config struct {
+	// CacheTTL is how long verdicts are cached.
+	CacheTTL time.Duration
+	// MaxEntries bounds the number of cached verdicts.
+	MaxEntries int
+	// FailOpen accepts urls while the lookup fails instead of rejecting
+	// them with ErrUnavailable.
+	FailOpen bool
+}
+
+// Checker checks urls through a Lookuper and caches the verdicts. It is
+// safe for concurrent use.
+type Checker struct {
+	lookup Lookuper
+	cfg    Config
+	log    *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	threats []string
+	expires time.Time
+}
+
+// NewChecker returns a Checker over lookup. log may be nil.
+func NewChecker(lookup Lookuper, cfg Config, log *slog.Logger) *Checker {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = DefaultCacheTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	return &Checker{
+		lookup:  lookup,
+		cfg:     cfg,
+		log:     log,
+		now:     time.Now,
+		entries: make(map[string]entry),
+	}
+}
+
+// CheckURL returns a *ThreatError for urls listed as unsafe. When the
+// lookup fails it returns an error matching ErrUnavailable, or nil when
+// the Checker fails open.
+func (c *Checker) CheckURL(ctx context.Context, url string) error {
+	threats, ok := c.cached(url)
+	if !ok {
+		var err error
+
+		threats, err = c.lookup.Lookup(ctx, url)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			c.log.Error("url reputation lookup",
+				slog.String("url", url),
+				slog.Bool("fail open", c.cfg.FailOpen),
+				sl.Error(err),
+			)
+
+			if c.cfg.FailOpen {
+				return nil
+			}
+			return fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+
+		c.store(url, threats)
+	}
+
+	if len(threats) > 0 {
+		return &ThreatError{Threats: threats}
+	}
+
+	return nil
+}
+
+func (c *Checker) cached(url string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[url]
+	if !ok || !c.now().Before(e.expires) {
+		return nil, false
+	}
+	return e.threats, true
+}
+
+func (c *Checker) store(url string, threats []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	if len(c.entries) >= c.cfg.MaxEntries {
+		for key, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, key)
+			}
+		}
+	}
+
+	// still full of fresh verdicts, start over rather than grow
+	if len(c.entries) >= c.cfg.MaxEntries {
+		clear(c.entries)
+	}
+
+	c.entries[url] = entry{threats: threats, expires: now.Add(c.cfg.CacheTTL)}
+}
@@ -0,0 +1,150 @@
+package reputation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSafeBrowsing answers threatMatches:find like the Lookup API for
+// the listed urls.
+type fakeSafeBrowsing struct {
+	listed map[string]string
+	down   atomic.Bool
+	calls  atomic.Int32
+}
+
+func (f *fakeSafeBrowsing) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.calls.Add(1)
+
+	if f.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost || r.URL.Path != lookupPath || r.URL.Query().Get("key") != "test-key" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var req findRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	type match struct {
+		ThreatType      string      `json:"threatType"`
+		PlatformType    string      `json:"platformType"`
+		ThreatEntryType string      `json:"threatEntryType"`
+		Threat          threatEntry `json:"threat"`
+		CacheDuration   string      `json:"cacheDuration"`
+	}
+
+	var matches []match
+	for _, e := range req.ThreatInfo.ThreatEntries {
+		if threat, ok := f.listed[e.URL]; ok {
+			matches = append(matches, match{threat, "ANY_PLATFORM", "URL", e, "300s"})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(matches) == 0 {
+		w.Write([]byte(`{}`))
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"matches": matches})
+}
+
+func newFake(t *testing.T) (*fakeSafeBrowsing, *SafeBrowsing) {
+	t.Helper()
+
+	fake := &fakeSafeBrowsing{listed: map[string]string{
+		"http://phish.example/login": "SOCIAL_ENGINEERING",
+	}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	return fake, NewSafeBrowsing(SafeBrowsingConfig{Endpoint: srv.URL + "/", APIKey: "test-key"}, srv.Client())
+}
+
+func TestSafeBrowsing_Lookup(t *testing.T) {
+	ctx := context.Background()
+	fake, sb := newFake(t)
+
+	threats, err := sb.Lookup(ctx, "http://phish.example/login")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SOCIAL_ENGINEERING"}, threats)
+
+	threats, err = sb.Lookup(ctx, "https://www.google.com")
+	require.NoError(t, err)
+	assert.Empty(t, threats)
+
+	fake.down.Store(true)
+	_, err = sb.Lookup(ctx, "https://www.google.com")
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+}
+
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+	fake, sb := newFake(t)
+
+	now := time.Date(2025, 6, 25, 15, 0, 0, 0, time.UTC)
+	c := NewChecker(sb, Config{CacheTTL: time.Minute}, nil)
+	c.now = func() time.Time { return now }
+
+	err := c.CheckURL(ctx, "http://phish.example/login")
+	assert.ErrorIs(t, err, ErrUnsafe)
+	var terr *ThreatError
+	require.ErrorAs(t, err, &terr)
+	assert.Equal(t, []string{"SOCIAL_ENGINEERING"}, terr.Threats)
+
+	require.NoError(t, c.CheckURL(ctx, "https://www.google.com"))
+	assert.EqualValues(t, 2, fake.calls.Load())
+
+	// both verdicts are cached
+	assert.ErrorIs(t, c.CheckURL(ctx, "http://phish.example/login"), ErrUnsafe)
+	assert.NoError(t, c.CheckURL(ctx, "https://www.google.com"))
+	assert.EqualValues(t, 2, fake.calls.Load())
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, c.CheckURL(ctx, "https://www.google.com"))
+	assert.EqualValues(t, 3, fake.calls.Load())
+}
+
+func TestChecker_Unavailable(t *testing.T) {
+	ctx := context.Background()
+	fake, sb := newFake(t)
+	fake.down.Store(true)
+
+	closed := NewChecker(sb, Config{}, nil)
+	assert.ErrorIs(t, closed.CheckURL(ctx, "https://www.google.com"), ErrUnavailable)
+
+	open := NewChecker(sb, Config{FailOpen: true}, nil)
+	assert.NoError(t, open.CheckURL(ctx, "https://www.google.com"))
+
+	// failures are not cached
+	fake.down.Store(false)
+	assert.ErrorIs(t, open.CheckURL(ctx, "http://phish.example/login"), ErrUnsafe)
+	assert.NoError(t, closed.CheckURL(ctx, "https://www.google.com"))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, open.CheckURL(cancelled, "https://www.example.com"), context.Canceled)
+}
+
+func TestChecker_MaxEntries(t *testing.T) {
+	_, sb := newFake(t)
+
+	c := NewChecker(sb, Config{MaxEntries: 2}, nil)
+	for _, u := range []string{"https://a.example", "https://b.example", "https://c.example"} {
+		require.NoError(t, c.CheckURL(context.Background(), u))
+	}
+	assert.LessOrEqual(t, len(c.entries), 2)
+}
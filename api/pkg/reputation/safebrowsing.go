@@ -0,0 +1,188 @@
+package reputation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+)
+
+const (
+	// DefaultSafeBrowsingEndpoint is the Google Safe Browsing API.
+	DefaultSafeBrowsingEndpoint = "https://safebrowsing.googleapis.com"
+	// DefaultClientID identifies the shortener to the API.
+	DefaultClientID = "url-shortener"
+	// DefaultTimeout bounds a lookup without a configured timeout.
+	DefaultTimeout = 2 * time.Second
+
+	lookupPath = "/v4/threatMatches:find"
+	// maxResponseSize bounds the response body that is read.
+	maxResponseSize = 1 << 20
+)
+
+// DefaultThreatTypes are looked up without configured threat types.
+var DefaultThreatTypes = []string{
+	"MALWARE",
+	"SOCIAL_ENGINEERING",
+	"UNWANTED_SOFTWARE",
+	"POTENTIALLY_HARMFUL_APPLICATION",
+}
+
+var ErrUnexpectedStatus = errors.New("unexpected status of the lookup api")
+
+// SafeBrowsingConfig configures the lookup. The zero value, apart from
+// the key, talks to Google.
+type SafeBrowsingConfig struct {
+	// Endpoint is the base url of the API, DefaultSafeBrowsingEndpoint
+	// when empty. Tests point it at a local fake.
+	Endpoint string
+	APIKey   string
+	ClientID string
+	// ThreatTypes are the threats looked up, DefaultThreatTypes when
+	// empty.
+	ThreatTypes []string
+	// Timeout bounds a lookup, DefaultTimeout when zero.
+	Timeout time.Duration
+}
+
+// SafeBrowsing looks urls up with the Safe Browsing Lookup API v4
+// (threatMatches:find). It satisfies Lookuper.
+type SafeBrowsing struct {
+	cfg    SafeBrowsingConfig
+	client *http.Client
+}
+
+// NewSafeBrowsing returns a lookup client. client may be nil, then
+// http.DefaultClient is used.
+func NewSafeBrowsing(cfg SafeBrowsingConfig, client *http.Client) *SafeBrowsing {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultSafeBrowsingEndpoint
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if cfg.ClientID == "" {
+		cfg.ClientID = DefaultClientID
+	}
+	if len(cfg.ThreatTypes) == 0 {
+		cfg.ThreatTypes = DefaultThreatTypes
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &SafeBrowsing{cfg: cfg, client: client}
+}
+
+type clientInfo struct {
+	ClientID      string `json:"clientId"`
+	ClientVersion string `json:"clientVersion"`
+}
+
+type threatEntry struct {
+	URL string `json:"url"`
+}
+
+type threatInfo struct {
+	ThreatTypes      []string      `json:"threatTypes"`
+	PlatformTypes    []string      `json:"platformTypes"`
+	ThreatEntryTypes []string      `json:"threatEntryTypes"`
+	ThreatEntries    []threatEntry `json:"threatEntries"`
+}
+
+type findRequest struct {
+	Client     clientInfo `json:"client"`
+	ThreatInfo threatInfo `json:"threatInfo"`
+}
+
+type threatMatch struct {
+	ThreatType string `json:"threatType"`
+}
+
+type findResponse struct {
+	Matches []threatMatch `json:"matches"`
+}
+
+// Lookup returns the threat types url is listed for.
+func (s *SafeBrowsing) Lookup(ctx context.Context, url string) ([]string, error) {
+	const fn = "reputation.(*SafeBrowsing).Lookup"
+
+	wp := wraper.New(fn)
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	body, err := json.Marshal(findRequest{
+		Client: clientInfo{ClientID: s.cfg.ClientID, ClientVersion: "1.0.0"},
+		ThreatInfo: threatInfo{
+			ThreatTypes:      s.cfg.ThreatTypes,
+			PlatformTypes:    []string{"ANY_PLATFORM"},
+			ThreatEntryTypes: []string{"URL"},
+			ThreatEntries:    []threatEntry{{URL: url}},
+		},
+	})
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	endpoint := s.cfg.Endpoint + lookupPath
+	if s.cfg.APIKey != "" {
+		endpoint += "?" + neturl.Values{"key": {s.cfg.APIKey}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, wp.Wrap(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, wp.Wrap(fmt.Errorf("%w: %s", ErrUnexpectedStatus, res.Status))
+	}
+
+	var found findResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&found); err != nil {
+		return nil, wp.Wrap(err)
+	}
+
+	// only url is looked up, every match is about it
+	var threats []string
+	for _, m := range found.Matches {
+		if !slices.Contains(threats, m.ThreatType) {
+			threats = append(threats, m.ThreatType)
+		}
+	}
+
+	return threats, nil
+}
+
+// Load returns the Checker configured by cfg, over the Safe Browsing
+// Lookup API. client may be nil.
+func Load(cfg *config.ReputationConfig, client *http.Client, log *slog.Logger) *Checker {
+	sb := NewSafeBrowsing(SafeBrowsingConfig{
+		Endpoint:    cfg.Endpoint,
+		APIKey:      cfg.APIKey,
+		ThreatTypes: cfg.ThreatTypes,
+		Timeout:     cfg.Timeout,
+	}, client)
+
+	return NewChecker(sb, Config{CacheTTL: cfg.CacheTTL, FailOpen: cfg.FailOpen}, log)
+}
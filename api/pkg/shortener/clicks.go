@@ -0,0 +1,195 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+)
+
+const recordClicksTimeout = 5 * time.Second
+
+// ErrLinkExhausted means the link served all the redirects it may.
+var ErrLinkExhausted = errors.New("link reached its click limit")
+
+// ClickCounter counts the redirects of click-limited links, by the cache
+// key of the link. A counter shared by every instance keeps the limit
+// across them; the Redis cache satisfies it.
+type ClickCounter interface {
+	// Click counts a redirect unless limit redirects were counted. It
+	// returns the redirects counted and whether this one was, or
+	// ErrCacheMiss when key has no counter yet.
+	Click(ctx context.Context, key string, limit int64) (clicks int64, ok bool, err error)
+	// Count returns the redirects counted, or ErrCacheMiss when key has no
+	// counter yet.
+	Count(ctx context.Context, key string) (int64, error)
+	// Seed starts the counter of key at clicks unless it has one.
+	Seed(ctx context.Context, key string, clicks int64) error
+	// Reset drops the counter of key.
+	Reset(ctx context.Context, key string) error
+}
+
+// WithClickCounter returns a copy of s counting the redirects of
+// click-limited links with c. The copy shares the store and the cache with
+// s.
+func (s *Shortener) WithClickCounter(c ClickCounter) *Shortener {
+	cp := *s
+	cp.cfg.Clicks = c
+	return &cp
+}
+
+// click counts a redirect to the link t saved under alias. The counter
+// starts at the redirects the store counted, and every redirect counted is
+// recorded in the store in the background. Exhausted links are evicted, so
+// visits of them read the store until the counter refuses them.
+func (s *Shortener) click(ctx context.Context, alias string, t Target) error {
+	const fn = "shortener.(*Shortener).click"
+
+	wp := wraper.New(fn)
+
+	key := cacheKey(ctx, alias)
+
+	clicks, ok, err := s.cfg.Clicks.Click(ctx, key, t.MaxClicks)
+	if errors.Is(err, ErrCacheMiss) {
+		stored, storeErr := s.store.GetTarget(ctx, alias)
+		if storeErr != nil {
+			if errors.Is(storeErr, ErrLinkExhausted) {
+				s.evict(ctx, alias)
+			}
+			return wp.Wrap(storeErr)
+		}
+
+		if err := s.cfg.Clicks.Seed(ctx, key, stored.Clicks); err != nil {
+			return wp.Wrap(err)
+		}
+
+		clicks, ok, err = s.cfg.Clicks.Click(ctx, key, t.MaxClicks)
+	}
+	if err != nil {
+		return wp.Wrap(err)
+	}
+
+	if !ok {
+		s.evict(ctx, alias)
+		return ErrLinkExhausted
+	}
+
+	if clicks >= t.MaxClicks {
+		s.evict(ctx, alias)
+	}
+
+	s.recordClicks(ctx, alias, clicks)
+	return nil
+}
+
+// checkClicks returns ErrLinkExhausted when the link t saved under alias
+// served all its redirects, without counting one.
+func (s *Shortener) checkClicks(ctx context.Context, alias string, t Target) error {
+	const fn = "shortener.(*Shortener).checkClicks"
+
+	wp := wraper.New(fn)
+
+	clicks, err := s.cfg.Clicks.Count(ctx, cacheKey(ctx, alias))
+	if errors.Is(err, ErrCacheMiss) {
+		stored, storeErr := s.store.GetTarget(ctx, alias)
+		if storeErr != nil {
+			return wp.Wrap(storeErr)
+		}
+		clicks, err = stored.Clicks, nil
+	}
+	if err != nil {
+		return wp.Wrap(err)
+	}
+
+	if clicks >= t.MaxClicks {
+		return ErrLinkExhausted
+	}
+	return nil
+}
+
+func (s *Shortener) recordClicks(ctx context.Context, alias string, clicks int64) {
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordClicksTimeout)
+
+	go func() {
+		defer cancel()
+
+		if err := s.store.RecordClicks(recordCtx, alias, clicks); err != nil {
+			s.log.Error("record clicks",
+				slog.String("key", alias),
+				slog.Int64("clicks", clicks),
+				sl.Error(err),
+			)
+		}
+	}()
+}
+
+// resetClicks drops the counter of alias, a new link saved under it starts
+// counting anew.
+func (s *Shortener) resetClicks(ctx context.Context, alias string) {
+	if err := s.cfg.Clicks.Reset(ctx, cacheKey(ctx, alias)); err != nil {
+		s.log.Error("reset clicks",
+			slog.String("key", alias),
+			sl.Error(err),
+		)
+	}
+}
+
+// localCounter counts clicks in memory. It keeps the limit only as long as
+// no other instance redirects the same links.
+type localCounter struct {
+	mu     sync.Mutex
+	clicks map[string]int64
+}
+
+func newLocalCounter() *localCounter {
+	return &localCounter{clicks: make(map[string]int64)}
+}
+
+func (c *localCounter) Click(_ context.Context, key string, limit int64) (int64, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clicks, ok := c.clicks[key]
+	if !ok {
+		return 0, false, ErrCacheMiss
+	}
+	if clicks >= limit {
+		return clicks, false, nil
+	}
+
+	c.clicks[key] = clicks + 1
+	return clicks + 1, true, nil
+}
+
+func (c *localCounter) Count(_ context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clicks, ok := c.clicks[key]
+	if !ok {
+		return 0, ErrCacheMiss
+	}
+	return clicks, nil
+}
+
+func (c *localCounter) Seed(_ context.Context, key string, clicks int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.clicks[key]; !ok {
+		c.clicks[key] = clicks
+	}
+	return nil
+}
+
+func (c *localCounter) Reset(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.clicks, key)
+	return nil
+}
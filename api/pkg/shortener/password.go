@@ -0,0 +1,127 @@
+package shortener
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// DefaultAccessTTL is how long an access token to a protected link is
+	// valid.
+	DefaultAccessTTL = 15 * time.Minute
+
+	// maxPasswordLength is the most bcrypt hashes.
+	maxPasswordLength = 72
+)
+
+var (
+	// ErrPasswordRequired means the link is protected and no valid access
+	// token was passed, see WithAccess.
+	ErrPasswordRequired = apierr.ErrPasswordRequired
+	// ErrWrongPassword means the password does not unlock the link.
+	ErrWrongPassword = apierr.ErrWrongPassword
+)
+
+type accessKey struct{}
+
+// WithAccess returns a ctx carrying the access token Unlock returned.
+// Redirect lets it through to the protected link the token was issued for
+// until the token expires.
+func WithAccess(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, accessKey{}, token)
+}
+
+func accessFrom(ctx context.Context) string {
+	token, _ := ctx.Value(accessKey{}).(string)
+	return token
+}
+
+// Unlock checks password against the link saved under alias and returns
+// an access token to it, valid for AccessTTL. Links without password are
+// unlocked by any password.
+func (s *Shortener) Unlock(ctx context.Context, alias, password string) (string, error) {
+	const fn = "shortener.(*Shortener).Unlock"
+
+	t, err := s.Target(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+
+	if t.PasswordHash != "" {
+		err := bcrypt.CompareHashAndPassword([]byte(t.PasswordHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return "", ErrWrongPassword
+		}
+		if err != nil {
+			return "", wraper.Wrap(fn, err)
+		}
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(s.cfg.AccessTTL).Unix(), 10)
+
+	return expires + "." + s.sign(ctx, alias, t, expires), nil
+}
+
+// checkAccess returns ErrPasswordRequired unless t is not protected or ctx
+// carries a valid access token to it.
+func (s *Shortener) checkAccess(ctx context.Context, alias string, t Target) error {
+	if t.PasswordHash == "" {
+		return nil
+	}
+
+	expires, mac, ok := strings.Cut(accessFrom(ctx), ".")
+	if !ok {
+		return ErrPasswordRequired
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() >= unix {
+		return ErrPasswordRequired
+	}
+
+	if !hmac.Equal([]byte(mac), []byte(s.sign(ctx, alias, t, expires))) {
+		return ErrPasswordRequired
+	}
+
+	return nil
+}
+
+// sign returns the signature of an access token to the link of t. It
+// covers the password hash, so that changing the password revokes the
+// tokens issued before.
+func (s *Shortener) sign(ctx context.Context, alias string, t Target, expires string) string {
+	h := hmac.New(sha256.New, s.cfg.AccessSecret)
+	for _, part := range []string{DomainFrom(ctx).Name, alias, t.PasswordHash, expires} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// hashPassword returns the bcrypt hash of password, empty for an empty
+// password.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+
+	if len(password) > maxPasswordLength {
+		return "", &OptionsError{Field: "password", Reason: "must be at most 72 bytes"}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
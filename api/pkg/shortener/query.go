@@ -0,0 +1,149 @@
+package shortener
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const maxUTMLength = 256
+
+// Destination returns where a visit of the link of t with the query
+// incoming goes: the url of t with its UTM parameters and, depending on
+// its passthrough mode, the incoming parameters. The query of the url is
+// kept as it is stored, parameters are appended after it in their order,
+// the fragment stays at the end.
+func Destination(t Target, incoming url.Values) (string, error) {
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return "", err
+	}
+
+	pairs := splitQuery(u.RawQuery)
+
+	if t.UTM != nil {
+		for _, p := range t.UTM.Params() {
+			if !hasKey(pairs, p[0]) {
+				pairs = append(pairs, encodePair(p[0], p[1]))
+			}
+		}
+	}
+
+	switch t.Passthrough {
+	case PassthroughIncoming:
+		pairs = slices.DeleteFunc(pairs, func(pair string) bool {
+			_, ok := incoming[pairKey(pair)]
+			return ok
+		})
+		pairs = appendValues(pairs, incoming, nil)
+
+	case PassthroughDestination:
+		have := make(map[string]bool, len(pairs))
+		for _, pair := range pairs {
+			have[pairKey(pair)] = true
+		}
+		pairs = appendValues(pairs, incoming, have)
+	}
+
+	u.RawQuery = strings.Join(pairs, "&")
+
+	return u.String(), nil
+}
+
+// splitQuery splits a raw query into its encoded pairs, empty ones left
+// out.
+func splitQuery(raw string) []string {
+	var pairs []string
+	for _, pair := range strings.Split(raw, "&") {
+		if pair != "" {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// pairKey returns the decoded key of an encoded pair.
+func pairKey(pair string) string {
+	key, _, _ := strings.Cut(pair, "=")
+	if k, err := url.QueryUnescape(key); err == nil {
+		return k
+	}
+	return key
+}
+
+func hasKey(pairs []string, key string) bool {
+	return slices.ContainsFunc(pairs, func(pair string) bool {
+		return pairKey(pair) == key
+	})
+}
+
+func encodePair(key, value string) string {
+	return url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
+
+// appendValues appends the pairs of values ordered by key, every value of
+// duplicate keys in order, unless skip has the key.
+func appendValues(pairs []string, values url.Values, skip map[string]bool) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if !skip[key] {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		for _, v := range values[key] {
+			pairs = append(pairs, encodePair(key, v))
+		}
+	}
+	return pairs
+}
+
+// OptionsError tells which option of a link is invalid. It matches
+// ErrInvalidOptions.
+type OptionsError struct {
+	Field  string
+	Reason string
+}
+
+func (e *OptionsError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrInvalidOptions, e.Field, e.Reason)
+}
+
+func (e *OptionsError) Is(target error) bool {
+	return target == ErrInvalidOptions
+}
+
+// validateOptions reports the first option no link can have as an
+// *OptionsError.
+func validateOptions(opts Options) error {
+	switch opts.Passthrough {
+	case PassthroughOff, PassthroughIncoming, PassthroughDestination:
+	default:
+		return &OptionsError{Field: "passthrough", Reason: "must be incoming or destination"}
+	}
+
+	if opts.UTM != nil {
+		for _, p := range opts.UTM.Params() {
+			if len(p[1]) > maxUTMLength {
+				return &OptionsError{Field: "utm." + strings.TrimPrefix(p[0], "utm_"), Reason: fmt.Sprintf("must be at most %d bytes", maxUTMLength)}
+			}
+		}
+	}
+
+	if opts.MaxClicks < 0 {
+		return &OptionsError{Field: "max_clicks", Reason: "must not be negative"}
+	}
+
+	if opts.ActiveFrom != nil && opts.ActiveUntil != nil && !opts.ActiveFrom.Before(*opts.ActiveUntil) {
+		return &OptionsError{Field: "active_until", Reason: "must be after active_from"}
+	}
+
+	if err := validateRules(opts.Rules); err != nil {
+		return err
+	}
+
+	return validateVariants(opts.Variants)
+}
@@ -0,0 +1,98 @@
+package shortener_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestination(t *testing.T) {
+	utm := &shortener.UTM{Source: "newsletter", Medium: "email", Campaign: "spring sale"}
+
+	testCases := []struct {
+		name     string
+		url      string
+		opts     shortener.Options
+		incoming url.Values
+		want     string
+	}{
+		{
+			name:     "without options",
+			url:      "https://example.com/a?b=1#top",
+			incoming: url.Values{"utm_source": {"x"}},
+			want:     "https://example.com/a?b=1#top",
+		},
+		{
+			name: "utm",
+			url:  "https://example.com/a",
+			opts: shortener.Options{UTM: utm},
+			want: "https://example.com/a?utm_source=newsletter&utm_medium=email&utm_campaign=spring+sale",
+		},
+		{
+			name: "utm of the url are kept",
+			url:  "https://example.com/a?utm_source=site#top",
+			opts: shortener.Options{UTM: &shortener.UTM{Source: "newsletter", Medium: "email"}},
+			want: "https://example.com/a?utm_source=site&utm_medium=email#top",
+		},
+		{
+			name:     "incoming wins",
+			url:      "https://example.com/a?a=1&b=2#top",
+			opts:     shortener.Options{Passthrough: shortener.PassthroughIncoming},
+			incoming: url.Values{"a": {"9"}, "c": {"3"}},
+			want:     "https://example.com/a?b=2&a=9&c=3#top",
+		},
+		{
+			name:     "destination wins",
+			url:      "https://example.com/a?a=1&b=2#top",
+			opts:     shortener.Options{Passthrough: shortener.PassthroughDestination},
+			incoming: url.Values{"a": {"9"}, "c": {"3"}},
+			want:     "https://example.com/a?a=1&b=2&c=3#top",
+		},
+		{
+			name:     "incoming wins over utm",
+			url:      "https://example.com/a",
+			opts:     shortener.Options{Passthrough: shortener.PassthroughIncoming, UTM: &shortener.UTM{Source: "newsletter", Medium: "email"}},
+			incoming: url.Values{"utm_source": {"twitter"}},
+			want:     "https://example.com/a?utm_medium=email&utm_source=twitter",
+		},
+		{
+			name:     "duplicate keys",
+			url:      "https://example.com/a?tag=a&tag=b&x=1",
+			opts:     shortener.Options{Passthrough: shortener.PassthroughIncoming},
+			incoming: url.Values{"tag": {"c", "d"}},
+			want:     "https://example.com/a?x=1&tag=c&tag=d",
+		},
+		{
+			name:     "duplicate keys of the destination",
+			url:      "https://example.com/a?tag=a&tag=b",
+			opts:     shortener.Options{Passthrough: shortener.PassthroughDestination},
+			incoming: url.Values{"tag": {"c"}, "q": {"1", "2"}},
+			want:     "https://example.com/a?tag=a&tag=b&q=1&q=2",
+		},
+		{
+			name:     "encoded characters",
+			url:      "https://example.com/caf%C3%A9?q=a%2Bb&utm%5Fsource=site",
+			opts:     shortener.Options{Passthrough: shortener.PassthroughDestination, UTM: utm},
+			incoming: url.Values{"utm_source": {"x"}, "name": {"a b&c=d"}, "é": {"ü"}},
+			want:     "https://example.com/caf%C3%A9?q=a%2Bb&utm%5Fsource=site&utm_medium=email&utm_campaign=spring+sale&name=a+b%26c%3Dd&%C3%A9=%C3%BC",
+		},
+		{
+			name:     "empty incoming",
+			url:      "https://example.com/a?",
+			opts:     shortener.Options{Passthrough: shortener.PassthroughIncoming},
+			incoming: url.Values{},
+			want:     "https://example.com/a?",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shortener.Destination(shortener.Target{URL: tt.url, Options: tt.opts}, tt.incoming)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRules is the most rules a link may have.
+const maxRules = 20
+
+// Visitor is what the rules of a link match a visit on.
+type Visitor struct {
+	UserAgent      string
+	AcceptLanguage string
+	// Country is the ISO 3166 code of the country of the visitor, as told
+	// by a proxy in front of the server.
+	Country string
+	// Header holds the headers rules with a header condition match on.
+	Header http.Header
+	// Time is when the visit happened, now when zero.
+	Time time.Time
+	// Key identifies the visitor, visits with the same key get the same
+	// variant of a link. Visits without one get a random variant.
+	Key string
+}
+
+type visitorKey struct{}
+
+// WithVisitor returns a ctx telling Visit who visits the link. Without it
+// only the time conditions of rules can match.
+func WithVisitor(ctx context.Context, v Visitor) context.Context {
+	return context.WithValue(ctx, visitorKey{}, v)
+}
+
+func visitorFrom(ctx context.Context) Visitor {
+	v, _ := ctx.Value(visitorKey{}).(Visitor)
+	if v.Time.IsZero() {
+		v.Time = time.Now()
+	}
+	return v
+}
+
+// MatchRule returns the index of the first rule v matches, or -1.
+func MatchRule(rules []Rule, v Visitor) int {
+	return slices.IndexFunc(rules, func(r Rule) bool {
+		return matches(r, v)
+	})
+}
+
+func matches(r Rule, v Visitor) bool {
+	if len(r.Platforms) > 0 && !slices.Contains(r.Platforms, PlatformOf(v.UserAgent)) {
+		return false
+	}
+
+	if len(r.Languages) > 0 {
+		lang := preferredLanguage(v.AcceptLanguage)
+		if !slices.ContainsFunc(r.Languages, func(l string) bool { return matchLanguage(l, lang) }) {
+			return false
+		}
+	}
+
+	if len(r.Countries) > 0 && !slices.ContainsFunc(r.Countries, func(c string) bool { return strings.EqualFold(c, v.Country) }) {
+		return false
+	}
+
+	if r.From != nil && v.Time.Before(*r.From) {
+		return false
+	}
+	if r.Until != nil && !v.Time.Before(*r.Until) {
+		return false
+	}
+
+	if h := r.Header; h != nil {
+		values := v.Header.Values(h.Name)
+		if len(values) == 0 || h.Value != "" && !slices.Contains(values, h.Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PlatformOf returns the platform userAgent names, empty for unknown
+// ones. Android and iOS are told apart from the desktop systems they
+// mention as well.
+func PlatformOf(userAgent string) Platform {
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iPod"):
+		return PlatformIOS
+	case strings.Contains(userAgent, "Android"):
+		return PlatformAndroid
+	case strings.Contains(userAgent, "Windows"):
+		return PlatformWindows
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		return PlatformMacOS
+	case strings.Contains(userAgent, "Linux"), strings.Contains(userAgent, "X11"):
+		return PlatformLinux
+	}
+	return ""
+}
+
+// preferredLanguage returns the language of an Accept-Language header
+// with the highest quality, the first one of equal ones.
+func preferredLanguage(header string) string {
+	var (
+		best  string
+		bestQ float64
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+
+	return best
+}
+
+// matchLanguage reports whether tag is the language range r or a more
+// specific one, de matching de-AT.
+func matchLanguage(r, tag string) bool {
+	r, tag = strings.ToLower(r), strings.ToLower(tag)
+	return tag == r || strings.HasPrefix(tag, r+"-")
+}
+
+// validateRules reports the first invalid rule as an *OptionsError.
+func validateRules(rules []Rule) error {
+	if len(rules) > maxRules {
+		return &OptionsError{Field: "rules", Reason: fmt.Sprintf("must be at most %d", maxRules)}
+	}
+
+	for i, r := range rules {
+		field := func(name string) string { return fmt.Sprintf("rules[%d].%s", i, name) }
+
+		if err := validateURL(r.URL); err != nil {
+			return &OptionsError{Field: field("url"), Reason: "must be an http(s) url"}
+		}
+
+		for _, p := range r.Platforms {
+			switch p {
+			case PlatformIOS, PlatformAndroid, PlatformWindows, PlatformMacOS, PlatformLinux:
+			default:
+				return &OptionsError{Field: field("platforms"), Reason: "must be ios, android, windows, macos or linux"}
+			}
+		}
+
+		if slices.ContainsFunc(r.Languages, func(l string) bool { return strings.TrimSpace(l) == "" }) {
+			return &OptionsError{Field: field("languages"), Reason: "must not be empty"}
+		}
+
+		if slices.ContainsFunc(r.Countries, func(c string) bool { return len(c) != 2 }) {
+			return &OptionsError{Field: field("countries"), Reason: "must be ISO 3166 alpha-2 codes"}
+		}
+
+		if r.From != nil && r.Until != nil && !r.From.Before(*r.Until) {
+			return &OptionsError{Field: field("until"), Reason: "must be after from"}
+		}
+
+		if r.Header != nil && !validHeaderName(r.Header.Name) {
+			return &OptionsError{Field: field("header.name"), Reason: "must be a header name"}
+		}
+	}
+
+	return nil
+}
+
+// validHeaderName reports whether name is a token, as header names are.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 127 || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package shortener_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchRule(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	from, until := now.Add(-time.Hour), now.Add(time.Hour)
+
+	testCases := []struct {
+		name    string
+		rule    shortener.Rule
+		visitor shortener.Visitor
+		want    int
+	}{
+		{
+			name:    "no conditions",
+			rule:    shortener.Rule{},
+			visitor: shortener.Visitor{},
+			want:    0,
+		},
+		{
+			name:    "platform",
+			rule:    shortener.Rule{Platforms: []shortener.Platform{shortener.PlatformIOS, shortener.PlatformAndroid}},
+			visitor: shortener.Visitor{UserAgent: "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X)"},
+			want:    0,
+		},
+		{
+			name:    "other platform",
+			rule:    shortener.Rule{Platforms: []shortener.Platform{shortener.PlatformIOS}},
+			visitor: shortener.Visitor{UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"},
+			want:    -1,
+		},
+		{
+			name:    "language range",
+			rule:    shortener.Rule{Languages: []string{"de"}},
+			visitor: shortener.Visitor{AcceptLanguage: "en;q=0.8, de-AT, fr;q=0.9"},
+			want:    0,
+		},
+		{
+			name:    "language of lower quality",
+			rule:    shortener.Rule{Languages: []string{"fr"}},
+			visitor: shortener.Visitor{AcceptLanguage: "en;q=0.8, de-AT, fr;q=0.9"},
+			want:    -1,
+		},
+		{
+			name:    "no language",
+			rule:    shortener.Rule{Languages: []string{"en"}},
+			visitor: shortener.Visitor{},
+			want:    -1,
+		},
+		{
+			name:    "country",
+			rule:    shortener.Rule{Countries: []string{"DE", "AT"}},
+			visitor: shortener.Visitor{Country: "at"},
+			want:    0,
+		},
+		{
+			name:    "unknown country",
+			rule:    shortener.Rule{Countries: []string{"DE"}},
+			visitor: shortener.Visitor{},
+			want:    -1,
+		},
+		{
+			name:    "in window",
+			rule:    shortener.Rule{From: &from, Until: &until},
+			visitor: shortener.Visitor{Time: now},
+			want:    0,
+		},
+		{
+			name:    "window ended",
+			rule:    shortener.Rule{Until: &from},
+			visitor: shortener.Visitor{Time: now},
+			want:    -1,
+		},
+		{
+			name:    "window not started",
+			rule:    shortener.Rule{From: &until},
+			visitor: shortener.Visitor{Time: now},
+			want:    -1,
+		},
+		{
+			name:    "header present",
+			rule:    shortener.Rule{Header: &shortener.HeaderCondition{Name: "X-Beta"}},
+			visitor: shortener.Visitor{Header: http.Header{"X-Beta": {"1"}}},
+			want:    0,
+		},
+		{
+			name:    "header value",
+			rule:    shortener.Rule{Header: &shortener.HeaderCondition{Name: "X-Beta", Value: "2"}},
+			visitor: shortener.Visitor{Header: http.Header{"X-Beta": {"1"}}},
+			want:    -1,
+		},
+		{
+			name: "every condition",
+			rule: shortener.Rule{
+				Platforms: []shortener.Platform{shortener.PlatformAndroid},
+				Countries: []string{"DE"},
+				Languages: []string{"de"},
+			},
+			visitor: shortener.Visitor{UserAgent: "Mozilla/5.0 (Linux; Android 14)", Country: "DE", AcceptLanguage: "en"},
+			want:    -1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, shortener.MatchRule([]shortener.Rule{tc.rule}, tc.visitor))
+		})
+	}
+
+	// the first matching rule wins
+	rules := []shortener.Rule{
+		{URL: "https://example.com/de", Countries: []string{"DE"}},
+		{URL: "https://example.com/mobile", Platforms: []shortener.Platform{shortener.PlatformAndroid}},
+		{URL: "https://example.com/any"},
+	}
+	assert.Equal(t, 1, shortener.MatchRule(rules, shortener.Visitor{UserAgent: "Android", Country: "FR"}))
+	assert.Equal(t, 0, shortener.MatchRule(rules, shortener.Visitor{UserAgent: "Android", Country: "DE"}))
+	assert.Equal(t, 2, shortener.MatchRule(rules, shortener.Visitor{}))
+}
+
+func TestPlatformOf(t *testing.T) {
+	testCases := []struct {
+		userAgent string
+		want      shortener.Platform
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15", shortener.PlatformIOS},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36", shortener.PlatformAndroid},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36", shortener.PlatformWindows},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15", shortener.PlatformMacOS},
+		{"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0", shortener.PlatformLinux},
+		{"curl/8.4.0", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.userAgent, func(t *testing.T) {
+			assert.Equal(t, tc.want, shortener.PlatformOf(tc.userAgent))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package shortener
+
+import (
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+)
+
+var (
+	// ErrLinkNotActive means the link is visited before its active_from,
+	// the error is a *NotActiveError.
+	ErrLinkNotActive = apierr.ErrLinkNotActive
+	// ErrLinkExpired means the link is visited after its active_until.
+	ErrLinkExpired = apierr.ErrLinkExpired
+)
+
+// NotActiveError tells when a link visited too early becomes active.
+type NotActiveError struct {
+	From time.Time
+}
+
+func (e *NotActiveError) Error() string {
+	return ErrLinkNotActive.Error() + ", it is active from " + e.From.UTC().Format(time.RFC3339)
+}
+
+func (e *NotActiveError) Is(target error) bool {
+	return target == ErrLinkNotActive
+}
+
+// checkActive reports whether opts let the link redirect at now.
+func checkActive(opts Options, now time.Time) error {
+	if opts.ActiveFrom != nil && now.Before(*opts.ActiveFrom) {
+		return &NotActiveError{From: *opts.ActiveFrom}
+	}
+	if opts.ActiveUntil != nil && !now.Before(*opts.ActiveUntil) {
+		return ErrLinkExpired
+	}
+	return nil
+}
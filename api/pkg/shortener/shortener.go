@@ -0,0 +1,739 @@
+// Package shortener is the url shortener without a transport. It can be
+// embedded into other Go services; the HTTP and gRPC servers of this
+// repository are thin adapters over it.
+//
+//	svc := shortener.New(store, cache, shortener.Config{}, log)
+//
+//	alias, err := svc.Shorten(ctx, "https://www.google.com", "")
+//	url, err := svc.Resolve(ctx, alias)
+//
+// Aliases are scoped to the domain of the context, see WithDomain;
+// every domain has its own aliases. Links may carry Options changing where
+// a visit goes, see Redirect, and be protected by a password, see Unlock.
+package shortener
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/cache"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/random"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/apierr"
+)
+
+const (
+	DefaultAliasLength = 6
+	// DefaultMaxRetries is how many generated aliases are tried before
+	// Shorten gives up.
+	DefaultMaxRetries = 10
+
+	setCacheTimeout = time.Second
+)
+
+// Stores and caches report these errors, wrapped or not.
+var (
+	// ErrURLNotFound means no link has the alias.
+	ErrURLNotFound = errors.New("url not found")
+	// ErrAliasExist means the alias is taken by another link.
+	ErrAliasExist = errors.New("url exists")
+	// ErrLinkDisabled means the link was disabled, e.g. because its
+	// destination kept failing health checks.
+	ErrLinkDisabled = errors.New("link is disabled")
+	// ErrCacheMiss means the cache has no url for the alias.
+	ErrCacheMiss = cache.ErrKeyNotExist
+)
+
+var (
+	ErrEmptyURL   = apierr.ErrEmptyURL
+	ErrInvalidURL = apierr.ErrInvalidURLFormat
+	ErrEmptyAlias = apierr.ErrEmptyAlias
+	// ErrInvalidOptions means the options of a link are not supported.
+	ErrInvalidOptions = apierr.ErrInvalidOptions
+	// ErrMaxRetries means no free alias was found among the generated ones.
+	ErrMaxRetries = errors.New("max retries for generate unique alias")
+)
+
+// Store keeps the links. database.Database implementations satisfy it.
+type Store interface {
+	// SaveURL returns ErrAliasExist when the alias is taken.
+	SaveURL(ctx context.Context, url string, alias string) error
+	// SaveTarget saves a link with options, it returns ErrAliasExist when
+	// the alias is taken.
+	SaveTarget(ctx context.Context, alias string, t Target) error
+	// GetTarget returns ErrURLNotFound when no link has the alias,
+	// ErrLinkDisabled when the link is disabled and ErrLinkExhausted when
+	// it served its click limit.
+	GetTarget(ctx context.Context, alias string) (Target, error)
+	// UpdateURL returns ErrURLNotFound when no link has the alias.
+	UpdateURL(ctx context.Context, alias string, url string) error
+	// SetOptions returns ErrURLNotFound when no link has the alias.
+	SetOptions(ctx context.Context, alias string, opts Options) error
+	// DeleteURL returns ErrURLNotFound when no link has the alias.
+	DeleteURL(ctx context.Context, alias string) (int64, error)
+	// RecordClicks raises the redirects counted for the link saved under
+	// alias to clicks.
+	RecordClicks(ctx context.Context, alias string, clicks int64) error
+}
+
+// Cache keeps resolved urls by alias, prefixed with the domain for other
+// domains than the default one. Links with options are kept as JSON.
+// cache.Cache implementations satisfy it.
+type Cache interface {
+	// Get returns ErrCacheMiss when the alias is not cached.
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value any) error
+	// Expire extends the lifetime of a cached alias.
+	Expire(ctx context.Context, key string) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Policy decides which urls links may point to. policy.Policy satisfies
+// it.
+type Policy interface {
+	// Check returns an error when url must not be a destination.
+	Check(ctx context.Context, url string) error
+}
+
+// ReputationChecker tells whether a url is known to be malicious.
+// reputation.Checker satisfies it.
+type ReputationChecker interface {
+	// CheckURL returns an error when url must not be a destination, e.g.
+	// because it is listed for phishing.
+	CheckURL(ctx context.Context, url string) error
+}
+
+// Enricher describes the pages of saved links in the background.
+// metadata.Enricher satisfies it.
+type Enricher interface {
+	// Enqueue schedules describing the page of the link saved under alias
+	// in the domain of ctx and must not block.
+	Enqueue(ctx context.Context, alias, url string)
+}
+
+// Config tunes a Shortener. The zero value uses the defaults.
+type Config struct {
+	// AliasLength is the length of generated aliases, unless the domain
+	// of the context has its own.
+	AliasLength int
+	// MaxRetries is how many generated aliases are tried.
+	MaxRetries int
+	// Policy checks the urls of new and updated links, every valid url
+	// is accepted when nil.
+	Policy Policy
+	// Reputation checks the urls of new and updated links after the
+	// policy, nothing is checked when nil.
+	Reputation ReputationChecker
+	// CheckOnResolve checks the reputation of resolved urls as well, urls
+	// may be listed after the link was created.
+	CheckOnResolve bool
+	// Enricher is told about created and updated links, nothing is
+	// enriched when nil.
+	Enricher Enricher
+	// AccessSecret signs the access tokens to protected links. A random
+	// secret is used when empty, tokens are then only valid for this
+	// Shortener.
+	AccessSecret []byte
+	// AccessTTL is how long access tokens are valid.
+	AccessTTL time.Duration
+	// Clicks counts the redirects of click-limited links. A counter in
+	// memory is used when nil, which keeps the limit only as long as no
+	// other Shortener redirects the same links.
+	Clicks ClickCounter
+}
+
+// Shortener saves, resolves, updates and deletes links. Resolved urls are
+// read through the cache, changes evict it. It is safe for concurrent use.
+type Shortener struct {
+	store Store
+	cache Cache
+	cfg   Config
+	log   *slog.Logger
+}
+
+// New returns a Shortener over store. cache may be nil, then every
+// Resolve reads the store; log may be nil, then nothing is logged.
+func New(store Store, cache Cache, cfg Config, log *slog.Logger) *Shortener {
+	if cfg.AliasLength <= 0 {
+		cfg.AliasLength = DefaultAliasLength
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = DefaultMaxRetries
+	}
+	if len(cfg.AccessSecret) == 0 {
+		cfg.AccessSecret = make([]byte, 32)
+		_, _ = rand.Read(cfg.AccessSecret)
+	}
+	if cfg.AccessTTL <= 0 {
+		cfg.AccessTTL = DefaultAccessTTL
+	}
+	if cfg.Clicks == nil {
+		cfg.Clicks = newLocalCounter()
+	}
+	if cache == nil {
+		cache = nopCache{}
+	}
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	return &Shortener{
+		store: store,
+		cache: cache,
+		cfg:   cfg,
+		log:   log,
+	}
+}
+
+// Config returns the configuration in use, with the defaults filled in.
+func (s *Shortener) Config() Config {
+	return s.cfg
+}
+
+// WithPolicy returns a copy of s checking urls with p. The copy shares the
+// store and the cache with s.
+func (s *Shortener) WithPolicy(p Policy) *Shortener {
+	c := *s
+	c.cfg.Policy = p
+	return &c
+}
+
+// Shorten validates url and saves it under alias, or under a generated
+// alias when alias is empty. It returns the alias the url was saved under.
+func (s *Shortener) Shorten(ctx context.Context, url, alias string) (string, error) {
+	return s.ShortenWith(ctx, url, alias, Options{}, "")
+}
+
+// ShortenWith is Shorten for a link with options, protected by password
+// unless it is empty.
+func (s *Shortener) ShortenWith(ctx context.Context, url, alias string, opts Options, password string) (string, error) {
+	const fn = "shortener.(*Shortener).ShortenWith"
+
+	wp := wraper.New(fn)
+
+	if err := s.checkURL(ctx, url); err != nil {
+		return "", err
+	}
+
+	if err := validateOptions(opts); err != nil {
+		return "", err
+	}
+
+	if err := s.checkDestinations(ctx, opts); err != nil {
+		return "", err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return "", err
+	}
+
+	t := Target{URL: url, Options: opts, PasswordHash: hash}
+
+	if alias != "" {
+		if err := s.save(ctx, alias, t); err != nil {
+			return "", wp.Wrap(err)
+		}
+
+		s.saved(ctx, alias, t)
+		return alias, nil
+	}
+
+	length := s.cfg.AliasLength
+	if d := DomainFrom(ctx); d.AliasLength > 0 {
+		length = d.AliasLength
+	}
+
+	for range s.cfg.MaxRetries {
+		generated := random.StringRandV2(length)
+
+		err := s.save(ctx, generated, t)
+		if err == nil {
+			s.saved(ctx, generated, t)
+			return generated, nil
+		}
+
+		if !errors.Is(err, ErrAliasExist) {
+			return "", wp.Wrap(err)
+		}
+	}
+
+	return "", wp.Wrap(ErrMaxRetries)
+}
+
+// save saves a link, plain links with SaveURL.
+func (s *Shortener) save(ctx context.Context, alias string, t Target) error {
+	if t.IsPlain() {
+		return s.store.SaveURL(ctx, t.URL, alias)
+	}
+	return s.store.SaveTarget(ctx, alias, t)
+}
+
+// Resolve returns where a visit of alias without query goes, see
+// Redirect.
+func (s *Shortener) Resolve(ctx context.Context, alias string) (string, error) {
+	return s.Redirect(ctx, alias, nil)
+}
+
+// Redirect returns where a visit of alias with the query incoming goes,
+// see Visit.
+func (s *Shortener) Redirect(ctx context.Context, alias string, incoming url.Values) (string, error) {
+	v, err := s.Visit(ctx, alias, incoming)
+	if err != nil {
+		return "", err
+	}
+	return v.URL, nil
+}
+
+// Visit is where a visit of a link goes.
+type Visit struct {
+	// URL is the destination, see Destination.
+	URL string
+	// Interstitial tells to warn the visitor before sending them to URL.
+	Interstitial bool
+	// Rule is the index of the rule of the link that picked URL, -1 when
+	// no rule matched.
+	Rule int
+	// Variant is the index of the variant of the link that picked URL, -1
+	// when a rule matched or the link has no variants.
+	Variant int
+	// CreatedAt and Metadata describe the link, they are empty when the
+	// store does not tell.
+	CreatedAt time.Time
+	Metadata  *Metadata
+}
+
+// Visit returns where a visit of alias with the query incoming goes: the
+// url of the first rule of the link the visitor of ctx matches, see
+// WithVisitor, the url of the variant picked for the visitor, or the url
+// of the link. Protected links return
+// ErrPasswordRequired unless ctx carries an access token to them, see
+// WithAccess. Every visit of a click-limited link counts as a redirect,
+// exhausted links return ErrLinkExhausted. Visits outside of the active
+// window of the link return a *NotActiveError or ErrLinkExpired.
+func (s *Shortener) Visit(ctx context.Context, alias string, incoming url.Values) (Visit, error) {
+	return s.visit(ctx, alias, incoming, true)
+}
+
+// Peek returns where a visit of alias with the query incoming goes, like
+// Visit, without counting it as a redirect: previews and lookups of a
+// click-limited link leave its redirects to visitors. Exhausted links
+// still return ErrLinkExhausted.
+func (s *Shortener) Peek(ctx context.Context, alias string, incoming url.Values) (Visit, error) {
+	return s.visit(ctx, alias, incoming, false)
+}
+
+// visit returns where a visit of alias goes, counting it as a redirect of
+// a click-limited link when click is set.
+func (s *Shortener) visit(ctx context.Context, alias string, incoming url.Values, click bool) (Visit, error) {
+	const fn = "shortener.(*Shortener).visit"
+
+	t, err := s.Target(ctx, alias)
+	if err != nil {
+		return Visit{}, err
+	}
+
+	visitor := visitorFrom(ctx)
+
+	// cached links carry their window, so it is checked on every visit
+	if err := checkActive(t.Options, visitor.Time); err != nil {
+		return Visit{}, err
+	}
+
+	if err := s.checkAccess(ctx, alias, t); err != nil {
+		return Visit{}, err
+	}
+
+	v := Visit{
+		Rule:         MatchRule(t.Rules, visitor),
+		Variant:      -1,
+		Interstitial: t.Interstitial,
+		CreatedAt:    t.CreatedAt,
+		Metadata:     t.Metadata,
+	}
+	if v.Rule >= 0 {
+		t.URL = t.Rules[v.Rule].URL
+	} else if len(t.Variants) > 0 {
+		v.Variant = PickVariant(t.Variants, variantKey(alias, visitor.Key))
+		t.URL = t.Variants[v.Variant].URL
+	}
+
+	// Target checked the url of the link, not the one of the rule or the
+	// variant served instead
+	if v.Rule >= 0 || v.Variant >= 0 {
+		if err := s.checkReputation(ctx, t.URL); err != nil {
+			return Visit{}, err
+		}
+	}
+
+	if t.MaxClicks > 0 {
+		check := s.checkClicks
+		if click {
+			check = s.click
+		}
+		if err := check(ctx, alias, t); err != nil {
+			return Visit{}, err
+		}
+	}
+
+	dest, err := Destination(t, incoming)
+	if err != nil {
+		return Visit{}, wraper.Wrap(fn, err)
+	}
+
+	v.URL = dest
+	return v, nil
+}
+
+// Target returns the link saved under alias, from the cache if possible.
+// Links read from the store are cached in the background. With
+// CheckOnResolve links failing the reputation check are not returned.
+func (s *Shortener) Target(ctx context.Context, alias string) (Target, error) {
+	const fn = "shortener.(*Shortener).Target"
+
+	wp := wraper.New(fn)
+
+	if strings.TrimSpace(alias) == "" {
+		return Target{}, ErrEmptyAlias
+	}
+
+	key := cacheKey(ctx, alias)
+
+	cached, err := s.cache.Get(ctx, key)
+	if err == nil {
+		if err := s.cache.Expire(ctx, key); err != nil {
+			s.log.Error("expire alias",
+				slog.String("key", alias),
+				slog.String("value", cached),
+				sl.Error(err),
+			)
+		}
+
+		t, err := decodeTarget(cached)
+		if err == nil {
+			return s.checkResolved(ctx, t)
+		}
+
+		s.log.Error("decode cached link",
+			slog.String("key", alias),
+			sl.Error(err),
+		)
+	} else if !errors.Is(err, ErrCacheMiss) {
+		s.log.Error("get URL from cache",
+			slog.String("key", alias),
+			sl.Error(err),
+		)
+	}
+
+	t, err := s.store.GetTarget(ctx, alias)
+	if err != nil {
+		return Target{}, wp.Wrap(err)
+	}
+
+	value, err := encodeTarget(t)
+	if err != nil {
+		return Target{}, wp.Wrap(err)
+	}
+
+	setCtx, cancel := context.WithTimeout(context.Background(), setCacheTimeout)
+
+	go func() {
+		defer cancel()
+
+		if err := s.cache.Set(setCtx, key, value); err != nil {
+			s.log.Error("cache URL",
+				slog.String("key", alias),
+				slog.String("value", value),
+				sl.Error(err),
+			)
+		}
+	}()
+
+	return s.checkResolved(ctx, t)
+}
+
+// checkResolved checks the reputation of a resolved link when configured.
+func (s *Shortener) checkResolved(ctx context.Context, t Target) (Target, error) {
+	if err := s.checkReputation(ctx, t.URL); err != nil {
+		return Target{}, err
+	}
+	return t, nil
+}
+
+// checkReputation checks the reputation of a url about to be served when
+// configured.
+func (s *Shortener) checkReputation(ctx context.Context, url string) error {
+	if s.cfg.Reputation == nil || !s.cfg.CheckOnResolve {
+		return nil
+	}
+	return s.cfg.Reputation.CheckURL(ctx, url)
+}
+
+// Update points alias to url.
+func (s *Shortener) Update(ctx context.Context, alias, url string) error {
+	const fn = "shortener.(*Shortener).Update"
+
+	wp := wraper.New(fn)
+
+	if strings.TrimSpace(alias) == "" {
+		return ErrEmptyAlias
+	}
+
+	if err := s.checkURL(ctx, url); err != nil {
+		return err
+	}
+
+	if err := s.store.UpdateURL(ctx, alias, url); err != nil {
+		return wp.Wrap(err)
+	}
+
+	s.evict(ctx, alias)
+	s.enrich(ctx, alias, url)
+	return nil
+}
+
+// SetOptions replaces the options of the link saved under alias.
+func (s *Shortener) SetOptions(ctx context.Context, alias string, opts Options) error {
+	const fn = "shortener.(*Shortener).SetOptions"
+
+	wp := wraper.New(fn)
+
+	if strings.TrimSpace(alias) == "" {
+		return ErrEmptyAlias
+	}
+
+	if err := validateOptions(opts); err != nil {
+		return err
+	}
+
+	if err := s.checkDestinations(ctx, opts); err != nil {
+		return err
+	}
+
+	if err := s.store.SetOptions(ctx, alias, opts); err != nil {
+		return wp.Wrap(err)
+	}
+
+	s.evict(ctx, alias)
+	return nil
+}
+
+// Delete moves the link saved under alias to the trash.
+func (s *Shortener) Delete(ctx context.Context, alias string) error {
+	const fn = "shortener.(*Shortener).Delete"
+
+	wp := wraper.New(fn)
+
+	if strings.TrimSpace(alias) == "" {
+		return ErrEmptyAlias
+	}
+
+	if _, err := s.store.DeleteURL(ctx, alias); err != nil {
+		return wp.Wrap(err)
+	}
+
+	s.evict(ctx, alias)
+	s.resetClicks(ctx, alias)
+	return nil
+}
+
+// Evict drops the cached urls of aliases changed without the Shortener,
+// e.g. by a bulk import.
+func (s *Shortener) Evict(ctx context.Context, aliases ...string) {
+	for _, alias := range aliases {
+		s.evict(ctx, alias)
+	}
+}
+
+func (s *Shortener) evict(ctx context.Context, alias string) {
+	if err := s.cache.Delete(ctx, cacheKey(ctx, alias)); err != nil && !errors.Is(err, ErrCacheMiss) {
+		s.log.Error("deleting URL from cache",
+			slog.String("key", alias),
+			sl.Error(err),
+		)
+	}
+}
+
+// WithReputation returns a copy of s checking urls with r, on Resolve too
+// when onResolve is set. The copy shares the store and the cache with s.
+func (s *Shortener) WithReputation(r ReputationChecker, onResolve bool) *Shortener {
+	c := *s
+	c.cfg.Reputation = r
+	c.cfg.CheckOnResolve = onResolve
+	return &c
+}
+
+// WithEnricher returns a copy of s telling e about created and updated
+// links. The copy shares the store and the cache with s.
+func (s *Shortener) WithEnricher(e Enricher) *Shortener {
+	c := *s
+	c.cfg.Enricher = e
+	return &c
+}
+
+// saved tells about the link t saved under alias. A click-limited link
+// does not inherit the counter of a deleted link with its alias.
+func (s *Shortener) saved(ctx context.Context, alias string, t Target) {
+	if t.MaxClicks > 0 {
+		s.resetClicks(ctx, alias)
+	}
+	s.enrich(ctx, alias, t.URL)
+}
+
+func (s *Shortener) enrich(ctx context.Context, alias, url string) {
+	if s.cfg.Enricher != nil {
+		s.cfg.Enricher.Enqueue(ctx, alias, url)
+	}
+}
+
+// linkPrefix namespaces the cached links, so that no alias names a key
+// of the counters sharing the cache, e.g. clicks: or ratelimit:.
+const linkPrefix = "link:"
+
+// cacheKey returns the key alias is cached under. Keys of the default
+// domain are the prefixed aliases; other domains are separated from the
+// alias by a NUL byte, which Postgres never stores in an alias.
+func cacheKey(ctx context.Context, alias string) string {
+	if d := DomainFrom(ctx).Name; d != "" {
+		return linkPrefix + d + "\x00" + alias
+	}
+	return linkPrefix + alias
+}
+
+// encodeTarget returns the cached form of t: the bare url for plain
+// links nothing describes, so that they stay readable in the cache, and
+// JSON otherwise.
+func encodeTarget(t Target) (string, error) {
+	if t.IsPlain() && t.CreatedAt.IsZero() && t.Metadata == nil {
+		return t.URL, nil
+	}
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// decodeTarget reads what encodeTarget wrote. Urls never start with a
+// brace, they are http(s) urls.
+func decodeTarget(value string) (Target, error) {
+	if !strings.HasPrefix(value, "{") {
+		return Target{URL: value}, nil
+	}
+
+	var t Target
+	err := json.Unmarshal([]byte(value), &t)
+	return t, err
+}
+
+// CheckURL validates url and checks it against the policy and its
+// reputation like Shorten does, without saving anything. Links saved past
+// Shorten, e.g. by imports, are checked with it.
+func (s *Shortener) CheckURL(ctx context.Context, url string) error {
+	return s.checkURL(ctx, url)
+}
+
+// checkURL validates url and checks it against the policy and its
+// reputation.
+func (s *Shortener) checkURL(ctx context.Context, url string) error {
+	if err := validateURL(url); err != nil {
+		return err
+	}
+
+	return s.checkDestination(ctx, url)
+}
+
+// checkDestinations checks the urls of the rules and variants of opts like
+// the urls of links. They are valid urls, validateOptions checked them.
+func (s *Shortener) checkDestinations(ctx context.Context, opts Options) error {
+	for _, r := range opts.Rules {
+		if err := s.checkDestination(ctx, r.URL); err != nil {
+			return err
+		}
+	}
+	for _, v := range opts.Variants {
+		if err := s.checkDestination(ctx, v.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDestination checks a valid url against the policy and its
+// reputation.
+func (s *Shortener) checkDestination(ctx context.Context, url string) error {
+	if s.cfg.Policy != nil {
+		if err := s.cfg.Policy.Check(ctx, url); err != nil {
+			return err
+		}
+	}
+
+	if s.cfg.Reputation != nil {
+		return s.cfg.Reputation.CheckURL(ctx, url)
+	}
+
+	return nil
+}
+
+func validateURL(url string) error {
+	if url == "" {
+		return ErrEmptyURL
+	}
+
+	if !IsValidURL(url) {
+		return ErrInvalidURL
+	}
+
+	return nil
+}
+
+// IsValidURL reports whether urlToCheck is an absolute http(s) url.
+func IsValidURL(urlToCheck string) bool {
+	const (
+		HTTP  = "http"
+		HTTPs = "https"
+	)
+
+	if urlToCheck == "" {
+		return false
+	}
+
+	if strings.ContainsAny(urlToCheck, " \t\n\r\b\a") {
+		return false
+	}
+
+	u, err := url.ParseRequestURI(urlToCheck)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return false
+	}
+
+	if u.Scheme != HTTP && u.Scheme != HTTPs {
+		return false
+	}
+
+	if strings.Contains(u.Host, "..") || strings.Contains(u.Host, " ") {
+		return false
+	}
+
+	return true
+}
+
+// nopCache is used without a cache, every alias is a miss.
+type nopCache struct{}
+
+func (nopCache) Get(context.Context, string) (string, error) { return "", ErrCacheMiss }
+func (nopCache) Set(context.Context, string, any) error      { return nil }
+func (nopCache) Expire(context.Context, string) error        { return nil }
+func (nopCache) Delete(context.Context, string) error        { return nil }
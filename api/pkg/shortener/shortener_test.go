@@ -0,0 +1,958 @@
+package shortener_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/policy"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/reputation"
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore is a Store as an embedding service would write it, with the
+// types of this package only.
+type memStore struct {
+	mu    sync.Mutex
+	links map[string]string
+	opts  map[string]shortener.Options
+	// hashes are the password hashes of protected links
+	hashes map[string]string
+	// clicks are the recorded redirects of click-limited links
+	clicks map[string]int64
+	// taken makes SaveURL report this many generated aliases as taken
+	taken int
+	err   error
+	saves int
+}
+
+var _ shortener.Store = (*memStore)(nil)
+
+func newMemStore() *memStore {
+	return &memStore{
+		links:  make(map[string]string),
+		opts:   make(map[string]shortener.Options),
+		hashes: make(map[string]string),
+		clicks: make(map[string]int64),
+	}
+}
+
+// key scopes alias to the domain of ctx.
+func key(ctx context.Context, alias string) string {
+	if d := shortener.DomainFrom(ctx).Name; d != "" {
+		return d + "/" + alias
+	}
+	return alias
+}
+
+func (s *memStore) SaveURL(ctx context.Context, url, alias string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alias = key(ctx, alias)
+
+	s.saves++
+	if s.err != nil {
+		return s.err
+	}
+	if _, ok := s.links[alias]; ok || s.taken > 0 {
+		s.taken--
+		return shortener.ErrAliasExist
+	}
+	s.links[alias] = url
+	return nil
+}
+
+func (s *memStore) SaveTarget(ctx context.Context, alias string, t shortener.Target) error {
+	if err := s.SaveURL(ctx, t.URL, alias); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.opts[key(ctx, alias)] = t.Options
+	s.hashes[key(ctx, alias)] = t.PasswordHash
+	return nil
+}
+
+func (s *memStore) GetTarget(ctx context.Context, alias string) (shortener.Target, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alias = key(ctx, alias)
+
+	if s.err != nil {
+		return shortener.Target{}, s.err
+	}
+	url, ok := s.links[alias]
+	if !ok {
+		return shortener.Target{}, shortener.ErrURLNotFound
+	}
+	t := shortener.Target{URL: url, Options: s.opts[alias], PasswordHash: s.hashes[alias], Clicks: s.clicks[alias]}
+	if t.MaxClicks > 0 && t.Clicks >= t.MaxClicks {
+		return shortener.Target{}, shortener.ErrLinkExhausted
+	}
+	return t, nil
+}
+
+func (s *memStore) SetOptions(ctx context.Context, alias string, opts shortener.Options) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alias = key(ctx, alias)
+
+	if _, ok := s.links[alias]; !ok {
+		return shortener.ErrURLNotFound
+	}
+	s.opts[alias] = opts
+	return nil
+}
+
+func (s *memStore) UpdateURL(ctx context.Context, alias, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alias = key(ctx, alias)
+
+	if _, ok := s.links[alias]; !ok {
+		return shortener.ErrURLNotFound
+	}
+	s.links[alias] = url
+	return nil
+}
+
+func (s *memStore) DeleteURL(ctx context.Context, alias string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alias = key(ctx, alias)
+
+	if _, ok := s.links[alias]; !ok {
+		return 0, shortener.ErrURLNotFound
+	}
+	delete(s.links, alias)
+	delete(s.clicks, alias)
+	return 1, nil
+}
+
+func (s *memStore) RecordClicks(ctx context.Context, alias string, clicks int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alias = key(ctx, alias)
+
+	if _, ok := s.links[alias]; !ok {
+		return shortener.ErrURLNotFound
+	}
+	s.clicks[alias] = max(s.clicks[alias], clicks)
+	return nil
+}
+
+// recorded returns the redirects recorded for alias.
+func (s *memStore) recorded(alias string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.clicks[alias]
+}
+
+type memCache struct {
+	mu   sync.Mutex
+	vals map[string]string
+	// set receives the keys written by Set
+	set chan string
+}
+
+func newMemCache() *memCache {
+	return &memCache{vals: make(map[string]string), set: make(chan string, 10)}
+}
+
+func (c *memCache) Get(_ context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.vals[key]
+	if !ok {
+		return "", shortener.ErrCacheMiss
+	}
+	return v, nil
+}
+
+func (c *memCache) Set(_ context.Context, key string, value any) error {
+	c.mu.Lock()
+	c.vals[key] = value.(string)
+	c.mu.Unlock()
+
+	c.set <- key
+	return nil
+}
+
+func (c *memCache) Expire(context.Context, string) error { return nil }
+
+func (c *memCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.vals[key]; !ok {
+		return shortener.ErrCacheMiss
+	}
+	delete(c.vals, key)
+	return nil
+}
+
+func (c *memCache) cached(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.vals[key]
+	return v, ok
+}
+
+func TestShorten(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("custom alias", func(t *testing.T) {
+		store := newMemStore()
+		svc := shortener.New(store, nil, shortener.Config{}, nil)
+
+		alias, err := svc.Shorten(ctx, "https://google.com", "google")
+		require.NoError(t, err)
+		assert.Equal(t, "google", alias)
+
+		_, err = svc.Shorten(ctx, "https://google.com", "google")
+		assert.ErrorIs(t, err, shortener.ErrAliasExist)
+	})
+
+	t.Run("generated alias", func(t *testing.T) {
+		store := newMemStore()
+		store.taken = 2
+		svc := shortener.New(store, nil, shortener.Config{AliasLength: 8}, nil)
+
+		alias, err := svc.Shorten(ctx, "https://google.com", "")
+		require.NoError(t, err)
+		assert.Len(t, alias, 8)
+		assert.Equal(t, 3, store.saves)
+		assert.Equal(t, "https://google.com", store.links[alias])
+	})
+
+	t.Run("max retries", func(t *testing.T) {
+		store := newMemStore()
+		store.taken = 100
+		svc := shortener.New(store, nil, shortener.Config{MaxRetries: 3}, nil)
+
+		_, err := svc.Shorten(ctx, "https://google.com", "")
+		assert.ErrorIs(t, err, shortener.ErrMaxRetries)
+		assert.Equal(t, 3, store.saves)
+	})
+
+	t.Run("store failure is not retried", func(t *testing.T) {
+		store := newMemStore()
+		store.err = errors.New("connection refused")
+		svc := shortener.New(store, nil, shortener.Config{}, nil)
+
+		_, err := svc.Shorten(ctx, "https://google.com", "")
+		assert.ErrorIs(t, err, store.err)
+		assert.Equal(t, 1, store.saves)
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		store := newMemStore()
+		svc := shortener.New(store, nil, shortener.Config{}, nil)
+
+		_, err := svc.Shorten(ctx, "", "google")
+		assert.ErrorIs(t, err, shortener.ErrEmptyURL)
+
+		_, err = svc.Shorten(ctx, "ftp://google.com", "google")
+		assert.ErrorIs(t, err, shortener.ErrInvalidURL)
+
+		assert.Zero(t, store.saves)
+	})
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	cache := newMemCache()
+	svc := shortener.New(store, cache, shortener.Config{}, nil)
+
+	_, err := svc.Shorten(ctx, "https://google.com", "google")
+	require.NoError(t, err)
+
+	url, err := svc.Resolve(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", url)
+
+	select {
+	case key := <-cache.set:
+		assert.Equal(t, "link:google", key)
+	case <-time.After(time.Second):
+		t.Fatal("resolved url was not cached")
+	}
+
+	// served from the cache while the store is down
+	store.err = errors.New("connection refused")
+
+	url, err = svc.Resolve(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", url)
+
+	_, err = svc.Resolve(ctx, "unknown")
+	assert.ErrorIs(t, err, store.err)
+
+	store.err = nil
+
+	_, err = svc.Resolve(ctx, "unknown")
+	assert.ErrorIs(t, err, shortener.ErrURLNotFound)
+
+	_, err = svc.Resolve(ctx, " ")
+	assert.ErrorIs(t, err, shortener.ErrEmptyAlias)
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	cache := newMemCache()
+	svc := shortener.New(store, cache, shortener.Config{}, nil)
+
+	_, err := svc.Shorten(ctx, "https://google.com", "google")
+	require.NoError(t, err)
+	_, err = svc.Resolve(ctx, "google")
+	require.NoError(t, err)
+	<-cache.set
+
+	require.NoError(t, svc.Update(ctx, "google", "https://google.org"))
+
+	_, ok := cache.cached("link:google")
+	assert.False(t, ok, "stale url still cached")
+
+	url, err := svc.Resolve(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.org", url)
+
+	assert.ErrorIs(t, svc.Update(ctx, "unknown", "https://google.org"), shortener.ErrURLNotFound)
+	assert.ErrorIs(t, svc.Update(ctx, "google", "not a url"), shortener.ErrInvalidURL)
+	assert.ErrorIs(t, svc.Update(ctx, "", "https://google.org"), shortener.ErrEmptyAlias)
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	cache := newMemCache()
+	svc := shortener.New(store, cache, shortener.Config{}, nil)
+
+	_, err := svc.Shorten(ctx, "https://google.com", "google")
+	require.NoError(t, err)
+	_, err = svc.Resolve(ctx, "google")
+	require.NoError(t, err)
+	<-cache.set
+
+	require.NoError(t, svc.Delete(ctx, "google"))
+
+	_, ok := cache.cached("link:google")
+	assert.False(t, ok, "deleted url still cached")
+
+	_, err = svc.Resolve(ctx, "google")
+	assert.ErrorIs(t, err, shortener.ErrURLNotFound)
+
+	assert.ErrorIs(t, svc.Delete(ctx, "google"), shortener.ErrURLNotFound)
+	assert.ErrorIs(t, svc.Delete(ctx, ""), shortener.ErrEmptyAlias)
+}
+
+func TestConfig(t *testing.T) {
+	cfg := shortener.New(newMemStore(), nil, shortener.Config{}, nil).Config()
+	assert.Equal(t, shortener.DefaultAliasLength, cfg.AliasLength)
+	assert.Equal(t, shortener.DefaultMaxRetries, cfg.MaxRetries)
+}
+
+func TestPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	svc := shortener.New(store, nil, shortener.Config{}, nil).
+		WithPolicy(policy.New(policy.Config{SelfHosts: []string{"sho.rt"}}, nil))
+
+	_, err := svc.Shorten(ctx, "http://169.254.169.254/latest/meta-data", "meta")
+	assert.ErrorIs(t, err, policy.ErrRejected)
+	assert.Equal(t, policy.ReasonPrivateAddress, policy.Reason(err))
+
+	_, err = svc.Shorten(ctx, "https://google.com", "google")
+	require.NoError(t, err)
+
+	err = svc.Update(ctx, "google", "https://sho.rt/google")
+	assert.Equal(t, policy.ReasonSelfReference, policy.Reason(err))
+	assert.Equal(t, "https://google.com", store.links["google"])
+	assert.Equal(t, 1, store.saves)
+}
+
+// listChecker rejects the listed urls.
+type listChecker map[string]bool
+
+func (l listChecker) CheckURL(_ context.Context, url string) error {
+	if l[url] {
+		return &reputation.ThreatError{Threats: []string{"SOCIAL_ENGINEERING"}}
+	}
+	return nil
+}
+
+func TestReputation(t *testing.T) {
+	ctx := context.Background()
+
+	listed := listChecker{"http://phish.example/login": true}
+	store := newMemStore()
+	cache := newMemCache()
+
+	svc := shortener.New(store, cache, shortener.Config{}, nil).WithReputation(listed, false)
+
+	_, err := svc.Shorten(ctx, "http://phish.example/login", "phish")
+	assert.ErrorIs(t, err, reputation.ErrUnsafe)
+	assert.Zero(t, store.saves)
+
+	_, err = svc.Shorten(ctx, "https://google.com", "google")
+	require.NoError(t, err)
+
+	// listed after the link was created
+	listed["https://google.com"] = true
+
+	url, err := svc.Resolve(ctx, "google")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", url)
+	<-cache.set
+
+	svc = svc.WithReputation(listed, true)
+
+	// cached urls are checked as well
+	url, err = svc.Resolve(ctx, "google")
+	assert.ErrorIs(t, err, reputation.ErrUnsafe)
+	assert.Empty(t, url)
+
+	// the destinations of rules and variants are checked when served
+	svc = svc.WithReputation(listed, false)
+
+	iphone := shortener.WithVisitor(ctx, shortener.Visitor{UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"})
+
+	_, err = svc.ShortenWith(ctx, "https://app.example.com", "app", shortener.Options{
+		Rules: []shortener.Rule{{URL: "https://apps.example.com/ios", Platforms: []shortener.Platform{shortener.PlatformIOS}}},
+	}, "")
+	require.NoError(t, err)
+
+	_, err = svc.ShortenWith(ctx, "https://split.example.com", "split", shortener.Options{
+		Variants: []shortener.Variant{{URL: "https://a.example.com", Weight: 1}, {URL: "https://b.example.com", Weight: 1}},
+	}, "")
+	require.NoError(t, err)
+
+	listed["https://apps.example.com/ios"] = true
+	listed["https://a.example.com"] = true
+	listed["https://b.example.com"] = true
+	svc = svc.WithReputation(listed, true)
+
+	_, err = svc.Visit(iphone, "app", nil)
+	assert.ErrorIs(t, err, reputation.ErrUnsafe)
+
+	_, err = svc.Visit(ctx, "split", nil)
+	assert.ErrorIs(t, err, reputation.ErrUnsafe)
+
+	visit, err := svc.Visit(ctx, "app", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://app.example.com", visit.URL)
+}
+
+// queue records the links it is told about.
+type queue []string
+
+func (q *queue) Enqueue(_ context.Context, alias, url string) {
+	*q = append(*q, alias+" "+url)
+}
+
+func TestEnricher(t *testing.T) {
+	ctx := context.Background()
+
+	q := &queue{}
+	svc := shortener.New(newMemStore(), nil, shortener.Config{}, nil).WithEnricher(q)
+
+	_, err := svc.Shorten(ctx, "https://google.com", "google")
+	require.NoError(t, err)
+
+	generated, err := svc.Shorten(ctx, "https://go.dev", "")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Update(ctx, "google", "https://www.google.com"))
+
+	// failed saves are not enriched
+	_, err = svc.Shorten(ctx, "https://google.com", "google")
+	require.ErrorIs(t, err, shortener.ErrAliasExist)
+
+	assert.Equal(t, queue{
+		"google https://google.com",
+		generated + " https://go.dev",
+		"google https://www.google.com",
+	}, *q)
+}
+
+func TestDomains(t *testing.T) {
+	store := newMemStore()
+	cache := newMemCache()
+	svc := shortener.New(store, cache, shortener.Config{AliasLength: 6}, nil)
+
+	brand := shortener.WithDomain(context.Background(), shortener.Domain{Name: "go.brand-a.com", AliasLength: 4})
+
+	// the same alias in two domains
+	_, err := svc.Shorten(context.Background(), "https://google.com", "docs")
+	require.NoError(t, err)
+	_, err = svc.Shorten(brand, "https://brand-a.com/docs", "docs")
+	require.NoError(t, err)
+
+	url, err := svc.Resolve(brand, "docs")
+	require.NoError(t, err)
+	assert.Equal(t, "https://brand-a.com/docs", url)
+
+	select {
+	case key := <-cache.set:
+		assert.Equal(t, "link:go.brand-a.com\x00docs", key)
+	case <-time.After(time.Second):
+		t.Fatal("resolved url was not cached")
+	}
+
+	// the cached url of the domain is not served for the default namespace
+	url, err = svc.Resolve(context.Background(), "docs")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", url)
+
+	// generated aliases have the length of the domain
+	alias, err := svc.Shorten(brand, "https://brand-a.com", "")
+	require.NoError(t, err)
+	assert.Len(t, alias, 4)
+
+	require.NoError(t, svc.Delete(brand, "docs"))
+
+	_, ok := cache.cached("link:go.brand-a.com\x00docs")
+	assert.False(t, ok)
+
+	_, err = svc.Resolve(context.Background(), "docs")
+	assert.NoError(t, err)
+}
+
+func TestOptions(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	cache := newMemCache()
+	svc := shortener.New(store, cache, shortener.Config{}, nil)
+
+	opts := shortener.Options{
+		Passthrough: shortener.PassthroughIncoming,
+		UTM:         &shortener.UTM{Source: "newsletter"},
+	}
+
+	_, err := svc.ShortenWith(ctx, "https://google.com/search?q=go", "google", opts, "")
+	require.NoError(t, err)
+
+	dest, err := svc.Redirect(ctx, "google", url.Values{"q": {"gopher"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com/search?utm_source=newsletter&q=gopher", dest)
+
+	select {
+	case <-cache.set:
+	case <-time.After(time.Second):
+		t.Fatal("resolved link was not cached")
+	}
+
+	// links with options are cached with them
+	cached, _ := cache.cached("link:google")
+	assert.JSONEq(t, `{"url": "https://google.com/search?q=go", "passthrough": "incoming", "utm": {"source": "newsletter"}}`, cached)
+
+	store.err = errors.New("connection refused")
+
+	dest, err = svc.Redirect(ctx, "google", url.Values{"q": {"gopher"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com/search?utm_source=newsletter&q=gopher", dest)
+
+	store.err = nil
+
+	// changed options evict the cached link
+	require.NoError(t, svc.SetOptions(ctx, "google", shortener.Options{}))
+
+	_, ok := cache.cached("link:google")
+	assert.False(t, ok)
+
+	dest, err = svc.Redirect(ctx, "google", url.Values{"q": {"gopher"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com/search?q=go", dest)
+
+	_, err = svc.ShortenWith(ctx, "https://google.com", "", shortener.Options{Passthrough: "always"}, "")
+	assert.ErrorIs(t, err, shortener.ErrInvalidOptions)
+
+	err = svc.SetOptions(ctx, "google", shortener.Options{UTM: &shortener.UTM{Campaign: strings.Repeat("x", 257)}})
+	assert.ErrorIs(t, err, shortener.ErrInvalidOptions)
+
+	err = svc.SetOptions(ctx, "unknown", shortener.Options{})
+	assert.ErrorIs(t, err, shortener.ErrURLNotFound)
+
+	require.NoError(t, svc.SetOptions(ctx, "google", shortener.Options{Interstitial: true}))
+
+	visit, err := svc.Visit(ctx, "google", nil)
+	require.NoError(t, err)
+	assert.Equal(t, shortener.Visit{URL: "https://google.com/search?q=go", Interstitial: true, Rule: -1, Variant: -1}, visit)
+}
+
+func TestPasswords(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	svc := shortener.New(store, nil, shortener.Config{AccessSecret: []byte("secret")}, nil)
+
+	_, err := svc.ShortenWith(ctx, "https://wiki.example.com", "wiki", shortener.Options{}, "hunter2")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(store.hashes["wiki"], "$2"), "password is not stored as a bcrypt hash")
+
+	_, err = svc.Resolve(ctx, "wiki")
+	assert.ErrorIs(t, err, shortener.ErrPasswordRequired)
+
+	_, err = svc.Unlock(ctx, "wiki", "hunter3")
+	assert.ErrorIs(t, err, shortener.ErrWrongPassword)
+
+	token, err := svc.Unlock(ctx, "wiki", "hunter2")
+	require.NoError(t, err)
+
+	dest, err := svc.Resolve(shortener.WithAccess(ctx, token), "wiki")
+	require.NoError(t, err)
+	assert.Equal(t, "https://wiki.example.com", dest)
+
+	// tokens are signed by the secret
+	other := shortener.New(store, nil, shortener.Config{AccessSecret: []byte("other")}, nil)
+	_, err = other.Resolve(shortener.WithAccess(ctx, token), "wiki")
+	assert.ErrorIs(t, err, shortener.ErrPasswordRequired)
+
+	// and only let through to the link they were issued for
+	_, err = svc.ShortenWith(ctx, "https://hr.example.com", "hr", shortener.Options{}, "hunter2")
+	require.NoError(t, err)
+
+	_, err = svc.Resolve(shortener.WithAccess(ctx, token), "hr")
+	assert.ErrorIs(t, err, shortener.ErrPasswordRequired)
+
+	_, err = svc.Resolve(shortener.WithAccess(ctx, "1.forged"), "wiki")
+	assert.ErrorIs(t, err, shortener.ErrPasswordRequired)
+
+	// links without password are plain
+	_, err = svc.Shorten(ctx, "https://example.com", "open")
+	require.NoError(t, err)
+	assert.Empty(t, store.hashes["open"])
+
+	_, err = svc.ShortenWith(ctx, "https://example.com", "", shortener.Options{}, strings.Repeat("x", 73))
+	assert.ErrorIs(t, err, shortener.ErrInvalidOptions)
+}
+
+func TestRules(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	cache := newMemCache()
+	svc := shortener.New(store, cache, shortener.Config{}, nil).
+		WithPolicy(policy.New(policy.Config{}, nil))
+
+	opts := shortener.Options{Rules: []shortener.Rule{
+		{URL: "https://apps.apple.com/app/id1", Platforms: []shortener.Platform{shortener.PlatformIOS}},
+		{URL: "https://play.google.com/store/apps/details?id=app", Platforms: []shortener.Platform{shortener.PlatformAndroid}},
+	}}
+
+	_, err := svc.ShortenWith(ctx, "https://app.example.com", "app", opts, "")
+	require.NoError(t, err)
+
+	iphone := shortener.WithVisitor(ctx, shortener.Visitor{UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"})
+
+	visit, err := svc.Visit(iphone, "app", nil)
+	require.NoError(t, err)
+	assert.Equal(t, shortener.Visit{URL: "https://apps.apple.com/app/id1", Rule: 0, Variant: -1}, visit)
+
+	select {
+	case <-cache.set:
+	case <-time.After(time.Second):
+		t.Fatal("resolved link was not cached")
+	}
+
+	// rules are cached with the link
+	store.err = errors.New("connection refused")
+
+	android := shortener.WithVisitor(ctx, shortener.Visitor{UserAgent: "Mozilla/5.0 (Linux; Android 14)"})
+
+	visit, err = svc.Visit(android, "app", nil)
+	require.NoError(t, err)
+	assert.Equal(t, shortener.Visit{URL: "https://play.google.com/store/apps/details?id=app", Rule: 1, Variant: -1}, visit)
+
+	visit, err = svc.Visit(ctx, "app", nil)
+	require.NoError(t, err)
+	assert.Equal(t, shortener.Visit{URL: "https://app.example.com", Rule: -1, Variant: -1}, visit)
+
+	store.err = nil
+
+	from, until := time.Now(), time.Now().Add(time.Hour)
+
+	// destinations of rules are checked as the link's own
+	_, err = svc.ShortenWith(ctx, "https://app.example.com", "", shortener.Options{Rules: []shortener.Rule{{URL: "http://127.0.0.1/admin"}}}, "")
+	assert.ErrorIs(t, err, policy.ErrRejected)
+
+	invalid := []struct {
+		rule  shortener.Rule
+		field string
+	}{
+		{shortener.Rule{URL: "ftp://files.example.com"}, "rules[0].url"},
+		{shortener.Rule{URL: "https://example.com", Platforms: []shortener.Platform{"beos"}}, "rules[0].platforms"},
+		{shortener.Rule{URL: "https://example.com", Countries: []string{"DEU"}}, "rules[0].countries"},
+		{shortener.Rule{URL: "https://example.com", From: &until, Until: &from}, "rules[0].until"},
+		{shortener.Rule{URL: "https://example.com", Header: &shortener.HeaderCondition{Name: "X Beta"}}, "rules[0].header.name"},
+	}
+	for _, tc := range invalid {
+		err = svc.SetOptions(ctx, "app", shortener.Options{Rules: []shortener.Rule{tc.rule}})
+		assert.ErrorIs(t, err, shortener.ErrInvalidOptions)
+
+		var optsErr *shortener.OptionsError
+		if assert.ErrorAs(t, err, &optsErr) {
+			assert.Equal(t, tc.field, optsErr.Field)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	svc := shortener.New(store, nil, shortener.Config{}, nil)
+
+	opts := shortener.Options{
+		Rules: []shortener.Rule{{URL: "https://example.com/de", Countries: []string{"DE"}}},
+		Variants: []shortener.Variant{
+			{URL: "https://example.com/a", Weight: 70},
+			{URL: "https://example.com/b", Weight: 30},
+		},
+	}
+
+	_, err := svc.ShortenWith(ctx, "https://example.com", "landing", opts, "")
+	require.NoError(t, err)
+
+	visitor := shortener.WithVisitor(ctx, shortener.Visitor{Key: "0123456789abcdef"})
+
+	visit, err := svc.Visit(visitor, "landing", nil)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, visit.Variant, 0)
+	assert.Equal(t, -1, visit.Rule)
+	assert.Equal(t, opts.Variants[visit.Variant].URL, visit.URL)
+
+	for range 10 {
+		again, err := svc.Visit(visitor, "landing", nil)
+		require.NoError(t, err)
+		assert.Equal(t, visit, again, "variant of a visitor changed")
+	}
+
+	// rules are tried first
+	german := shortener.WithVisitor(ctx, shortener.Visitor{Key: "0123456789abcdef", Country: "DE"})
+
+	visit, err = svc.Visit(german, "landing", nil)
+	require.NoError(t, err)
+	assert.Equal(t, shortener.Visit{URL: "https://example.com/de", Rule: 0, Variant: -1}, visit)
+
+	// weights are changed with the options of the link
+	opts.Variants[0].Weight, opts.Variants[1].Weight = 1, 999
+	require.NoError(t, svc.SetOptions(ctx, "landing", opts))
+	assert.Equal(t, opts, store.opts["landing"])
+
+	invalid := []struct {
+		variants []shortener.Variant
+		field    string
+	}{
+		{[]shortener.Variant{{URL: "https://example.com/a", Weight: 1}}, "variants"},
+		{[]shortener.Variant{{URL: "https://example.com/a", Weight: 1}, {URL: "https://example.com/b", Weight: 0}}, "variants[1].weight"},
+		{[]shortener.Variant{{URL: "mailto:growth@example.com", Weight: 1}, {URL: "https://example.com/b", Weight: 1}}, "variants[0].url"},
+	}
+	for _, tc := range invalid {
+		err = svc.SetOptions(ctx, "landing", shortener.Options{Variants: tc.variants})
+		assert.ErrorIs(t, err, shortener.ErrInvalidOptions)
+
+		var optsErr *shortener.OptionsError
+		if assert.ErrorAs(t, err, &optsErr) {
+			assert.Equal(t, tc.field, optsErr.Field)
+		}
+	}
+}
+
+func TestClickLimit(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	cache := newMemCache()
+	svc := shortener.New(store, cache, shortener.Config{}, nil)
+
+	_, err := svc.ShortenWith(ctx, "https://files.example.com/report.pdf", "report", shortener.Options{MaxClicks: 3}, "")
+	require.NoError(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		served    int
+		exhausted int
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := svc.Visit(ctx, "report", nil)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err == nil:
+				served++
+			case errors.Is(err, shortener.ErrLinkExhausted):
+				exhausted++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, served)
+	assert.Equal(t, 47, exhausted)
+
+	assert.Eventually(t, func() bool { return store.recorded("report") == 3 }, time.Second, 10*time.Millisecond,
+		"clicks were not recorded in the store")
+
+	_, err = svc.Visit(ctx, "report", nil)
+	assert.ErrorIs(t, err, shortener.ErrLinkExhausted)
+
+	_, ok := cache.cached("link:report")
+	assert.False(t, ok, "exhausted link is still cached")
+
+	// a counter starting anew starts at the recorded clicks
+	restarted := shortener.New(store, nil, shortener.Config{}, nil)
+
+	_, err = restarted.Visit(ctx, "report", nil)
+	assert.ErrorIs(t, err, shortener.ErrLinkExhausted)
+
+	// a raised limit serves the difference
+	require.NoError(t, svc.SetOptions(ctx, "report", shortener.Options{MaxClicks: 4}))
+
+	_, err = svc.Visit(ctx, "report", nil)
+	require.NoError(t, err)
+
+	_, err = svc.Visit(ctx, "report", nil)
+	assert.ErrorIs(t, err, shortener.ErrLinkExhausted)
+
+	// a new link under the alias counts anew
+	require.NoError(t, svc.Delete(ctx, "report"))
+
+	_, err = svc.ShortenWith(ctx, "https://files.example.com/report-v2.pdf", "report", shortener.Options{MaxClicks: 1}, "")
+	require.NoError(t, err)
+
+	_, err = svc.Visit(ctx, "report", nil)
+	require.NoError(t, err)
+
+	_, err = svc.Shorten(ctx, "https://example.com", "unlimited")
+	require.NoError(t, err)
+
+	for range 5 {
+		_, err = svc.Visit(ctx, "unlimited", nil)
+		require.NoError(t, err)
+	}
+	assert.Zero(t, store.recorded("unlimited"))
+
+	_, err = svc.ShortenWith(ctx, "https://example.com", "", shortener.Options{MaxClicks: -1}, "")
+	assert.ErrorIs(t, err, shortener.ErrInvalidOptions)
+}
+
+func TestPeek(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	svc := shortener.New(store, newMemCache(), shortener.Config{}, nil)
+
+	_, err := svc.ShortenWith(ctx, "https://files.example.com/report.pdf", "report", shortener.Options{MaxClicks: 1}, "")
+	require.NoError(t, err)
+
+	for range 3 {
+		visit, err := svc.Peek(ctx, "report", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "https://files.example.com/report.pdf", visit.URL)
+	}
+
+	_, err = svc.Visit(ctx, "report", nil)
+	require.NoError(t, err)
+
+	_, err = svc.Peek(ctx, "report", nil)
+	assert.ErrorIs(t, err, shortener.ErrLinkExhausted)
+
+	_, err = svc.Visit(ctx, "report", nil)
+	assert.ErrorIs(t, err, shortener.ErrLinkExhausted)
+
+	assert.Eventually(t, func() bool { return store.recorded("report") == 1 }, time.Second, 10*time.Millisecond,
+		"clicks were not recorded in the store")
+}
+
+func TestSchedule(t *testing.T) {
+	ctx := context.Background()
+
+	store := newMemStore()
+	cache := newMemCache()
+	svc := shortener.New(store, cache, shortener.Config{}, nil)
+
+	launch := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	end := launch.AddDate(0, 0, 7)
+
+	opts := shortener.Options{ActiveFrom: &launch, ActiveUntil: &end, MaxClicks: 10}
+
+	_, err := svc.ShortenWith(ctx, "https://shop.example.com/launch", "launch", opts, "")
+	require.NoError(t, err)
+
+	at := func(now time.Time) context.Context {
+		return shortener.WithVisitor(ctx, shortener.Visitor{Time: now})
+	}
+
+	_, err = svc.Visit(at(launch.Add(-time.Minute)), "launch", nil)
+	assert.ErrorIs(t, err, shortener.ErrLinkNotActive)
+
+	var notActive *shortener.NotActiveError
+	if assert.ErrorAs(t, err, &notActive) {
+		assert.Equal(t, launch, notActive.From)
+	}
+
+	// visits before the window are not counted
+	assert.Zero(t, store.recorded("launch"))
+
+	visit, err := svc.Visit(at(launch), "launch", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://shop.example.com/launch", visit.URL)
+
+	select {
+	case <-cache.set:
+	case <-time.After(time.Second):
+		t.Fatal("resolved link was not cached")
+	}
+
+	// the cached link ends with its window
+	store.err = errors.New("connection refused")
+
+	_, err = svc.Visit(at(end.Add(-time.Second)), "launch", nil)
+	require.NoError(t, err)
+
+	_, err = svc.Visit(at(end), "launch", nil)
+	assert.ErrorIs(t, err, shortener.ErrLinkExpired)
+
+	store.err = nil
+
+	_, err = svc.ShortenWith(ctx, "https://shop.example.com/launch", "", shortener.Options{ActiveFrom: &end, ActiveUntil: &launch}, "")
+
+	var optsErr *shortener.OptionsError
+	if assert.ErrorAs(t, err, &optsErr) {
+		assert.Equal(t, "active_until", optsErr.Field)
+	}
+}
@@ -0,0 +1,185 @@
+package shortener
+
+import (
+	"context"
+	"time"
+)
+
+// The types links are made of. Stores of this repository use them as
+// they are, stores written outside of it implement Store with them.
+
+// Passthrough tells how the query of a short url is merged into the url
+// of the link.
+type Passthrough string
+
+const (
+	// PassthroughOff drops the query of the short url.
+	PassthroughOff Passthrough = ""
+	// PassthroughIncoming adds the query of the short url, its values
+	// replace the ones of the url.
+	PassthroughIncoming Passthrough = "incoming"
+	// PassthroughDestination adds the parameters of the short url the url
+	// does not have.
+	PassthroughDestination Passthrough = "destination"
+)
+
+// UTM are the campaign parameters added to the url of a link on redirect,
+// unless the url has them.
+type UTM struct {
+	Source   string `json:"source,omitempty" xml:"source" form:"source"`
+	Medium   string `json:"medium,omitempty" xml:"medium" form:"medium"`
+	Campaign string `json:"campaign,omitempty" xml:"campaign" form:"campaign"`
+	Term     string `json:"term,omitempty" xml:"term" form:"term"`
+	Content  string `json:"content,omitempty" xml:"content" form:"content"`
+}
+
+// Params returns the query parameters of u in the order of the fields,
+// empty ones left out.
+func (u UTM) Params() [][2]string {
+	var params [][2]string
+
+	for _, p := range [][2]string{
+		{"utm_source", u.Source},
+		{"utm_medium", u.Medium},
+		{"utm_campaign", u.Campaign},
+		{"utm_term", u.Term},
+		{"utm_content", u.Content},
+	} {
+		if p[1] != "" {
+			params = append(params, p)
+		}
+	}
+
+	return params
+}
+
+// Options change how a link redirects.
+type Options struct {
+	Passthrough Passthrough `json:"passthrough,omitempty"`
+	UTM         *UTM        `json:"utm,omitempty"`
+	// Interstitial shows visitors a warning page with the url before they
+	// leave.
+	Interstitial bool `json:"interstitial,omitempty"`
+	// Rules send visits to other urls than the one of the link, the first
+	// matching rule wins.
+	Rules []Rule `json:"rules,omitempty"`
+	// Variants split the visits no rule matches between urls by weight.
+	Variants []Variant `json:"variants,omitempty"`
+	// MaxClicks is how many redirects the link serves before it is
+	// exhausted, unlimited when zero.
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	// ActiveFrom and ActiveUntil bound when the link redirects, ActiveUntil
+	// excluded. Either may be nil.
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+}
+
+// IsZero reports whether o redirects like a link without options.
+func (o Options) IsZero() bool {
+	return o.Passthrough == PassthroughOff && (o.UTM == nil || *o.UTM == UTM{}) && !o.Interstitial &&
+		len(o.Rules) == 0 && len(o.Variants) == 0 && o.MaxClicks == 0 && o.ActiveFrom == nil && o.ActiveUntil == nil
+}
+
+// Platform is the operating system family a User-Agent names.
+type Platform string
+
+const (
+	PlatformIOS     Platform = "ios"
+	PlatformAndroid Platform = "android"
+	PlatformWindows Platform = "windows"
+	PlatformMacOS   Platform = "macos"
+	PlatformLinux   Platform = "linux"
+)
+
+// Rule sends the visits matching all of its conditions to URL. Conditions
+// listing several values match any of them, empty ones match every visit.
+type Rule struct {
+	URL string `json:"url" xml:"url" form:"url"`
+	// Platforms are matched against the User-Agent.
+	Platforms []Platform `json:"platforms,omitempty" xml:"platform" form:"platforms"`
+	// Languages are matched against the most preferred language of
+	// Accept-Language, de matching de-AT as well.
+	Languages []string `json:"languages,omitempty" xml:"language" form:"languages"`
+	// Countries are ISO 3166 codes matched against the country header.
+	Countries []string `json:"countries,omitempty" xml:"country" form:"countries"`
+	// From and Until bound the time of the visit, Until excluded.
+	From  *time.Time `json:"from,omitempty" xml:"from" form:"from"`
+	Until *time.Time `json:"until,omitempty" xml:"until" form:"until"`
+	// Header is matched against the headers of the visit.
+	Header *HeaderCondition `json:"header,omitempty" xml:"header" form:"header"`
+}
+
+// HeaderCondition matches visits sending the header Name with Value, or
+// with any value when Value is empty.
+type HeaderCondition struct {
+	Name  string `json:"name" xml:"name" form:"name"`
+	Value string `json:"value,omitempty" xml:"value" form:"value"`
+}
+
+// Variant is one of the urls of a link split by weight, a variant with
+// weight 3 getting three times the visits of one with weight 1.
+type Variant struct {
+	URL    string `json:"url" xml:"url" form:"url"`
+	Weight int    `json:"weight" xml:"weight" form:"weight"`
+}
+
+// Target is what redirecting to a link takes, it is cached by alias.
+type Target struct {
+	URL string `json:"url"`
+	Options
+	// PasswordHash is the hash of the password protecting the link, empty
+	// for links anyone may visit.
+	PasswordHash string `json:"password_hash,omitempty"`
+	// Clicks are the redirects the store counted for a click-limited link
+	// when it was read. It changes with every redirect, so it is not
+	// cached.
+	Clicks int64 `json:"-"`
+
+	// CreatedAt and Metadata describe the link on the pages shown to its
+	// visitors, so that they are cached with it.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	Metadata  *Metadata `json:"metadata,omitempty"`
+}
+
+// IsPlain reports whether t is a bare url, without options or password.
+func (t Target) IsPlain() bool {
+	return t.Options.IsZero() && t.PasswordHash == ""
+}
+
+// Metadata describes the page a link points to. Fields the page does not
+// have are empty.
+type Metadata struct {
+	Title         string    `json:"title,omitempty"`
+	OGTitle       string    `json:"og_title,omitempty"`
+	OGDescription string    `json:"og_description,omitempty"`
+	OGImage       string    `json:"og_image,omitempty"`
+	FetchedAt     time.Time `json:"fetched_at"`
+}
+
+// Domain is a host links are served on. Every domain has its own aliases.
+type Domain struct {
+	Name string `json:"name"`
+	// AliasLength is the length of generated aliases, the default of the
+	// server when zero.
+	AliasLength int `json:"alias_length,omitempty"`
+	// NotFoundURL is where unknown aliases redirect to instead of
+	// answering 404.
+	NotFoundURL string `json:"not_found_url,omitempty"`
+	// CreatedAt is nil for configured domains.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type domainKey struct{}
+
+// WithDomain returns a copy of ctx scoping the aliases used with it to d.
+// Stores read and write aliases in the namespace of the domain of ctx.
+func WithDomain(ctx context.Context, d Domain) context.Context {
+	return context.WithValue(ctx, domainKey{}, d)
+}
+
+// DomainFrom returns the domain of ctx, the zero Domain of DefaultDomain
+// without one.
+func DomainFrom(ctx context.Context) Domain {
+	d, _ := ctx.Value(domainKey{}).(Domain)
+	return d
+}
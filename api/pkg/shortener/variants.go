@@ -0,0 +1,79 @@
+package shortener
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+)
+
+const (
+	// maxVariants is the most variants a link may have.
+	maxVariants = 10
+	// maxWeight is the highest weight of a variant.
+	maxWeight = 1000
+)
+
+// PickVariant returns the index of the variant key falls on, every variant
+// getting a share of the keys in proportion to its weight. The same key
+// always picks the same variant, changed weights only move the keys of
+// the shares that changed. An empty key picks a random variant. variants
+// must be valid.
+func PickVariant(variants []Variant, key string) int {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+
+	// point is where in [0, 1) key falls, the variants taking up the
+	// interval in order
+	var point float64
+	if key == "" {
+		point = rand.Float64()
+	} else {
+		sum := sha256.Sum256([]byte(key))
+		point = float64(binary.BigEndian.Uint64(sum[:])>>11) / (1 << 53)
+	}
+
+	n := point * float64(total)
+	for i, v := range variants {
+		if n < float64(v.Weight) {
+			return i
+		}
+		n -= float64(v.Weight)
+	}
+	return len(variants) - 1
+}
+
+// variantKey returns the key the visitor key picks the variant of alias
+// with, so a visitor does not fall on the same share of every link.
+func variantKey(alias, key string) string {
+	if key == "" {
+		return ""
+	}
+	return alias + "\x00" + key
+}
+
+// validateVariants reports the first invalid variant as an *OptionsError.
+func validateVariants(variants []Variant) error {
+	switch {
+	case len(variants) == 1:
+		return &OptionsError{Field: "variants", Reason: "must be at least 2"}
+	case len(variants) > maxVariants:
+		return &OptionsError{Field: "variants", Reason: fmt.Sprintf("must be at most %d", maxVariants)}
+	}
+
+	for i, v := range variants {
+		field := func(name string) string { return fmt.Sprintf("variants[%d].%s", i, name) }
+
+		if err := validateURL(v.URL); err != nil {
+			return &OptionsError{Field: field("url"), Reason: "must be an http(s) url"}
+		}
+
+		if v.Weight < 1 || v.Weight > maxWeight {
+			return &OptionsError{Field: field("weight"), Reason: fmt.Sprintf("must be between 1 and %d", maxWeight)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package shortener_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/Pshimaf-Git/url-shortener/api/pkg/shortener"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants := []shortener.Variant{
+		{URL: "https://example.com/a", Weight: 70},
+		{URL: "https://example.com/b", Weight: 30},
+	}
+
+	const visitors = 10000
+
+	picked := make([]int, len(variants))
+	for i := range visitors {
+		key := strconv.Itoa(i)
+
+		v := shortener.PickVariant(variants, key)
+		picked[v]++
+
+		// the same visitor always gets the same variant
+		assert.Equal(t, v, shortener.PickVariant(variants, key))
+	}
+
+	assert.InDelta(t, 0.7, float64(picked[0])/visitors, 0.03)
+	assert.InDelta(t, 0.3, float64(picked[1])/visitors, 0.03)
+
+	picked = make([]int, len(variants))
+	for range visitors {
+		picked[shortener.PickVariant(variants, "")]++
+	}
+
+	assert.InDelta(t, 0.7, float64(picked[0])/visitors, 0.03)
+	assert.InDelta(t, 0.3, float64(picked[1])/visitors, 0.03)
+}
+
+func TestPickVariant_Reweighted(t *testing.T) {
+	before := []shortener.Variant{
+		{URL: "https://example.com/a", Weight: 70},
+		{URL: "https://example.com/b", Weight: 30},
+	}
+	after := []shortener.Variant{
+		{URL: "https://example.com/a", Weight: 6},
+		{URL: "https://example.com/b", Weight: 4},
+	}
+
+	const visitors = 10000
+
+	moved := 0
+	for i := range visitors {
+		key := strconv.Itoa(i)
+
+		was, is := shortener.PickVariant(before, key), shortener.PickVariant(after, key)
+		if was != is {
+			// only visitors of the shrunk share move
+			assert.Equal(t, 0, was)
+			moved++
+		}
+	}
+
+	assert.InDelta(t, 0.1, float64(moved)/visitors, 0.02)
+}
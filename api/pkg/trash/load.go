@@ -0,0 +1,16 @@
+package trash
+
+import (
+	"log/slog"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/config"
+)
+
+// Load returns the Purger configured by cfg. log may be nil.
+func Load(cfg *config.TrashConfig, store Store, log *slog.Logger) *Purger {
+	return New(store, Config{
+		Retention: cfg.Retention,
+		Interval:  cfg.PurgeInterval,
+		BatchSize: cfg.BatchSize,
+	}, log)
+}
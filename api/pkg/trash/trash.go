@@ -0,0 +1,118 @@
+// Package trash purges deleted links once they are past the retention.
+// Until then a link in the trash can be restored and keeps its alias.
+//
+//	purger := trash.New(db, trash.Config{Retention: 7 * 24 * time.Hour}, log)
+//	go purger.Run(ctx)
+package trash
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/sl"
+	"github.com/Pshimaf-Git/url-shortener/api/internal/lib/wraper"
+)
+
+const (
+	DefaultRetention = 30 * 24 * time.Hour
+	DefaultInterval  = time.Hour
+	DefaultBatchSize = 1000
+)
+
+// Store removes deleted links. database.Database implementations satisfy
+// it.
+type Store interface {
+	PurgeURLs(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// Config tunes a Purger. The zero value uses the defaults.
+type Config struct {
+	// Retention is how long a deleted link can be restored.
+	Retention time.Duration
+	// Interval is how often Run purges.
+	Interval time.Duration
+	// BatchSize is the most links removed by one statement.
+	BatchSize int
+}
+
+// Purger removes links deleted longer than the retention ago.
+type Purger struct {
+	store Store
+	cfg   Config
+	log   *slog.Logger
+	now   func() time.Time
+}
+
+// New returns a Purger over store. log may be nil.
+func New(store Store, cfg Config, log *slog.Logger) *Purger {
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRetention
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if log == nil {
+		log = slog.New(slog.DiscardHandler)
+	}
+
+	return &Purger{
+		store: store,
+		cfg:   cfg,
+		log:   log,
+		now:   time.Now,
+	}
+}
+
+// Retention returns how long a deleted link can be restored.
+func (p *Purger) Retention() time.Duration {
+	return p.cfg.Retention
+}
+
+// Run purges every Interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			p.log.Error("trash purge", sl.Error(err))
+		}
+		if n > 0 {
+			p.log.Info("purged deleted links", slog.Int64("count", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce removes every link past the retention, a batch at a time, and
+// returns how many were removed.
+func (p *Purger) RunOnce(ctx context.Context) (int64, error) {
+	const fn = "trash.(*Purger).RunOnce"
+
+	wp := wraper.New(fn)
+
+	before := p.now().Add(-p.cfg.Retention)
+
+	var total int64
+	for {
+		n, err := p.store.PurgeURLs(ctx, before, p.cfg.BatchSize)
+		total += n
+		if err != nil {
+			return total, wp.Wrap(err)
+		}
+
+		if n < int64(p.cfg.BatchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore keeps the deletion times of the links in the trash.
+type memStore struct {
+	deleted []time.Time
+	calls   int
+	err     error
+}
+
+func (s *memStore) PurgeURLs(_ context.Context, before time.Time, limit int) (int64, error) {
+	s.calls++
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	var (
+		kept []time.Time
+		n    int64
+	)
+	for _, at := range s.deleted {
+		if at.Before(before) && n < int64(limit) {
+			n++
+			continue
+		}
+		kept = append(kept, at)
+	}
+	s.deleted = kept
+	return n, nil
+}
+
+func TestRunOnce(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	store := &memStore{}
+	for i := range 5 {
+		store.deleted = append(store.deleted, now.Add(-48*time.Hour-time.Duration(i)*time.Minute))
+	}
+	store.deleted = append(store.deleted, now.Add(-time.Hour))
+
+	p := New(store, Config{Retention: 24 * time.Hour, BatchSize: 2}, nil)
+	p.now = func() time.Time { return now }
+
+	n, err := p.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	// batches run until one comes back short
+	assert.Equal(t, 3, store.calls)
+	// links within the retention stay restorable
+	assert.Equal(t, []time.Time{now.Add(-time.Hour)}, store.deleted)
+
+	n, err = p.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestRunOnce_Error(t *testing.T) {
+	errDown := errors.New("database down")
+
+	p := New(&memStore{err: errDown}, Config{}, nil)
+
+	_, err := p.RunOnce(context.Background())
+	assert.ErrorIs(t, err, errDown)
+}
+
+func TestNew_Defaults(t *testing.T) {
+	p := New(&memStore{}, Config{}, nil)
+
+	assert.Equal(t, DefaultRetention, p.Retention())
+	assert.Equal(t, DefaultInterval, p.cfg.Interval)
+	assert.Equal(t, DefaultBatchSize, p.cfg.BatchSize)
+}